@@ -0,0 +1,210 @@
+package aml
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// DefaultQueueSize bounds the committed transactions waiting for the
+// asynchronous rules.
+const DefaultQueueSize = 1024
+
+// Engine evaluates transactions against the rules. Blocking rules run in
+// Screen, inside the transfer's database transaction, so a hit can stop the
+// transfer before it moves money. The other rules run on committed
+// transactions handed to Observe, in the background once Run has started.
+type Engine struct {
+	db       *gorm.DB
+	rules    []Rule
+	blocking map[string]bool
+	queue    chan observed
+
+	// mu guards running, so Observe never queues a transaction after Run
+	// has drained the queue for the last time.
+	mu      sync.RWMutex
+	running bool
+}
+
+type observed struct {
+	ctx         context.Context
+	transaction models.Transaction
+}
+
+// NewEngine returns an engine running rules, of which those named in
+// blocking are run synchronously by Screen.
+func NewEngine(db *gorm.DB, rules []Rule, blocking []string) (*Engine, error) {
+	e := &Engine{
+		db:       db,
+		rules:    rules,
+		blocking: map[string]bool{},
+		queue:    make(chan observed, DefaultQueueSize),
+	}
+	for _, name := range blocking {
+		found := false
+		for _, rule := range rules {
+			found = found || rule.Name() == name
+		}
+		if !found {
+			return nil, fmt.Errorf("aml: unknown blocking rule %q", name)
+		}
+		e.blocking[name] = true
+	}
+	return e, nil
+}
+
+// Screen runs the blocking rules on t before it is committed, reading
+// history through tx. It returns an alert per rule that fired; the caller
+// holds the transfer when there are any.
+func (e *Engine) Screen(ctx context.Context, tx *gorm.DB, t *models.Transaction) (_ []models.AMLAlert, err error) {
+	_, span := tracing.Start(ctx, "aml.Engine.Screen")
+	defer tracing.End(span, &err)
+
+	alerts, err := e.evaluate(tx, t, time.Now(), func(rule Rule) bool { return e.blocking[rule.Name()] })
+	span.SetAttributes(attribute.Int("aml.alerts", len(alerts)))
+	return alerts, err
+}
+
+// Observe hands a committed transaction to the asynchronous rules. Without a
+// running worker, or when the queue is full, they run before Observe
+// returns, so no transaction goes unscreened.
+func (e *Engine) Observe(ctx context.Context, t models.Transaction) {
+	ctx = context.WithoutCancel(ctx)
+
+	e.mu.RLock()
+	if e.running {
+		select {
+		case e.queue <- observed{ctx: ctx, transaction: t}:
+			e.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	e.mu.RUnlock()
+	e.process(ctx, t)
+}
+
+// Run evaluates observed transactions until ctx is cancelled, then works
+// off whatever is still queued.
+func (e *Engine) Run(ctx context.Context) {
+	e.mu.Lock()
+	e.running = true
+	e.mu.Unlock()
+
+	for {
+		select {
+		case o := <-e.queue:
+			e.process(o.ctx, o.transaction)
+		case <-ctx.Done():
+			e.mu.Lock()
+			e.running = false
+			e.mu.Unlock()
+			for {
+				select {
+				case o := <-e.queue:
+					e.process(o.ctx, o.transaction)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// process runs the rules Screen has not already run on t and opens a case
+// if any fire. Transfers were screened by the blocking rules before commit;
+// other transactions were not, so every rule applies to them.
+func (e *Engine) process(ctx context.Context, t models.Transaction) {
+	var err error
+	ctx, span := tracing.Start(ctx, "aml.Engine.Observe",
+		attribute.Int64("transaction.id", int64(t.ID)),
+	)
+	defer tracing.End(span, &err)
+
+	screened := t.Type == models.TransactionTypeTransfer
+	alerts, err := e.evaluate(e.db.WithContext(ctx), &t, t.CreatedAt, func(rule Rule) bool {
+		return !screened || !e.blocking[rule.Name()]
+	})
+	if err == nil && len(alerts) > 0 {
+		// The case belongs to the transaction's tenant whichever context
+		// the transaction was observed in.
+		for i := range alerts {
+			alerts[i].TenantID = t.TenantID
+		}
+		amlCase := &models.AMLCase{
+			TenantID:      t.TenantID,
+			TransactionID: t.ID,
+			WalletID:      actingWallet(&t),
+			Status:        models.AMLCaseStatusOpen,
+			Alerts:        alerts,
+		}
+		if err = repositories.NewAMLCaseRepository(e.db.WithContext(ctx)).Create(amlCase); err == nil {
+			logCase(ctx, amlCase)
+		}
+	}
+	if err != nil {
+		logging.For("aml").ErrorContext(ctx, "screening failed",
+			slog.Uint64("transaction_id", uint64(t.ID)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (e *Engine) evaluate(db *gorm.DB, t *models.Transaction, now time.Time, run func(Rule) bool) ([]models.AMLAlert, error) {
+	h := history{
+		transactions: repositories.NewTransactionRepository(db),
+		wallets:      repositories.NewWalletRepository(db),
+	}
+	var alerts []models.AMLAlert
+	for _, rule := range e.rules {
+		if !run(rule) {
+			continue
+		}
+		hit, err := rule.Evaluate(h, t, now)
+		if err != nil {
+			return nil, fmt.Errorf("aml rule %s: %w", rule.Name(), err)
+		}
+		if hit != nil {
+			alerts = append(alerts, models.AMLAlert{Rule: hit.Rule, Severity: hit.Severity, Details: hit.Details})
+		}
+	}
+	return alerts, nil
+}
+
+// logCase records a newly opened case.
+func logCase(ctx context.Context, amlCase *models.AMLCase) {
+	rules := make([]string, len(amlCase.Alerts))
+	for i, alert := range amlCase.Alerts {
+		rules[i] = alert.Rule
+	}
+	logging.For("aml").WarnContext(ctx, "aml case opened",
+		slog.Uint64("case_id", uint64(amlCase.ID)),
+		slog.Uint64("transaction_id", uint64(amlCase.TransactionID)),
+		slog.Uint64("wallet_id", uint64(amlCase.WalletID)),
+		slog.Bool("held", amlCase.Held),
+		slog.Any("rules", rules),
+	)
+}
+
+type history struct {
+	transactions *repositories.TransactionRepository
+	wallets      *repositories.WalletRepository
+}
+
+func (h history) Activity(walletID uint, since time.Time) ([]models.Transaction, error) {
+	return h.transactions.ActivitySince(walletID, since)
+}
+
+func (h history) Wallet(id uint) (*models.Wallet, error) {
+	return h.wallets.GetByID(id)
+}
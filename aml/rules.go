@@ -0,0 +1,182 @@
+// Package aml monitors money movements for signs of money laundering. Rules
+// look at a transaction together with the recent activity of the wallets
+// involved and report a Hit when it matches a suspicious pattern; the
+// Engine turns hits into cases for analysts.
+package aml
+
+import (
+	"fmt"
+	"time"
+
+	"wallet-api/models"
+)
+
+// Rule names, as used in the aml.blocking configuration.
+const (
+	RuleStructuring           = "structuring"
+	RuleRapidMovement         = "rapid_movement"
+	RuleNewWalletCounterparty = "new_wallet_counterparty"
+)
+
+// History is what rules may look back at.
+type History interface {
+	// Activity returns the completed transactions into or out of a wallet
+	// created at or after since, oldest first.
+	Activity(walletID uint, since time.Time) ([]models.Transaction, error)
+	Wallet(id uint) (*models.Wallet, error)
+}
+
+// Hit is a rule firing on a transaction.
+type Hit struct {
+	Rule     string
+	Severity string
+	Details  string
+}
+
+// Rule checks one transaction. now is when the transaction is being
+// screened, or when it was committed if it is evaluated afterwards. t may
+// not have been saved yet, so rules must not expect it in History; once it
+// has been, it is skipped there by ID.
+type Rule interface {
+	Name() string
+	Evaluate(h History, t *models.Transaction, now time.Time) (*Hit, error)
+}
+
+// Structuring flags a wallet making Count or more transactions just under
+// the reporting Threshold within Window, the classic way of keeping each
+// one below the amount that would be reported. "Just under" means within
+// Margin (a fraction of Threshold) below it. Transfers are counted against
+// the sending wallet, deposits against the receiving one.
+type Structuring struct {
+	Threshold int64
+	Margin    float64
+	Count     int
+	Window    time.Duration
+}
+
+func (r Structuring) Name() string { return RuleStructuring }
+
+func (r Structuring) Evaluate(h History, t *models.Transaction, now time.Time) (*Hit, error) {
+	floor := r.Threshold - int64(float64(r.Threshold)*r.Margin)
+	inBand := func(amount int64) bool { return amount >= floor && amount < r.Threshold }
+	if !inBand(t.Amount) {
+		return nil, nil
+	}
+
+	walletID := actingWallet(t)
+	activity, err := h.Activity(walletID, now.Add(-r.Window))
+	if err != nil {
+		return nil, err
+	}
+	count := 1
+	for _, prior := range activity {
+		if isSelf(&prior, t) || actingWallet(&prior) != walletID || prior.Type != t.Type {
+			continue
+		}
+		if inBand(prior.Amount) {
+			count++
+		}
+	}
+	if count < r.Count {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:     r.Name(),
+		Severity: models.AMLSeverityHigh,
+		Details: fmt.Sprintf("%d %ss between %d and %d by wallet %d within %s",
+			count, t.Type, floor, r.Threshold, walletID, r.Window),
+	}, nil
+}
+
+// RapidMovement flags a transfer that sends on at least Ratio of what its
+// wallet received within Window: money passing straight through a wallet.
+// Windows that received less than MinAmount are ignored.
+type RapidMovement struct {
+	Window    time.Duration
+	Ratio     float64
+	MinAmount int64
+}
+
+func (r RapidMovement) Name() string { return RuleRapidMovement }
+
+func (r RapidMovement) Evaluate(h History, t *models.Transaction, now time.Time) (*Hit, error) {
+	if t.SourceWalletID == nil {
+		return nil, nil
+	}
+
+	walletID := *t.SourceWalletID
+	activity, err := h.Activity(walletID, now.Add(-r.Window))
+	if err != nil {
+		return nil, err
+	}
+	var received int64
+	sent := t.Amount
+	for _, prior := range activity {
+		switch {
+		case isSelf(&prior, t):
+		case prior.TargetWalletID == walletID:
+			received += prior.Amount
+		default:
+			sent += prior.Amount
+		}
+	}
+	if received == 0 || received < r.MinAmount || float64(sent) < r.Ratio*float64(received) {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:     r.Name(),
+		Severity: models.AMLSeverityMedium,
+		Details: fmt.Sprintf("wallet %d sent on %d of %d received within %s",
+			walletID, sent, received, r.Window),
+	}, nil
+}
+
+// NewWalletCounterparty flags a transfer of at least MinAmount to another
+// user's wallet that was created less than MaxAge ago.
+type NewWalletCounterparty struct {
+	MaxAge    time.Duration
+	MinAmount int64
+}
+
+func (r NewWalletCounterparty) Name() string { return RuleNewWalletCounterparty }
+
+func (r NewWalletCounterparty) Evaluate(h History, t *models.Transaction, now time.Time) (*Hit, error) {
+	if t.SourceWalletID == nil || t.Amount < r.MinAmount {
+		return nil, nil
+	}
+
+	target, err := h.Wallet(t.TargetWalletID)
+	if err != nil {
+		return nil, err
+	}
+	age := now.Sub(target.CreatedAt)
+	if age >= r.MaxAge {
+		return nil, nil
+	}
+	source, err := h.Wallet(*t.SourceWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if source.UserID == target.UserID {
+		return nil, nil
+	}
+	return &Hit{
+		Rule:     r.Name(),
+		Severity: models.AMLSeverityMedium,
+		Details: fmt.Sprintf("%d sent to wallet %d of user %d, created %s before",
+			t.Amount, target.ID, target.UserID, age.Round(time.Second)),
+	}, nil
+}
+
+// actingWallet is the wallet that initiated t: the sender of a transfer, the
+// receiver of a deposit.
+func actingWallet(t *models.Transaction) uint {
+	if t.SourceWalletID != nil {
+		return *t.SourceWalletID
+	}
+	return t.TargetWalletID
+}
+
+func isSelf(prior, t *models.Transaction) bool {
+	return t.ID != 0 && prior.ID == t.ID
+}
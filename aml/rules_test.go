@@ -0,0 +1,160 @@
+package aml
+
+import (
+	"testing"
+	"time"
+
+	"wallet-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeHistory serves rules from memory.
+type fakeHistory struct {
+	transactions []models.Transaction
+	wallets      map[uint]models.Wallet
+}
+
+func (h *fakeHistory) Activity(walletID uint, since time.Time) ([]models.Transaction, error) {
+	var out []models.Transaction
+	for _, t := range h.transactions {
+		involved := t.TargetWalletID == walletID || (t.SourceWalletID != nil && *t.SourceWalletID == walletID)
+		if involved && !t.CreatedAt.Before(since) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (h *fakeHistory) Wallet(id uint) (*models.Wallet, error) {
+	w, ok := h.wallets[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &w, nil
+}
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func transfer(id, from, to uint, amount int64, at time.Time) models.Transaction {
+	return models.Transaction{
+		ID: id, SourceWalletID: &from, TargetWalletID: to, Amount: amount,
+		Type: models.TransactionTypeTransfer, Status: models.TransactionStatusCompleted, CreatedAt: at,
+	}
+}
+
+func deposit(id, to uint, amount int64, at time.Time) models.Transaction {
+	return models.Transaction{
+		ID: id, TargetWalletID: to, Amount: amount,
+		Type: models.TransactionTypeDeposit, Status: models.TransactionStatusCompleted, CreatedAt: at,
+	}
+}
+
+func TestStructuring(t *testing.T) {
+	rule := Structuring{Threshold: 10_000, Margin: 0.1, Count: 3, Window: 24 * time.Hour}
+
+	h := &fakeHistory{transactions: []models.Transaction{
+		deposit(1, 1, 9_500, now.Add(-2*time.Hour)),
+		deposit(2, 1, 9_900, now.Add(-time.Hour)),
+		deposit(3, 1, 5_000, now.Add(-time.Hour)),    // not just under
+		deposit(4, 2, 9_800, now.Add(-time.Hour)),    // another wallet
+		deposit(5, 1, 9_700, now.Add(-30*time.Hour)), // outside the window
+	}}
+
+	t.Run("third just under the threshold", func(t *testing.T) {
+		next := deposit(0, 1, 9_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		require.NotNil(t, hit)
+		assert.Equal(t, RuleStructuring, hit.Rule)
+		assert.Equal(t, models.AMLSeverityHigh, hit.Severity)
+		assert.Contains(t, hit.Details, "3 deposits between 9000 and 10000")
+	})
+
+	t.Run("at the threshold", func(t *testing.T) {
+		next := deposit(0, 1, 10_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		assert.Nil(t, hit, "amounts that will be reported anyway are not structuring")
+	})
+
+	t.Run("committed transaction is not counted twice", func(t *testing.T) {
+		hit, err := rule.Evaluate(h, &h.transactions[1], now)
+		require.NoError(t, err)
+		assert.Nil(t, hit)
+	})
+
+	t.Run("transfers are counted separately", func(t *testing.T) {
+		next := transfer(0, 1, 2, 9_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		assert.Nil(t, hit)
+	})
+}
+
+func TestRapidMovement(t *testing.T) {
+	rule := RapidMovement{Window: time.Hour, Ratio: 0.9, MinAmount: 1_000}
+
+	h := &fakeHistory{transactions: []models.Transaction{
+		deposit(1, 1, 5_000, now.Add(-40*time.Minute)),
+		transfer(2, 3, 1, 5_000, now.Add(-20*time.Minute)),
+		transfer(3, 1, 2, 4_000, now.Add(-10*time.Minute)),
+		deposit(4, 4, 500, now.Add(-10*time.Minute)),
+	}}
+
+	t.Run("most of what came in goes out", func(t *testing.T) {
+		next := transfer(0, 1, 2, 5_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		require.NotNil(t, hit)
+		assert.Equal(t, "wallet 1 sent on 9000 of 10000 received within 1h0m0s", hit.Details)
+	})
+
+	t.Run("below the ratio", func(t *testing.T) {
+		next := transfer(0, 1, 2, 4_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		assert.Nil(t, hit)
+	})
+
+	t.Run("small amounts received are ignored", func(t *testing.T) {
+		next := transfer(0, 4, 2, 500, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		assert.Nil(t, hit)
+	})
+
+	t.Run("deposits are not screened", func(t *testing.T) {
+		next := deposit(0, 1, 50_000, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		assert.Nil(t, hit)
+	})
+}
+
+func TestNewWalletCounterparty(t *testing.T) {
+	rule := NewWalletCounterparty{MaxAge: 24 * time.Hour, MinAmount: 1_000}
+
+	h := &fakeHistory{wallets: map[uint]models.Wallet{
+		1: {ID: 1, UserID: 10, CreatedAt: now.Add(-90 * 24 * time.Hour)},
+		2: {ID: 2, UserID: 20, CreatedAt: now.Add(-2 * time.Hour)},
+		3: {ID: 3, UserID: 10, CreatedAt: now.Add(-time.Hour)},
+		4: {ID: 4, UserID: 20, CreatedAt: now.Add(-48 * time.Hour)},
+	}}
+
+	evaluate := func(to uint, amount int64) *Hit {
+		next := transfer(0, 1, to, amount, time.Time{})
+		hit, err := rule.Evaluate(h, &next, now)
+		require.NoError(t, err)
+		return hit
+	}
+
+	if hit := evaluate(2, 1_000); assert.NotNil(t, hit) {
+		assert.Equal(t, "1000 sent to wallet 2 of user 20, created 2h0m0s before", hit.Details)
+	}
+	assert.Nil(t, evaluate(2, 999), "below the minimum amount")
+	assert.Nil(t, evaluate(3, 5_000), "the sender's own new wallet")
+	assert.Nil(t, evaluate(4, 5_000), "wallet old enough")
+}
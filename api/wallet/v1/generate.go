@@ -0,0 +1,5 @@
+// Package walletv1 contains the protobuf messages and gRPC stubs generated
+// from wallet.proto.
+package walletv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative wallet/v1/wallet.proto
@@ -0,0 +1,158 @@
+// Package auth authenticates callers by API key. Middleware and the gRPC
+// interceptors turn the key a request presents into a Principal in its
+// context, which the rate limiter, the audit log and the handlers then use
+// to tell callers apart. Requests without a key are anonymous; routes that
+// need a role refuse them with Require.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidKey is returned for keys that match no API key, or a revoked
+// one.
+var ErrInvalidKey = errors.New("invalid API key")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	KeyID uint
+	Name  string
+	Role  string
+	// TenantID is the tenant the key belongs to.
+	TenantID uint
+	// UserID is the user a user key acts for.
+	UserID *uint
+}
+
+// PrincipalFor returns the principal authenticated by key.
+func PrincipalFor(key *models.APIKey) Principal {
+	return Principal{
+		KeyID:    key.ID,
+		Name:     key.Name,
+		Role:     key.Role,
+		TenantID: key.TenantID,
+		UserID:   key.UserID,
+	}
+}
+
+// Subject names the principal for rate limiting and the audit log: the
+// user a user key acts for, otherwise the key itself.
+func (p Principal) Subject() string {
+	if p.Role == models.APIKeyRoleUser && p.UserID != nil {
+		return "user:" + strconv.FormatUint(uint64(*p.UserID), 10)
+	}
+	return "api_key:" + strconv.FormatUint(uint64(p.KeyID), 10)
+}
+
+// Has reports whether the principal may act in role. Admins may do
+// everything operators may.
+func (p Principal) Has(role string) bool {
+	return p.Role == role || (p.Role == models.APIKeyRoleAdmin && role == models.APIKeyRoleOperator)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p. It also names p for the
+// rate limiter and as the actor of the audit log.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	ctx = ratelimit.WithPrincipal(ctx, p.Subject())
+	ctx = logging.WithActor(ctx, p.Subject())
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or false for anonymous
+// requests.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// KeyAuthenticator looks up the API key with the given secret, returning
+// ErrInvalidKey when there is none. services.APIKeyService implements it.
+type KeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+// Authenticator turns the API keys requests present into principals.
+type Authenticator struct {
+	keys KeyAuthenticator
+}
+
+func NewAuthenticator(keys KeyAuthenticator) *Authenticator {
+	return &Authenticator{keys: keys}
+}
+
+// authenticate returns ctx with the principal authenticated by header, the
+// raw Authorization value. An empty header leaves the call anonymous.
+func (a *Authenticator) authenticate(ctx context.Context, header string) (context.Context, error) {
+	if header == "" {
+		return ctx, nil
+	}
+	scheme, secret, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(secret) == "" {
+		return nil, ErrInvalidKey
+	}
+	key, err := a.keys.Authenticate(ctx, strings.TrimSpace(secret))
+	if err != nil {
+		return nil, err
+	}
+	return WithPrincipal(ctx, PrincipalFor(key)), nil
+}
+
+// Middleware authenticates the "Authorization: Bearer <key>" header,
+// answering 401 for keys that are unknown or revoked. Requests without the
+// header go on anonymously.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, err := a.authenticate(c.Request.Context(), c.GetHeader("Authorization"))
+		switch {
+		case errors.Is(err, ErrInvalidKey):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logging.For("auth").ErrorContext(c.Request.Context(), "API key lookup failed", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not authenticate request"})
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Require refuses requests that are anonymous with 401 and those whose
+// principal may not act in role with 403. It goes after Middleware.
+func Require(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := FromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "an API key is required"})
+			return
+		}
+		if !p.Has(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires the " + role + " role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// StaticKeys is a fixed set of API keys by secret, for tests.
+type StaticKeys map[string]models.APIKey
+
+func (k StaticKeys) Authenticate(_ context.Context, secret string) (*models.APIKey, error) {
+	key, ok := k[secret]
+	if !ok || key.Revoked() {
+		return nil, ErrInvalidKey
+	}
+	return &key, nil
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+var keys = StaticKeys{
+	"wk_user":     {ID: 1, TenantID: 2, Name: "alice's app", Role: models.APIKeyRoleUser, UserID: uintPtr(7)},
+	"wk_operator": {ID: 2, TenantID: 1, Name: "bob", Role: models.APIKeyRoleOperator},
+	"wk_admin":    {ID: 3, TenantID: 1, Name: "root", Role: models.APIKeyRoleAdmin},
+}
+
+type failingKeys struct{}
+
+func (failingKeys) Authenticate(context.Context, string) (*models.APIKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestPrincipal(t *testing.T) {
+	user := PrincipalFor(&models.APIKey{ID: 1, Role: models.APIKeyRoleUser, UserID: uintPtr(7)})
+	assert.Equal(t, "user:7", user.Subject())
+	assert.True(t, user.Has(models.APIKeyRoleUser))
+	assert.False(t, user.Has(models.APIKeyRoleOperator))
+
+	operator := PrincipalFor(&models.APIKey{ID: 2, Role: models.APIKeyRoleOperator})
+	assert.Equal(t, "api_key:2", operator.Subject())
+	assert.True(t, operator.Has(models.APIKeyRoleOperator))
+	assert.False(t, operator.Has(models.APIKeyRoleAdmin))
+
+	admin := PrincipalFor(&models.APIKey{ID: 3, Role: models.APIKeyRoleAdmin})
+	assert.True(t, admin.Has(models.APIKeyRoleOperator))
+	assert.True(t, admin.Has(models.APIKeyRoleAdmin))
+	assert.False(t, admin.Has(models.APIKeyRoleUser), "admins do not act for users")
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(a *Authenticator, header string, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, context.Context) {
+		var ctx context.Context
+		router := gin.New()
+		handlers = append([]gin.HandlerFunc{a.Middleware()}, handlers...)
+		router.GET("/", append(handlers, func(c *gin.Context) {
+			ctx = c.Request.Context()
+			c.Status(http.StatusNoContent)
+		})...)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		router.ServeHTTP(w, req)
+		return w, ctx
+	}
+	a := NewAuthenticator(keys)
+
+	t.Run("anonymous", func(t *testing.T) {
+		w, ctx := serve(a, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		_, ok := FromContext(ctx)
+		assert.False(t, ok)
+		assert.Empty(t, ratelimit.Principal(ctx))
+	})
+
+	t.Run("valid key", func(t *testing.T) {
+		w, ctx := serve(a, "Bearer wk_user")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		p, ok := FromContext(ctx)
+		if assert.True(t, ok) {
+			assert.Equal(t, uint(1), p.KeyID)
+			assert.Equal(t, uint(2), p.TenantID)
+		}
+		assert.Equal(t, "user:7", ratelimit.Principal(ctx))
+		assert.Equal(t, "user:7", logging.Actor(ctx))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		for _, header := range []string{"Bearer wk_unknown", "Basic d2tfdXNlcg==", "Bearer "} {
+			w, _ := serve(a, header)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+		}
+	})
+
+	t.Run("store failure", func(t *testing.T) {
+		w, _ := serve(NewAuthenticator(failingKeys{}), "Bearer wk_user")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("require", func(t *testing.T) {
+		w, _ := serve(a, "", Require(models.APIKeyRoleOperator))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = serve(a, "Bearer wk_user", Require(models.APIKeyRoleOperator))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = serve(a, "Bearer wk_operator", Require(models.APIKeyRoleOperator))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w, _ = serve(a, "Bearer wk_admin", Require(models.APIKeyRoleOperator))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
+
+func TestAuthenticator_UnaryServerInterceptor(t *testing.T) {
+	call := func(a *Authenticator, ctx context.Context) (Principal, bool, error) {
+		var p Principal
+		var ok bool
+		_, err := a.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{},
+			func(ctx context.Context, _ any) (any, error) {
+				p, ok = FromContext(ctx)
+				return nil, nil
+			})
+		return p, ok, err
+	}
+	withKey := func(value string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", value))
+	}
+	a := NewAuthenticator(keys)
+
+	_, ok, err := call(a, context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	p, ok, err := call(a, withKey("Bearer wk_operator"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bob", p.Name)
+
+	_, _, err = call(a, withKey("Bearer wk_unknown"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, _, err = call(NewAuthenticator(failingKeys{}), withKey("Bearer wk_user"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
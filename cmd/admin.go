@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"wallet-api/config"
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/services"
+	"wallet-api/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// adminEnv is what an admin command runs with: the same services the API
+// uses, a context naming the operator for the audit log and the tenant
+// acted on, and the output format.
+type adminEnv struct {
+	ctx       context.Context
+	users     *services.UserService
+	wallets   *services.WalletService
+	transfers *services.TransferService
+	apiKeys   *services.APIKeyService
+	json      bool
+	out       io.Writer
+}
+
+// runAdmin parses the command's flags and the configuration, connects to the
+// database and calls run with the leading numeric IDs from args, so commands
+// read as "wallet show 42 --json". It returns 2 for usage errors and 1 when
+// run fails.
+func runAdmin(fs *flag.FlagSet, args []string, ids []string, run func(env *adminEnv, ids []uint) error) int {
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	tenant := fs.Uint("tenant", models.DefaultTenantID, "ID of the tenant whose data the command acts on")
+
+	var parsed []uint
+	for _, name := range ids {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			fmt.Fprintf(os.Stderr, "usage: wallet-api %s <%s> [flags]\n", fs.Name(), name)
+			return 2
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid %s %q\n", name, args[0])
+			return 2
+		}
+		parsed, args = append(parsed, uint(id)), args[1:]
+	}
+
+	cfg, err := config.LoadWithFlags(fs, args, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	setupLogging(cfg.Logging)
+
+	db := openDB(cfg.Database, cfg.Logging)
+	userRepo := repositories.NewUserRepository(db)
+	walletRepo := repositories.NewWalletRepository(db)
+	// No worker runs here, so the AML rules screen deposits inline
+	monitor := transactionMonitor(newMonitor(cfg, db))
+	screener := newScreener(cfg.Sanctions)
+	// Likewise emails are sent inline, without the queue
+	mailer := newMailer(cfg.Mail)
+	templates := newTemplates()
+	verifier := services.NewEmailVerificationService(repositories.NewEmailVerificationRepository(db), userRepo, db,
+		mailer, templates, emailVerification(cfg.Mail))
+	transfers := services.NewTransferService(repositories.NewTransactionRepository(db), walletRepo, db, kycLimits(cfg.KYC),
+		monitor, transferScreening(cfg.Sanctions, screener), riskScorer(cfg),
+		transferNotifier(cfg, userRepo, walletRepo, mailer, templates))
+	env := &adminEnv{
+		ctx:       tenancy.WithTenant(logging.WithActor(context.Background(), operator()), *tenant),
+		users:     services.NewUserService(userRepo, db, screener, verifier),
+		wallets:   services.NewWalletService(walletRepo, userRepo, db),
+		transfers: transfers,
+		apiKeys:   services.NewAPIKeyService(repositories.NewAPIKeyRepository(db), userRepo, db),
+		json:      *jsonOut,
+		out:       os.Stdout,
+	}
+
+	if err := run(env, parsed); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Name(), err)
+		return 1
+	}
+	return 0
+}
+
+// operator names the person running the command in audit log records.
+func operator() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return "cli:" + name
+	}
+	return "cli:unknown"
+}
+
+// print writes v as indented JSON, or rows as aligned "key value" lines.
+func (env *adminEnv) print(v interface{}, rows [][2]string) error {
+	if env.json {
+		enc := json.NewEncoder(env.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(env.out, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+	}
+	return tw.Flush()
+}
+
+// notFound rewords gorm.ErrRecordNotFound for the terminal.
+func notFound(err error, what string, id uint) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %d not found", what, id)
+	}
+	return err
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func walletRows(w *models.Wallet) [][2]string {
+	frozen := "no"
+	if w.Frozen() {
+		frozen = fmt.Sprintf("since %s: %s", formatTime(w.FrozenAt), w.FrozenReason)
+	}
+	return [][2]string{
+		{"ID", strconv.FormatUint(uint64(w.ID), 10)},
+		{"User", strconv.FormatUint(uint64(w.UserID), 10)},
+		{"Balance", strconv.FormatInt(w.Balance, 10)},
+		{"Currency", w.Currency},
+		{"Frozen", frozen},
+		{"Created", formatTime(&w.CreatedAt)},
+		{"Updated", formatTime(&w.UpdatedAt)},
+	}
+}
+
+// runUser implements `wallet-api user show <id>`.
+func runUser(args []string) int {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "usage: wallet-api user show <id> [--json]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("user show", flag.ContinueOnError)
+	return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+		u, err := env.users.GetByID(env.ctx, ids[0])
+		if err != nil {
+			return notFound(err, "user", ids[0])
+		}
+		wallets, err := env.wallets.GetByUserID(env.ctx, u.ID)
+		if err != nil {
+			return err
+		}
+
+		walletIDs := make([]string, len(wallets))
+		for i, w := range wallets {
+			walletIDs[i] = fmt.Sprintf("%d (balance %d %s)", w.ID, w.Balance, w.Currency)
+		}
+		return env.print(
+			struct {
+				User    *models.User    `json:"user"`
+				Wallets []models.Wallet `json:"wallets"`
+			}{u, wallets},
+			[][2]string{
+				{"ID", strconv.FormatUint(uint64(u.ID), 10)},
+				{"Name", u.Name},
+				{"Email", u.Email},
+				{"KYC level", string(u.KYCLevel)},
+				{"Created", formatTime(&u.CreatedAt)},
+				{"Wallets", strings.Join(walletIDs, ", ")},
+			},
+		)
+	})
+}
+
+// runWallet implements `wallet-api wallet show|freeze|unfreeze <id>`.
+func runWallet(args []string) int {
+	const walletUsage = "usage: wallet-api wallet show|freeze|unfreeze <id> [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, walletUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("wallet "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "show":
+		return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+			w, err := env.wallets.GetByID(env.ctx, ids[0])
+			if err != nil {
+				return notFound(err, "wallet", ids[0])
+			}
+			return env.print(w, walletRows(w))
+		})
+	case "freeze":
+		reason := fs.String("reason", "", "why the wallet is frozen (required)")
+		return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+			w, err := env.wallets.Freeze(env.ctx, ids[0], *reason)
+			if err != nil {
+				return notFound(err, "wallet", ids[0])
+			}
+			return env.print(w, walletRows(w))
+		})
+	case "unfreeze":
+		return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+			w, err := env.wallets.Unfreeze(env.ctx, ids[0])
+			if err != nil {
+				return notFound(err, "wallet", ids[0])
+			}
+			return env.print(w, walletRows(w))
+		})
+	default:
+		fmt.Fprintln(os.Stderr, walletUsage)
+		return 2
+	}
+}
+
+// printTransaction prints t as the API's transaction history does.
+func (env *adminEnv) printTransaction(t *models.Transaction) error {
+	return env.print(models.TransferResponse{
+		ID:              t.ID,
+		SourceWalletID:  t.SourceWalletID,
+		TargetWalletID:  t.TargetWalletID,
+		Amount:          t.Amount,
+		Fee:             t.Fee,
+		Type:            t.Type,
+		ReferenceNumber: t.ReferenceNumber,
+		Status:          t.Status,
+		Description:     t.Description,
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+	}, transactionRows(t))
+}
+
+func transactionRows(t *models.Transaction) [][2]string {
+	source := "-"
+	if t.SourceWalletID != nil {
+		source = strconv.FormatUint(uint64(*t.SourceWalletID), 10)
+	}
+	return [][2]string{
+		{"ID", strconv.FormatUint(uint64(t.ID), 10)},
+		{"Reference", t.ReferenceNumber},
+		{"Type", string(t.Type)},
+		{"Status", t.Status},
+		{"Amount", strconv.FormatInt(t.Amount, 10)},
+		{"Fee", strconv.FormatInt(t.Fee, 10)},
+		{"Source", source},
+		{"Target", strconv.FormatUint(uint64(t.TargetWalletID), 10)},
+		{"Description", t.Description},
+		{"Created", formatTime(&t.CreatedAt)},
+	}
+}
+
+// runDeposit implements `wallet-api deposit --wallet --amount --reason`.
+func runDeposit(args []string) int {
+	fs := flag.NewFlagSet("deposit", flag.ContinueOnError)
+	walletID := fs.Uint("wallet", 0, "wallet to credit (required)")
+	amount := fs.Int64("amount", 0, "amount in minor units (required)")
+	reason := fs.String("reason", "", "why the deposit is made, stored on the transaction (required)")
+
+	return runAdmin(fs, args, nil, func(env *adminEnv, _ []uint) error {
+		if *walletID == 0 {
+			return errors.New("--wallet is required")
+		}
+		t, err := env.transfers.ManualDeposit(env.ctx, *walletID, *amount, *reason)
+		if err != nil {
+			return notFound(err, "wallet", *walletID)
+		}
+		return env.printTransaction(t)
+	})
+}
+
+// runTx implements `wallet-api tx show <id>`.
+func runTx(args []string) int {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "usage: wallet-api tx show <id> [--json]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("tx show", flag.ContinueOnError)
+	return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+		t, err := env.transfers.GetTransactionByID(env.ctx, ids[0])
+		if err != nil {
+			return notFound(err, "transaction", ids[0])
+		}
+		return env.printTransaction(t)
+	})
+}
+
+func apiKeyRows(k *models.APIKey) [][2]string {
+	user := "-"
+	if k.UserID != nil {
+		user = strconv.FormatUint(uint64(*k.UserID), 10)
+	}
+	return [][2]string{
+		{"ID", strconv.FormatUint(uint64(k.ID), 10)},
+		{"Name", k.Name},
+		{"Role", k.Role},
+		{"User", user},
+		{"Prefix", k.Prefix},
+		{"Created", formatTime(&k.CreatedAt)},
+		{"Revoked", formatTime(k.RevokedAt)},
+	}
+}
+
+// runAPIKey implements `wallet-api api-key create|list|revoke`. Keys are
+// issued in the --tenant tenant; admin keys act on any tenant.
+func runAPIKey(args []string) int {
+	const apiKeyUsage = "usage: wallet-api api-key create|list|revoke [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, apiKeyUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("api-key "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "create":
+		name := fs.String("name", "", "who or what the key is for (required)")
+		role := fs.String("role", models.APIKeyRoleUser, "user, operator or admin")
+		userID := fs.Uint("user", 0, "user a user key acts for")
+		return runAdmin(fs, args[1:], nil, func(env *adminEnv, _ []uint) error {
+			var user *uint
+			if *userID != 0 {
+				user = userID
+			}
+			secret, k, err := env.apiKeys.Issue(env.ctx, services.APIKeyDetails{Name: *name, Role: *role, UserID: user})
+			if err != nil {
+				return notFound(err, "user", *userID)
+			}
+			return env.print(
+				struct {
+					*models.APIKey
+					Secret string `json:"secret"`
+				}{k, secret},
+				append(apiKeyRows(k), [2]string{"Secret", secret + " (shown only once)"}),
+			)
+		})
+	case "list":
+		return runAdmin(fs, args[1:], nil, func(env *adminEnv, _ []uint) error {
+			keys, err := env.apiKeys.List(env.ctx)
+			if err != nil {
+				return err
+			}
+			if env.json {
+				return env.print(keys, nil)
+			}
+			tw := tabwriter.NewWriter(env.out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tROLE\tPREFIX\tREVOKED")
+			for _, k := range keys {
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Role, k.Prefix, formatTime(k.RevokedAt))
+			}
+			return tw.Flush()
+		})
+	case "revoke":
+		return runAdmin(fs, args[1:], []string{"id"}, func(env *adminEnv, ids []uint) error {
+			k, err := env.apiKeys.Revoke(env.ctx, ids[0])
+			if err != nil {
+				return notFound(err, "API key", ids[0])
+			}
+			return env.print(k, apiKeyRows(k))
+		})
+	default:
+		fmt.Fprintln(os.Stderr, apiKeyUsage)
+		return 2
+	}
+}
@@ -2,81 +2,731 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"syscall"
+	"time"
 
+	"wallet-api/aml"
+	"wallet-api/auth"
+	"wallet-api/config"
+	"wallet-api/grpcserver"
 	"wallet-api/handlers"
+	"wallet-api/health"
+	"wallet-api/jobs"
+	"wallet-api/logging"
+	"wallet-api/mail"
+	"wallet-api/metrics"
 	"wallet-api/models"
+	"wallet-api/openapi"
+	"wallet-api/paylink"
+	"wallet-api/ratelimit"
 	"wallet-api/repositories"
+	"wallet-api/risk"
+	"wallet-api/sanctions"
 	"wallet-api/services"
+	"wallet-api/tenancy"
+	"wallet-api/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const usage = `Usage: wallet-api [command] [flags]
+
+Commands:
+  serve                          run the HTTP and gRPC servers (default)
+  migrate                        migrate the database schema without starting the servers
+  reconcile                      run the end-of-day reconciliation for one day
+  config print                   print the effective configuration with secrets redacted
+
+Admin commands (add --json for machine-readable output):
+  user show <id>                 show a user and their wallets
+  wallet show <id>               show a wallet
+  wallet freeze <id> --reason    stop a wallet from sending or receiving money
+  wallet unfreeze <id>           lift a freeze
+  deposit --wallet --amount --reason
+                                 credit a wallet
+  tx show <id>                   show a transaction
+  api-key create --name --role [--user]
+                                 issue an API key and print its secret once
+  api-key list                   list API keys
+  api-key revoke <id>            stop an API key from authenticating
+
+Run "wallet-api serve -h" to list the configuration flags.
+`
+
 func main() {
-	// Database connection
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(loadConfig("serve", args))
+	case "migrate":
+		os.Exit(runMigrate(args))
+	case "reconcile":
+		os.Exit(runReconcile(args))
+	case "config":
+		os.Exit(runConfig(args))
+	case "user":
+		os.Exit(runUser(args))
+	case "wallet":
+		os.Exit(runWallet(args))
+	case "deposit":
+		os.Exit(runDeposit(args))
+	case "tx":
+		os.Exit(runTx(args))
+	case "api-key":
+		os.Exit(runAPIKey(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+func loadConfig(name string, args []string) *config.Config {
+	cfg, err := config.Load(name, args, os.LookupEnv)
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	setupLogging(cfg.Logging)
+	return cfg
+}
+
+// setupLogging installs the JSON slog logger as the default, which also
+// routes the standard log package through it. cfg has been validated.
+func setupLogging(cfg config.LoggingConfig) {
+	level, _ := logging.ParseLevel(cfg.Level)
+	levels, _ := logging.ParseLevels(cfg.Levels)
+	slog.SetDefault(logging.New(os.Stderr, logging.Options{
+		Level:  level,
+		Levels: levels,
+		Format: cfg.Format,
+	}))
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// runConfig implements `wallet-api config print`.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintf(os.Stderr, "usage: wallet-api config print [flags]\n")
+		return 2
+	}
+
+	cfg, err := config.Load("config print", args[1:], os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runMigrate implements `wallet-api migrate`, for deployments that migrate
+// the schema before rolling out new servers.
+func runMigrate(args []string) int {
+	cfg, err := config.Load("migrate", args, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	setupLogging(cfg.Logging)
+
+	if err := migrate(openDB(cfg.Database, cfg.Logging)); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// migrationModels lists every model migrated at startup. The readiness probe
+// checks the same list.
+var migrationModels = []interface{}{
+	&models.Tenant{},
+	&models.User{},
+	&models.Wallet{},
+	&models.Transaction{},
+	&models.BalanceSnapshot{},
+	&models.Discrepancy{},
+	&models.RateLimitBucket{},
+	&models.VerificationCase{},
+	&models.VerificationDocument{},
+	&models.VerificationEvent{},
+	&models.AMLCase{},
+	&models.AMLAlert{},
+	&models.ScreeningHit{},
+	&models.Erasure{},
+	&models.EmailVerification{},
+	&models.WalletMember{},
+	&models.TransferApproval{},
+	&models.ApprovalVote{},
+	&models.PaymentRequest{},
+	&models.PaymentLink{},
+	&models.PaymentLinkUse{},
+	&models.EscrowAgreement{},
+	&models.APIKey{},
+}
+
+// openDB connects to the database and applies the pool settings. It leaves
+// the schema alone: only serve and the migrate command change it.
+func openDB(cfg config.DatabaseConfig, logCfg config.LoggingConfig) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.URL), &gorm.Config{
+		Logger: logging.NewGormLogger(slog.Default(), time.Duration(logCfg.SlowQueryThreshold)),
+	})
+	if err != nil {
+		fatal("Failed to connect to database", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to access database pool", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime))
+
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		fatal("Failed to install database tracing", err)
+	}
+	if err := db.Use(tenancy.NewGormPlugin()); err != nil {
+		fatal("Failed to install tenant scoping", err)
+	}
+
+	return db
+}
+
+// migrate brings the schema up to date and creates the default tenant.
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(migrationModels...); err != nil {
+		return err
+	}
+	// Emails used to be unique across the whole database; they are now
+	// unique per tenant.
+	if db.Migrator().HasIndex(&models.User{}, "idx_users_email") {
+		if err := db.Migrator().DropIndex(&models.User{}, "idx_users_email"); err != nil {
+			return err
+		}
+	}
+	if err := repositories.NewTenantRepository(db).EnsureDefault(); err != nil {
+		return fmt.Errorf("create the default tenant: %w", err)
+	}
+	return nil
+}
+
+// kycLimits converts the configured limits for TransferService.
+func kycLimits(cfg config.KYCConfig) services.KYCLimits {
+	return services.KYCLimits{
+		models.KYCLevelNone:  {MaxBalance: cfg.NoneMaxBalance, DailyOutgoing: cfg.NoneDailyOutgoing},
+		models.KYCLevelBasic: {MaxBalance: cfg.BasicMaxBalance, DailyOutgoing: cfg.BasicDailyOutgoing},
+		models.KYCLevelFull:  {MaxBalance: cfg.FullMaxBalance, DailyOutgoing: cfg.FullDailyOutgoing},
+	}
+}
+
+// amlRules builds the monitoring rules from cfg.
+func amlRules(cfg config.AMLConfig) []aml.Rule {
+	return []aml.Rule{
+		aml.Structuring{
+			Threshold: cfg.StructuringThreshold,
+			Margin:    cfg.StructuringMargin,
+			Count:     cfg.StructuringCount,
+			Window:    time.Duration(cfg.StructuringWindow),
+		},
+		aml.RapidMovement{
+			Window:    time.Duration(cfg.RapidMovementWindow),
+			Ratio:     cfg.RapidMovementRatio,
+			MinAmount: cfg.RapidMovementMinAmount,
+		},
+		aml.NewWalletCounterparty{
+			MaxAge:    time.Duration(cfg.NewWalletMaxAge),
+			MinAmount: cfg.NewWalletMinAmount,
+		},
+	}
+}
+
+// newMonitor returns the AML engine, or nil when monitoring is switched off.
+func newMonitor(cfg *config.Config, db *gorm.DB) *aml.Engine {
+	if !cfg.Features.AML {
+		return nil
+	}
+	engine, err := aml.NewEngine(db, amlRules(cfg.AML), cfg.AML.Blocking)
+	if err != nil {
+		fatal("Failed to set up AML monitoring", err)
+	}
+	return engine
+}
+
+// transactionMonitor keeps a disabled engine from becoming a non-nil
+// interface value.
+func transactionMonitor(engine *aml.Engine) services.TransactionMonitor {
+	if engine == nil {
+		return nil
+	}
+	return engine
+}
+
+// newScreener loads the sanctions lists, or returns nil when none are
+// configured.
+func newScreener(cfg config.SanctionsConfig) *sanctions.Screener {
+	if len(cfg.Lists) == 0 {
+		return nil
+	}
+	entries, err := sanctions.LoadFiles(cfg.Lists)
+	if err != nil {
+		fatal("Failed to load sanctions lists", err)
+	}
+	slog.Info("sanctions lists loaded", slog.Int("lists", len(cfg.Lists)), slog.Int("entries", len(entries)))
+	return sanctions.NewScreener(cfg.Threshold, entries)
+}
+
+// transferScreening configures the screening of large transfers.
+func transferScreening(cfg config.SanctionsConfig, screener *sanctions.Screener) services.TransferScreening {
+	return services.TransferScreening{Screener: screener, MinAmount: cfg.TransferThreshold}
+}
+
+// riskScorer returns the fraud risk scorer, or nil when scoring is switched
+// off.
+func riskScorer(cfg *config.Config) services.RiskScorer {
+	if !cfg.Features.Risk {
+		return nil
+	}
+	return risk.Heuristic{
+		Window:         time.Duration(cfg.Risk.Window),
+		AmountMultiple: cfg.Risk.AmountMultiple,
+		NightStart:     cfg.Risk.NightStart,
+		NightEnd:       cfg.Risk.NightEnd,
+		ReviewScore:    cfg.Risk.ReviewScore,
+		DenyScore:      cfg.Risk.DenyScore,
 	}
+}
+
+// newMailer returns the mailer of the configured driver.
+func newMailer(cfg config.MailConfig) mail.Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		mailer, err := mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+		if err != nil {
+			fatal("Failed to set up SMTP mailer", err)
+		}
+		return mailer
+	case "file":
+		mailer, err := mail.NewFileMailer(cfg.Dir, cfg.From)
+		if err != nil {
+			fatal("Failed to set up file mailer", err)
+		}
+		return mailer
+	default:
+		return mail.LogMailer{Logger: logging.For("mail")}
+	}
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// newTemplates parses the email templates, which render amounts like the
+// statement exports do, in the currency of the wallet each email is about.
+func newTemplates() *mail.Templates {
+	templates, err := mail.NewTemplates()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to parse email templates", err)
 	}
+	return templates
+}
+
+// paymentLinkSigner returns the signer of payment link tokens. Without a
+// configured secret a random one is used, which does not outlive the process.
+func paymentLinkSigner(cfg config.PaymentLinksConfig) *paylink.Signer {
+	if cfg.Secret != "" {
+		return paylink.NewSigner([]byte(cfg.Secret))
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		fatal("Failed to generate payment link secret", err)
+	}
+	slog.Warn("payment_links.secret is not set; payment links will stop working when the server restarts")
+	return paylink.NewSigner(secret)
+}
+
+// emailVerification configures the verification emails.
+func emailVerification(cfg config.MailConfig) services.EmailVerificationConfig {
+	return services.EmailVerificationConfig{
+		URL:      cfg.VerifyURL,
+		TTL:      time.Duration(cfg.VerificationTTL),
+		Cooldown: time.Duration(cfg.ResendCooldown),
+	}
+}
 
-	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.User{}, &models.Wallet{}, &models.Transaction{})
+// transferNotifier returns the transfer email notifier, or nil when
+// notifications are switched off.
+func transferNotifier(cfg *config.Config, userRepo *repositories.UserRepository, walletRepo *repositories.WalletRepository,
+	mailer mail.Mailer, templates *mail.Templates) services.TransferNotifier {
+	if !cfg.Features.Notifications {
+		return nil
+	}
+	return services.NewNotifier(userRepo, walletRepo, mailer, templates, services.NotificationThresholds{
+		LargeDebit: cfg.Notifications.LargeDebit,
+		LowBalance: cfg.Notifications.LowBalance,
+	})
+}
+
+// newRateLimiter builds the limiter from cfg. The Postgres store also prunes
+// idle buckets as a background worker.
+func newRateLimiter(cfg config.RateLimitConfig, db *gorm.DB, workers *jobs.Group) *ratelimit.Limiter {
+	var store ratelimit.Store
+	switch cfg.Store {
+	case "postgres":
+		pg := ratelimit.NewPostgresStore(db)
+		workers.Go("ratelimit-prune", pg)
+		store = pg
+	default:
+		store = ratelimit.NewMemoryStore()
+	}
+
+	policies := map[ratelimit.Class]ratelimit.Policy{}
+	for class, rate := range map[ratelimit.Class]string{
+		ratelimit.ClassRead:   cfg.Reads,
+		ratelimit.ClassWrite:  cfg.Writes,
+		ratelimit.ClassMoney:  cfg.Money,
+		ratelimit.ClassSignup: cfg.Signups,
+	} {
+		// Validated by config.Load.
+		limit, period, _ := config.ParseRate(rate)
+		policies[class] = ratelimit.Policy{Limit: limit, Period: period}
+	}
+	return ratelimit.NewLimiter(store, policies)
+}
+
+func serve(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		fatal("Failed to set up tracing", err)
+	}
+
+	gin.SetMode(cfg.HTTP.Mode)
+	db := openDB(cfg.Database, cfg.Logging)
+	if err := migrate(db); err != nil {
+		fatal("Failed to migrate database", err)
 	}
 
 	// Repositories
+	tenantRepo := repositories.NewTenantRepository(db)
 	userRepo := repositories.NewUserRepository(db)
 	walletRepo := repositories.NewWalletRepository(db)
 	transactionRepo := repositories.NewTransactionRepository(db)
+	discrepancyRepo := repositories.NewDiscrepancyRepository(db)
+	verificationCaseRepo := repositories.NewVerificationCaseRepository(db)
+	amlCaseRepo := repositories.NewAMLCaseRepository(db)
+	screeningHitRepo := repositories.NewScreeningHitRepository(db)
+	erasureRepo := repositories.NewErasureRepository(db)
+	emailVerificationRepo := repositories.NewEmailVerificationRepository(db)
+	walletMemberRepo := repositories.NewWalletMemberRepository(db)
+	transferApprovalRepo := repositories.NewTransferApprovalRepository(db)
+	paymentRequestRepo := repositories.NewPaymentRequestRepository(db)
+	paymentLinkRepo := repositories.NewPaymentLinkRepository(db)
+	escrowRepo := repositories.NewEscrowRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+
+	// AML monitoring and sanctions screening
+	monitor := newMonitor(cfg, db)
+	screener := newScreener(cfg.Sanctions)
+
+	// Emails are queued and sent by a background worker
+	mailQueue := mail.NewQueue(newMailer(cfg.Mail))
+	templates := newTemplates()
 
 	// Services
-	userService := services.NewUserService(userRepo)
-	walletService := services.NewWalletService(walletRepo, userRepo)
-	transferService := services.NewTransferService(transactionRepo, walletRepo, db)
+	tenantService := services.NewTenantService(tenantRepo, db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, db)
+	emailVerificationService := services.NewEmailVerificationService(emailVerificationRepo, userRepo, db,
+		mailQueue, templates, emailVerification(cfg.Mail))
+	userService := services.NewUserService(userRepo, db, screener, emailVerificationService)
+	walletService := services.NewWalletService(walletRepo, userRepo, db)
+	pocketService := services.NewPocketService(walletRepo, db)
+	transferService := services.NewTransferService(transactionRepo, walletRepo, db, kycLimits(cfg.KYC),
+		transactionMonitor(monitor), transferScreening(cfg.Sanctions, screener), riskScorer(cfg),
+		transferNotifier(cfg, userRepo, walletRepo, mailQueue, templates))
+	statementService := services.NewStatementService(transactionRepo, walletRepo)
+	reconciliationService := services.NewReconciliationService(walletRepo, discrepancyRepo, db)
+	kycService := services.NewKYCService(verificationCaseRepo, userRepo, db)
+	amlService := services.NewAMLService(amlCaseRepo, transferService, db)
+	screeningService := services.NewScreeningService(screeningHitRepo, transferService, db)
+	privacyService := services.NewPrivacyService(userRepo, walletRepo, transactionRepo, verificationCaseRepo,
+		erasureRepo, db, time.Duration(cfg.Privacy.Retention))
+	membershipService := services.NewMembershipService(walletMemberRepo, walletRepo, db)
+	approvalService := services.NewApprovalService(transferApprovalRepo, walletRepo, transferService, db)
+	paymentRequestService := services.NewPaymentRequestService(paymentRequestRepo, walletRepo, transferService, db)
+	paymentLinkService := services.NewPaymentLinkService(paymentLinkRepo, walletRepo, transferService, db,
+		paymentLinkSigner(cfg.PaymentLinks), services.PaymentLinkConfig{
+			URL: cfg.PaymentLinks.URL,
+			TTL: time.Duration(cfg.PaymentLinks.TTL),
+		})
+	escrowService := services.NewEscrowService(escrowRepo, walletRepo, transferService, db, services.EscrowConfig{
+		Timeout: time.Duration(cfg.Escrow.Timeout),
+	})
 
 	// Handlers
+	tenantHandler := handlers.NewTenantHandler(tenantService)
 	userHandler := handlers.NewUserHandler(userService)
 	walletHandler := handlers.NewWalletHandler(walletService)
+	pocketHandler := handlers.NewPocketHandler(pocketService)
+	membershipHandler := handlers.NewMembershipHandler(membershipService)
+	approvalHandler := handlers.NewApprovalHandler(approvalService)
 	transferHandler := handlers.NewTransferHandler(transferService)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequestService)
+	paymentLinkHandler := handlers.NewPaymentLinkHandler(paymentLinkService)
+	escrowHandler := handlers.NewEscrowHandler(escrowService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+	kycHandler := handlers.NewKYCHandler(kycService)
+	amlHandler := handlers.NewAMLHandler(amlService)
+	screeningHandler := handlers.NewScreeningHandler(screeningService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService)
+
+	// Health checks
+	checks := []health.Check{
+		health.Database(db),
+		health.Migrations(db, migrationModels...),
+	}
+
+	// Background jobs. The mail queue starts first so it is stopped last,
+	// after the workers that may still send emails.
+	workers := jobs.NewGroup()
+	workers.Go("mail", mailQueue)
+	if cfg.Features.EODReconciliation {
+		job := jobs.NewEndOfDayReconciliation(reconciliationService, time.Duration(cfg.Reconciliation.Delay))
+		workers.Go("eod-reconciliation", job)
+		checks = append(checks, health.Worker("eod-reconciliation", job.Healthy))
+	}
+	if monitor != nil {
+		workers.Go("aml-monitor", monitor)
+	}
+	workers.Go("escrow-timeouts", jobs.NewEscrowTimeouts(escrowService, time.Duration(cfg.Escrow.SweepInterval)))
+	healthHandler := health.NewHandler(2*time.Second, checks...)
+
+	// Rate limiting
+	var limiter *ratelimit.Limiter
+	if cfg.Features.RateLimit {
+		limiter = newRateLimiter(cfg.RateLimit, db, workers)
+	}
 
 	// Router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, recoverPanic))
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		fatal("Failed to set trusted proxies", err)
+	}
+
+	// Prometheus metrics. The middleware goes first so every route below is
+	// measured.
+	if cfg.Features.Metrics {
+		router.Use(metrics.Middleware())
+		router.GET("/metrics", metrics.Handler())
+		sqlDB, err := db.DB()
+		if err != nil {
+			fatal("Failed to access database pool", err)
+		}
+		if err := metrics.RegisterDB(sqlDB, "wallet"); err != nil {
+			fatal("Failed to register database metrics", err)
+		}
+	}
+
+	// Tracing: one server span per request, continuing incoming traceparent
+	router.Use(tracing.Middleware(cfg.Tracing.ServiceName))
 
-	// Routes
+	// Request IDs and structured access logs
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(risk.DeviceMiddleware())
+	router.Use(logging.AccessLog(logging.For("http")))
+
+	// Liveness and readiness probes
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// Routes. Callers are authenticated by API key, and every route but the
+	// tenant routes is scoped to the tenant the request names.
+	authenticator := auth.NewAuthenticator(apiKeyService)
+	tenantResolver := tenancy.NewResolver(db, cfg.Tenancy.Required)
 	v1 := router.Group("/api/v1")
-	{
-		// User routes
-		v1.POST("/users", userHandler.Create)
-		v1.GET("/users/:id", userHandler.GetByID)
+	handlers.RegisterRoutes(v1, handlers.Handlers{
+		User:           userHandler,
+		Wallet:         walletHandler,
+		Pocket:         pocketHandler,
+		Membership:     membershipHandler,
+		Approval:       approvalHandler,
+		Transfer:       transferHandler,
+		PaymentRequest: paymentRequestHandler,
+		PaymentLink:    paymentLinkHandler,
+		Escrow:         escrowHandler,
+		Statement:      statementHandler,
+		Reconciliation: reconciliationHandler,
+		KYC:            kycHandler,
+		AML:            amlHandler,
+		Screening:      screeningHandler,
+		Privacy:        privacyHandler,
+		Email:          emailVerificationHandler,
+		Tenant:         tenantHandler,
+		Auth:           authenticator,
+		RateLimit:      limiter,
+		Tenancy:        tenantResolver,
+		ExportTimeout:  time.Duration(cfg.HTTP.ExportTimeout),
+	})
+
+	// API documentation
+	if cfg.Features.Docs {
+		router.GET("/openapi.json", openapi.Handler())
+		router.GET("/docs", openapi.DocsHandler())
+	}
 
-		// Wallet routes
-		v1.POST("/wallets", walletHandler.Create)
-		v1.GET("/wallets/:id", walletHandler.GetByID)
-		v1.GET("/users/:id/wallets", walletHandler.GetByUserID)
+	serveErr := make(chan error, 2)
 
-		// Transfer routes
-		v1.POST("/transfers", transferHandler.Transfer)
-		v1.POST("/deposits", transferHandler.Deposit)
-		v1.GET("/wallets/:id/transactions", transferHandler.GetTransactions)
+	// gRPC server, sharing the same services as the REST API
+	var grpcServer *grpc.Server
+	if cfg.Features.GRPC {
+		lis, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GRPC.Port))
+		if err != nil {
+			fatal("Failed to listen on gRPC port", err)
+		}
+		grpcServer = grpc.NewServer(
+			grpc.StatsHandler(otelgrpc.NewServerHandler()),
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor(), tenantResolver.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor(), tenantResolver.StreamServerInterceptor()),
+		)
+		grpcserver.NewServer(userService, walletService, transferService).Register(grpcServer)
+		go func() {
+			slog.Info("gRPC server starting", "port", cfg.GRPC.Port)
+			if err := grpcServer.Serve(lis); err != nil {
+				serveErr <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
 	}
 
 	// Start the server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.HTTP.Port),
+		Handler:           router,
+		ReadTimeout:       time.Duration(cfg.HTTP.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTP.IdleTimeout),
+	}
+	go func() {
+		slog.Info("HTTP server starting", "port", cfg.HTTP.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("HTTP server: %w", err)
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining", "timeout", cfg.HTTP.ShutdownTimeout.String())
+	case err := <-serveErr:
+		slog.Error("Failed to serve", "error", err)
+		exitCode = 1
+	}
+	stop()
+
+	if err := shutdown(time.Duration(cfg.HTTP.ShutdownGrace), time.Duration(cfg.HTTP.ShutdownTimeout), healthHandler, server, grpcServer, workers, db, shutdownTracing); err != nil {
+		slog.Error("Shutdown incomplete", "error", err)
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// shutdown stops accepting traffic and tears the process down in order:
+// readiness is failed first and the server keeps serving for grace, so load
+// balancers see it before new connections are refused. Then in-flight HTTP
+// and gRPC calls are drained, background workers are stopped, and the
+// database pool is closed so nothing above it loses its connection
+// mid-transaction. Buffered spans are flushed last so those of the drained
+// requests are exported too. timeout bounds everything after the grace.
+func shutdown(grace, timeout time.Duration, probes *health.Handler, server *http.Server, grpcServer *grpc.Server, workers *jobs.Group, db *gorm.DB, flushTraces func(context.Context) error) error {
+	probes.SetShuttingDown()
+	time.Sleep(grace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+
+	if err := server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("HTTP server: %w", err))
+	}
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+			errs = append(errs, errors.New("gRPC server: forced stop after timeout"))
+		}
 	}
 
-	log.Printf("Server starting on port %s...", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := workers.Stop(ctx); err != nil {
+		errs = append(errs, err)
 	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("database: %w", err))
+		}
+	}
+
+	if err := flushTraces(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	if len(errs) == 0 {
+		slog.Info("Shutdown complete")
+	}
+	return errors.Join(errs...)
+}
+
+// recoverPanic answers 500 after a handler panic and logs the stack with the
+// request's ID so the failing request can be found.
+func recoverPanic(c *gin.Context, recovered any) {
+	logging.For("http").ErrorContext(c.Request.Context(), "panic recovered",
+		"panic", fmt.Sprint(recovered),
+		"stack", string(debug.Stack()),
+	)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wallet-api/config"
+	"wallet-api/repositories"
+	"wallet-api/services"
+)
+
+// runReconcile implements `wallet-api reconcile [--date YYYY-MM-DD]`. It
+// returns 1 when any discrepancy is found so cron and CI can alert on it,
+// and 2 when the job itself fails or its report cannot be written.
+func runReconcile(args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	date := fs.String("date", time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02"), "UTC day to reconcile (YYYY-MM-DD)")
+	cfg, err := config.LoadWithFlags(fs, args, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	setupLogging(cfg.Logging)
+
+	day, err := time.Parse("2006-01-02", *date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --date %q: expected YYYY-MM-DD\n", *date)
+		return 2
+	}
+
+	db := openDB(cfg.Database, cfg.Logging)
+	reconciliationService := services.NewReconciliationService(
+		repositories.NewWalletRepository(db),
+		repositories.NewDiscrepancyRepository(db),
+		db,
+	)
+
+	report, err := reconciliationService.RunEndOfDay(context.Background(), day)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconciliation failed: %v\n", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "writing the report failed: %v\n", err)
+		return 2
+	}
+
+	if len(report.Discrepancies) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,497 @@
+// Package config loads the typed server configuration from a YAML or TOML
+// file, environment variables and command-line flags, in that order of
+// precedence.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the complete runtime configuration of the binary.
+type Config struct {
+	Database       DatabaseConfig       `yaml:"database" toml:"database"`
+	HTTP           HTTPConfig           `yaml:"http" toml:"http"`
+	GRPC           GRPCConfig           `yaml:"grpc" toml:"grpc"`
+	Reconciliation ReconciliationConfig `yaml:"reconciliation" toml:"reconciliation"`
+	Tracing        TracingConfig        `yaml:"tracing" toml:"tracing"`
+	Logging        LoggingConfig        `yaml:"logging" toml:"logging"`
+	RateLimit      RateLimitConfig      `yaml:"rate_limit" toml:"rate_limit"`
+	KYC            KYCConfig            `yaml:"kyc" toml:"kyc"`
+	AML            AMLConfig            `yaml:"aml" toml:"aml"`
+	Sanctions      SanctionsConfig      `yaml:"sanctions" toml:"sanctions"`
+	Risk           RiskConfig           `yaml:"risk" toml:"risk"`
+	Privacy        PrivacyConfig        `yaml:"privacy" toml:"privacy"`
+	Mail           MailConfig           `yaml:"mail" toml:"mail"`
+	Notifications  NotificationsConfig  `yaml:"notifications" toml:"notifications"`
+	Tenancy        TenancyConfig        `yaml:"tenancy" toml:"tenancy"`
+	PaymentLinks   PaymentLinksConfig   `yaml:"payment_links" toml:"payment_links"`
+	Escrow         EscrowConfig         `yaml:"escrow" toml:"escrow"`
+	Features       FeaturesConfig       `yaml:"features" toml:"features"`
+}
+
+type DatabaseConfig struct {
+	URL             string   `yaml:"url" toml:"url" env:"DATABASE_URL" flag:"database-url" usage:"PostgreSQL connection string" secret:"true"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections (0 = unlimited)"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection (0 = forever)"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" usage:"maximum idle time of a connection (0 = forever)"`
+}
+
+type HTTPConfig struct {
+	Port              int      `yaml:"port" toml:"port" env:"PORT" flag:"port" usage:"HTTP listen port"`
+	Mode              string   `yaml:"mode" toml:"mode" env:"GIN_MODE" flag:"gin-mode" usage:"gin mode: debug, release or test"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" usage:"maximum duration for reading a request"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http-read-header-timeout" usage:"maximum duration for reading request headers"`
+	WriteTimeout      Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" usage:"maximum duration for writing a response"`
+	ExportTimeout     Duration `yaml:"export_timeout" toml:"export_timeout" env:"HTTP_EXPORT_TIMEOUT" flag:"http-export-timeout" usage:"maximum duration for streaming a statement export, replacing write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" usage:"keep-alive idle timeout"`
+	TrustedProxies    []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" flag:"http-trusted-proxies" usage:"comma-separated IPs or CIDRs allowed to set X-Forwarded-For"`
+	ShutdownGrace     Duration `yaml:"shutdown_grace" toml:"shutdown_grace" env:"HTTP_SHUTDOWN_GRACE" flag:"http-shutdown-grace" usage:"how long to keep serving with readiness failed on SIGTERM, so load balancers stop routing first"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout" usage:"how long to drain in-flight requests and workers on SIGTERM"`
+}
+
+type GRPCConfig struct {
+	Port int `yaml:"port" toml:"port" env:"GRPC_PORT" flag:"grpc-port" usage:"gRPC listen port"`
+}
+
+type ReconciliationConfig struct {
+	// Delay is how long after midnight UTC the end-of-day job runs.
+	Delay Duration `yaml:"delay" toml:"delay" env:"EOD_RECONCILIATION_DELAY" flag:"eod-reconciliation-delay" usage:"how long after midnight UTC the end-of-day job runs"`
+}
+
+type TracingConfig struct {
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME" flag:"tracing-service-name" usage:"service.name reported on every span"`
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"where spans go: none, stdout or otlp"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" flag:"tracing-otlp-endpoint" usage:"OTLP gRPC collector host:port"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure" env:"TRACING_OTLP_INSECURE" flag:"tracing-otlp-insecure" usage:"connect to the OTLP collector without TLS"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" usage:"fraction of new traces recorded, 0 to 1"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum level: debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT" flag:"log-format" usage:"json or text"`
+	// Levels overrides Level per component, e.g. "gorm=debug".
+	Levels             []string `yaml:"levels" toml:"levels" env:"LOG_LEVELS" flag:"log-levels" usage:"comma-separated component=level overrides, e.g. gorm=debug,http=warn"`
+	SlowQueryThreshold Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold" env:"LOG_SLOW_QUERY_THRESHOLD" flag:"log-slow-query-threshold" usage:"SQL statements slower than this are logged at warn (0 = never)"`
+}
+
+// RateLimitConfig sets the token-bucket policies, each written as
+// "<requests>/<period>", e.g. "300/1m".
+type RateLimitConfig struct {
+	Store   string `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE" flag:"rate-limit-store" usage:"where buckets are kept: memory (per instance) or postgres (shared)"`
+	Reads   string `yaml:"reads" toml:"reads" env:"RATE_LIMIT_READS" flag:"rate-limit-reads" usage:"policy for GET routes, e.g. 300/1m"`
+	Writes  string `yaml:"writes" toml:"writes" env:"RATE_LIMIT_WRITES" flag:"rate-limit-writes" usage:"policy for other writes such as creating wallets"`
+	Money   string `yaml:"money" toml:"money" env:"RATE_LIMIT_MONEY" flag:"rate-limit-money" usage:"policy for transfers and deposits"`
+	Signups string `yaml:"signups" toml:"signups" env:"RATE_LIMIT_SIGNUPS" flag:"rate-limit-signups" usage:"policy for creating users"`
+}
+
+// KYCConfig caps balances and outgoing transfers per KYC level, in minor
+// units. Zero means unlimited.
+type KYCConfig struct {
+	NoneMaxBalance     int64 `yaml:"none_max_balance" toml:"none_max_balance" env:"KYC_NONE_MAX_BALANCE" flag:"kyc-none-max-balance" usage:"maximum wallet balance of unverified users"`
+	NoneDailyOutgoing  int64 `yaml:"none_daily_outgoing" toml:"none_daily_outgoing" env:"KYC_NONE_DAILY_OUTGOING" flag:"kyc-none-daily-outgoing" usage:"maximum sent by unverified users in 24 hours"`
+	BasicMaxBalance    int64 `yaml:"basic_max_balance" toml:"basic_max_balance" env:"KYC_BASIC_MAX_BALANCE" flag:"kyc-basic-max-balance" usage:"maximum wallet balance at the basic level"`
+	BasicDailyOutgoing int64 `yaml:"basic_daily_outgoing" toml:"basic_daily_outgoing" env:"KYC_BASIC_DAILY_OUTGOING" flag:"kyc-basic-daily-outgoing" usage:"maximum sent in 24 hours at the basic level"`
+	FullMaxBalance     int64 `yaml:"full_max_balance" toml:"full_max_balance" env:"KYC_FULL_MAX_BALANCE" flag:"kyc-full-max-balance" usage:"maximum wallet balance at the full level"`
+	FullDailyOutgoing  int64 `yaml:"full_daily_outgoing" toml:"full_daily_outgoing" env:"KYC_FULL_DAILY_OUTGOING" flag:"kyc-full-daily-outgoing" usage:"maximum sent in 24 hours at the full level"`
+}
+
+// AMLConfig tunes the transaction monitoring rules. Amounts are in minor
+// units. Rules named in Blocking run before a transfer commits and hold it
+// for review when they fire; the rest run after commit and only raise cases.
+type AMLConfig struct {
+	StructuringThreshold   int64    `yaml:"structuring_threshold" toml:"structuring_threshold" env:"AML_STRUCTURING_THRESHOLD" flag:"aml-structuring-threshold" usage:"reporting threshold that structured transactions stay just under"`
+	StructuringMargin      float64  `yaml:"structuring_margin" toml:"structuring_margin" env:"AML_STRUCTURING_MARGIN" flag:"aml-structuring-margin" usage:"how far under the threshold counts as just under, as a fraction of it"`
+	StructuringCount       int      `yaml:"structuring_count" toml:"structuring_count" env:"AML_STRUCTURING_COUNT" flag:"aml-structuring-count" usage:"transactions just under the threshold within the window that raise an alert"`
+	StructuringWindow      Duration `yaml:"structuring_window" toml:"structuring_window" env:"AML_STRUCTURING_WINDOW" flag:"aml-structuring-window" usage:"window in which structured transactions are counted"`
+	RapidMovementWindow    Duration `yaml:"rapid_movement_window" toml:"rapid_movement_window" env:"AML_RAPID_MOVEMENT_WINDOW" flag:"aml-rapid-movement-window" usage:"window in which funds received and sent on are compared"`
+	RapidMovementRatio     float64  `yaml:"rapid_movement_ratio" toml:"rapid_movement_ratio" env:"AML_RAPID_MOVEMENT_RATIO" flag:"aml-rapid-movement-ratio" usage:"fraction of funds received within the window that, once sent on, raises an alert"`
+	RapidMovementMinAmount int64    `yaml:"rapid_movement_min_amount" toml:"rapid_movement_min_amount" env:"AML_RAPID_MOVEMENT_MIN_AMOUNT" flag:"aml-rapid-movement-min-amount" usage:"funds received within the window below this are ignored"`
+	NewWalletMaxAge        Duration `yaml:"new_wallet_max_age" toml:"new_wallet_max_age" env:"AML_NEW_WALLET_MAX_AGE" flag:"aml-new-wallet-max-age" usage:"target wallets younger than this count as new"`
+	NewWalletMinAmount     int64    `yaml:"new_wallet_min_amount" toml:"new_wallet_min_amount" env:"AML_NEW_WALLET_MIN_AMOUNT" flag:"aml-new-wallet-min-amount" usage:"transfers to new wallets from this amount raise an alert"`
+	Blocking               []string `yaml:"blocking" toml:"blocking" env:"AML_BLOCKING" flag:"aml-blocking" usage:"comma-separated rules that hold transfers for review: structuring, rapid_movement, new_wallet_counterparty"`
+}
+
+// SanctionsConfig points at the sanctions list files new users and large
+// transfers are screened against. Screening is off while Lists is empty.
+type SanctionsConfig struct {
+	Lists             []string `yaml:"lists" toml:"lists" env:"SANCTIONS_LISTS" flag:"sanctions-lists" usage:"comma-separated sanctions list files, .csv or .xml"`
+	Threshold         float64  `yaml:"threshold" toml:"threshold" env:"SANCTIONS_THRESHOLD" flag:"sanctions-threshold" usage:"name similarity from which a name counts as a match, above 0 and at most 1"`
+	TransferThreshold int64    `yaml:"transfer_threshold" toml:"transfer_threshold" env:"SANCTIONS_TRANSFER_THRESHOLD" flag:"sanctions-transfer-threshold" usage:"transfers from this amount, in minor units, screen both owners"`
+}
+
+// RiskConfig tunes the fraud risk scoring of transfers. Scores run from 0
+// to 100; transfers scoring ReviewScore or more are held for review, from
+// DenyScore they are refused.
+type RiskConfig struct {
+	Window         Duration `yaml:"window" toml:"window" env:"RISK_WINDOW" flag:"risk-window" usage:"how far back a wallet's transfers count as its history"`
+	AmountMultiple float64  `yaml:"amount_multiple" toml:"amount_multiple" env:"RISK_AMOUNT_MULTIPLE" flag:"risk-amount-multiple" usage:"amounts above this multiple of the wallet's average transfer are unusual"`
+	NightStart     int      `yaml:"night_start" toml:"night_start" env:"RISK_NIGHT_START" flag:"risk-night-start" usage:"hour (UTC) from which transfers count as sent at night"`
+	NightEnd       int      `yaml:"night_end" toml:"night_end" env:"RISK_NIGHT_END" flag:"risk-night-end" usage:"hour (UTC) at which the night ends; equal to night_start for no night"`
+	ReviewScore    int      `yaml:"review_score" toml:"review_score" env:"RISK_REVIEW_SCORE" flag:"risk-review-score" usage:"score from which transfers are held for review"`
+	DenyScore      int      `yaml:"deny_score" toml:"deny_score" env:"RISK_DENY_SCORE" flag:"risk-deny-score" usage:"score from which transfers are refused"`
+}
+
+// PrivacyConfig governs data subject requests. Erased users' wallets and
+// transactions are kept for Retention to meet record-keeping obligations.
+type PrivacyConfig struct {
+	Retention Duration `yaml:"retention" toml:"retention" env:"PRIVACY_RETENTION" flag:"privacy-retention" usage:"how long financial records of erased users are kept"`
+}
+
+// MailConfig selects how emails are delivered and governs email
+// verification. The log driver writes emails to the log and the file driver
+// writes them to Dir as .eml files, for development; smtp sends them.
+type MailConfig struct {
+	Driver          string   `yaml:"driver" toml:"driver" env:"MAIL_DRIVER" flag:"mail-driver" usage:"how emails are delivered: log, file or smtp"`
+	From            string   `yaml:"from" toml:"from" env:"MAIL_FROM" flag:"mail-from" usage:"sender address of every email"`
+	Dir             string   `yaml:"dir" toml:"dir" env:"MAIL_DIR" flag:"mail-dir" usage:"directory the file driver writes emails to"`
+	SMTPHost        string   `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP server host"`
+	SMTPPort        int      `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT" flag:"smtp-port" usage:"SMTP server port"`
+	SMTPUsername    string   `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME" flag:"smtp-username" usage:"SMTP username; empty to send without authenticating"`
+	SMTPPassword    string   `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD" flag:"smtp-password" usage:"SMTP password" secret:"true"`
+	VerifyURL       string   `yaml:"verify_url" toml:"verify_url" env:"MAIL_VERIFY_URL" flag:"mail-verify-url" usage:"page verification links point to; the token is added as a query parameter"`
+	VerificationTTL Duration `yaml:"verification_ttl" toml:"verification_ttl" env:"MAIL_VERIFICATION_TTL" flag:"mail-verification-ttl" usage:"how long an email verification link is valid"`
+	ResendCooldown  Duration `yaml:"resend_cooldown" toml:"resend_cooldown" env:"MAIL_RESEND_COOLDOWN" flag:"mail-resend-cooldown" usage:"minimum time between verification emails to one user"`
+}
+
+// NotificationsConfig sets when transfer emails are sent, in minor units.
+// Zero turns the email off.
+type NotificationsConfig struct {
+	LargeDebit int64 `yaml:"large_debit" toml:"large_debit" env:"NOTIFY_LARGE_DEBIT" flag:"notify-large-debit" usage:"transfers from this amount email the sender"`
+	LowBalance int64 `yaml:"low_balance" toml:"low_balance" env:"NOTIFY_LOW_BALANCE" flag:"notify-low-balance" usage:"transfers taking a wallet below this balance email the sender"`
+}
+
+// TenancyConfig governs how requests name their tenant. Requests with an
+// API key belong to the key's tenant; unless Required, anonymous requests
+// without an X-Tenant-ID header belong to the default tenant.
+type TenancyConfig struct {
+	Required bool `yaml:"required" toml:"required" env:"TENANCY_REQUIRED" flag:"tenancy-required" usage:"reject anonymous requests that do not name a tenant in X-Tenant-ID"`
+}
+
+// PaymentLinksConfig governs payment links. Their tokens are signed with
+// Secret; without one a random secret is used, and links stop working when
+// the server restarts.
+type PaymentLinksConfig struct {
+	Secret string   `yaml:"secret" toml:"secret" env:"PAYMENT_LINK_SECRET" flag:"payment-link-secret" usage:"key payment link tokens are signed with, at least 32 bytes" secret:"true"`
+	URL    string   `yaml:"url" toml:"url" env:"PAYMENT_LINK_URL" flag:"payment-link-url" usage:"URL payment links and their QR codes point to; the token is appended to the path"`
+	TTL    Duration `yaml:"ttl" toml:"ttl" env:"PAYMENT_LINK_TTL" flag:"payment-link-ttl" usage:"how long payment links are valid unless created with an expiry"`
+}
+
+// EscrowConfig governs escrow agreements. The server refunds expired ones
+// every SweepInterval.
+type EscrowConfig struct {
+	Timeout       Duration `yaml:"timeout" toml:"timeout" env:"ESCROW_TIMEOUT" flag:"escrow-timeout" usage:"how long escrow agreements wait for confirmation before they are refunded, unless created with an expiry"`
+	SweepInterval Duration `yaml:"sweep_interval" toml:"sweep_interval" env:"ESCROW_SWEEP_INTERVAL" flag:"escrow-sweep-interval" usage:"how often expired escrow agreements are refunded"`
+}
+
+// FeaturesConfig switches optional subsystems on and off.
+type FeaturesConfig struct {
+	GRPC              bool `yaml:"grpc" toml:"grpc" env:"FEATURE_GRPC" flag:"feature-grpc" usage:"serve the gRPC API"`
+	Docs              bool `yaml:"docs" toml:"docs" env:"FEATURE_DOCS" flag:"feature-docs" usage:"serve /openapi.json and /docs"`
+	Metrics           bool `yaml:"metrics" toml:"metrics" env:"FEATURE_METRICS" flag:"feature-metrics" usage:"serve Prometheus metrics on /metrics"`
+	RateLimit         bool `yaml:"rate_limit" toml:"rate_limit" env:"RATE_LIMIT_ENABLED" flag:"feature-rate-limit" usage:"limit request rates per client and route class"`
+	AML               bool `yaml:"aml" toml:"aml" env:"AML_ENABLED" flag:"feature-aml" usage:"screen transactions against the AML rules"`
+	Risk              bool `yaml:"risk" toml:"risk" env:"RISK_ENABLED" flag:"feature-risk" usage:"score transfers for fraud risk before they commit"`
+	Notifications     bool `yaml:"notifications" toml:"notifications" env:"NOTIFICATIONS_ENABLED" flag:"feature-notifications" usage:"email wallet owners about their transfers"`
+	EODReconciliation bool `yaml:"eod_reconciliation" toml:"eod_reconciliation" env:"EOD_RECONCILIATION_ENABLED" flag:"feature-eod-reconciliation" usage:"run the end-of-day reconciliation job in the server"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			URL:             "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnMaxIdleTime: Duration(5 * time.Minute),
+		},
+		HTTP: HTTPConfig{
+			Port:              8080,
+			Mode:              "debug",
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(60 * time.Second),
+			ExportTimeout:     Duration(30 * time.Minute),
+			IdleTimeout:       Duration(120 * time.Second),
+			ShutdownGrace:     Duration(5 * time.Second),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		GRPC: GRPCConfig{
+			Port: 9090,
+		},
+		Reconciliation: ReconciliationConfig{
+			Delay: Duration(15 * time.Minute),
+		},
+		Tracing: TracingConfig{
+			ServiceName: "wallet-api",
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:              "info",
+			Format:             "json",
+			SlowQueryThreshold: Duration(200 * time.Millisecond),
+		},
+		RateLimit: RateLimitConfig{
+			Store:   "memory",
+			Reads:   "300/1m",
+			Writes:  "60/1m",
+			Money:   "30/1m",
+			Signups: "5/1h",
+		},
+		KYC: KYCConfig{
+			NoneMaxBalance:     100_000,
+			NoneDailyOutgoing:  50_000,
+			BasicMaxBalance:    1_000_000,
+			BasicDailyOutgoing: 500_000,
+		},
+		AML: AMLConfig{
+			StructuringThreshold:   1_000_000,
+			StructuringMargin:      0.1,
+			StructuringCount:       3,
+			StructuringWindow:      Duration(24 * time.Hour),
+			RapidMovementWindow:    Duration(time.Hour),
+			RapidMovementRatio:     0.9,
+			RapidMovementMinAmount: 100_000,
+			NewWalletMaxAge:        Duration(24 * time.Hour),
+			NewWalletMinAmount:     100_000,
+			Blocking:               []string{"new_wallet_counterparty"},
+		},
+		Sanctions: SanctionsConfig{
+			Threshold:         0.88,
+			TransferThreshold: 100_000,
+		},
+		Risk: RiskConfig{
+			Window:         Duration(90 * 24 * time.Hour),
+			AmountMultiple: 5,
+			NightStart:     0,
+			NightEnd:       5,
+			ReviewScore:    50,
+			DenyScore:      80,
+		},
+		Privacy: PrivacyConfig{
+			Retention: Duration(10 * 365 * 24 * time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:          "log",
+			From:            "Wallet <no-reply@wallet.local>",
+			Dir:             "mail",
+			SMTPPort:        587,
+			VerifyURL:       "http://localhost:8080/verify-email",
+			VerificationTTL: Duration(24 * time.Hour),
+			ResendCooldown:  Duration(time.Minute),
+		},
+		Notifications: NotificationsConfig{
+			LargeDebit: 100_000,
+			LowBalance: 1_000,
+		},
+		PaymentLinks: PaymentLinksConfig{
+			URL: "http://localhost:8080/api/v1/pay",
+			TTL: Duration(24 * time.Hour),
+		},
+		Escrow: EscrowConfig{
+			Timeout:       Duration(14 * 24 * time.Hour),
+			SweepInterval: Duration(time.Minute),
+		},
+		Features: FeaturesConfig{
+			GRPC:          true,
+			Docs:          true,
+			Metrics:       true,
+			RateLimit:     true,
+			AML:           true,
+			Risk:          true,
+			Notifications: true,
+		},
+	}
+}
+
+// Validate reports every invalid value at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.URL != "", "database.url is required")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time must not be negative")
+
+	check(validPort(c.HTTP.Port), "http.port must be between 1 and 65535, got %d", c.HTTP.Port)
+	check(c.HTTP.Mode == "debug" || c.HTTP.Mode == "release" || c.HTTP.Mode == "test",
+		"http.mode must be debug, release or test, got %q", c.HTTP.Mode)
+	check(c.HTTP.ReadTimeout >= 0, "http.read_timeout must not be negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read_header_timeout must not be negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout must not be negative")
+	check(c.HTTP.ExportTimeout >= 0, "http.export_timeout must not be negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle_timeout must not be negative")
+	check(c.HTTP.ShutdownGrace >= 0, "http.shutdown_grace must not be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout must be positive")
+	for _, proxy := range c.HTTP.TrustedProxies {
+		check(validProxy(proxy), "http.trusted_proxies: %q is not an IP address or CIDR", proxy)
+	}
+
+	if c.Features.GRPC {
+		check(validPort(c.GRPC.Port), "grpc.port must be between 1 and 65535, got %d", c.GRPC.Port)
+		check(c.GRPC.Port != c.HTTP.Port, "grpc.port and http.port must differ")
+	}
+
+	check(c.Reconciliation.Delay >= 0 && time.Duration(c.Reconciliation.Delay) < 24*time.Hour,
+		"reconciliation.delay must be between 0 and 24h")
+
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "otlp",
+		"tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	check(validLevel(c.Logging.Level), "logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	check(c.Logging.Format == "json" || c.Logging.Format == "text", "logging.format must be json or text, got %q", c.Logging.Format)
+	for _, pair := range c.Logging.Levels {
+		component, level, ok := strings.Cut(pair, "=")
+		check(ok && component != "" && validLevel(level), "logging.levels: %q is not component=level", pair)
+	}
+	check(c.Logging.SlowQueryThreshold >= 0, "logging.slow_query_threshold must not be negative")
+
+	check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "postgres",
+		"rate_limit.store must be memory or postgres, got %q", c.RateLimit.Store)
+	for name, rate := range map[string]string{
+		"reads": c.RateLimit.Reads, "writes": c.RateLimit.Writes,
+		"money": c.RateLimit.Money, "signups": c.RateLimit.Signups,
+	} {
+		_, _, err := ParseRate(rate)
+		check(err == nil, "rate_limit.%s: %v", name, err)
+	}
+
+	for name, limit := range map[string]int64{
+		"none_max_balance": c.KYC.NoneMaxBalance, "none_daily_outgoing": c.KYC.NoneDailyOutgoing,
+		"basic_max_balance": c.KYC.BasicMaxBalance, "basic_daily_outgoing": c.KYC.BasicDailyOutgoing,
+		"full_max_balance": c.KYC.FullMaxBalance, "full_daily_outgoing": c.KYC.FullDailyOutgoing,
+	} {
+		check(limit >= 0, "kyc.%s must not be negative", name)
+	}
+
+	check(c.AML.StructuringThreshold > 0, "aml.structuring_threshold must be positive")
+	check(c.AML.StructuringMargin > 0 && c.AML.StructuringMargin < 1, "aml.structuring_margin must be between 0 and 1, got %v", c.AML.StructuringMargin)
+	check(c.AML.StructuringCount > 0, "aml.structuring_count must be positive")
+	check(c.AML.StructuringWindow > 0, "aml.structuring_window must be positive")
+	check(c.AML.RapidMovementWindow > 0, "aml.rapid_movement_window must be positive")
+	check(c.AML.RapidMovementRatio > 0 && c.AML.RapidMovementRatio <= 1, "aml.rapid_movement_ratio must be above 0 and at most 1, got %v", c.AML.RapidMovementRatio)
+	check(c.AML.RapidMovementMinAmount >= 0, "aml.rapid_movement_min_amount must not be negative")
+	check(c.AML.NewWalletMaxAge > 0, "aml.new_wallet_max_age must be positive")
+	check(c.AML.NewWalletMinAmount >= 0, "aml.new_wallet_min_amount must not be negative")
+	for _, rule := range c.AML.Blocking {
+		check(rule == "structuring" || rule == "rapid_movement" || rule == "new_wallet_counterparty",
+			"aml.blocking: unknown rule %q", rule)
+	}
+
+	check(c.Sanctions.Threshold > 0 && c.Sanctions.Threshold <= 1, "sanctions.threshold must be above 0 and at most 1, got %v", c.Sanctions.Threshold)
+	check(c.Sanctions.TransferThreshold >= 0, "sanctions.transfer_threshold must not be negative")
+
+	check(c.Risk.Window > 0, "risk.window must be positive")
+	check(c.Risk.AmountMultiple >= 1, "risk.amount_multiple must be at least 1, got %v", c.Risk.AmountMultiple)
+	check(c.Risk.NightStart >= 0 && c.Risk.NightStart < 24, "risk.night_start must be an hour between 0 and 23, got %d", c.Risk.NightStart)
+	check(c.Risk.NightEnd >= 0 && c.Risk.NightEnd < 24, "risk.night_end must be an hour between 0 and 23, got %d", c.Risk.NightEnd)
+	check(c.Risk.ReviewScore > 0 && c.Risk.ReviewScore <= c.Risk.DenyScore && c.Risk.DenyScore <= 100,
+		"risk scores must satisfy 0 < review_score (%d) <= deny_score (%d) <= 100", c.Risk.ReviewScore, c.Risk.DenyScore)
+
+	check(c.Privacy.Retention > 0, "privacy.retention must be positive")
+
+	check(c.Mail.Driver == "log" || c.Mail.Driver == "file" || c.Mail.Driver == "smtp",
+		"mail.driver must be log, file or smtp, got %q", c.Mail.Driver)
+	_, err := mail.ParseAddress(c.Mail.From)
+	check(err == nil, "mail.from: %q is not an email address", c.Mail.From)
+	if c.Mail.Driver == "file" {
+		check(c.Mail.Dir != "", "mail.dir is required by the file driver")
+	}
+	if c.Mail.Driver == "smtp" {
+		check(c.Mail.SMTPHost != "", "mail.smtp_host is required by the smtp driver")
+		check(validPort(c.Mail.SMTPPort), "mail.smtp_port must be between 1 and 65535, got %d", c.Mail.SMTPPort)
+	}
+	verifyURL, err := url.Parse(c.Mail.VerifyURL)
+	check(err == nil && verifyURL.IsAbs(), "mail.verify_url: %q is not an absolute URL", c.Mail.VerifyURL)
+	check(c.Mail.VerificationTTL > 0, "mail.verification_ttl must be positive")
+	check(c.Mail.ResendCooldown >= 0, "mail.resend_cooldown must not be negative")
+
+	check(c.Notifications.LargeDebit >= 0, "notifications.large_debit must not be negative")
+	check(c.Notifications.LowBalance >= 0, "notifications.low_balance must not be negative")
+
+	check(c.PaymentLinks.Secret == "" || len(c.PaymentLinks.Secret) >= 32, "payment_links.secret must be at least 32 bytes")
+	linkURL, err := url.Parse(c.PaymentLinks.URL)
+	check(err == nil && linkURL.IsAbs(), "payment_links.url: %q is not an absolute URL", c.PaymentLinks.URL)
+	check(c.PaymentLinks.TTL > 0, "payment_links.ttl must be positive")
+
+	check(c.Escrow.Timeout > 0, "escrow.timeout must be positive")
+	check(c.Escrow.SweepInterval > 0, "escrow.sweep_interval must be positive")
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validLevel(level string) bool {
+	var l slog.Level
+	return l.UnmarshalText([]byte(level)) == nil
+}
+
+// ParseRate splits a rate such as "300/1m" into a request count and period.
+func ParseRate(rate string) (int, time.Duration, error) {
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not <requests>/<period>", rate)
+	}
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("%q: requests must be a positive integer", rate)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("%q: period must be a positive duration such as 1m", rate)
+	}
+	return limit, d, nil
+}
+
+func validProxy(proxy string) bool {
+	if strings.Contains(proxy, "/") {
+		_, _, err := net.ParseCIDR(proxy)
+		return err == nil
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// Duration is a time.Duration written as a string such as "30s" or "5m" in
+// files, environment variables and flags.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load("test", nil, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+http:
+  port: 8000
+  mode: release
+  read_timeout: 10s
+database:
+  max_open_conns: 50
+features:
+  docs: false
+`)
+
+	cfg, err := Load("test",
+		[]string{"--config", path, "--port", "7000"},
+		env(map[string]string{"PORT": "9000", "GIN_MODE": "test", "DB_MAX_OPEN_CONNS": "40"}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, 7000, cfg.HTTP.Port, "flags override environment and file")
+	assert.Equal(t, "test", cfg.HTTP.Mode, "environment overrides file")
+	assert.Equal(t, 40, cfg.Database.MaxOpenConns, "environment overrides file")
+	assert.Equal(t, Duration(10*time.Second), cfg.HTTP.ReadTimeout, "file overrides defaults")
+	assert.False(t, cfg.Features.Docs, "file overrides defaults")
+	assert.Equal(t, Default().HTTP.WriteTimeout, cfg.HTTP.WriteTimeout, "unset values keep defaults")
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[http]
+port = 8181
+trusted_proxies = ["10.0.0.0/8", "192.168.1.1"]
+
+[database]
+conn_max_lifetime = "1h"
+`)
+
+	cfg, err := Load("test", nil, env(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, 8181, cfg.HTTP.Port)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.HTTP.TrustedProxies)
+	assert.Equal(t, Duration(time.Hour), cfg.Database.ConnMaxLifetime)
+}
+
+func TestLoad_Int64(t *testing.T) {
+	cfg, err := Load("test", []string{"--kyc-full-max-balance", "10000000000"}, env(map[string]string{"KYC_NONE_MAX_BALANCE": "250"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10_000_000_000), cfg.KYC.FullMaxBalance)
+	assert.Equal(t, int64(250), cfg.KYC.NoneMaxBalance)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Run("unknown file key", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "http:\n  prot: 8080\n")
+		_, err := Load("test", []string{"--config", path}, env(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed environment value", func(t *testing.T) {
+		_, err := Load("test", nil, env(map[string]string{"HTTP_READ_TIMEOUT": "soon"}))
+		assert.ErrorContains(t, err, "HTTP_READ_TIMEOUT")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := Load("test",
+			[]string{"--port", "70000", "--gin-mode", "verbose", "--http-trusted-proxies", "not-an-ip"},
+			env(nil),
+		)
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "http.port")
+		assert.ErrorContains(t, err, "http.mode")
+		assert.ErrorContains(t, err, "not-an-ip")
+	})
+
+	t.Run("negative durations", func(t *testing.T) {
+		_, err := Load("test", []string{"--http-export-timeout", "-1s", "--http-shutdown-grace", "-5s"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "http.export_timeout")
+		assert.ErrorContains(t, err, "http.shutdown_grace")
+	})
+
+	t.Run("idle exceeds open connections", func(t *testing.T) {
+		_, err := Load("test", []string{"--db-max-open-conns", "2", "--db-max-idle-conns", "5"}, env(nil))
+		assert.ErrorContains(t, err, "max_idle_conns")
+	})
+
+	t.Run("logging", func(t *testing.T) {
+		_, err := Load("test", nil, env(map[string]string{"LOG_LEVEL": "loud", "LOG_LEVELS": "gorm=debug,jobs"}))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "logging.level")
+		assert.ErrorContains(t, err, `"jobs"`)
+	})
+
+	t.Run("tracing", func(t *testing.T) {
+		_, err := Load("test", []string{"--tracing-exporter", "jaeger", "--tracing-sample-ratio", "1.5"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "tracing.exporter")
+		assert.ErrorContains(t, err, "tracing.sample_ratio")
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		_, err := Load("test", nil, env(map[string]string{
+			"RATE_LIMIT_STORE": "redis", "RATE_LIMIT_MONEY": "30", "RATE_LIMIT_SIGNUPS": "0/1h",
+		}))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "rate_limit.store")
+		assert.ErrorContains(t, err, "rate_limit.money")
+		assert.ErrorContains(t, err, "rate_limit.signups")
+	})
+
+	t.Run("kyc", func(t *testing.T) {
+		_, err := Load("test", []string{"--kyc-basic-max-balance", "-1"}, env(nil))
+		assert.ErrorContains(t, err, "kyc.basic_max_balance")
+	})
+
+	t.Run("aml", func(t *testing.T) {
+		_, err := Load("test", []string{"--aml-structuring-margin", "1.5"}, env(map[string]string{
+			"AML_BLOCKING": "structuring,velocity",
+		}))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "aml.structuring_margin")
+		assert.ErrorContains(t, err, `aml.blocking: unknown rule "velocity"`)
+		assert.NotContains(t, err.Error(), `"structuring"`)
+	})
+
+	t.Run("sanctions", func(t *testing.T) {
+		_, err := Load("test", []string{"--sanctions-threshold", "0", "--sanctions-transfer-threshold", "-5"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "sanctions.threshold")
+		assert.ErrorContains(t, err, "sanctions.transfer_threshold")
+	})
+
+	t.Run("risk", func(t *testing.T) {
+		_, err := Load("test", []string{"--risk-night-end", "24", "--risk-review-score", "90"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "risk.night_end")
+		assert.ErrorContains(t, err, "review_score (90) <= deny_score (80)")
+	})
+
+	t.Run("privacy", func(t *testing.T) {
+		_, err := Load("test", []string{"--privacy-retention", "0s"}, env(nil))
+		assert.ErrorContains(t, err, "privacy.retention must be positive")
+	})
+
+	t.Run("mail", func(t *testing.T) {
+		_, err := Load("test", []string{"--mail-driver", "smtp", "--mail-from", "nobody", "--mail-verify-url", "/verify"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "mail.from")
+		assert.ErrorContains(t, err, "mail.smtp_host is required")
+		assert.ErrorContains(t, err, "mail.verify_url")
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		_, err := Load("test", []string{"--notify-low-balance", "-1"}, env(nil))
+		assert.ErrorContains(t, err, "notifications.low_balance must not be negative")
+	})
+
+	t.Run("payment links", func(t *testing.T) {
+		_, err := Load("test", []string{"--payment-link-secret", "short", "--payment-link-url", "pay"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "payment_links.secret")
+		assert.ErrorContains(t, err, "payment_links.url")
+	})
+
+	t.Run("escrow", func(t *testing.T) {
+		_, err := Load("test", []string{"--escrow-timeout", "0s", "--escrow-sweep-interval", "-1m"}, env(nil))
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "escrow.timeout must be positive")
+		assert.ErrorContains(t, err, "escrow.sweep_interval must be positive")
+	})
+}
+
+func TestParseRate(t *testing.T) {
+	limit, period, err := ParseRate("300/1m")
+	require.NoError(t, err)
+	assert.Equal(t, 300, limit)
+	assert.Equal(t, time.Minute, period)
+
+	for _, bad := range []string{"", "300", "x/1m", "-1/1m", "10/", "10/0s"} {
+		_, _, err := ParseRate(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPrint_Redacts(t *testing.T) {
+	t.Run("url", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.URL = "postgres://postgres:s3cret@db:5432/wallet_db"
+
+		var buf bytes.Buffer
+		require.NoError(t, cfg.Print(&buf))
+		assert.NotContains(t, buf.String(), "s3cret")
+		assert.Contains(t, buf.String(), "postgres://postgres:REDACTED@db:5432/wallet_db")
+		assert.Equal(t, "postgres://postgres:s3cret@db:5432/wallet_db", cfg.Database.URL, "original is untouched")
+	})
+
+	t.Run("url password parameter", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.URL = "postgres://db:5432/wallet_db?user=postgres&password=s3cret&sslmode=disable"
+
+		var buf bytes.Buffer
+		require.NoError(t, cfg.Print(&buf))
+		assert.NotContains(t, buf.String(), "s3cret")
+		assert.Contains(t, buf.String(), "password=REDACTED")
+		assert.Contains(t, buf.String(), "sslmode=disable")
+	})
+
+	t.Run("key value dsn", func(t *testing.T) {
+		cfg := Default()
+
+		var buf bytes.Buffer
+		require.NoError(t, cfg.Print(&buf))
+		assert.Contains(t, buf.String(), "password=REDACTED")
+		assert.Contains(t, buf.String(), "read_timeout: 15s")
+	})
+
+	t.Run("key value dsn with a quoted password", func(t *testing.T) {
+		cfg := Default()
+		cfg.Database.URL = `host=db user=postgres password='hunter 2\'x' dbname=wallet_db`
+
+		var buf bytes.Buffer
+		require.NoError(t, cfg.Print(&buf))
+		assert.NotContains(t, buf.String(), "hunter")
+		assert.NotContains(t, buf.String(), `2\'x`)
+		assert.Contains(t, buf.String(), "password=REDACTED dbname=wallet_db")
+	})
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "REDACTED"
+
+// dsnPassword matches a key/value DSN password, quoted values included:
+// password='pa ss' or password = s3cret.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted returns a copy of c with secrets masked. Connection strings keep
+// everything but the password so they stay useful for debugging.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.HTTP.TrustedProxies = append([]string(nil), c.HTTP.TrustedProxies...)
+
+	walk(reflect.ValueOf(&out).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") != "true" || value.Kind() != reflect.String || value.String() == "" {
+			return
+		}
+		value.SetString(redactSecret(value.String()))
+	})
+	return &out
+}
+
+func redactSecret(secret string) string {
+	if u, err := url.Parse(secret); err == nil && u.Scheme != "" && u.Host != "" {
+		if u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+			}
+		}
+		// libpq also takes the password as a query parameter.
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", redacted)
+			u.RawQuery = query.Encode()
+		}
+		return u.String()
+	}
+	if dsnPassword.MatchString(secret) {
+		return dsnPassword.ReplaceAllString(secret, "${1}"+redacted)
+	}
+	return redacted
+}
+
+// Print writes the redacted configuration as YAML.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
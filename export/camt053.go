@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"wallet-api/models"
+)
+
+// camt053Writer renders ISO 20022 BankToCustomerStatement messages
+// (camt.053.001.02).
+type camt053Writer struct {
+	w    *bufio.Writer
+	opts Options
+}
+
+func newCAMT053Writer(w io.Writer, opts Options) *camt053Writer {
+	return &camt053Writer{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (c *camt053Writer) Begin(header models.StatementHeader) error {
+	c.opts.begin(header)
+	id := fmt.Sprintf("STMT-%d-%s", header.WalletID, header.To.UTC().Format("20060102150405"))
+	created := isoDateTime(header.GeneratedAt)
+
+	_, err := fmt.Fprintf(c.w, `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr>
+      <MsgId>%s</MsgId>
+      <CreDtTm>%s</CreDtTm>
+    </GrpHdr>
+    <Stmt>
+      <Id>%s</Id>
+      <CreDtTm>%s</CreDtTm>
+      <FrToDt>
+        <FrDtTm>%s</FrDtTm>
+        <ToDtTm>%s</ToDtTm>
+      </FrToDt>
+      <Acct>
+        <Id>
+          <Othr>
+            <Id>%d</Id>
+          </Othr>
+        </Id>
+        <Ccy>%s</Ccy>
+      </Acct>
+%s%s`,
+		id, created, id, created,
+		isoDateTime(header.From), isoDateTime(header.To),
+		header.WalletID, escape(c.opts.Currency),
+		c.balance("OPBD", header.OpeningBalance, header.From),
+		c.balance("CLBD", header.ClosingBalance, header.To),
+	)
+	return err
+}
+
+func (c *camt053Writer) balance(code string, amount int64, at time.Time) string {
+	return fmt.Sprintf(`      <Bal>
+        <Tp>
+          <CdOrPrtry>
+            <Cd>%s</Cd>
+          </CdOrPrtry>
+        </Tp>
+        <Amt Ccy="%s">%s</Amt>
+        <CdtDbtInd>%s</CdtDbtInd>
+        <Dt>
+          <DtTm>%s</DtTm>
+        </Dt>
+      </Bal>
+`, code, escape(c.opts.Currency), FormatAmount(abs(amount), c.opts.minorUnits), creditDebit(amount), isoDateTime(at))
+}
+
+func (c *camt053Writer) Line(line models.StatementLine) error {
+	_, err := fmt.Fprintf(c.w, `      <Ntry>
+        <NtryRef>%d</NtryRef>
+        <Amt Ccy="%s">%s</Amt>
+        <CdtDbtInd>%s</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt>
+          <DtTm>%s</DtTm>
+        </BookgDt>
+        <ValDt>
+          <DtTm>%s</DtTm>
+        </ValDt>
+        <AcctSvcrRef>%s</AcctSvcrRef>
+        <BkTxCd>
+          <Prtry>
+            <Cd>%s</Cd>
+            <Issr>WALLETAPI</Issr>
+          </Prtry>
+        </BkTxCd>
+        <NtryDtls>
+          <TxDtls>
+            <Refs>
+              <EndToEndId>%s</EndToEndId>
+            </Refs>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+`,
+		line.TransactionID,
+		escape(c.opts.Currency),
+		FormatAmount(abs(line.Amount), c.opts.minorUnits),
+		creditDebit(line.Amount),
+		isoDateTime(line.CreatedAt),
+		isoDateTime(line.CreatedAt),
+		escape(line.ReferenceNumber),
+		escape(string(line.Type)),
+		escape(line.ReferenceNumber),
+	)
+	return err
+}
+
+func (c *camt053Writer) End() error {
+	if _, err := io.WriteString(c.w, "    </Stmt>\n  </BkToCstmrStmt>\n</Document>\n"); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+func creditDebit(amount int64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func isoDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// escape makes s safe to embed as XML character data or attribute value.
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
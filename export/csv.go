@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"wallet-api/models"
+)
+
+type csvWriter struct {
+	w    *csv.Writer
+	opts Options
+}
+
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), opts: opts}
+}
+
+func (c *csvWriter) Begin(header models.StatementHeader) error {
+	c.opts.begin(header)
+	return c.w.Write([]string{
+		"date", "transaction_id", "reference_number", "type",
+		"counterparty_wallet_id", "amount", "balance", "currency",
+	})
+}
+
+func (c *csvWriter) Line(line models.StatementLine) error {
+	counterparty := ""
+	if line.CounterpartyID != nil {
+		counterparty = strconv.FormatUint(uint64(*line.CounterpartyID), 10)
+	}
+
+	err := c.w.Write([]string{
+		line.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatUint(uint64(line.TransactionID), 10),
+		line.ReferenceNumber,
+		string(line.Type),
+		counterparty,
+		FormatAmount(line.Amount, c.opts.minorUnits),
+		FormatAmount(line.RunningBalance, c.opts.minorUnits),
+		c.opts.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	return c.w.Error()
+}
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
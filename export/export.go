@@ -0,0 +1,137 @@
+// Package export renders wallet statements in formats accepted by accounting
+// tools. Every writer streams: lines are written as they arrive and nothing
+// but the header is held in memory. It also packs a user's data into the
+// archive handed over on a data subject access request.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"wallet-api/models"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+// Formats lists the supported formats in the order they are documented.
+var Formats = []string{FormatCSV, FormatOFX, FormatCAMT053}
+
+// DefaultCurrency is used when neither the statement header nor
+// Options.Currency names a currency.
+const DefaultCurrency = models.DefaultTenantCurrency
+
+// Options controls how amounts are rendered.
+type Options struct {
+	// Currency is the ISO 4217 code written into the statement when its
+	// header, which carries the wallet's, names none.
+	Currency string
+
+	// minorUnits is the number of decimal places of Currency; wallet amounts
+	// are stored in the smallest unit.
+	minorUnits int
+}
+
+// Writer is implemented by every format. It matches
+// services.StatementWriter.
+type Writer interface {
+	Begin(header models.StatementHeader) error
+	Line(line models.StatementLine) error
+	End() error
+}
+
+// begin takes the currency from the statement header when it names one,
+// and renders amounts with its minor units.
+func (o *Options) begin(header models.StatementHeader) {
+	if header.Currency != "" {
+		o.Currency = header.Currency
+	}
+	o.minorUnits = MinorUnits(o.Currency)
+}
+
+// NewWriter returns a writer for format that renders to w.
+func NewWriter(format string, w io.Writer, opts Options) (Writer, error) {
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	opts.minorUnits = MinorUnits(opts.Currency)
+
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, opts), nil
+	case FormatOFX:
+		return newOFXWriter(w, opts), nil
+	case FormatCAMT053:
+		return newCAMT053Writer(w, opts), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatCAMT053:
+		return "application/xml"
+	}
+	return "application/octet-stream"
+}
+
+// Extension returns the file extension of a format, without the dot.
+func Extension(format string) string {
+	switch format {
+	case FormatOFX:
+		return "ofx"
+	case FormatCAMT053:
+		return "xml"
+	}
+	return format
+}
+
+// FormatAmount renders an amount in minor units as a decimal string, e.g.
+// 150075 with two minor units becomes "1500.75".
+func FormatAmount(amount int64, minorUnits int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if minorUnits <= 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+
+	scale := int64(1)
+	for i := 0; i < minorUnits; i++ {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/scale, minorUnits, amount%scale)
+}
+
+// MinorUnits returns the number of decimal places ISO 4217 gives currency:
+// 0 for JPY, 3 for KWD and 2 for most others.
+func MinorUnits(currency string) int {
+	switch strings.ToUpper(currency) {
+	case "BIF", "CLP", "DJF", "GNF", "ISK", "JPY", "KMF", "KRW", "PYG",
+		"RWF", "UGX", "UYI", "VND", "VUV", "XAF", "XOF", "XPF":
+		return 0
+	case "BHD", "IQD", "JOD", "KWD", "LYD", "OMR", "TND":
+		return 3
+	case "CLF", "UYW":
+		return 4
+	}
+	return 2
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
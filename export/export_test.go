@@ -0,0 +1,223 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wallet-api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func sampleStatement() (models.StatementHeader, []models.StatementLine) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+	counterparty := uint(42)
+
+	header := models.StatementHeader{
+		WalletID:       17,
+		Currency:       "EUR",
+		From:           from,
+		To:             to,
+		OpeningBalance: 10000,
+		ClosingBalance: 12550,
+		GeneratedAt:    time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC),
+	}
+	lines := []models.StatementLine{
+		{
+			TransactionID:   101,
+			Type:            models.TransactionTypeDeposit,
+			ReferenceNumber: "DEP-1741000000000000000",
+			Amount:          5000,
+			RunningBalance:  15000,
+			CreatedAt:       time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			TransactionID:   102,
+			Type:            models.TransactionTypeTransfer,
+			ReferenceNumber: "TRF-1742000000000000000 <rent & bills>",
+			CounterpartyID:  &counterparty,
+			Amount:          -2450,
+			RunningBalance:  12550,
+			CreatedAt:       time.Date(2025, 3, 15, 18, 5, 7, 0, time.UTC),
+		},
+	}
+	return header, lines
+}
+
+func render(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, Options{})
+	require.NoError(t, err)
+
+	header, lines := sampleStatement()
+	require.NoError(t, w.Begin(header))
+	for _, line := range lines {
+		require.NoError(t, w.Line(line))
+	}
+	require.NoError(t, w.End())
+	return buf.Bytes()
+}
+
+func TestWriters_Golden(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			got := render(t, format)
+			golden := filepath.Join("testdata", "statement."+format+".golden")
+
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+
+			if format != FormatCSV {
+				assert.NoError(t, wellFormed(got))
+			}
+		})
+	}
+}
+
+func wellFormed(doc []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestWriters_Currency(t *testing.T) {
+	header, lines := sampleStatement()
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			for _, tc := range []struct {
+				header   string
+				options  string
+				expected string
+			}{
+				{"GBP", "EUR", "GBP"},
+				{"", "EUR", "EUR"},
+				{"", "", DefaultCurrency},
+			} {
+				var buf bytes.Buffer
+				w, err := NewWriter(format, &buf, Options{Currency: tc.options})
+				require.NoError(t, err)
+				header.Currency = tc.header
+				require.NoError(t, w.Begin(header))
+				require.NoError(t, w.Line(lines[0]))
+				require.NoError(t, w.End())
+				assert.Contains(t, buf.String(), tc.expected)
+				if tc.expected != "EUR" {
+					assert.NotContains(t, buf.String(), "EUR")
+				}
+			}
+		})
+	}
+}
+
+func TestWriters_MinorUnits(t *testing.T) {
+	header, lines := sampleStatement()
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			for currency, expected := range map[string]string{"JPY": "5000", "KWD": "5.000"} {
+				var buf bytes.Buffer
+				w, err := NewWriter(format, &buf, Options{})
+				require.NoError(t, err)
+				header.Currency = currency
+				require.NoError(t, w.Begin(header))
+				require.NoError(t, w.Line(lines[0]))
+				require.NoError(t, w.End())
+				assert.Contains(t, buf.String(), expected, currency)
+				assert.NotContains(t, buf.String(), "50.00", currency)
+			}
+		})
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := NewWriter("qif", &bytes.Buffer{}, Options{})
+	assert.Error(t, err)
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "1500.75", FormatAmount(150075, 2))
+	assert.Equal(t, "-0.05", FormatAmount(-5, 2))
+	assert.Equal(t, "0.00", FormatAmount(0, 2))
+	assert.Equal(t, "1200", FormatAmount(1200, 0))
+	assert.Equal(t, "1.200", FormatAmount(1200, 3))
+}
+
+func TestMinorUnits(t *testing.T) {
+	assert.Equal(t, 2, MinorUnits("USD"))
+	assert.Equal(t, 2, MinorUnits("eur"))
+	assert.Equal(t, 0, MinorUnits("JPY"))
+	assert.Equal(t, 3, MinorUnits("KWD"))
+}
+
+func TestWriteUserData(t *testing.T) {
+	created := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	source := uint(7)
+	data := &models.UserExport{
+		ExportedAt: time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC),
+		User:       models.User{ID: 3, Name: "Jane Doe", Email: "jane@example.com", CreatedAt: created},
+		Wallets:    []models.Wallet{{ID: 7, UserID: 3, Balance: 2500, CreatedAt: created}},
+		Transactions: []models.Transaction{{
+			ID:              11,
+			SourceWalletID:  &source,
+			TargetWalletID:  8,
+			Amount:          500,
+			Type:            models.TransactionTypeTransfer,
+			ReferenceNumber: "TRF-1",
+			Status:          models.TransactionStatusCompleted,
+			DeviceID:        "device-1",
+			CreatedAt:       created,
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteUserData(&buf, data))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+	}
+	require.Len(t, files, 4)
+
+	var profile models.User
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, "jane@example.com", profile.Email)
+
+	var wallets []models.WalletResponse
+	require.NoError(t, json.Unmarshal(files["wallets.json"], &wallets))
+	require.Len(t, wallets, 1)
+	assert.Equal(t, int64(2500), wallets[0].Balance)
+
+	var transactions []map[string]interface{}
+	require.NoError(t, json.Unmarshal(files["transactions.json"], &transactions))
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "TRF-1", transactions[0]["reference_number"])
+	assert.NotContains(t, transactions[0], "device_id", "internal fields are not exported")
+
+	assert.JSONEq(t, "[]", string(files["verifications.json"]))
+}
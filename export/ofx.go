@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"wallet-api/models"
+)
+
+// ofxTime is the OFX datetime format in UTC.
+const ofxTime = "20060102150405.000[0:GMT]"
+
+// ofxWriter renders OFX 2.1.1 (XML) bank statements.
+type ofxWriter struct {
+	w      *bufio.Writer
+	opts   Options
+	header models.StatementHeader
+}
+
+func newOFXWriter(w io.Writer, opts Options) *ofxWriter {
+	return &ofxWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (o *ofxWriter) Begin(header models.StatementHeader) error {
+	o.header = header
+	o.opts.begin(header)
+	_, err := fmt.Fprintf(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <SIGNONMSGSRSV1>
+    <SONRS>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <DTSERVER>%s</DTSERVER>
+      <LANGUAGE>ENG</LANGUAGE>
+    </SONRS>
+  </SIGNONMSGSRSV1>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <TRNUID>%d</TRNUID>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <STMTRS>
+        <CURDEF>%s</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>WALLETAPI</BANKID>
+          <ACCTID>%d</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <DTSTART>%s</DTSTART>
+          <DTEND>%s</DTEND>
+`,
+		ofxDate(header.GeneratedAt),
+		header.WalletID,
+		escape(o.opts.Currency),
+		header.WalletID,
+		ofxDate(header.From),
+		ofxDate(header.To),
+	)
+	return err
+}
+
+func (o *ofxWriter) Line(line models.StatementLine) error {
+	name := "Deposit"
+	if line.CounterpartyID != nil {
+		name = fmt.Sprintf("Wallet %d", *line.CounterpartyID)
+	}
+
+	_, err := fmt.Fprintf(o.w, `          <STMTTRN>
+            <TRNTYPE>%s</TRNTYPE>
+            <DTPOSTED>%s</DTPOSTED>
+            <TRNAMT>%s</TRNAMT>
+            <FITID>%d</FITID>
+            <NAME>%s</NAME>
+            <MEMO>%s</MEMO>
+          </STMTTRN>
+`,
+		ofxTransactionType(line),
+		ofxDate(line.CreatedAt),
+		FormatAmount(line.Amount, o.opts.minorUnits),
+		line.TransactionID,
+		escape(name),
+		escape(line.ReferenceNumber),
+	)
+	return err
+}
+
+func (o *ofxWriter) End() error {
+	_, err := fmt.Fprintf(o.w, `        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>%s</BALAMT>
+          <DTASOF>%s</DTASOF>
+        </LEDGERBAL>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`,
+		FormatAmount(o.header.ClosingBalance, o.opts.minorUnits),
+		ofxDate(o.header.To),
+	)
+	if err != nil {
+		return err
+	}
+	return o.w.Flush()
+}
+
+func ofxTransactionType(line models.StatementLine) string {
+	switch {
+	case line.Type == models.TransactionTypeDeposit:
+		return "DEP"
+	case line.Type == models.TransactionTypeTransfer, line.Type == models.TransactionTypePocket,
+		line.Type == models.TransactionTypeEscrow:
+		return "XFER"
+	case line.Amount < 0:
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format(ofxTime)
+}
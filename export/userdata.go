@@ -0,0 +1,82 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+
+	"wallet-api/models"
+)
+
+// UserDataContentType is the MIME type of a user data export.
+const UserDataContentType = "application/zip"
+
+// WriteUserData renders a user's data as a zip archive of JSON files:
+// profile.json, wallets.json, transactions.json and verifications.json.
+// Wallets and transactions use the same shape as the API responses.
+func WriteUserData(w io.Writer, data *models.UserExport) error {
+	wallets := make([]models.WalletResponse, 0, len(data.Wallets))
+	for _, wallet := range data.Wallets {
+		wallets = append(wallets, models.WalletResponse{
+			ID:        wallet.ID,
+			UserID:    wallet.UserID,
+			Balance:   wallet.Balance,
+			Currency:  wallet.Currency,
+			FrozenAt:  wallet.FrozenAt,
+			ParentID:  wallet.ParentID,
+			Name:      wallet.Name,
+			CreatedAt: wallet.CreatedAt,
+			UpdatedAt: wallet.UpdatedAt,
+		})
+	}
+
+	transactions := make([]models.TransferResponse, 0, len(data.Transactions))
+	for _, t := range data.Transactions {
+		transactions = append(transactions, models.TransferResponse{
+			ID:              t.ID,
+			SourceWalletID:  t.SourceWalletID,
+			TargetWalletID:  t.TargetWalletID,
+			Amount:          t.Amount,
+			Fee:             t.Fee,
+			Type:            t.Type,
+			ReferenceNumber: t.ReferenceNumber,
+			Status:          t.Status,
+			Description:     t.Description,
+			CreatedAt:       t.CreatedAt,
+			UpdatedAt:       t.UpdatedAt,
+		})
+	}
+
+	verifications := data.Verifications
+	if verifications == nil {
+		verifications = []models.VerificationCase{}
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"profile.json", data.User},
+		{"wallets.json", wallets},
+		{"transactions.json", transactions},
+		{"verifications.json", verifications},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: data.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
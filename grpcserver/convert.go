@@ -0,0 +1,56 @@
+package grpcserver
+
+import (
+	walletv1 "wallet-api/api/wallet/v1"
+	"wallet-api/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func userToProto(u *models.User) *walletv1.User {
+	return &walletv1.User{
+		Id:        uint64(u.ID),
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		UpdatedAt: timestamppb.New(u.UpdatedAt),
+	}
+}
+
+func walletToProto(w *models.Wallet) *walletv1.Wallet {
+	return &walletv1.Wallet{
+		Id:        uint64(w.ID),
+		UserId:    uint64(w.UserID),
+		Balance:   w.Balance,
+		Currency:  w.Currency,
+		CreatedAt: timestamppb.New(w.CreatedAt),
+		UpdatedAt: timestamppb.New(w.UpdatedAt),
+	}
+}
+
+// walletFromProto is the wallet a CreateWalletRequest asks for; an empty
+// currency is left for the service to default.
+func walletFromProto(req *walletv1.CreateWalletRequest) models.Wallet {
+	return models.Wallet{
+		UserID:   uint(req.GetUserId()),
+		Currency: req.GetCurrency(),
+	}
+}
+
+func transactionToProto(t *models.Transaction) *walletv1.Transaction {
+	pb := &walletv1.Transaction{
+		Id:              uint64(t.ID),
+		TargetWalletId:  uint64(t.TargetWalletID),
+		Amount:          t.Amount,
+		Type:            string(t.Type),
+		ReferenceNumber: t.ReferenceNumber,
+		Status:          t.Status,
+		CreatedAt:       timestamppb.New(t.CreatedAt),
+		UpdatedAt:       timestamppb.New(t.UpdatedAt),
+	}
+	if t.SourceWalletID != nil {
+		id := uint64(*t.SourceWalletID)
+		pb.SourceWalletId = &id
+	}
+	return pb
+}
@@ -0,0 +1,253 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	walletv1 "wallet-api/api/wallet/v1"
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// DefaultWatchInterval is how often WatchWallet checks a wallet for changes.
+const DefaultWatchInterval = time.Second
+
+// Statuses of a transfer reported in TransferResponse.Status.
+const (
+	TransferCompleted        = "completed"
+	TransferHeld             = "held"
+	TransferAwaitingApproval = "awaiting_approval"
+)
+
+// Postgres error codes of transactions that lost a race with another.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// Server implements the wallet.v1 gRPC services on top of the same services
+// used by the REST handlers, so both transports share business logic.
+type Server struct {
+	walletv1.UnimplementedUserServiceServer
+	walletv1.UnimplementedWalletServiceServer
+	walletv1.UnimplementedTransferServiceServer
+
+	userService     services.UserServiceInterface
+	walletService   services.IWalletService
+	transferService services.ITransferService
+
+	// WatchInterval is the polling interval used by WatchWallet.
+	WatchInterval time.Duration
+}
+
+func NewServer(
+	userService services.UserServiceInterface,
+	walletService services.IWalletService,
+	transferService services.ITransferService,
+) *Server {
+	return &Server{
+		userService:     userService,
+		walletService:   walletService,
+		transferService: transferService,
+		WatchInterval:   DefaultWatchInterval,
+	}
+}
+
+// Register attaches all three services to the given gRPC server.
+func (s *Server) Register(gs *grpc.Server) {
+	walletv1.RegisterUserServiceServer(gs, s)
+	walletv1.RegisterWalletServiceServer(gs, s)
+	walletv1.RegisterTransferServiceServer(gs, s)
+}
+
+func (s *Server) CreateUser(ctx context.Context, req *walletv1.CreateUserRequest) (*walletv1.User, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	user := models.User{Name: req.GetName(), Email: req.GetEmail()}
+	if err := s.userService.Create(ctx, &user); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return userToProto(&user), nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *walletv1.GetUserRequest) (*walletv1.User, error) {
+	user, err := s.userService.GetByID(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return userToProto(user), nil
+}
+
+func (s *Server) CreateWallet(ctx context.Context, req *walletv1.CreateWalletRequest) (*walletv1.Wallet, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	wallet := walletFromProto(req)
+	if err := s.walletService.Create(ctx, &wallet); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return walletToProto(&wallet), nil
+}
+
+func (s *Server) GetWallet(ctx context.Context, req *walletv1.GetWalletRequest) (*walletv1.Wallet, error) {
+	wallet, err := s.walletService.GetByID(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return walletToProto(wallet), nil
+}
+
+func (s *Server) ListUserWallets(ctx context.Context, req *walletv1.ListUserWalletsRequest) (*walletv1.ListUserWalletsResponse, error) {
+	wallets, err := s.walletService.GetByUserID(ctx, uint(req.GetUserId()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	resp := &walletv1.ListUserWalletsResponse{}
+	for i := range wallets {
+		resp.Wallets = append(resp.Wallets, walletToProto(&wallets[i]))
+	}
+	return resp, nil
+}
+
+// WatchWallet sends the wallet immediately and then again whenever its
+// balance or update time changes. Polling keeps it correct when several
+// instances share the database.
+func (s *Server) WatchWallet(req *walletv1.WatchWalletRequest, stream grpc.ServerStreamingServer[walletv1.Wallet]) error {
+	ctx := stream.Context()
+	wallet, err := s.walletService.GetByID(ctx, uint(req.GetId()))
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+	if err := stream.Send(walletToProto(wallet)); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.WatchInterval)
+	defer ticker.Stop()
+
+	last := wallet
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := s.walletService.GetByID(ctx, uint(req.GetId()))
+			if err != nil {
+				return toStatus(ctx, err)
+			}
+			if current.Balance == last.Balance && current.UpdatedAt.Equal(last.UpdatedAt) {
+				continue
+			}
+			if err := stream.Send(walletToProto(current)); err != nil {
+				return err
+			}
+			last = current
+		}
+	}
+}
+
+func (s *Server) Transfer(ctx context.Context, req *walletv1.TransferRequest) (*walletv1.TransferResponse, error) {
+	if req.GetSourceWalletId() == 0 || req.GetTargetWalletId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "source_wallet_id and target_wallet_id are required")
+	}
+
+	err := s.transferService.Transfer(ctx, uint(req.GetSourceWalletId()), uint(req.GetTargetWalletId()), req.GetAmount())
+	var held *services.HeldError
+	if errors.As(err, &held) {
+		return &walletv1.TransferResponse{
+			Message:       "transfer held for review",
+			Status:        TransferHeld,
+			TransactionId: uint64(held.TransactionID),
+		}, nil
+	}
+	var pending *services.ApprovalRequiredError
+	if errors.As(err, &pending) {
+		return &walletv1.TransferResponse{
+			Message:    "transfer awaiting approval",
+			Status:     TransferAwaitingApproval,
+			ApprovalId: uint64(pending.ApprovalID),
+		}, nil
+	}
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &walletv1.TransferResponse{Message: "transfer successful", Status: TransferCompleted}, nil
+}
+
+func (s *Server) Deposit(ctx context.Context, req *walletv1.DepositRequest) (*walletv1.DepositResponse, error) {
+	if req.GetWalletId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "wallet_id is required")
+	}
+
+	if err := s.transferService.Deposit(ctx, uint(req.GetWalletId()), req.GetAmount()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &walletv1.DepositResponse{Message: "deposit successful"}, nil
+}
+
+func (s *Server) ListTransactions(ctx context.Context, req *walletv1.ListTransactionsRequest) (*walletv1.ListTransactionsResponse, error) {
+	transactions, err := s.transferService.GetTransactionsByWalletID(ctx, uint(req.GetWalletId()))
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	resp := &walletv1.ListTransactionsResponse{}
+	for i := range transactions {
+		resp.Transactions = append(resp.Transactions, transactionToProto(&transactions[i]))
+	}
+	return resp, nil
+}
+
+// toStatus maps service errors onto gRPC codes, so clients can tell a
+// request they must change from one that may succeed later and from a
+// failure on the server's side. Errors the services do not report as a
+// rejection are Internal; their details are logged rather than returned.
+func toStatus(ctx context.Context, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrEmailInUse):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, services.ErrInsufficientBalance),
+		errors.Is(err, services.ErrWalletFrozen),
+		errors.Is(err, services.ErrUserDeactivated),
+		errors.Is(err, services.ErrScreeningPending):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, services.ErrInitiatorRequired):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, services.ErrKYCLimitExceeded), errors.Is(err, services.ErrSpendingCapExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, services.ErrNotWalletMember),
+		errors.Is(err, services.ErrWalletRoleForbidden),
+		errors.Is(err, services.ErrScreeningBlocked),
+		errors.Is(err, services.ErrTransferDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case services.IsInvalid(err),
+		errors.Is(err, services.ErrPocketWallet),
+		errors.Is(err, services.ErrUnsupportedCurrency):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &pgErr) && (pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected):
+		// Lost a race with a concurrent transaction; the call can be retried.
+		return status.Error(codes.Aborted, "conflict with a concurrent request")
+	}
+	logging.For("grpc").ErrorContext(ctx, "request failed", "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
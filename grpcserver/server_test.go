@@ -0,0 +1,375 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	walletv1 "wallet-api/api/wallet/v1"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+// fakeStore backs in-memory implementations of the three service interfaces.
+type fakeStore struct {
+	mu           sync.Mutex
+	users        map[uint]*models.User
+	wallets      map[uint]*models.Wallet
+	transactions []models.Transaction
+}
+
+type fakeUserService struct{ *fakeStore }
+type fakeWalletService struct{ *fakeStore }
+type fakeTransferService struct{ *fakeStore }
+
+func (s fakeUserService) Create(_ context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = uint(len(s.users) + 1)
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s fakeUserService) GetByID(_ context.Context, id uint) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Transfers of these amounts are held for review and wait for approval.
+const (
+	heldAmount     = 666
+	approvalAmount = 777
+)
+
+// The gRPC API does not manage users beyond creating and reading them.
+var errNotServed = errors.New("not served over gRPC")
+
+func (s fakeUserService) Update(context.Context, uint, services.UserUpdate) (*models.User, error) {
+	return nil, errNotServed
+}
+
+func (s fakeUserService) List(context.Context, repositories.UserFilter, int, int) (*models.UserPage, error) {
+	return nil, errNotServed
+}
+
+func (s fakeUserService) Deactivate(context.Context, uint, string) (*models.User, error) {
+	return nil, errNotServed
+}
+
+func (s fakeUserService) Reactivate(context.Context, uint) (*models.User, error) {
+	return nil, errNotServed
+}
+
+func (s fakeWalletService) Create(_ context.Context, wallet *models.Wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[wallet.UserID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	wallet.ID = uint(len(s.wallets) + 1)
+	if wallet.Currency == "" {
+		wallet.Currency = models.DefaultTenantCurrency
+	}
+	wallet.UpdatedAt = time.Now()
+	s.wallets[wallet.ID] = wallet
+	return nil
+}
+
+func (s fakeWalletService) GetByID(_ context.Context, id uint) (*models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.wallets[id]; ok {
+		copy := *w
+		return &copy, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s fakeWalletService) GetByUserID(_ context.Context, userID uint) ([]models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var wallets []models.Wallet
+	for _, w := range s.wallets {
+		if w.UserID == userID {
+			wallets = append(wallets, *w)
+		}
+	}
+	return wallets, nil
+}
+
+func (s fakeTransferService) Transfer(_ context.Context, sourceWalletID, targetWalletID uint, amount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, target := s.wallets[sourceWalletID], s.wallets[targetWalletID]
+	if source == nil || target == nil {
+		return gorm.ErrRecordNotFound
+	}
+	if source.Balance < amount {
+		return services.ErrInsufficientBalance
+	}
+	// Amounts the fakes treat like the real services' review and approval
+	// thresholds.
+	switch amount {
+	case heldAmount:
+		s.transactions = append(s.transactions, models.Transaction{
+			ID: uint(len(s.transactions) + 1), SourceWalletID: &sourceWalletID, TargetWalletID: targetWalletID,
+			Amount: amount, Type: models.TransactionTypeTransfer, Status: models.TransactionStatusPending,
+		})
+		return &services.HeldError{TransactionID: uint(len(s.transactions))}
+	case approvalAmount:
+		return &services.ApprovalRequiredError{ApprovalID: 7}
+	}
+	source.Balance -= amount
+	target.Balance += amount
+	source.UpdatedAt, target.UpdatedAt = time.Now(), time.Now()
+	s.transactions = append(s.transactions, models.Transaction{
+		ID: uint(len(s.transactions) + 1), SourceWalletID: &sourceWalletID, TargetWalletID: targetWalletID,
+		Amount: amount, Type: models.TransactionTypeTransfer, Status: "completed",
+	})
+	return nil
+}
+
+func (s fakeTransferService) Deposit(_ context.Context, walletID uint, amount int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wallet := s.wallets[walletID]
+	if wallet == nil {
+		return gorm.ErrRecordNotFound
+	}
+	wallet.Balance += amount
+	wallet.UpdatedAt = time.Now()
+	s.transactions = append(s.transactions, models.Transaction{
+		ID: uint(len(s.transactions) + 1), TargetWalletID: walletID,
+		Amount: amount, Type: models.TransactionTypeDeposit, Status: "completed",
+	})
+	return nil
+}
+
+func (s fakeTransferService) GetTransactionsByWalletID(_ context.Context, walletID uint) ([]models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var transactions []models.Transaction
+	for _, t := range s.transactions {
+		if t.TargetWalletID == walletID || (t.SourceWalletID != nil && *t.SourceWalletID == walletID) {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+func setupTestClient(t *testing.T) *grpc.ClientConn {
+	store := &fakeStore{users: map[uint]*models.User{}, wallets: map[uint]*models.Wallet{}}
+	server := NewServer(fakeUserService{store}, fakeWalletService{store}, fakeTransferService{store})
+	server.WatchInterval = 10 * time.Millisecond
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	server.Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestServer_CompleteFlow(t *testing.T) {
+	conn := setupTestClient(t)
+	ctx := context.Background()
+	users := walletv1.NewUserServiceClient(conn)
+	wallets := walletv1.NewWalletServiceClient(conn)
+	transfers := walletv1.NewTransferServiceClient(conn)
+
+	john, err := users.CreateUser(ctx, &walletv1.CreateUserRequest{Name: "John Doe", Email: "john@example.com"})
+	require.NoError(t, err)
+	jane, err := users.CreateUser(ctx, &walletv1.CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com"})
+	require.NoError(t, err)
+
+	source, err := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: john.Id})
+	require.NoError(t, err)
+	target, err := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: jane.Id})
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultTenantCurrency, source.Currency)
+
+	euros, err := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: john.Id, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", euros.Currency)
+
+	_, err = transfers.Deposit(ctx, &walletv1.DepositRequest{WalletId: source.Id, Amount: 1000})
+	require.NoError(t, err)
+	_, err = transfers.Transfer(ctx, &walletv1.TransferRequest{SourceWalletId: source.Id, TargetWalletId: target.Id, Amount: 400})
+	require.NoError(t, err)
+
+	got, err := wallets.GetWallet(ctx, &walletv1.GetWalletRequest{Id: source.Id})
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), got.Balance)
+
+	list, err := wallets.ListUserWallets(ctx, &walletv1.ListUserWalletsRequest{UserId: jane.Id})
+	require.NoError(t, err)
+	require.Len(t, list.Wallets, 1)
+	assert.Equal(t, int64(400), list.Wallets[0].Balance)
+	assert.Equal(t, models.DefaultTenantCurrency, list.Wallets[0].Currency)
+
+	history, err := transfers.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: source.Id})
+	require.NoError(t, err)
+	require.Len(t, history.Transactions, 2)
+	assert.Nil(t, history.Transactions[0].SourceWalletId)
+	assert.Equal(t, source.Id, history.Transactions[1].GetSourceWalletId())
+}
+
+func TestServer_Errors(t *testing.T) {
+	conn := setupTestClient(t)
+	ctx := context.Background()
+
+	t.Run("missing user", func(t *testing.T) {
+		_, err := walletv1.NewUserServiceClient(conn).GetUser(ctx, &walletv1.GetUserRequest{Id: 42})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("invalid create user", func(t *testing.T) {
+		_, err := walletv1.NewUserServiceClient(conn).CreateUser(ctx, &walletv1.CreateUserRequest{Name: "John"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("insufficient balance", func(t *testing.T) {
+		users := walletv1.NewUserServiceClient(conn)
+		wallets := walletv1.NewWalletServiceClient(conn)
+		u, err := users.CreateUser(ctx, &walletv1.CreateUserRequest{Name: "John", Email: "john@example.com"})
+		require.NoError(t, err)
+		a, _ := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: u.Id})
+		b, _ := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: u.Id})
+
+		_, err = walletv1.NewTransferServiceClient(conn).Transfer(ctx, &walletv1.TransferRequest{SourceWalletId: a.Id, TargetWalletId: b.Id, Amount: 10})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestServer_TransferPending(t *testing.T) {
+	conn := setupTestClient(t)
+	ctx := context.Background()
+	users := walletv1.NewUserServiceClient(conn)
+	wallets := walletv1.NewWalletServiceClient(conn)
+	transfers := walletv1.NewTransferServiceClient(conn)
+
+	u, err := users.CreateUser(ctx, &walletv1.CreateUserRequest{Name: "John", Email: "john@example.com"})
+	require.NoError(t, err)
+	a, _ := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: u.Id})
+	b, _ := wallets.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: u.Id})
+	_, err = transfers.Deposit(ctx, &walletv1.DepositRequest{WalletId: a.Id, Amount: 5000})
+	require.NoError(t, err)
+
+	t.Run("completed", func(t *testing.T) {
+		resp, err := transfers.Transfer(ctx, &walletv1.TransferRequest{SourceWalletId: a.Id, TargetWalletId: b.Id, Amount: 100})
+		require.NoError(t, err)
+		assert.Equal(t, TransferCompleted, resp.Status)
+		assert.Zero(t, resp.TransactionId)
+		assert.Zero(t, resp.ApprovalId)
+	})
+
+	t.Run("held for review", func(t *testing.T) {
+		resp, err := transfers.Transfer(ctx, &walletv1.TransferRequest{SourceWalletId: a.Id, TargetWalletId: b.Id, Amount: heldAmount})
+		require.NoError(t, err)
+		assert.Equal(t, TransferHeld, resp.Status)
+		assert.NotZero(t, resp.TransactionId)
+
+		history, err := transfers.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: a.Id})
+		require.NoError(t, err)
+		held := history.Transactions[len(history.Transactions)-1]
+		assert.Equal(t, resp.TransactionId, held.Id)
+		assert.Equal(t, models.TransactionStatusPending, held.Status)
+	})
+
+	t.Run("awaiting approval", func(t *testing.T) {
+		resp, err := transfers.Transfer(ctx, &walletv1.TransferRequest{SourceWalletId: a.Id, TargetWalletId: b.Id, Amount: approvalAmount})
+		require.NoError(t, err)
+		assert.Equal(t, TransferAwaitingApproval, resp.Status)
+		assert.Equal(t, uint64(7), resp.ApprovalId)
+		assert.Zero(t, resp.TransactionId)
+	})
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"not found", gorm.ErrRecordNotFound, codes.NotFound},
+		{"invalid", errors.Join(errors.New("context"), services.ErrPocketWallet), codes.InvalidArgument},
+		{"email in use", services.ErrEmailInUse, codes.AlreadyExists},
+		{"insufficient balance", services.ErrInsufficientBalance, codes.FailedPrecondition},
+		{"frozen", services.ErrWalletFrozen, codes.FailedPrecondition},
+		{"deactivated", services.ErrUserDeactivated, codes.FailedPrecondition},
+		{"KYC limit", fmt.Errorf("%w: over the daily limit", services.ErrKYCLimitExceeded), codes.ResourceExhausted},
+		{"spending cap", services.ErrSpendingCapExceeded, codes.ResourceExhausted},
+		{"not a member", services.ErrNotWalletMember, codes.PermissionDenied},
+		{"denied", services.ErrTransferDenied, codes.PermissionDenied},
+		{"no user key", services.ErrInitiatorRequired, codes.Unauthenticated},
+		{"serialization failure", fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), codes.Aborted},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, codes.Aborted},
+		{"cancelled", context.Canceled, codes.Canceled},
+		{"database down", errors.New("dial tcp: connection refused"), codes.Internal},
+		{"other database error", &pgconn.PgError{Code: "23503"}, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := toStatus(context.Background(), tt.err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	t.Run("internal details are not returned", func(t *testing.T) {
+		err := toStatus(context.Background(), errors.New(`pq: relation "wallets" does not exist`))
+		assert.NotContains(t, status.Convert(err).Message(), "wallets")
+	})
+}
+
+func TestServer_WatchWallet(t *testing.T) {
+	conn := setupTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := walletv1.NewUserServiceClient(conn).CreateUser(ctx, &walletv1.CreateUserRequest{Name: "John", Email: "john@example.com"})
+	require.NoError(t, err)
+	wallet, err := walletv1.NewWalletServiceClient(conn).CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: user.Id})
+	require.NoError(t, err)
+
+	stream, err := walletv1.NewWalletServiceClient(conn).WatchWallet(ctx, &walletv1.WatchWalletRequest{Id: wallet.Id})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), first.Balance)
+
+	_, err = walletv1.NewTransferServiceClient(conn).Deposit(ctx, &walletv1.DepositRequest{WalletId: wallet.Id, Amount: 250})
+	require.NoError(t, err)
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), update.Balance)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AMLHandler struct {
+	amlService services.IAMLService
+}
+
+func NewAMLHandler(amlService services.IAMLService) *AMLHandler {
+	return &AMLHandler{amlService: amlService}
+}
+
+// DecideAMLCaseRequest is the body of a decision on a case. The analyst is
+// the operator whose API key made the request.
+type DecideAMLCaseRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *AMLHandler) ListCases(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.AMLCaseStatusOpen &&
+		status != models.AMLCaseStatusCleared && status != models.AMLCaseStatusConfirmed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, cleared or confirmed"})
+		return
+	}
+
+	cases, err := h.amlService.ListCases(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cases)
+}
+
+func (h *AMLHandler) GetCase(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid AML case ID"})
+		return
+	}
+
+	amlCase, err := h.amlService.GetCase(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "AML case not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, amlCase)
+}
+
+func (h *AMLHandler) Clear(c *gin.Context) {
+	h.decide(c, h.amlService.Clear)
+}
+
+func (h *AMLHandler) Confirm(c *gin.Context) {
+	h.decide(c, h.amlService.Confirm)
+}
+
+func (h *AMLHandler) decide(c *gin.Context, decide func(context.Context, uint, string, string) (*models.AMLCase, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid AML case ID"})
+		return
+	}
+
+	var req DecideAMLCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	analyst, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	amlCase, err := decide(c.Request.Context(), uint(id), analyst, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "AML case not found"})
+		case errors.Is(err, services.ErrAMLCaseClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, amlCase)
+}
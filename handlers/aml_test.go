@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock AMLService
+type MockAMLService struct {
+	mock.Mock
+}
+
+func (m *MockAMLService) ListCases(ctx context.Context, status string) ([]models.AMLCase, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AMLCase), args.Error(1)
+}
+
+func (m *MockAMLService) GetCase(ctx context.Context, id uint) (*models.AMLCase, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AMLCase), args.Error(1)
+}
+
+func (m *MockAMLService) Clear(ctx context.Context, id uint, analyst, note string) (*models.AMLCase, error) {
+	args := m.Called(id, analyst, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AMLCase), args.Error(1)
+}
+
+func (m *MockAMLService) Confirm(ctx context.Context, id uint, analyst, note string) (*models.AMLCase, error) {
+	args := m.Called(id, analyst, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AMLCase), args.Error(1)
+}
+
+func TestAMLHandler_ListCases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list := func(handler *AMLHandler, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/aml/cases"+query, nil)
+		handler.ListCases(c)
+		return w
+	}
+
+	t.Run("open cases", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		cases := []models.AMLCase{{
+			ID: 1, TransactionID: 9, WalletID: 3, Status: models.AMLCaseStatusOpen, Held: true,
+			Alerts: []models.AMLAlert{{Rule: "new_wallet_counterparty", Severity: models.AMLSeverityMedium}},
+		}}
+		mockService.On("ListCases", "open").Return(cases, nil)
+
+		w := list(handler, "?status=open")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []models.AMLCase
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response, 1) {
+			assert.True(t, response[0].Held)
+			assert.Len(t, response[0].Alerts, 1)
+		}
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		w := list(handler, "?status=pending")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListCases")
+	})
+}
+
+func TestAMLHandler_GetCase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(handler *AMLHandler, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/aml/cases/"+id, nil)
+		handler.GetCase(c)
+		return w
+	}
+
+	t.Run("found", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		mockService.On("GetCase", uint(1)).Return(&models.AMLCase{ID: 1, Status: models.AMLCaseStatusOpen}, nil)
+
+		assert.Equal(t, http.StatusOK, get(handler, "1").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		mockService.On("GetCase", uint(2)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, get(handler, "2").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, get(handler, "abc").Code)
+		mockService.AssertNotCalled(t, "GetCase")
+	})
+}
+
+func TestAMLHandler_Decide(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// decide calls action as the named analyst, or anonymously when analyst
+	// is empty.
+	decide := func(action func(*gin.Context), id, analyst string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/aml/cases/"+id, bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		if analyst != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+				auth.Principal{KeyID: 1, Name: analyst, Role: models.APIKeyRoleOperator}))
+		}
+		action(c)
+		return w
+	}
+
+	t.Run("clear", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		cleared := &models.AMLCase{ID: 1, Status: models.AMLCaseStatusCleared, Analyst: "erin"}
+		mockService.On("Clear", uint(1), "erin", "known payee").Return(cleared, nil)
+
+		w := decide(handler.Clear, "1", "erin", DecideAMLCaseRequest{Note: "known payee"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("analyst required", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		w := decide(handler.Confirm, "1", "", DecideAMLCaseRequest{Note: "mule account"})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "Confirm")
+	})
+
+	t.Run("confirm without note", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		mockService.On("Confirm", uint(1), "erin", "").Return(nil, errors.New("a note is required to confirm a case"))
+
+		w := decide(handler.Confirm, "1", "erin", DecideAMLCaseRequest{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already closed", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		mockService.On("Clear", uint(1), "frank", "").Return(nil, services.ErrAMLCaseClosed)
+
+		w := decide(handler.Clear, "1", "frank", DecideAMLCaseRequest{})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("case not found", func(t *testing.T) {
+		mockService := new(MockAMLService)
+		handler := NewAMLHandler(mockService)
+
+		mockService.On("Confirm", uint(42), "erin", "mule").Return(nil, gorm.ErrRecordNotFound)
+
+		w := decide(handler.Confirm, "42", "erin", DecideAMLCaseRequest{Note: "mule"})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
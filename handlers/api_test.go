@@ -1,23 +1,73 @@
 package handlers
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"wallet-api/aml"
+	"wallet-api/auth"
+	"wallet-api/logging"
+	"wallet-api/mail"
+	"wallet-api/metrics"
 	"wallet-api/models"
+	"wallet-api/paylink"
+	"wallet-api/ratelimit"
 	"wallet-api/repositories"
+	"wallet-api/risk"
+	"wallet-api/sanctions"
 	"wallet-api/services"
+	"wallet-api/tenancy"
+	"wallet-api/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/gorm"
 )
 
 func setupTestServer(t *testing.T) (*gin.Engine, *gorm.DB) {
+	return setupRateLimitedTestServer(t, nil)
+}
+
+// setupRateLimitedTestServer is setupTestServer with the given limiter, built
+// on the test database, applied to the API routes.
+func setupRateLimitedTestServer(t *testing.T, newLimiter func(*gorm.DB) *ratelimit.Limiter) (*gin.Engine, *gorm.DB) {
+	return setupTestServerWith(t, testServerOptions{newLimiter: newLimiter})
+}
+
+// testServerOptions adds optional components to the test server. Limiter
+// and monitor are built on the test database when set.
+type testServerOptions struct {
+	newLimiter func(*gorm.DB) *ratelimit.Limiter
+	newMonitor func(*gorm.DB) services.TransactionMonitor
+	// screening screens new users with its Screener and transfers from its
+	// MinAmount.
+	screening services.TransferScreening
+	scorer    services.RiskScorer
+	// mailer, when set, receives verification emails for new and changed
+	// addresses and notifications of transfers above notify's thresholds.
+	mailer mail.Mailer
+	notify services.NotificationThresholds
+}
+
+func setupTestServerWith(t *testing.T, opts testServerOptions) (*gin.Engine, *gorm.DB) {
 	// Setup test database
 	db := setupTestDB(t)
 	truncateTables(t, db)
@@ -27,35 +77,98 @@ func setupTestServer(t *testing.T) (*gin.Engine, *gorm.DB) {
 	walletRepo := repositories.NewWalletRepository(db)
 	transactionRepo := repositories.NewTransactionRepository(db)
 
+	var monitor services.TransactionMonitor
+	if opts.newMonitor != nil {
+		monitor = opts.newMonitor(db)
+	}
+
 	// Initialize services
-	userService := services.NewUserService(userRepo)
-	walletService := services.NewWalletService(walletRepo, userRepo)
-	transferService := services.NewTransferService(transactionRepo, walletRepo, db)
+	templates, err := mail.NewTemplates()
+	assert.NoError(t, err)
+	emailVerificationService := services.NewEmailVerificationService(repositories.NewEmailVerificationRepository(db), userRepo, db,
+		opts.mailer, templates, services.EmailVerificationConfig{URL: "https://wallet.example/verify", TTL: time.Hour, Cooldown: time.Minute})
+	var verifier services.EmailVerifier
+	var notifier services.TransferNotifier
+	if opts.mailer != nil {
+		verifier = emailVerificationService
+		notifier = services.NewNotifier(userRepo, walletRepo, opts.mailer, templates, opts.notify)
+	}
+	userService := services.NewUserService(userRepo, db, opts.screening.Screener, verifier)
+	walletService := services.NewWalletService(walletRepo, userRepo, db)
+	pocketService := services.NewPocketService(walletRepo, db)
+	transferService := services.NewTransferService(transactionRepo, walletRepo, db, services.DefaultKYCLimits(), monitor, opts.screening, opts.scorer, notifier)
+	statementService := services.NewStatementService(transactionRepo, walletRepo)
+	reconciliationService := services.NewReconciliationService(walletRepo, repositories.NewDiscrepancyRepository(db), db)
+	kycService := services.NewKYCService(repositories.NewVerificationCaseRepository(db), userRepo, db)
+	amlService := services.NewAMLService(repositories.NewAMLCaseRepository(db), transferService, db)
+	screeningService := services.NewScreeningService(repositories.NewScreeningHitRepository(db), transferService, db)
+	privacyService := services.NewPrivacyService(userRepo, walletRepo, transactionRepo, repositories.NewVerificationCaseRepository(db),
+		repositories.NewErasureRepository(db), db, 24*time.Hour)
+	membershipService := services.NewMembershipService(repositories.NewWalletMemberRepository(db), walletRepo, db)
+	approvalService := services.NewApprovalService(repositories.NewTransferApprovalRepository(db), walletRepo, transferService, db)
+	paymentRequestService := services.NewPaymentRequestService(repositories.NewPaymentRequestRepository(db), walletRepo, transferService, db)
+	paymentLinkService := services.NewPaymentLinkService(repositories.NewPaymentLinkRepository(db), walletRepo, transferService, db,
+		paylink.NewSigner([]byte("test-payment-link-secret-0123456789")), services.PaymentLinkConfig{URL: "https://wallet.example/api/v1/pay", TTL: time.Hour})
+	escrowService := services.NewEscrowService(repositories.NewEscrowRepository(db), walletRepo, transferService, db, services.EscrowConfig{Timeout: time.Hour})
 
 	// Initialize handlers
 	userHandler := NewUserHandler(userService)
 	walletHandler := NewWalletHandler(walletService)
+	pocketHandler := NewPocketHandler(pocketService)
+	membershipHandler := NewMembershipHandler(membershipService)
+	approvalHandler := NewApprovalHandler(approvalService)
 	transferHandler := NewTransferHandler(transferService)
+	paymentRequestHandler := NewPaymentRequestHandler(paymentRequestService)
+	paymentLinkHandler := NewPaymentLinkHandler(paymentLinkService)
+	escrowHandler := NewEscrowHandler(escrowService)
+	statementHandler := NewStatementHandler(statementService)
+	reconciliationHandler := NewReconciliationHandler(reconciliationService)
+	kycHandler := NewKYCHandler(kycService)
+	amlHandler := NewAMLHandler(amlService)
+	screeningHandler := NewScreeningHandler(screeningService)
+	privacyHandler := NewPrivacyHandler(privacyService)
+	emailVerificationHandler := NewEmailVerificationHandler(emailVerificationService)
+	tenantHandler := NewTenantHandler(services.NewTenantService(repositories.NewTenantRepository(db), db))
 
 	// Setup router
-	router := gin.Default()
-	api := router.Group("/api/v1")
-	{
-		// User routes
-		api.POST("/users", userHandler.Create)
-		api.GET("/users/:id", userHandler.GetByID)
+	assert.NoError(t, db.Use(tracing.NewGormPlugin()))
+	assert.NoError(t, db.Use(tenancy.NewGormPlugin()))
 
-		// Wallet routes
-		api.POST("/wallets", walletHandler.Create)
-		api.GET("/wallets/:id", walletHandler.GetByID)
-		api.GET("/users/:id/wallets", walletHandler.GetByUserID)
-
-		// Transfer routes
-		api.POST("/transfers", transferHandler.Transfer)
-		api.POST("/deposits", transferHandler.Deposit)
-		api.GET("/wallets/:id/transactions", transferHandler.GetTransactions)
+	var limiter *ratelimit.Limiter
+	if opts.newLimiter != nil {
+		limiter = opts.newLimiter(db)
 	}
 
+	router := gin.Default()
+	router.Use(metrics.Middleware())
+	router.Use(tracing.Middleware("wallet-api"))
+	router.Use(logging.RequestIDMiddleware())
+	router.Use(risk.DeviceMiddleware())
+	router.GET("/metrics", metrics.Handler())
+	api := router.Group("/api/v1")
+	RegisterRoutes(api, Handlers{
+		User:           userHandler,
+		Wallet:         walletHandler,
+		Pocket:         pocketHandler,
+		Membership:     membershipHandler,
+		Approval:       approvalHandler,
+		Transfer:       transferHandler,
+		PaymentRequest: paymentRequestHandler,
+		PaymentLink:    paymentLinkHandler,
+		Escrow:         escrowHandler,
+		Statement:      statementHandler,
+		Reconciliation: reconciliationHandler,
+		KYC:            kycHandler,
+		AML:            amlHandler,
+		Screening:      screeningHandler,
+		Privacy:        privacyHandler,
+		Email:          emailVerificationHandler,
+		Tenant:         tenantHandler,
+		Auth:           auth.NewAuthenticator(services.NewAPIKeyService(repositories.NewAPIKeyRepository(db), userRepo, db)),
+		RateLimit:      limiter,
+		Tenancy:        tenancy.NewResolver(db, false),
+	})
+
 	return router, db
 }
 
@@ -155,11 +268,9 @@ func TestAPI_CompleteFlow(t *testing.T) {
 		"target_wallet_id": wallet2ID,
 		"amount":           500,
 	}
-	jsonTransfer, _ := json.Marshal(transferPayload)
-	req, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonTransfer))
-	req.Header.Set("Content-Type", "application/json")
-	w = httptest.NewRecorder()
-	router.ServeHTTP(w, req)
+	w = sendJSON(router, http.MethodPost, "/api/v1/transfers", transferPayload)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "transfers are sent with a user key")
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, userID), transferPayload)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	var transferResponse map[string]string
@@ -244,11 +355,7 @@ func TestAPI_ErrorCases(t *testing.T) {
 			"target_wallet_id": wallet2.ID,
 			"amount":           1000, // Try to transfer 1000 when balance is 0
 		}
-		jsonData, _ := json.Marshal(payload)
-		req, _ := http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonData))
-		req.Header.Set("Content-Type", "application/json")
-		w := httptest.NewRecorder()
-		router.ServeHTTP(w, req)
+		w := sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, user1.ID), payload)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
@@ -305,3 +412,2100 @@ func createTestWallet(t *testing.T, router *gin.Engine, userID uint) models.Wall
 	assert.NoError(t, err)
 	return response
 }
+
+func TestAPI_Statement(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user1 := createTestUser(t, router, "John Doe", "john@example.com")
+	user2 := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet1 := createTestWallet(t, router, user1.ID)
+	wallet2 := createTestWallet(t, router, user2.ID)
+
+	before := time.Now().UTC()
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet1.ID, "amount": 1000})
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, user1.ID), map[string]interface{}{
+		"source_wallet_id": wallet1.ID,
+		"target_wallet_id": wallet2.ID,
+		"amount":           300,
+	})
+	after := time.Now().UTC().Add(time.Second)
+
+	// Balance before any activity is zero, afterwards matches Wallet.Balance
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/balance?as_of=%s", wallet1.ID, before.Add(-time.Second).Format(time.RFC3339)), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var balance models.BalanceResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	assert.Equal(t, int64(0), balance.Balance)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/balance", wallet1.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	assert.Equal(t, int64(700), balance.Balance)
+
+	// Statement lines carry a running balance ending at the closing balance
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/statement?from=%s&to=%s",
+		wallet1.ID, before.Add(-time.Second).Format(time.RFC3339), after.Format(time.RFC3339)), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var statement models.Statement
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &statement))
+	assert.Equal(t, int64(0), statement.OpeningBalance)
+	if assert.Equal(t, 2, len(statement.Lines)) {
+		assert.Equal(t, int64(1000), statement.Lines[0].RunningBalance)
+		assert.Equal(t, int64(-300), statement.Lines[1].Amount)
+		assert.Equal(t, int64(700), statement.Lines[1].RunningBalance)
+	}
+	assert.Equal(t, int64(700), statement.ClosingBalance)
+}
+
+func TestAPI_Reconciliation(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user := createTestUser(t, router, "John Doe", "john@example.com")
+	wallet := createTestWallet(t, router, user.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet.ID, "amount": 1000})
+
+	today := time.Now().UTC().Format("2006-01-02")
+
+	// Only operators reconcile
+	w := sendJSON(router, http.MethodPost, "/api/v1/reconciliations", map[string]interface{}{"date": today})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	opsKey := issueKey(t, db, "ops@example.com", models.APIKeyRoleOperator, nil)
+	reconcile := func() *httptest.ResponseRecorder {
+		return postJSONAs(t, router, "/api/v1/reconciliations", opsKey, map[string]interface{}{"date": today})
+	}
+
+	// Balances explained by the transactions reconcile cleanly
+	w = reconcile()
+	var report models.ReconciliationReport
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.WalletsChecked)
+	assert.Empty(t, report.Discrepancies)
+
+	// A balance edited behind the ledger's back is flagged
+	assert.NoError(t, db.Exec("UPDATE wallets SET balance = balance + 50 WHERE id = ?", wallet.ID).Error)
+	w = reconcile()
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	if assert.Len(t, report.Discrepancies, 1) {
+		assert.Equal(t, int64(1000), report.Discrepancies[0].ExpectedBalance)
+		assert.Equal(t, int64(1050), report.Discrepancies[0].ActualBalance)
+		assert.Equal(t, int64(50), report.Discrepancies[0].Difference)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/discrepancies?status=open", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var discrepancies []models.Discrepancy
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &discrepancies))
+	assert.Len(t, discrepancies, 1)
+
+	// The operator resolving it is recorded from their key
+	resolve := fmt.Sprintf("/api/v1/discrepancies/%d/resolve", discrepancies[0].ID)
+	w = sendJSONAs(router, http.MethodPost, resolve, userKey(t, db, user.ID), map[string]interface{}{"note": "manual correction"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSONAs(router, http.MethodPost, resolve, opsKey, map[string]interface{}{"note": "manual correction"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var discrepancy models.Discrepancy
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &discrepancy))
+	assert.Equal(t, "ops@example.com", discrepancy.ResolvedBy)
+	assert.Equal(t, "manual correction", discrepancy.ResolutionNote)
+
+	// Reconciling the day again reopens it without the old resolution
+	w = reconcile()
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Len(t, report.Discrepancies, 1)
+	assert.NoError(t, db.First(&discrepancy, discrepancies[0].ID).Error)
+	assert.Equal(t, models.DiscrepancyStatusOpen, discrepancy.Status)
+	assert.Empty(t, discrepancy.ResolutionNote)
+	assert.Empty(t, discrepancy.ResolvedBy)
+	assert.Nil(t, discrepancy.ResolvedAt)
+}
+
+func TestAPI_FrozenWallet(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user := createTestUser(t, router, "John Doe", "john@example.com")
+	wallet1 := createTestWallet(t, router, user.ID)
+	wallet2 := createTestWallet(t, router, user.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet1.ID, "amount": 1000})
+
+	// Operators freeze and credit wallets through the same services as the
+	// CLI does
+	ctx := context.Background()
+	walletRepo := repositories.NewWalletRepository(db)
+	walletService := services.NewWalletService(walletRepo, repositories.NewUserRepository(db), db)
+	transferService := services.NewTransferService(repositories.NewTransactionRepository(db), walletRepo, db, services.DefaultKYCLimits(), nil, services.TransferScreening{}, nil, nil)
+
+	_, err := walletService.Freeze(ctx, wallet2.ID, "  ")
+	assert.Error(t, err, "a reason is required")
+	frozen, err := walletService.Freeze(ctx, wallet2.ID, "chargeback investigation")
+	assert.NoError(t, err)
+	assert.True(t, frozen.Frozen())
+
+	key := userKey(t, db, user.ID)
+	transfer := func() *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"source_wallet_id": wallet1.ID, "target_wallet_id": wallet2.ID, "amount": 100,
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := transfer()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "wallet is frozen")
+
+	_, err = transferService.ManualDeposit(ctx, wallet2.ID, 500, "goodwill credit")
+	assert.ErrorIs(t, err, services.ErrWalletFrozen)
+
+	_, err = walletService.Unfreeze(ctx, wallet2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, transfer().Code)
+
+	_, err = transferService.ManualDeposit(ctx, wallet2.ID, 500, "")
+	assert.Error(t, err, "a reason is required")
+	deposit, err := transferService.ManualDeposit(ctx, wallet2.ID, 500, "goodwill credit")
+	assert.NoError(t, err)
+	assert.Equal(t, "goodwill credit", deposit.Description)
+
+	var balance int64
+	db.Model(&models.Wallet{}).Where("id = ?", wallet2.ID).Pluck("balance", &balance)
+	assert.Equal(t, int64(600), balance)
+}
+
+func TestAPI_KYC(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice", "alice@example.com")
+	bob := createTestUser(t, router, "Bob", "bob@example.com")
+	assert.Equal(t, models.KYCLevelNone, alice.KYCLevel)
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+
+	// Unverified users may hold at most 100,000
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 100000})
+	w := sendJSON(router, http.MethodPost, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "KYC limit exceeded")
+
+	// ...and send at most 50,000 in 24 hours
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 50000,
+	})
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 1,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "KYC limit exceeded")
+
+	// Alice applies for basic verification
+	submission := map[string]interface{}{
+		"level": "basic",
+		"documents": []map[string]interface{}{
+			{"type": "passport", "issuing_country": "DE", "reference": "docs/alice/passport.pdf", "expires_on": "2099-01-31"},
+		},
+	}
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/verifications", alice.ID), submission)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var submitted models.VerificationCase
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &submitted))
+	assert.Equal(t, models.VerificationStatusPending, submitted.Status)
+	assert.Len(t, submitted.Documents, 1)
+
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/verifications", alice.ID), submission)
+	assert.Equal(t, http.StatusConflict, w.Code, "one pending case at a time")
+
+	// An operator approves it from the review queue
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/verifications?status=pending", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var queue []models.VerificationCase
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &queue))
+	if assert.Len(t, queue, 1) {
+		assert.Equal(t, submitted.ID, queue[0].ID)
+	}
+
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/verifications/%d/approve", submitted.ID),
+		map[string]interface{}{"note": "passport matches"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "reviews need an operator key")
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/verifications/%d/approve", submitted.ID),
+		issueKey(t, db, "ops:carol", models.APIKeyRoleOperator, nil), map[string]interface{}{"note": "passport matches"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var approved models.VerificationCase
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approved))
+	assert.Equal(t, models.VerificationStatusApproved, approved.Status)
+	if assert.Len(t, approved.History, 2) {
+		assert.Equal(t, models.VerificationStatusPending, approved.History[0].Status)
+		assert.Equal(t, fmt.Sprintf("user:%d", alice.ID), approved.History[0].Actor)
+		assert.Equal(t, models.VerificationStatusApproved, approved.History[1].Status)
+		assert.Equal(t, "ops:carol", approved.History[1].Actor)
+	}
+
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/verifications/%d/reject", submitted.ID),
+		issueKey(t, db, "ops:dave", models.APIKeyRoleOperator, nil), map[string]interface{}{"note": "too late"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var user models.User
+	assert.NoError(t, db.First(&user, alice.ID).Error)
+	assert.Equal(t, models.KYCLevelBasic, user.KYCLevel)
+
+	// The basic limits now apply to Alice, while Bob is still capped
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 400000})
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 50000,
+	})
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 1,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Bob's balance is at the unverified maximum")
+}
+
+// recordingMonitor is a TransactionMonitor that remembers which committed
+// transactions it observed.
+type recordingMonitor struct {
+	services.TransactionMonitor
+	mu       sync.Mutex
+	observed []uint
+}
+
+func (m *recordingMonitor) Observe(ctx context.Context, t models.Transaction) {
+	m.mu.Lock()
+	m.observed = append(m.observed, t.ID)
+	m.mu.Unlock()
+	m.TransactionMonitor.Observe(ctx, t)
+}
+
+func (m *recordingMonitor) Observed() []uint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]uint(nil), m.observed...)
+}
+
+func TestAPI_AML(t *testing.T) {
+	monitor := &recordingMonitor{}
+	router, db := setupTestServerWith(t, testServerOptions{
+		newMonitor: func(db *gorm.DB) services.TransactionMonitor {
+			engine, err := aml.NewEngine(db, []aml.Rule{
+				aml.Structuring{Threshold: 10000, Margin: 0.1, Count: 2, Window: 24 * time.Hour},
+				aml.NewWalletCounterparty{MaxAge: 24 * time.Hour, MinAmount: 1000},
+			}, []string{aml.RuleNewWalletCounterparty})
+			assert.NoError(t, err)
+			monitor.TransactionMonitor = engine
+			return monitor
+		},
+	})
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice", "alice@example.com")
+	bob := createTestUser(t, router, "Bob", "bob@example.com")
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 20000})
+
+	balance := func(walletID uint) int64 {
+		var b int64
+		db.Model(&models.Wallet{}).Where("id = ?", walletID).Pluck("balance", &b)
+		return b
+	}
+	transferToBob := func() uint {
+		w := sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+			"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 5000,
+		})
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var held struct {
+			TransactionID uint `json:"transaction_id"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &held))
+		return held.TransactionID
+	}
+	openCases := func() []models.AMLCase {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/aml/cases?status=open", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var cases []models.AMLCase
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cases))
+		return cases
+	}
+
+	// Bob's wallet is brand new, so the transfer is held without moving money
+	transactionID := transferToBob()
+	var transaction models.Transaction
+	assert.NoError(t, db.First(&transaction, transactionID).Error)
+	assert.Equal(t, models.TransactionStatusPending, transaction.Status)
+	assert.Equal(t, int64(20000), balance(aliceWallet.ID))
+	assert.Equal(t, int64(0), balance(bobWallet.ID))
+
+	cases := openCases()
+	if !assert.Len(t, cases, 1) {
+		return
+	}
+	assert.True(t, cases[0].Held)
+	assert.Equal(t, transactionID, cases[0].TransactionID)
+	if assert.Len(t, cases[0].Alerts, 1) {
+		assert.Equal(t, aml.RuleNewWalletCounterparty, cases[0].Alerts[0].Rule)
+	}
+
+	// Clearing the case books the transfer
+	erin := issueKey(t, db, "aml:erin", models.APIKeyRoleOperator, nil)
+	w := sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/aml/cases/%d/clear", cases[0].ID),
+		erin, map[string]interface{}{"note": "Bob is Alice's landlord"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cleared models.AMLCase
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &cleared))
+	assert.Equal(t, models.AMLCaseStatusCleared, cleared.Status)
+	assert.Equal(t, "aml:erin", cleared.Analyst)
+	assert.NoError(t, db.First(&transaction, transactionID).Error)
+	assert.Equal(t, models.TransactionStatusCompleted, transaction.Status)
+	assert.Equal(t, int64(15000), balance(aliceWallet.ID))
+	assert.Equal(t, int64(5000), balance(bobWallet.ID))
+	assert.Contains(t, monitor.Observed(), transactionID, "the booked transfer goes to the remaining rules")
+
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/aml/cases/%d/confirm", cleared.ID),
+		issueKey(t, db, "aml:frank", models.APIKeyRoleOperator, nil), map[string]interface{}{"note": "second opinion"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Confirming the next one fails the transfer
+	transactionID = transferToBob()
+	cases = openCases()
+	if !assert.Len(t, cases, 1) {
+		return
+	}
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/aml/cases/%d/confirm", cases[0].ID),
+		map[string]interface{}{"note": "funds routed onwards within minutes"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "decisions need an operator key")
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/aml/cases/%d/confirm", cases[0].ID),
+		erin, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a note is required")
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/aml/cases/%d/confirm", cases[0].ID),
+		erin, map[string]interface{}{"note": "funds routed onwards within minutes"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, db.First(&transaction, transactionID).Error)
+	assert.Equal(t, models.TransactionStatusFailed, transaction.Status)
+	assert.Equal(t, int64(15000), balance(aliceWallet.ID))
+
+	// Deposits just under the threshold are caught after commit without
+	// holding anything
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 9500})
+	assert.Empty(t, openCases())
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 9900})
+	assert.Equal(t, int64(24400), balance(bobWallet.ID))
+	cases = openCases()
+	if assert.Len(t, cases, 1) {
+		assert.False(t, cases[0].Held)
+		assert.Equal(t, bobWallet.ID, cases[0].WalletID)
+		assert.Equal(t, aml.RuleStructuring, cases[0].Alerts[0].Rule)
+	}
+}
+
+func TestAPI_Sanctions(t *testing.T) {
+	entries, err := sanctions.LoadFiles([]string{"../sanctions/testdata/sdn.csv", "../sanctions/testdata/eu.xml"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router, db := setupTestServerWith(t, testServerOptions{
+		screening: services.TransferScreening{
+			Screener:  sanctions.NewScreener(sanctions.DefaultThreshold, entries),
+			MinAmount: 10000,
+		},
+	})
+	defer teardownTestDB(t, db)
+
+	pendingHits := func() []models.ScreeningHit {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/screening/hits?status=pending", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var hits []models.ScreeningHit
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hits))
+		return hits
+	}
+	screeningStatus := func(userID uint) models.ScreeningStatus {
+		var user models.User
+		assert.NoError(t, db.First(&user, userID).Error)
+		return user.ScreeningStatus
+	}
+
+	// A name resembling a listed one leaves the user pending review
+	ivan := createTestUser(t, router, "Ivan Petrof", "ivan@example.com")
+	assert.Equal(t, models.ScreeningStatusPending, ivan.ScreeningStatus)
+	hits := pendingHits()
+	if !assert.Len(t, hits, 1) {
+		return
+	}
+	assert.Equal(t, models.ScreeningSubjectUser, hits[0].Subject)
+	assert.Equal(t, ivan.ID, hits[0].UserID)
+	assert.Equal(t, "SDN-1001", hits[0].EntryID)
+
+	w := sendJSON(router, http.MethodPost, "/api/v1/wallets", map[string]interface{}{"user_id": ivan.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrScreeningPending.Error())
+
+	// Clearing the hit lets the user in, and the cleared entry is not raised
+	// again for them
+	grace := issueKey(t, db, "compliance:grace", models.APIKeyRoleOperator, nil)
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/clear", hits[0].ID),
+		map[string]interface{}{"note": "different date of birth"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "reviews need an operator key")
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/clear", hits[0].ID),
+		grace, map[string]interface{}{"note": "different date of birth"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.ScreeningStatusClear, screeningStatus(ivan.ID))
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/confirm", hits[0].ID),
+		issueKey(t, db, "compliance:heidi", models.APIKeyRoleOperator, nil), map[string]interface{}{"note": "second opinion"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+	assert.Equal(t, models.ScreeningStatusClear, alice.ScreeningStatus)
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	ivanWallet := createTestWallet(t, router, ivan.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 50000})
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": ivanWallet.ID, "amount": 10000,
+	})
+	assert.Empty(t, pendingHits())
+
+	// Bob signed up before his name was listed; large transfers to him are
+	// held until reviewed, small ones are not screened
+	bob := createTestUser(t, router, "Bob Jones", "bob@example.com")
+	bobWallet := createTestWallet(t, router, bob.ID)
+	assert.NoError(t, db.Model(&models.User{}).Where("id = ?", bob.ID).Update("name", "José Álvarez Ortega").Error)
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 500,
+	})
+
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 20000,
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var held struct {
+		TransactionID uint `json:"transaction_id"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &held))
+	hits = pendingHits()
+	if !assert.Len(t, hits, 1) {
+		return
+	}
+	assert.Equal(t, models.ScreeningSubjectTransfer, hits[0].Subject)
+	assert.Equal(t, bob.ID, hits[0].UserID)
+	if assert.NotNil(t, hits[0].TransactionID) {
+		assert.Equal(t, held.TransactionID, *hits[0].TransactionID)
+	}
+
+	// Confirming the hit fails the transfer and blocks Bob
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/confirm", hits[0].ID),
+		grace, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a note is required")
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/confirm", hits[0].ID),
+		grace, map[string]interface{}{"note": "passport number matches the listing"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var transaction models.Transaction
+	assert.NoError(t, db.First(&transaction, held.TransactionID).Error)
+	assert.Equal(t, models.TransactionStatusFailed, transaction.Status)
+	assert.Equal(t, models.ScreeningStatusBlocked, screeningStatus(bob.ID))
+
+	var wallet models.Wallet
+	assert.NoError(t, db.First(&wallet, bobWallet.ID).Error)
+	assert.Equal(t, int64(500), wallet.Balance)
+	w = sendJSON(router, http.MethodPost, "/api/v1/deposits", map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 100})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrScreeningBlocked.Error())
+}
+
+func TestAPI_SanctionsReleaseScored(t *testing.T) {
+	entries, err := sanctions.LoadFiles([]string{"../sanctions/testdata/sdn.csv"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	router, db := setupTestServerWith(t, testServerOptions{
+		screening: services.TransferScreening{
+			Screener:  sanctions.NewScreener(sanctions.DefaultThreshold, entries),
+			MinAmount: 10000,
+		},
+		scorer: risk.Heuristic{Window: 24 * time.Hour, AmountMultiple: 3, ReviewScore: 20, DenyScore: 100},
+	})
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+	bob := createTestUser(t, router, "Bob Jones", "bob@example.com")
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	assert.NoError(t, db.Model(&models.User{}).Where("id = ?", bob.ID).Update("name", "Ivan Petrov").Error)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 50000})
+
+	w := sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 20000,
+	})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var held struct {
+		TransactionID uint `json:"transaction_id"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &held))
+	var hits []models.ScreeningHit
+	assert.NoError(t, db.Where("transaction_id = ?", held.TransactionID).Find(&hits).Error)
+	if !assert.Len(t, hits, 1) {
+		return
+	}
+
+	// Clearing the hit scores the transfer it skipped while held: paying
+	// someone new calls for review, so it stays held under an AML case
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/screening/hits/%d/clear", hits[0].ID),
+		issueKey(t, db, "compliance:grace", models.APIKeyRoleOperator, nil), map[string]interface{}{"note": "different person"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var transaction models.Transaction
+	assert.NoError(t, db.First(&transaction, held.TransactionID).Error)
+	assert.Equal(t, models.TransactionStatusPending, transaction.Status)
+	if assert.NotNil(t, transaction.RiskScore) {
+		assert.Equal(t, risk.NewCounterpartyPoints, *transaction.RiskScore)
+	}
+	var amlCase models.AMLCase
+	assert.NoError(t, db.Preload("Alerts").Where("transaction_id = ?", held.TransactionID).First(&amlCase).Error)
+	assert.True(t, amlCase.Held)
+	if assert.Len(t, amlCase.Alerts, 1) {
+		assert.Equal(t, risk.AlertRule, amlCase.Alerts[0].Rule)
+	}
+
+	var wallet models.Wallet
+	assert.NoError(t, db.First(&wallet, aliceWallet.ID).Error)
+	assert.Equal(t, int64(50000), wallet.Balance)
+}
+
+func TestAPI_RiskScoring(t *testing.T) {
+	router, db := setupTestServerWith(t, testServerOptions{
+		scorer: risk.Heuristic{Window: 24 * time.Hour, AmountMultiple: 3, ReviewScore: 40, DenyScore: 70},
+	})
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice", "alice@example.com")
+	bob := createTestUser(t, router, "Bob", "bob@example.com")
+	carol := createTestUser(t, router, "Carol", "carol@example.com")
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	carolWallet := createTestWallet(t, router, carol.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 40000})
+
+	aliceKey := userKey(t, db, alice.ID)
+	transfer := func(targetWalletID uint, amount int64, device string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"source_wallet_id": aliceWallet.ID, "target_wallet_id": targetWalletID, "amount": amount,
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+aliceKey)
+		req.Header.Set(risk.DeviceHeader, device)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	last := func() models.Transaction {
+		var transaction models.Transaction
+		assert.NoError(t, db.Where("source_wallet_id = ?", aliceWallet.ID).Order("id DESC").First(&transaction).Error)
+		return transaction
+	}
+
+	// A first payment to Bob is new but otherwise unremarkable
+	assert.Equal(t, http.StatusOK, transfer(bobWallet.ID, 1000, "phone-1").Code)
+	scored := last()
+	if assert.NotNil(t, scored.RiskScore) {
+		assert.Equal(t, risk.NewCounterpartyPoints, *scored.RiskScore)
+	}
+	assert.Equal(t, "phone-1", scored.DeviceID)
+	if assert.Len(t, scored.RiskReasons, 1) {
+		assert.True(t, strings.HasPrefix(scored.RiskReasons[0], risk.SignalNewCounterparty+": "))
+	}
+	assert.Equal(t, http.StatusOK, transfer(bobWallet.ID, 1000, "phone-1").Code)
+	if scored = last(); assert.NotNil(t, scored.RiskScore) {
+		assert.Zero(t, *scored.RiskScore)
+		assert.Empty(t, scored.RiskReasons)
+	}
+
+	// Ten times the usual amount to someone new is held for an analyst
+	w := transfer(carolWallet.ID, 10000, "phone-1")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	held := last()
+	assert.Equal(t, models.TransactionStatusPending, held.Status)
+	var amlCase models.AMLCase
+	assert.NoError(t, db.Preload("Alerts").Where("transaction_id = ?", held.ID).First(&amlCase).Error)
+	assert.True(t, amlCase.Held)
+	if assert.Len(t, amlCase.Alerts, 1) {
+		assert.Equal(t, risk.AlertRule, amlCase.Alerts[0].Rule)
+		assert.Contains(t, amlCase.Alerts[0].Details, "risk score 55")
+	}
+
+	// From an unknown device as well it is refused outright, recorded as
+	// failed without telling the client why
+	w = transfer(carolWallet.ID, 10000, "laptop-9")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrTransferDenied.Error())
+	assert.NotContains(t, w.Body.String(), risk.SignalNewDevice)
+	denied := last()
+	assert.Equal(t, models.TransactionStatusFailed, denied.Status)
+	if assert.NotNil(t, denied.RiskScore) {
+		assert.Equal(t, 80, *denied.RiskScore)
+	}
+	assert.Len(t, denied.RiskReasons, 3)
+
+	var wallet models.Wallet
+	assert.NoError(t, db.First(&wallet, aliceWallet.ID).Error)
+	assert.Equal(t, int64(38000), wallet.Balance)
+}
+
+func TestAPI_Privacy(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+	bob := createTestUser(t, router, "Bob Jones", "bob@example.com")
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 1000})
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+		"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 1000,
+	})
+
+	// The export holds the profile, wallets and both transactions
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%d/export", alice.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if assert.NoError(t, err) {
+			files[f.Name], _ = io.ReadAll(r)
+			r.Close()
+		}
+	}
+	assert.Contains(t, string(files["profile.json"]), "alice@example.com")
+	var transactions []models.TransferResponse
+	assert.NoError(t, json.Unmarshal(files["transactions.json"], &transactions))
+	assert.Len(t, transactions, 2)
+
+	// Only operators erase users, and are recorded as the requester
+	path := fmt.Sprintf("/api/v1/users/%d/erasure", alice.ID)
+	w = sendJSON(router, http.MethodPost, path, map[string]interface{}{"reason": "user request"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, path, userKey(t, db, alice.ID), map[string]interface{}{"reason": "user request"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Bob still holds money, so he cannot be erased yet
+	dpoKey := issueKey(t, db, "privacy:dpo", models.APIKeyRoleOperator, nil)
+	erase := func(userID uint) *httptest.ResponseRecorder {
+		return sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/erasure", userID), dpoKey,
+			map[string]interface{}{"requested_by": "someone else", "reason": "user request"})
+	}
+	assert.Equal(t, http.StatusConflict, erase(bob.ID).Code)
+
+	// Alice's wallet is empty: she is pseudonymized and her wallet frozen,
+	// while the transfer stays on Bob's statement
+	w = erase(alice.ID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var erasure models.Erasure
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &erasure))
+	assert.Equal(t, alice.ID, erasure.UserID)
+	assert.Equal(t, "privacy:dpo", erasure.RequestedBy)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), erasure.RetainUntil, time.Minute)
+	assert.Equal(t, http.StatusConflict, erase(alice.ID).Code)
+
+	var user models.User
+	assert.NoError(t, db.Unscoped().First(&user, alice.ID).Error)
+	assert.Equal(t, "Erased user", user.Name)
+	assert.NotContains(t, user.Email, "alice")
+	assert.NotNil(t, user.ErasedAt)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", alice.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var wallet models.Wallet
+	assert.NoError(t, db.First(&wallet, aliceWallet.ID).Error)
+	assert.True(t, wallet.Frozen())
+	assert.Equal(t, services.ErasedWalletReason, wallet.FrozenReason)
+
+	var count int64
+	assert.NoError(t, db.Model(&models.Transaction{}).Where("target_wallet_id = ?", bobWallet.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%d/erasure", alice.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPI_PrivacyErasesEverywhere(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+
+	// Her name and address also sit in an email verification, a screening
+	// hit and a KYC case reviewed with a note naming her
+	assert.NoError(t, db.Create(&models.EmailVerification{
+		UserID: alice.ID, Email: "alice@example.com", TokenHash: "erasure-test", ExpiresAt: time.Now(),
+	}).Error)
+	assert.NoError(t, db.Create(&models.ScreeningHit{
+		Subject: models.ScreeningSubjectUser, UserID: alice.ID, ScreenedName: "Alice Smith",
+		List: "test", EntryID: "T-1", EntryName: "Alice Smyth", Score: 0.9, Status: models.ScreeningHitStatusCleared,
+	}).Error)
+	verification := models.VerificationCase{
+		UserID: alice.ID, RequestedLevel: models.KYCLevelBasic, Status: models.VerificationStatusApproved,
+		Reviewer: "compliance:grace", ReviewNote: "passport matches Alice Smith",
+		Documents: []models.VerificationDocument{{
+			Type: "passport", IssuingCountry: "GB", Reference: "documents/alice-smith-passport.pdf",
+		}},
+		History: []models.VerificationEvent{{
+			Status: models.VerificationStatusApproved, Actor: "compliance:grace", Note: "Alice Smith in person",
+		}},
+	}
+	assert.NoError(t, db.Create(&verification).Error)
+
+	w := sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/erasure", alice.ID),
+		issueKey(t, db, "privacy:dpo", models.APIKeyRoleOperator, nil), map[string]interface{}{"reason": "user request"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// No text column of any table still holds them
+	var columns []struct {
+		TableName  string
+		ColumnName string
+	}
+	assert.NoError(t, db.Raw(`SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND data_type IN ('text', 'character varying')`).Scan(&columns).Error)
+	assert.NotEmpty(t, columns)
+	for _, column := range columns {
+		for _, original := range []string{"alice smith", "alice@example.com", "alice-smith"} {
+			var count int64
+			assert.NoError(t, db.Table(column.TableName).
+				Where(fmt.Sprintf("LOWER(%q) LIKE ?", column.ColumnName), "%"+original+"%").
+				Count(&count).Error)
+			assert.Zero(t, count, "%s.%s still holds %q", column.TableName, column.ColumnName, original)
+		}
+	}
+}
+
+func TestAPI_UserManagement(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+	bob := createTestUser(t, router, "Bob Jones", "bob@example.com")
+	createTestUser(t, router, "Alicia Keys", "alicia@example.org")
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 1000})
+
+	// Email changes re-check uniqueness
+	w := sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/users/%d", alice.ID), map[string]string{"email": "bob@example.com"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/users/%d", alice.ID), map[string]string{"email": "alice@example.net"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "alice@example.net", updated.Email)
+	assert.Equal(t, "Alice Smith", updated.Name)
+
+	// Listing by name prefix, a page at a time
+	list := func(query string) models.UserPage {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/users"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page models.UserPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+	page := list("?name=ali&page_size=1")
+	assert.Equal(t, int64(2), page.Total)
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, alice.ID, page.Users[0].ID)
+	}
+	page = list("?name=ali&page_size=1&page=2")
+	if assert.Len(t, page.Users, 1) {
+		assert.Equal(t, "Alicia Keys", page.Users[0].Name)
+	}
+	assert.Equal(t, int64(1), list("?email=alicia%40").Total)
+	assert.Equal(t, int64(0), list("?name=%25").Total, "wildcards match literally")
+	assert.Equal(t, int64(0), list("?created_from="+url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))).Total)
+
+	// Only operators deactivate users, not even the users themselves
+	deactivate := fmt.Sprintf("/api/v1/users/%d/deactivate", bob.ID)
+	w = sendJSON(router, http.MethodPost, deactivate, map[string]string{"reason": "account closure requested"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, deactivate, userKey(t, db, bob.ID), map[string]string{"reason": "account closure requested"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// A deactivated user's wallets cannot move money, in either direction
+	opsKey := issueKey(t, db, "ops@example.com", models.APIKeyRoleOperator, nil)
+	w = sendJSONAs(router, http.MethodPost, deactivate, opsKey, map[string]string{"reason": "account closure requested"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	transfer := func() *httptest.ResponseRecorder {
+		return sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, alice.ID), map[string]interface{}{
+			"source_wallet_id": aliceWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 100,
+		})
+	}
+	w = transfer()
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrUserDeactivated.Error())
+	w = sendJSON(router, http.MethodPost, "/api/v1/deposits", map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 100})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, http.MethodPost, "/api/v1/wallets", map[string]interface{}{"user_id": bob.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, int64(1), list("?status=deactivated").Total)
+
+	w = sendJSONAs(router, http.MethodPost, deactivate, opsKey, map[string]string{"reason": "again"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	reactivate := fmt.Sprintf("/api/v1/users/%d/reactivate", bob.ID)
+	assert.Equal(t, http.StatusForbidden, sendJSONAs(router, http.MethodPost, reactivate, userKey(t, db, bob.ID), nil).Code)
+	postJSONAs(t, router, reactivate, opsKey, nil)
+	assert.Equal(t, http.StatusOK, transfer().Code)
+
+	// Deleted users cannot get new wallets
+	assert.NoError(t, db.Delete(&models.User{}, bob.ID).Error)
+	w = sendJSON(router, http.MethodPost, "/api/v1/wallets", map[string]interface{}{"user_id": bob.ID})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var count int64
+	assert.NoError(t, db.Model(&models.Wallet{}).Where("user_id = ?", bob.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// recordingMailer keeps the messages it is asked to send.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// take returns the messages sent so far and forgets them.
+func (m *recordingMailer) take() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages := m.messages
+	m.messages = nil
+	return messages
+}
+
+var verificationTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func TestAPI_EmailVerification(t *testing.T) {
+	mailer := &recordingMailer{}
+	router, db := setupTestServerWith(t, testServerOptions{
+		mailer: mailer,
+		notify: services.NotificationThresholds{LargeDebit: 500, LowBalance: 100},
+	})
+	defer teardownTestDB(t, db)
+
+	// New users are sent a verification link
+	alice := createTestUser(t, router, "Alice Smith", "alice@example.com")
+	messages := mailer.take()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+	assert.Equal(t, "alice@example.com", messages[0].To)
+	assert.Equal(t, "Confirm your email address", messages[0].Subject)
+	match := verificationTokenPattern.FindStringSubmatch(messages[0].Text)
+	if !assert.NotNil(t, match) {
+		return
+	}
+	firstToken := match[1]
+
+	resend := func(userID uint) *httptest.ResponseRecorder {
+		return sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/users/%d/email-verification", userID), nil)
+	}
+	verify := func(token string) *httptest.ResponseRecorder {
+		return sendJSON(router, http.MethodPost, "/api/v1/email-verification", map[string]string{"token": token})
+	}
+
+	// Resending is throttled; once the cooldown has passed, the new link
+	// supersedes the first
+	assert.Equal(t, http.StatusTooManyRequests, resend(alice.ID).Code)
+	assert.NoError(t, db.Model(&models.EmailVerification{}).Where("user_id = ?", alice.ID).
+		Update("created_at", time.Now().Add(-time.Hour)).Error)
+	assert.Equal(t, http.StatusAccepted, resend(alice.ID).Code)
+	messages = mailer.take()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+	token := verificationTokenPattern.FindStringSubmatch(messages[0].Text)[1]
+	assert.Equal(t, http.StatusGone, verify(firstToken).Code)
+	assert.Equal(t, http.StatusBadRequest, verify("not-a-token").Code)
+
+	w := verify(token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var verified models.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &verified))
+	assert.NotNil(t, verified.EmailVerifiedAt)
+	assert.Equal(t, http.StatusBadRequest, verify(token).Code, "tokens are single use")
+	assert.Equal(t, http.StatusConflict, resend(alice.ID).Code)
+
+	// Expired links are refused
+	bob := createTestUser(t, router, "Bob Jones", "bob@example.com")
+	bobToken := verificationTokenPattern.FindStringSubmatch(mailer.take()[0].Text)[1]
+	assert.NoError(t, db.Model(&models.EmailVerification{}).Where("user_id = ?", bob.ID).Updates(map[string]interface{}{
+		"created_at": time.Now().Add(-2 * time.Hour),
+		"expires_at": time.Now().Add(-time.Hour),
+	}).Error)
+	assert.Equal(t, http.StatusGone, verify(bobToken).Code)
+
+	// Transfer emails go to verified addresses only: Alice hears about her
+	// large debit but unverified Bob is not told what he received
+	aliceWallet := createTestWallet(t, router, alice.ID)
+	bobWallet := createTestWallet(t, router, bob.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": aliceWallet.ID, "amount": 1000})
+	transfer := func(sender models.User, source, target uint, amount int64) {
+		postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, sender.ID), map[string]interface{}{
+			"source_wallet_id": source, "target_wallet_id": target, "amount": amount,
+		})
+	}
+	transfer(alice, aliceWallet.ID, bobWallet.ID, 600)
+	messages = mailer.take()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "alice@example.com", messages[0].To)
+		assert.Equal(t, "6.00 USD was sent from your wallet", messages[0].Subject)
+	}
+
+	// Once verified, Bob is told of money received
+	assert.Equal(t, http.StatusAccepted, resend(bob.ID).Code)
+	assert.Equal(t, http.StatusOK, verify(verificationTokenPattern.FindStringSubmatch(mailer.take()[0].Text)[1]).Code)
+	transfer(alice, aliceWallet.ID, bobWallet.ID, 350)
+	messages = mailer.take()
+	subjects := map[string]string{}
+	for _, msg := range messages {
+		subjects[msg.Subject] = msg.To
+	}
+	assert.Equal(t, map[string]string{
+		"You received 3.50 USD":      "bob@example.com",
+		"Your wallet balance is low": "alice@example.com",
+	}, subjects)
+
+	// The low balance warning is sent when the balance crosses the
+	// threshold, not on every transfer below it
+	transfer(alice, aliceWallet.ID, bobWallet.ID, 10)
+	messages = mailer.take()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "bob@example.com", messages[0].To)
+	}
+
+	// Changing address clears the verification and sends a new link
+	w = sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/users/%d", alice.ID), map[string]string{"email": "alice@example.net"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Nil(t, updated.EmailVerifiedAt)
+	messages = mailer.take()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "alice@example.net", messages[0].To)
+	}
+}
+
+func TestAPI_Pockets(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user1 := createTestUser(t, router, "John Doe", "john@example.com")
+	user2 := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet := createTestWallet(t, router, user1.ID)
+	other := createTestWallet(t, router, user2.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet.ID, "amount": 1000})
+
+	w := sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/wallets/%d/pockets", wallet.ID), map[string]interface{}{
+		"name": "Holiday", "target_amount": 5000, "target_date": "2030-08-01",
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var pocket models.Wallet
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pocket))
+	if assert.NotNil(t, pocket.ParentID) {
+		assert.Equal(t, wallet.ID, *pocket.ParentID)
+	}
+	assert.Equal(t, user1.ID, pocket.UserID)
+
+	// Names are unique per parent, ignoring case
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/wallets/%d/pockets", wallet.ID), map[string]interface{}{"name": "holiday"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Pockets cannot hold pockets of their own
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/wallets/%d/pockets", pocket.ID), map[string]interface{}{"name": "Nested"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	postJSON(t, router, fmt.Sprintf("/api/v1/pockets/%d/fund", pocket.ID), map[string]interface{}{"amount": 400})
+	postJSON(t, router, fmt.Sprintf("/api/v1/pockets/%d/withdraw", pocket.ID), map[string]interface{}{"amount": 100})
+	w = sendJSON(router, http.MethodPost, fmt.Sprintf("/api/v1/pockets/%d/withdraw", pocket.ID), map[string]interface{}{"amount": 500})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// The parent shows its pockets and the balance across all of them
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var parent models.Wallet
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &parent))
+	assert.Equal(t, int64(700), parent.Balance)
+	if assert.NotNil(t, parent.TotalBalance) {
+		assert.Equal(t, int64(1000), *parent.TotalBalance)
+	}
+	if assert.Len(t, parent.Pockets, 1) {
+		assert.Equal(t, "Holiday", parent.Pockets[0].Name)
+		assert.Equal(t, int64(300), parent.Pockets[0].Balance)
+	}
+
+	// Money only leaves or enters a pocket through its parent
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, user1.ID), map[string]interface{}{
+		"source_wallet_id": pocket.ID, "target_wallet_id": other.ID, "amount": 100,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, user2.ID), map[string]interface{}{
+		"source_wallet_id": other.ID, "target_wallet_id": pocket.ID, "amount": 100,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSON(router, http.MethodPost, "/api/v1/deposits", map[string]interface{}{"wallet_id": pocket.ID, "amount": 100})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	name := "Summer"
+	w = sendJSON(router, http.MethodPatch, fmt.Sprintf("/api/v1/pockets/%d", pocket.ID), map[string]interface{}{"name": name, "target_date": ""})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pocket))
+	assert.Equal(t, name, pocket.Name)
+	assert.Nil(t, pocket.TargetDate)
+
+	// Closing sweeps the balance back to the parent
+	w = sendJSON(router, http.MethodDelete, fmt.Sprintf("/api/v1/pockets/%d", pocket.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var balance int64
+	db.Model(&models.Wallet{}).Where("id = ?", wallet.ID).Pluck("balance", &balance)
+	assert.Equal(t, int64(1000), balance)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/pockets", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var pockets []models.Wallet
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pockets))
+	assert.Empty(t, pockets)
+
+	// Every move was recorded against the parent
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/transactions", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var transactions []models.TransferResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transactions))
+	var moves int
+	for _, tx := range transactions {
+		if tx.Type == models.TransactionTypePocket {
+			moves++
+		}
+	}
+	assert.Equal(t, 3, moves)
+}
+
+func TestAPI_JointWallets(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	owner := createTestUser(t, router, "John Doe", "john@example.com")
+	coOwner := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	spender := createTestUser(t, router, "Jim Doe", "jim@example.com")
+	viewer := createTestUser(t, router, "Joan Doe", "joan@example.com")
+	wallet := createTestWallet(t, router, owner.ID)
+	other := createTestWallet(t, router, viewer.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet.ID, "amount": 5000})
+
+	// Only owners change the members, with their own user key
+	members := fmt.Sprintf("/api/v1/wallets/%d/members", wallet.ID)
+	ownerKey, coOwnerKey := userKey(t, db, owner.ID), userKey(t, db, coOwner.ID)
+	w := sendJSON(router, http.MethodPost, members, map[string]interface{}{"user_id": coOwner.ID, "role": "owner"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, members, coOwnerKey, map[string]interface{}{"user_id": coOwner.ID, "role": "owner"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "a stranger cannot add itself")
+	w = sendJSONAs(router, http.MethodPost, members, ownerKey, map[string]interface{}{"user_id": coOwner.ID, "role": "owner"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSONAs(router, http.MethodPost, members, coOwnerKey, map[string]interface{}{"user_id": spender.ID, "role": "spender", "spending_cap": 300})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSONAs(router, http.MethodPost, members, ownerKey, map[string]interface{}{"user_id": viewer.ID, "role": "viewer"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSONAs(router, http.MethodPost, members, ownerKey, map[string]interface{}{"user_id": viewer.ID, "role": "spender"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSONAs(router, http.MethodPatch, fmt.Sprintf("%s/%d", members, spender.ID), userKey(t, db, spender.ID), map[string]interface{}{"role": "owner"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "a spender cannot promote itself")
+
+	transfer := func(initiatedBy uint, amount int64) *httptest.ResponseRecorder {
+		return sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, initiatedBy), map[string]interface{}{
+			"source_wallet_id": wallet.ID, "target_wallet_id": other.ID, "amount": amount,
+		})
+	}
+	balance := func(walletID uint) int64 {
+		var balance int64
+		db.Model(&models.Wallet{}).Where("id = ?", walletID).Pluck("balance", &balance)
+		return balance
+	}
+
+	// Spenders move money up to their cap; viewers and strangers cannot
+	assert.Equal(t, http.StatusOK, transfer(spender.ID, 200).Code)
+	assert.Equal(t, http.StatusBadRequest, transfer(spender.ID, 200).Code)
+	assert.Equal(t, http.StatusForbidden, transfer(viewer.ID, 100).Code)
+	stranger := createTestUser(t, router, "Jack Doe", "jack@example.com")
+	assert.Equal(t, http.StatusForbidden, transfer(stranger.ID, 100).Code)
+	assert.Equal(t, int64(4800), balance(wallet.ID))
+
+	// Above the threshold both owners have to agree
+	w = sendJSONAs(router, http.MethodPut, fmt.Sprintf("/api/v1/wallets/%d/approval-rule", wallet.ID), ownerKey, map[string]interface{}{
+		"threshold": 1000, "required_approvals": 2,
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, transfer(owner.ID, 1000).Code)
+
+	w = transfer(owner.ID, 2000)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var pending struct {
+		ApprovalID uint `json:"approval_id"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	assert.NotZero(t, pending.ApprovalID)
+	assert.Equal(t, int64(3800), balance(wallet.ID))
+
+	// Only owners vote, once each, as the user of their key
+	approve := fmt.Sprintf("/api/v1/approvals/%d/approve", pending.ApprovalID)
+	w = sendJSON(router, http.MethodPost, approve, map[string]interface{}{"user_id": coOwner.ID})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, approve, userKey(t, db, spender.ID), map[string]interface{}{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSONAs(router, http.MethodPost, approve, ownerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = sendJSONAs(router, http.MethodPost, approve, ownerKey, map[string]interface{}{"user_id": coOwner.ID})
+	assert.Equal(t, http.StatusConflict, w.Code, "an owner cannot vote for another")
+
+	w = sendJSONAs(router, http.MethodPost, approve, coOwnerKey, map[string]interface{}{"note": "agreed"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var approval models.TransferApproval
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approval))
+	assert.Equal(t, models.TransferApprovalStatusApproved, approval.Status)
+	assert.NotNil(t, approval.TransactionID)
+	assert.Len(t, approval.Votes, 2)
+	assert.Equal(t, int64(1800), balance(wallet.ID))
+	assert.Equal(t, int64(3200), balance(other.ID))
+
+	w = sendJSONAs(router, http.MethodPost, approve, coOwnerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A single rejection makes two of two unreachable
+	w = transfer(coOwner.ID, 1500)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pending))
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/approvals/%d/reject", pending.ApprovalID), ownerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approval))
+	assert.Equal(t, models.TransferApprovalStatusRejected, approval.Status)
+	assert.Nil(t, approval.TransactionID)
+	assert.Equal(t, int64(1800), balance(wallet.ID))
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/approvals?status=rejected", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var approvals []models.TransferApproval
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &approvals))
+	assert.Len(t, approvals, 1)
+
+	// The rule still needs the co-owner
+	w = sendJSONAs(router, http.MethodDelete, fmt.Sprintf("%s/%d", members, coOwner.ID), ownerKey, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	rule := fmt.Sprintf("/api/v1/wallets/%d/approval-rule", wallet.ID)
+	w = sendJSONAs(router, http.MethodDelete, rule, userKey(t, db, spender.ID), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSONAs(router, http.MethodDelete, rule, ownerKey, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSONAs(router, http.MethodDelete, fmt.Sprintf("%s/%d", members, coOwner.ID), ownerKey, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusForbidden, transfer(coOwner.ID, 100).Code)
+}
+
+func TestAPI_Tenants(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	// Only admin keys manage tenants
+	acmeDetails := map[string]interface{}{
+		"name": "Acme", "slug": "acme", "currencies": []string{"EUR", "GBP"},
+		"limits": map[string]interface{}{"none": map[string]int64{"max_balance": 2000}},
+	}
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(router, http.MethodPost, "/api/v1/tenants", acmeDetails).Code)
+	operatorKey := issueKey(t, db, "ops", models.APIKeyRoleOperator, nil)
+	assert.Equal(t, http.StatusForbidden, sendJSONAs(router, http.MethodPost, "/api/v1/tenants", operatorKey, acmeDetails).Code)
+	rootKey := issueKey(t, db, "root", models.APIKeyRoleAdmin, nil)
+	w := sendJSONAs(router, http.MethodPost, "/api/v1/tenants", rootKey, acmeDetails)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var acme models.Tenant
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &acme))
+	assert.Equal(t, http.StatusConflict, sendJSONAs(router, http.MethodPost, "/api/v1/tenants", rootKey,
+		map[string]interface{}{"name": "Acme", "slug": "acme"}).Code)
+	acmeKey := issueKeyIn(t, db, acme.ID, "acme", models.APIKeyRoleOperator, nil)
+
+	// sendAs is sendJSONAs on behalf of a tenant; send uses the tenant's
+	// operator key, or none in the default tenant
+	sendAs := func(tenantID uint, secret, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		var body io.Reader
+		if payload != nil {
+			jsonData, _ := json.Marshal(payload)
+			body = bytes.NewBuffer(jsonData)
+		}
+		req, _ := http.NewRequest(method, path, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(tenancy.Header, strconv.FormatUint(uint64(tenantID), 10))
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send := func(tenantID uint, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		if tenantID == acme.ID {
+			return sendAs(tenantID, acmeKey, method, path, payload)
+		}
+		return sendAs(tenantID, "", method, path, payload)
+	}
+	create := func(tenantID uint, path string, payload interface{}, v interface{}) {
+		w := send(tenantID, http.MethodPost, path, payload)
+		if assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+		}
+	}
+	balance := func(walletID uint) int64 {
+		var balance int64
+		db.Model(&models.Wallet{}).Where("id = ?", walletID).Pluck("balance", &balance)
+		return balance
+	}
+
+	// The same address can sign up with both tenants
+	home := createTestUser(t, router, "John Doe", "john@example.com")
+	homeWallet := createTestWallet(t, router, home.ID)
+	assert.Equal(t, models.DefaultTenantCurrency, homeWallet.Currency)
+	var bob, carol, operator models.User
+	create(acme.ID, "/api/v1/users", map[string]string{"name": "Bob", "email": "john@example.com"}, &bob)
+	create(acme.ID, "/api/v1/users", map[string]string{"name": "Carol", "email": "carol@example.com"}, &carol)
+	create(acme.ID, "/api/v1/users", map[string]string{"name": "Acme Fees", "email": "fees@example.com"}, &operator)
+	assert.Equal(t, http.StatusConflict, send(acme.ID, http.MethodPost, "/api/v1/users",
+		map[string]string{"name": "Bob", "email": "john@example.com"}).Code)
+
+	var bobWallet, carolWallet, feeWallet, poundWallet models.Wallet
+	create(acme.ID, "/api/v1/wallets", map[string]interface{}{"user_id": bob.ID}, &bobWallet)
+	create(acme.ID, "/api/v1/wallets", map[string]interface{}{"user_id": carol.ID}, &carolWallet)
+	create(acme.ID, "/api/v1/wallets", map[string]interface{}{"user_id": operator.ID}, &feeWallet)
+	create(acme.ID, "/api/v1/wallets", map[string]interface{}{"user_id": carol.ID, "currency": "gbp"}, &poundWallet)
+	assert.Equal(t, "EUR", bobWallet.Currency)
+	assert.Equal(t, "GBP", poundWallet.Currency)
+	assert.Equal(t, http.StatusBadRequest, send(acme.ID, http.MethodPost, "/api/v1/wallets",
+		map[string]interface{}{"user_id": bob.ID, "currency": "USD"}).Code)
+	homeKey := userKey(t, db, home.ID)
+	bobKey := userKeyIn(t, db, acme.ID, bob.ID)
+
+	t.Run("cross-tenant reads", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send(models.DefaultTenantID, http.MethodGet, fmt.Sprintf("/api/v1/users/%d", bob.ID), nil).Code)
+		assert.Equal(t, http.StatusNotFound, send(models.DefaultTenantID, http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d", bobWallet.ID), nil).Code)
+		assert.Equal(t, http.StatusNotFound, send(acme.ID, http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d", homeWallet.ID), nil).Code)
+		assert.Equal(t, http.StatusOK, send(acme.ID, http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d", bobWallet.ID), nil).Code)
+
+		// Without the header a request belongs to the tenant of its key, or
+		// to the default tenant when it has none
+		assert.Equal(t, http.StatusNotFound, sendJSON(router, http.MethodGet, fmt.Sprintf("/api/v1/users/%d", bob.ID), nil).Code)
+		assert.Equal(t, http.StatusOK, sendJSONAs(router, http.MethodGet, fmt.Sprintf("/api/v1/users/%d", bob.ID), bobKey, nil).Code)
+
+		var page models.UserPage
+		w := send(acme.ID, http.MethodGet, "/api/v1/users", nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Len(t, page.Users, 3)
+		for _, user := range page.Users {
+			assert.NotEqual(t, home.ID, user.ID)
+		}
+
+		// Neither can a wallet be opened for another tenant's user
+		assert.Equal(t, http.StatusBadRequest, send(models.DefaultTenantID, http.MethodPost, "/api/v1/wallets",
+			map[string]interface{}{"user_id": bob.ID}).Code)
+	})
+
+	t.Run("unknown tenant", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, sendAs(acme.ID+100, rootKey, http.MethodGet, "/api/v1/users", nil).Code)
+	})
+
+	t.Run("the header cannot leave the key's tenant", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/users/%d", bob.ID)
+		assert.Equal(t, http.StatusUnauthorized, sendAs(acme.ID, "", http.MethodGet, path, nil).Code)
+		assert.Equal(t, http.StatusForbidden, sendAs(acme.ID, homeKey, http.MethodGet, path, nil).Code)
+		assert.Equal(t, http.StatusForbidden, sendAs(models.DefaultTenantID, acmeKey, http.MethodGet, "/api/v1/users", nil).Code)
+		assert.Equal(t, http.StatusOK, sendAs(acme.ID, rootKey, http.MethodGet, path, nil).Code)
+	})
+
+	t.Run("per-tenant limits", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(acme.ID, http.MethodPost, "/api/v1/deposits",
+			map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 2500}).Code)
+		assert.Equal(t, http.StatusOK, send(acme.ID, http.MethodPost, "/api/v1/deposits",
+			map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 1500}).Code)
+		// The default tenant keeps the configured limits
+		postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": homeWallet.ID, "amount": 2500})
+	})
+
+	t.Run("cross-tenant transfers", func(t *testing.T) {
+		w := sendJSONAs(router, http.MethodPost, "/api/v1/transfers", homeKey, map[string]interface{}{
+			"source_wallet_id": homeWallet.ID, "target_wallet_id": bobWallet.ID, "amount": 100,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = sendJSONAs(router, http.MethodPost, "/api/v1/transfers", bobKey, map[string]interface{}{
+			"source_wallet_id": bobWallet.ID, "target_wallet_id": homeWallet.ID, "amount": 100,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusBadRequest, send(models.DefaultTenantID, http.MethodPost, "/api/v1/deposits",
+			map[string]interface{}{"wallet_id": bobWallet.ID, "amount": 100}).Code)
+		assert.Equal(t, int64(2500), balance(homeWallet.ID))
+		assert.Equal(t, int64(1500), balance(bobWallet.ID))
+
+		w = send(models.DefaultTenantID, http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/transactions", bobWallet.ID), nil)
+		assert.NotContains(t, w.Body.String(), "DEP-")
+	})
+
+	t.Run("currencies", func(t *testing.T) {
+		w := sendAs(acme.ID, bobKey, http.MethodPost, "/api/v1/transfers", map[string]interface{}{
+			"source_wallet_id": bobWallet.ID, "target_wallet_id": poundWallet.ID, "amount": 100,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "different currencies")
+	})
+
+	t.Run("per-tenant fees", func(t *testing.T) {
+		tenantPath := fmt.Sprintf("/api/v1/tenants/%d", acme.ID)
+		w := sendJSONAs(router, http.MethodPatch, tenantPath, rootKey, map[string]interface{}{
+			"fees": map[string]interface{}{"EUR": map[string]interface{}{"fixed": 10, "basis_points": 100, "wallet_id": homeWallet.ID}},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "the fee wallet must be the tenant's")
+		w = sendJSONAs(router, http.MethodPatch, tenantPath, rootKey, map[string]interface{}{
+			"fees": map[string]interface{}{"EUR": map[string]interface{}{"fixed": 10, "basis_points": 100, "wallet_id": feeWallet.ID}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = sendAs(acme.ID, bobKey, http.MethodPost, "/api/v1/transfers", map[string]interface{}{
+			"source_wallet_id": bobWallet.ID, "target_wallet_id": carolWallet.ID, "amount": 1000,
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, int64(480), balance(bobWallet.ID))
+		assert.Equal(t, int64(1000), balance(carolWallet.ID))
+		assert.Equal(t, int64(20), balance(feeWallet.ID))
+
+		var transactions []models.TransferResponse
+		w = send(acme.ID, http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/transactions", feeWallet.ID), nil)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &transactions))
+		if assert.Len(t, transactions, 1) {
+			assert.Equal(t, models.TransactionTypeFee, transactions[0].Type)
+			assert.Equal(t, int64(20), transactions[0].Amount)
+		}
+
+		// The fee is on top of the amount, so it must be covered too
+		w = sendAs(acme.ID, bobKey, http.MethodPost, "/api/v1/transfers", map[string]interface{}{
+			"source_wallet_id": bobWallet.ID, "target_wallet_id": carolWallet.ID, "amount": 470,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// The default tenant charges nothing
+		postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, home.ID), map[string]interface{}{
+			"source_wallet_id": homeWallet.ID, "target_wallet_id": createTestWallet(t, router, home.ID).ID, "amount": 500,
+		})
+		assert.Equal(t, int64(2000), balance(homeWallet.ID))
+	})
+}
+
+func TestAPI_PaymentRequests(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	requester := createTestUser(t, router, "John Doe", "john@example.com")
+	payer := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet := createTestWallet(t, router, requester.ID)
+	payerWallet := createTestWallet(t, router, payer.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": payerWallet.ID, "amount": 5000})
+	payerKey := userKey(t, db, payer.ID)
+
+	create := func(payload map[string]interface{}) models.PaymentRequest {
+		w := sendJSON(router, http.MethodPost, "/api/v1/payment-requests", payload)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var request models.PaymentRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &request))
+		return request
+	}
+	request := create(map[string]interface{}{
+		"requester_wallet_id": wallet.ID, "payer_wallet_id": payerWallet.ID, "amount": 1500, "memo": "dinner",
+	})
+	assert.Equal(t, models.PaymentRequestStatusPending, request.Status)
+
+	// The payer sees it incoming, the requester outgoing
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/payment-requests/incoming?status=pending", payerWallet.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var requests []models.PaymentRequest
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &requests))
+	assert.Len(t, requests, 1)
+
+	// Only the requester's owners may cancel it, with their own user key
+	cancel := fmt.Sprintf("/api/v1/payment-requests/%d/cancel", request.ID)
+	requesterKey := userKey(t, db, requester.ID)
+	w = sendJSON(router, http.MethodPost, cancel, map[string]interface{}{"user_id": requester.ID})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, cancel, payerKey, map[string]interface{}{"user_id": requester.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code, "the payer cannot cancel as the requester")
+
+	// Of concurrent accepts, one pays the request and the rest find it paid
+	accept := fmt.Sprintf("/api/v1/payment-requests/%d/accept", request.ID)
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = sendJSONAs(router, http.MethodPost, accept, payerKey, map[string]interface{}{}).Code
+		}(i)
+	}
+	wg.Wait()
+	var paid, conflicts int
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			paid++
+		case http.StatusConflict:
+			conflicts++
+		}
+	}
+	assert.Equal(t, 1, paid)
+	assert.Equal(t, len(codes)-1, conflicts)
+
+	var balance int64
+	db.Model(&models.Wallet{}).Where("id = ?", payerWallet.ID).Pluck("balance", &balance)
+	assert.Equal(t, int64(3500), balance)
+	db.Model(&models.Wallet{}).Where("id = ?", wallet.ID).Pluck("balance", &balance)
+	assert.Equal(t, int64(1500), balance)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/payment-requests/%d", request.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &request))
+	assert.Equal(t, models.PaymentRequestStatusPaid, request.Status)
+	assert.NotNil(t, request.TransactionID)
+	w = sendJSONAs(router, http.MethodPost, cancel, requesterKey, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// An open request is paid by whichever wallet accepts it
+	open := create(map[string]interface{}{"requester_wallet_id": wallet.ID, "amount": 200})
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/payment-requests/%d/decline", open.ID), payerKey, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/payment-requests/%d/accept", open.ID), payerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/payment-requests/%d/accept", open.ID), payerKey, map[string]interface{}{"payer_wallet_id": payerWallet.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &open))
+	if assert.NotNil(t, open.PaidFromWalletID) {
+		assert.Equal(t, payerWallet.ID, *open.PaidFromWalletID)
+	}
+
+	// A request that cannot be covered stays pending; the payer declines it
+	large := create(map[string]interface{}{"requester_wallet_id": wallet.ID, "payer_wallet_id": payerWallet.ID, "amount": 10000})
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/payment-requests/%d/accept", large.ID), payerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	decline := fmt.Sprintf("/api/v1/payment-requests/%d/decline", large.ID)
+	w = sendJSONAs(router, http.MethodPost, decline, requesterKey, map[string]interface{}{"user_id": payer.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code, "the requester cannot decline as the payer")
+	w = sendJSONAs(router, http.MethodPost, decline, payerKey, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &large))
+	assert.Equal(t, models.PaymentRequestStatusDeclined, large.Status)
+
+	// Expired requests can no longer be paid
+	expiring := create(map[string]interface{}{
+		"requester_wallet_id": wallet.ID, "payer_wallet_id": payerWallet.ID, "amount": 100,
+		"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+	db.Model(&models.PaymentRequest{}).Where("id = ?", expiring.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/payment-requests/outgoing?status=expired", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &requests))
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, expiring.ID, requests[0].ID)
+	}
+	w = sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/payment-requests/%d/accept", expiring.ID), payerKey, map[string]interface{}{})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/payment-requests", map[string]interface{}{
+		"requester_wallet_id": wallet.ID, "amount": 100, "expires_at": "2001-01-01T00:00:00Z",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPI_PaymentLinks(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	merchant := createTestUser(t, router, "John Doe", "john@example.com")
+	customer := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet := createTestWallet(t, router, merchant.ID)
+	customerWallet := createTestWallet(t, router, customer.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": customerWallet.ID, "amount": 10000})
+	customerKey := userKey(t, db, customer.ID)
+
+	create := func(payload map[string]interface{}) models.PaymentLink {
+		w := sendJSON(router, http.MethodPost, "/api/v1/payment-links", payload)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var link models.PaymentLink
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+		return link
+	}
+	link := create(map[string]interface{}{"wallet_id": wallet.ID, "amount": 1500, "memo": "coffee"})
+	assert.Equal(t, models.PaymentLinkModeSingle, link.Mode)
+	assert.Equal(t, "https://wallet.example/api/v1/pay/"+link.Token, link.URL)
+
+	// The QR code carries the link's URL
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/payment-links/%d/qr?format=svg", link.ID), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+
+	// Anyone with the token sees what it asks for, without naming a tenant
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/pay/"+link.Token, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resolved models.PaymentLink
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resolved))
+	assert.Equal(t, wallet.ID, resolved.WalletID)
+	assert.Equal(t, int64(1500), resolved.Amount)
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/pay/"+link.Token+"x", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A single-use link takes one payment
+	pay := "/api/v1/pay/" + link.Token
+	w = sendJSON(router, http.MethodPost, pay, map[string]interface{}{"payer_wallet_id": customerWallet.ID})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "payments are sent with a user key")
+	w = sendJSONAs(router, http.MethodPost, pay, customerKey, map[string]interface{}{"payer_wallet_id": customerWallet.ID})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.Equal(t, models.PaymentLinkStatusUsed, link.Status)
+	assert.Equal(t, 1, link.UseCount)
+	w = sendJSONAs(router, http.MethodPost, pay, customerKey, map[string]interface{}{"payer_wallet_id": customerWallet.ID})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/payment-links/%d", link.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	if assert.Len(t, link.Uses, 1) {
+		assert.Equal(t, customerWallet.ID, link.Uses[0].PayerWalletID)
+		assert.NotZero(t, link.Uses[0].TransactionID)
+	}
+
+	// Concurrent payments never take a multi-use link past its limit
+	multi := create(map[string]interface{}{"wallet_id": wallet.ID, "amount": 100, "mode": "multi", "max_uses": 2})
+	codes := make([]int, 4)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = sendJSON(router, http.MethodPost, "/api/v1/pay/"+multi.Token,
+				map[string]interface{}{"payer_wallet_id": customerWallet.ID}).Code
+		}(i)
+	}
+	wg.Wait()
+	var paid int
+	for _, code := range codes {
+		if code == http.StatusOK {
+			paid++
+		}
+	}
+	assert.Equal(t, 2, paid)
+
+	var balance int64
+	db.Model(&models.Wallet{}).Where("id = ?", wallet.ID).Pluck("balance", &balance)
+	assert.Equal(t, int64(1700), balance)
+
+	// Only the wallet's owners may revoke a link, with their own user key
+	open := create(map[string]interface{}{"wallet_id": wallet.ID, "amount": 100, "mode": "multi"})
+	revoke := fmt.Sprintf("/api/v1/payment-links/%d/revoke", open.ID)
+	w = sendJSON(router, http.MethodPost, revoke, map[string]interface{}{"user_id": merchant.ID})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = sendJSONAs(router, http.MethodPost, revoke, customerKey, map[string]interface{}{"user_id": merchant.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code, "the customer cannot revoke as the merchant")
+	w = sendJSONAs(router, http.MethodPost, revoke, userKey(t, db, merchant.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSONAs(router, http.MethodPost, "/api/v1/pay/"+open.Token, customerKey, map[string]interface{}{"payer_wallet_id": customerWallet.ID})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/payment-links", wallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var links []models.PaymentLink
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &links))
+	assert.Len(t, links, 3)
+
+	w = sendJSON(router, http.MethodPost, "/api/v1/payment-links", map[string]interface{}{
+		"wallet_id": wallet.ID, "amount": 100, "mode": "single", "max_uses": 3,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPI_Escrow(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	buyer := createTestUser(t, router, "John Doe", "john@example.com")
+	seller := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	escrowOwner := createTestUser(t, router, "Marketplace Escrow", "escrow@example.com")
+	buyerWallet := createTestWallet(t, router, buyer.ID)
+	sellerWallet := createTestWallet(t, router, seller.ID)
+	escrowWallet := createTestWallet(t, router, escrowOwner.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": buyerWallet.ID, "amount": 10000})
+	buyerKey := userKey(t, db, buyer.ID)
+	sellerKey := userKey(t, db, seller.ID)
+	escrowKey := userKey(t, db, escrowOwner.ID)
+
+	balance := func(walletID uint) int64 {
+		var balance int64
+		db.Model(&models.Wallet{}).Where("id = ?", walletID).Pluck("balance", &balance)
+		return balance
+	}
+	create := func(payload map[string]interface{}) models.EscrowAgreement {
+		w := sendJSONAs(router, http.MethodPost, "/api/v1/escrows", buyerKey, payload)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var agreement models.EscrowAgreement
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &agreement))
+		return agreement
+	}
+	action := func(agreement models.EscrowAgreement, verb, secret string, payload map[string]interface{}) *httptest.ResponseRecorder {
+		return sendJSONAs(router, http.MethodPost, fmt.Sprintf("/api/v1/escrows/%d/%s", agreement.ID, verb), secret, payload)
+	}
+	deal := map[string]interface{}{"buyer_wallet_id": buyerWallet.ID, "seller_wallet_id": sellerWallet.ID, "amount": 3000}
+
+	// Nothing can be held before the tenant names its escrow wallet
+	w := sendJSONAs(router, http.MethodPost, "/api/v1/escrows", buyerKey, deal)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	tenantPath := fmt.Sprintf("/api/v1/tenants/%d", models.DefaultTenantID)
+	rootKey := issueKey(t, db, "root", models.APIKeyRoleAdmin, nil)
+	w = sendJSONAs(router, http.MethodPatch, tenantPath, rootKey, map[string]interface{}{
+		"escrow_wallets": map[string]uint{"USD": escrowWallet.ID},
+	})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	defer sendJSONAs(router, http.MethodPatch, tenantPath, rootKey, map[string]interface{}{"escrow_wallets": map[string]uint{}})
+
+	// Funding moves the money from the buyer into escrow
+	agreement := create(deal)
+	assert.Equal(t, models.EscrowStatusFunded, agreement.Status)
+	assert.Equal(t, escrowWallet.ID, agreement.EscrowWalletID)
+	assert.NotZero(t, agreement.FundingTransactionID)
+	assert.Equal(t, int64(7000), balance(buyerWallet.ID))
+	assert.Equal(t, int64(3000), balance(escrowWallet.ID))
+
+	// Only the buyer confirms delivery, which releases it to the seller
+	w = action(agreement, "confirm", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = action(agreement, "confirm", sellerKey, map[string]interface{}{"user_id": buyer.ID})
+	assert.Equal(t, http.StatusForbidden, w.Code, "the seller cannot confirm by naming the buyer")
+	w = action(agreement, "confirm", buyerKey, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &agreement))
+	assert.Equal(t, models.EscrowStatusReleased, agreement.Status)
+	assert.Equal(t, int64(3000), balance(sellerWallet.ID))
+	assert.Equal(t, int64(0), balance(escrowWallet.ID))
+	if assert.NotNil(t, agreement.ReleaseTransactionID) {
+		var release models.Transaction
+		assert.NoError(t, db.First(&release, *agreement.ReleaseTransactionID).Error)
+		assert.Equal(t, models.TransactionTypeEscrow, release.Type)
+		assert.Equal(t, escrowWallet.ID, *release.SourceWalletID)
+		assert.Equal(t, sellerWallet.ID, release.TargetWalletID)
+	}
+	w = action(agreement, "confirm", buyerKey, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A dispute blocks confirmation until an operator resolves it
+	disputed := create(deal)
+	w = action(disputed, "dispute", escrowKey, map[string]interface{}{"user_id": seller.ID, "reason": "buyer never collected"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "only the buyer and seller may dispute")
+	w = action(disputed, "dispute", sellerKey, map[string]interface{}{"reason": "buyer never collected"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = action(disputed, "confirm", buyerKey, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = action(disputed, "resolve", "", map[string]interface{}{"outcome": "refund"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = action(disputed, "resolve", buyerKey, map[string]interface{}{"outcome": "refund"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	opsKey := issueKey(t, db, "ops@example.com", models.APIKeyRoleOperator, nil)
+	w = action(disputed, "resolve", opsKey, map[string]interface{}{"outcome": "keep"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = action(disputed, "resolve", opsKey, map[string]interface{}{"outcome": "refund", "note": "no delivery"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &disputed))
+	assert.Equal(t, models.EscrowStatusRefunded, disputed.Status)
+	assert.Equal(t, "ops@example.com", disputed.ResolvedBy)
+	assert.NotNil(t, disputed.RefundTransactionID)
+	assert.Equal(t, int64(7000), balance(buyerWallet.ID))
+
+	// Concurrent confirmations release the money once
+	concurrent := create(deal)
+	codes := make([]int, 4)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = action(concurrent, "confirm", buyerKey, nil).Code
+		}(i)
+	}
+	wg.Wait()
+	var released int
+	for _, code := range codes {
+		if code == http.StatusOK {
+			released++
+		}
+	}
+	assert.Equal(t, 1, released)
+	assert.Equal(t, int64(6000), balance(sellerWallet.ID))
+	assert.Equal(t, int64(0), balance(escrowWallet.ID))
+
+	// An agreement left unconfirmed past its expiry goes back to the buyer
+	payload := map[string]interface{}{"expires_at": time.Now().Add(time.Second).Format(time.RFC3339Nano)}
+	for k, v := range deal {
+		payload[k] = v
+	}
+	expiring := create(payload)
+	time.Sleep(1100 * time.Millisecond)
+	w = action(expiring, "confirm", buyerKey, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/escrows/%d", expiring.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &expiring))
+	assert.Equal(t, models.EscrowStatusRefunded, expiring.Status)
+	assert.Equal(t, int64(4000), balance(buyerWallet.ID))
+	assert.Equal(t, int64(0), balance(escrowWallet.ID))
+
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/wallets/%d/escrows?status=refunded", sellerWallet.ID), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var agreements []models.EscrowAgreement
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &agreements))
+	assert.Len(t, agreements, 2)
+}
+
+func TestAPI_Metrics(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user1 := createTestUser(t, router, "John Doe", "john@example.com")
+	user2 := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet1 := createTestWallet(t, router, user1.ID)
+	wallet2 := createTestWallet(t, router, user2.ID)
+
+	// Counters are process-wide, so compare against a scrape taken first
+	before := scrapeMetrics(t, router)
+
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet1.ID, "amount": 1000})
+	postJSONAs(t, router, "/api/v1/transfers", userKey(t, db, user1.ID), map[string]interface{}{
+		"source_wallet_id": wallet1.ID,
+		"target_wallet_id": wallet2.ID,
+		"amount":           300,
+	})
+
+	w := sendJSONAs(router, http.MethodPost, "/api/v1/transfers", userKey(t, db, user1.ID), map[string]interface{}{
+		"source_wallet_id": wallet1.ID,
+		"target_wallet_id": wallet2.ID,
+		"amount":           5000,
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	after := scrapeMetrics(t, router)
+	delta := func(series string) float64 { return after[series] - before[series] }
+
+	assert.Equal(t, 1.0, delta(`wallet_deposits_total{outcome="success"}`))
+	assert.Equal(t, 1.0, delta(`wallet_transfers_total{outcome="success"}`))
+	assert.Equal(t, 1.0, delta(`wallet_transfers_total{outcome="insufficient_balance"}`))
+	assert.Equal(t, 1.0, delta(`wallet_insufficient_balance_rejections_total`))
+	assert.Equal(t, 1000.0, delta(`wallet_amount_moved_minor_units_total{currency="USD",type="deposit"}`))
+	assert.Equal(t, 300.0, delta(`wallet_amount_moved_minor_units_total{currency="USD",type="transfer"}`))
+	assert.Equal(t, 2.0, delta(`wallet_lock_wait_seconds_count{operation="transfer"}`))
+	assert.Equal(t, 1.0, delta(`wallet_http_request_duration_seconds_count{method="POST",route="/api/v1/transfers",status="400"}`))
+	assert.Equal(t, 1.0, delta(`wallet_http_request_duration_seconds_count{method="POST",route="/api/v1/transfers",status="200"}`))
+}
+
+func TestAPI_Tracing(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user1 := createTestUser(t, router, "John Doe", "john@example.com")
+	user2 := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet1 := createTestWallet(t, router, user1.ID)
+	wallet2 := createTestWallet(t, router, user2.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet1.ID, "amount": 1000})
+	key := userKey(t, db, user1.ID)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(previous)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"source_wallet_id": wallet1.ID,
+		"target_wallet_id": wallet2.ID,
+		"amount":           300,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+
+	// Request -> service -> one span per SQL statement of the transaction
+	if assert.Len(t, spans["/api/v1/transfers"], 1) && assert.Len(t, spans["TransferService.Transfer"], 1) {
+		server, service := spans["/api/v1/transfers"][0], spans["TransferService.Transfer"][0]
+		assert.Equal(t, server.SpanContext().SpanID(), service.Parent().SpanID())
+
+		// The API key is looked up before the transfer starts
+		if assert.Len(t, spans["gorm.query"], 3, "API key lookup, source and target wallet locks") &&
+			assert.Len(t, spans["APIKeyService.Authenticate"], 1) {
+			authenticate := spans["APIKeyService.Authenticate"][0]
+			assert.Equal(t, server.SpanContext().SpanID(), authenticate.Parent().SpanID())
+			assert.Equal(t, authenticate.SpanContext().SpanID(), spans["gorm.query"][0].Parent().SpanID())
+			spans["gorm.query"] = spans["gorm.query"][1:]
+		}
+		assert.Len(t, spans["gorm.update"], 2, "source and target balances")
+		assert.Len(t, spans["gorm.create"], 1, "transaction insert")
+		for _, name := range []string{"gorm.query", "gorm.update", "gorm.create"} {
+			for _, span := range spans[name] {
+				assert.Equal(t, service.SpanContext().SpanID(), span.Parent().SpanID(), name)
+			}
+		}
+	}
+}
+
+func TestAPI_RequestIDLogging(t *testing.T) {
+	router, db := setupTestServer(t)
+	defer teardownTestDB(t, db)
+
+	user1 := createTestUser(t, router, "John Doe", "john@example.com")
+	user2 := createTestUser(t, router, "Jane Doe", "jane@example.com")
+	wallet1 := createTestWallet(t, router, user1.ID)
+	wallet2 := createTestWallet(t, router, user2.ID)
+	postJSON(t, router, "/api/v1/deposits", map[string]interface{}{"wallet_id": wallet1.ID, "amount": 1000})
+	key := userKey(t, db, user1.ID)
+
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Options{Level: slog.LevelInfo, Levels: map[string]slog.Level{"gorm": slog.LevelDebug}})
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+	db.Logger = logging.NewGormLogger(logger, time.Second)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"source_wallet_id": wallet1.ID,
+		"target_wallet_id": wallet2.ID,
+		"amount":           300,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set(logging.RequestIDHeader, "transfer-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "transfer-42", w.Header().Get(logging.RequestIDHeader))
+
+	// Every SQL statement of the transaction and the commit log line carry
+	// the request ID
+	components := map[string]int{}
+	committed := false
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "transfer-42", record["request_id"], line)
+		components[fmt.Sprint(record["component"])]++
+		if record["msg"] == "transfer committed" {
+			committed = true
+			assert.Equal(t, "success", record["outcome"])
+		}
+	}
+	assert.True(t, committed)
+	assert.GreaterOrEqual(t, components["gorm"], 5, "two locks, two updates and an insert")
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	router, db := setupRateLimitedTestServer(t, func(db *gorm.DB) *ratelimit.Limiter {
+		return ratelimit.NewLimiter(ratelimit.NewPostgresStore(db), map[ratelimit.Class]ratelimit.Policy{
+			ratelimit.ClassRead:   {Limit: 100, Period: time.Minute},
+			ratelimit.ClassSignup: {Limit: 2, Period: time.Hour},
+		})
+	})
+	defer teardownTestDB(t, db)
+
+	signup := func(email, ip string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(map[string]interface{}{"name": "Rate Limited", "email": email})
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The signup policy allows two users per hour per client
+	w := signup("first@example.com", "203.0.113.7")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=3600", w.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusCreated, signup("second@example.com", "203.0.113.7").Code)
+
+	w = signup("third@example.com", "203.0.113.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1800", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+
+	var users int64
+	db.Model(&models.User{}).Count(&users)
+	assert.Equal(t, int64(2), users, "the rejected request never reached the handler")
+
+	// Other clients and other route classes have their own buckets
+	assert.Equal(t, http.StatusCreated, signup("fourth@example.com", "198.51.100.1").Code)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/discrepancies", nil)
+	req.RemoteAddr = "203.0.113.7:12345"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "99", w.Header().Get("RateLimit-Remaining"))
+
+	// Routes whose class has no policy are not limited
+	jsonData, _ := json.Marshal(map[string]interface{}{"user_id": 1})
+	req, _ = http.NewRequest(http.MethodPost, "/api/v1/wallets", bytes.NewBuffer(jsonData))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	var buckets int64
+	db.Model(&models.RateLimitBucket{}).Count(&buckets)
+	assert.Equal(t, int64(3), buckets)
+}
+
+// scrapeMetrics fetches /metrics and returns every sample keyed by its series
+// as written in the exposition format.
+func scrapeMetrics(t *testing.T, router *gin.Engine) map[string]float64 {
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	samples := map[string]float64{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err == nil {
+			samples[line[:i]] = value
+		}
+	}
+	return samples
+}
+
+// sendJSON sends payload as JSON and returns the response, whatever its status.
+func sendJSON(router *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// sendJSONAs is sendJSON authenticated with the API key secret.
+func sendJSONAs(router *gin.Engine, method, path, secret string, payload interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// issueKey issues an API key in the default tenant and returns its secret.
+func issueKey(t *testing.T, db *gorm.DB, name, role string, userID *uint) string {
+	return issueKeyIn(t, db, models.DefaultTenantID, name, role, userID)
+}
+
+// issueKeyIn issues an API key in the tenant and returns its secret.
+func issueKeyIn(t *testing.T, db *gorm.DB, tenantID uint, name, role string, userID *uint) string {
+	keys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db), repositories.NewUserRepository(db), db)
+	secret, _, err := keys.Issue(tenancy.WithTenant(context.Background(), tenantID),
+		services.APIKeyDetails{Name: name, Role: role, UserID: userID})
+	assert.NoError(t, err)
+	return secret
+}
+
+// userKey issues a user API key acting for the user and returns its secret.
+func userKey(t *testing.T, db *gorm.DB, userID uint) string {
+	return userKeyIn(t, db, models.DefaultTenantID, userID)
+}
+
+// userKeyIn is userKey for a user of the tenant.
+func userKeyIn(t *testing.T, db *gorm.DB, tenantID, userID uint) string {
+	return issueKeyIn(t, db, tenantID, fmt.Sprintf("user:%d", userID), models.APIKeyRoleUser, &userID)
+}
+
+// postJSONAs is postJSON authenticated with the API key secret.
+func postJSONAs(t *testing.T, router *gin.Engine, path, secret string, payload interface{}) *httptest.ResponseRecorder {
+	w := sendJSONAs(router, http.MethodPost, path, secret, payload)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w
+}
+
+func postJSON(t *testing.T, router *gin.Engine, path string, payload interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ApprovalHandler struct {
+	approvalService services.IApprovalService
+}
+
+func NewApprovalHandler(approvalService services.IApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{approvalService: approvalService}
+}
+
+// DecideApprovalRequest is an owner's vote on a transfer approval. The
+// owner voting is the user of the API key.
+type DecideApprovalRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *ApprovalHandler) ListByWallet(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	approvals, err := h.approvalService.ListByWallet(c.Request.Context(), uint(walletID), c.Query("status"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, approvals)
+}
+
+func (h *ApprovalHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid approval ID"})
+		return
+	}
+
+	approval, err := h.approvalService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "approval not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, h.approvalService.Approve)
+}
+
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, h.approvalService.Reject)
+}
+
+func (h *ApprovalHandler) decide(c *gin.Context, decide func(context.Context, uint, string) (*models.TransferApproval, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid approval ID"})
+		return
+	}
+
+	var req DecideApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approval, err := decide(c.Request.Context(), uint(id), req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "approval not found"})
+		case errors.Is(err, services.ErrInitiatorRequired):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrWalletRoleForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrApprovalDecided), errors.Is(err, services.ErrAlreadyVoted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock ApprovalService
+type MockApprovalService struct {
+	mock.Mock
+}
+
+func (m *MockApprovalService) Get(ctx context.Context, id uint) (*models.TransferApproval, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransferApproval), args.Error(1)
+}
+
+func (m *MockApprovalService) ListByWallet(ctx context.Context, walletID uint, status string) ([]models.TransferApproval, error) {
+	args := m.Called(walletID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TransferApproval), args.Error(1)
+}
+
+func (m *MockApprovalService) Approve(ctx context.Context, id uint, note string) (*models.TransferApproval, error) {
+	args := m.Called(id, keyUser(ctx), note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransferApproval), args.Error(1)
+}
+
+func (m *MockApprovalService) Reject(ctx context.Context, id uint, note string) (*models.TransferApproval, error) {
+	args := m.Called(id, keyUser(ctx), note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TransferApproval), args.Error(1)
+}
+
+func TestApprovalHandler_ListByWallet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("pending", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("ListByWallet", uint(1), models.TransferApprovalStatusPending).
+			Return([]models.TransferApproval{{ID: 4, SourceWalletID: 1, Status: models.TransferApprovalStatusPending}}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/approvals?status=pending", nil)
+		handler.ListByWallet(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []models.TransferApproval
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response, 1)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestApprovalHandler_Decide(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// approve votes as the user of a user API key, or anonymously when
+	// userID is 0.
+	approve := func(handler *ApprovalHandler, userID uint, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "4"}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/approvals/4/approve", bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		if userID != 0 {
+			asUser(c, userID)
+		}
+		handler.Approve(c)
+		return w
+	}
+
+	t.Run("approved", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		transactionID := uint(12)
+		mockService.On("Approve", uint(4), uint(2), "fine").
+			Return(&models.TransferApproval{ID: 4, Status: models.TransferApprovalStatusApproved, TransactionID: &transactionID}, nil)
+
+		w := approve(handler, 2, DecideApprovalRequest{Note: "fine"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.TransferApproval
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.TransferApprovalStatusApproved, response.Status)
+		assert.Equal(t, &transactionID, response.TransactionID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("user key required", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("Approve", uint(4), uint(0), "").Return(nil, services.ErrInitiatorRequired)
+
+		assert.Equal(t, http.StatusUnauthorized, approve(handler, 0, map[string]interface{}{"user_id": 2}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("voting as another owner", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("Approve", uint(4), uint(2), "").Return(nil, services.ErrAlreadyVoted)
+
+		// The user_id in the body is not the voter; the key's user is
+		assert.Equal(t, http.StatusConflict, approve(handler, 2, map[string]interface{}{"user_id": 5}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not an owner", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("Approve", uint(4), uint(3), "").Return(nil, services.ErrWalletRoleForbidden)
+
+		assert.Equal(t, http.StatusForbidden, approve(handler, 3, DecideApprovalRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already decided", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("Approve", uint(4), uint(2), "").Return(nil, services.ErrApprovalDecided)
+
+		assert.Equal(t, http.StatusConflict, approve(handler, 2, DecideApprovalRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockApprovalService)
+		handler := NewApprovalHandler(mockService)
+
+		mockService.On("Approve", uint(4), uint(2), "").Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, approve(handler, 2, DecideApprovalRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EscrowHandler struct {
+	escrowService services.IEscrowService
+}
+
+func NewEscrowHandler(escrowService services.IEscrowService) *EscrowHandler {
+	return &EscrowHandler{escrowService: escrowService}
+}
+
+// CreateEscrowRequest pays amount from the buyer's wallet into escrow for
+// the seller. Like a transfer, it is sent by the user of the API key.
+type CreateEscrowRequest struct {
+	BuyerWalletID  uint       `json:"buyer_wallet_id" binding:"required"`
+	SellerWalletID uint       `json:"seller_wallet_id" binding:"required"`
+	Amount         int64      `json:"amount" binding:"required,gt=0"`
+	Memo           string     `json:"memo"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+// DisputeEscrowRequest says why an agreement is disputed. It is disputed by
+// the user of the API key, who must own the buyer or seller wallet.
+type DisputeEscrowRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ResolveEscrowRequest settles a dispute. Outcome is release or refund; the
+// operator settling it is the one named by the API key.
+type ResolveEscrowRequest struct {
+	Outcome string `json:"outcome" binding:"required"`
+	Note    string `json:"note"`
+}
+
+func (h *EscrowHandler) Create(c *gin.Context) {
+	var req CreateEscrowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agreement, err := h.escrowService.Create(c.Request.Context(), services.EscrowDetails{
+		BuyerWalletID:  req.BuyerWalletID,
+		SellerWalletID: req.SellerWalletID,
+		Amount:         req.Amount,
+		Memo:           req.Memo,
+		ExpiresAt:      req.ExpiresAt,
+	})
+	// A held funding transfer still creates the agreement, as pending.
+	if errors.Is(err, services.ErrTransferHeld) {
+		c.JSON(http.StatusAccepted, agreement)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		respondEscrowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, agreement)
+}
+
+func (h *EscrowHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid escrow agreement ID"})
+		return
+	}
+
+	agreement, err := h.escrowService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "escrow agreement not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, agreement)
+}
+
+func (h *EscrowHandler) ListByWallet(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	agreements, err := h.escrowService.ListByWallet(c.Request.Context(), uint(walletID), c.Query("status"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, agreements)
+}
+
+// Confirm releases the agreement as the user of the API key, who must own
+// the buyer wallet.
+func (h *EscrowHandler) Confirm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid escrow agreement ID"})
+		return
+	}
+
+	agreement, err := h.escrowService.Confirm(c.Request.Context(), uint(id))
+	if err != nil {
+		respondEscrowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agreement)
+}
+
+func (h *EscrowHandler) Dispute(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid escrow agreement ID"})
+		return
+	}
+
+	var req DisputeEscrowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agreement, err := h.escrowService.Dispute(c.Request.Context(), uint(id), req.Reason)
+	if err != nil {
+		respondEscrowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agreement)
+}
+
+func (h *EscrowHandler) Resolve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid escrow agreement ID"})
+		return
+	}
+
+	var req ResolveEscrowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resolvedBy, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	agreement, err := h.escrowService.Resolve(c.Request.Context(), uint(id), services.EscrowResolution{
+		Outcome:    req.Outcome,
+		ResolvedBy: resolvedBy,
+		Note:       req.Note,
+	})
+	if err != nil {
+		respondEscrowError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agreement)
+}
+
+func respondEscrowError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "escrow agreement not found"})
+	case errors.Is(err, services.ErrInitiatorRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotWalletMember), errors.Is(err, services.ErrWalletRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEscrowClosed), errors.Is(err, services.ErrEscrowPending),
+		errors.Is(err, services.ErrEscrowDisputed), errors.Is(err, services.ErrEscrowNotDisputed),
+		errors.Is(err, services.ErrEscrowExpired), errors.Is(err, services.ErrEscrowNeedsApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
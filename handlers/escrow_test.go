@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock EscrowService
+type MockEscrowService struct {
+	mock.Mock
+}
+
+func (m *MockEscrowService) Create(ctx context.Context, details services.EscrowDetails) (*models.EscrowAgreement, error) {
+	args := m.Called(details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) Get(ctx context.Context, id uint) (*models.EscrowAgreement, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) ListByWallet(ctx context.Context, walletID uint, status string) ([]models.EscrowAgreement, error) {
+	args := m.Called(walletID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) Confirm(ctx context.Context, id uint) (*models.EscrowAgreement, error) {
+	args := m.Called(id, keyUser(ctx))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) Dispute(ctx context.Context, id uint, reason string) (*models.EscrowAgreement, error) {
+	args := m.Called(id, keyUser(ctx), reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) Resolve(ctx context.Context, id uint, resolution services.EscrowResolution) (*models.EscrowAgreement, error) {
+	args := m.Called(id, resolution)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EscrowAgreement), args.Error(1)
+}
+
+func (m *MockEscrowService) RefundExpired(ctx context.Context) (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func TestEscrowHandler_Create(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	details := services.EscrowDetails{BuyerWalletID: 1, SellerWalletID: 2, Amount: 5000, Memo: "bike"}
+	request := CreateEscrowRequest{BuyerWalletID: 1, SellerWalletID: 2, Amount: 5000, Memo: "bike"}
+
+	t.Run("funded", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Create", details).Return(&models.EscrowAgreement{
+			ID: 3, BuyerWalletID: 1, SellerWalletID: 2, EscrowWalletID: 9, Amount: 5000,
+			Status: models.EscrowStatusFunded, FundingTransactionID: 11,
+		}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows", request)
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.EscrowAgreement
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.EscrowStatusFunded, response.Status)
+		assert.Equal(t, uint(11), response.FundingTransactionID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("funding held", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Create", details).Return(&models.EscrowAgreement{
+			ID: 3, Status: models.EscrowStatusPending, FundingTransactionID: 11,
+		}, &services.HeldError{TransactionID: 11})
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows", request)
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var response models.EscrowAgreement
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.EscrowStatusPending, response.Status)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Create", details).Return(nil, gorm.ErrRecordNotFound)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows", request)
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestEscrowHandler_Confirm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "id", Value: "3"}
+
+	t.Run("released", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		releaseID := uint(12)
+		mockService.On("Confirm", uint(3), uint(1)).Return(&models.EscrowAgreement{
+			ID: 3, Status: models.EscrowStatusReleased, ReleaseTransactionID: &releaseID,
+		}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/confirm", nil, param)
+		asUser(c, 1)
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.EscrowAgreement
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.EscrowStatusReleased, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("the seller naming the buyer", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Confirm", uint(3), uint(2)).Return(nil, services.ErrWalletRoleForbidden)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/confirm", map[string]interface{}{"user_id": 1}, param)
+		asUser(c, 2)
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("without a user key", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Confirm", uint(3), uint(0)).Return(nil, services.ErrInitiatorRequired)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/confirm", map[string]interface{}{"user_id": 1}, param)
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("disputed", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Confirm", uint(3), uint(1)).Return(nil, services.ErrEscrowDisputed)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/confirm", nil, param)
+		asUser(c, 1)
+		handler.Confirm(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestEscrowHandler_Dispute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "id", Value: "3"}
+
+	t.Run("reason required", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/dispute", map[string]interface{}{}, param)
+		asUser(c, 2)
+		handler.Dispute(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Dispute")
+	})
+
+	t.Run("disputed", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Dispute", uint(3), uint(2), "never arrived").Return(&models.EscrowAgreement{
+			ID: 3, Status: models.EscrowStatusDisputed, DisputeReason: "never arrived",
+		}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/dispute", DisputeEscrowRequest{Reason: "never arrived"}, param)
+		asUser(c, 2)
+		handler.Dispute(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("as another user", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Dispute", uint(3), uint(5), "never arrived").Return(nil, services.ErrNotWalletMember)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/dispute",
+			map[string]interface{}{"user_id": 2, "reason": "never arrived"}, param)
+		asUser(c, 5)
+		handler.Dispute(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestEscrowHandler_Resolve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "id", Value: "3"}
+	resolution := services.EscrowResolution{Outcome: services.EscrowResolveRefund, ResolvedBy: "ops@example.com"}
+	// resolve calls Resolve as the operator ops@example.com.
+	resolve := func(handler *EscrowHandler, body interface{}) *httptest.ResponseRecorder {
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/resolve", body, param)
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+			auth.Principal{KeyID: 2, Name: "ops@example.com", Role: models.APIKeyRoleOperator}))
+		handler.Resolve(c)
+		return w
+	}
+
+	t.Run("refunded", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Resolve", uint(3), resolution).Return(&models.EscrowAgreement{
+			ID: 3, Status: models.EscrowStatusRefunded, ResolvedBy: "ops@example.com",
+		}, nil)
+
+		// A resolver named in the body is not the one recorded
+		w := resolve(handler, map[string]interface{}{"outcome": "refund", "resolved_by": "someone@example.com"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("operator required", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/escrows/3/resolve", ResolveEscrowRequest{Outcome: "refund"}, param)
+		handler.Resolve(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "Resolve")
+	})
+
+	t.Run("not disputed", func(t *testing.T) {
+		mockService := new(MockEscrowService)
+		handler := NewEscrowHandler(mockService)
+		mockService.On("Resolve", uint(3), resolution).Return(nil, services.ErrEscrowNotDisputed)
+
+		w := resolve(handler, ResolveEscrowRequest{Outcome: "refund"})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type KYCHandler struct {
+	kycService services.IKYCService
+}
+
+func NewKYCHandler(kycService services.IKYCService) *KYCHandler {
+	return &KYCHandler{kycService: kycService}
+}
+
+type DocumentRequest struct {
+	Type           string `json:"type" binding:"required"`
+	IssuingCountry string `json:"issuing_country" binding:"required,len=2"`
+	// Reference locates the uploaded file in document storage.
+	Reference string `json:"reference" binding:"required"`
+	// ExpiresOn is the document's expiry date (YYYY-MM-DD), if it has one.
+	ExpiresOn string `json:"expires_on"`
+}
+
+type SubmitVerificationRequest struct {
+	Level     models.KYCLevel   `json:"level" binding:"required"`
+	Documents []DocumentRequest `json:"documents" binding:"required,min=1,dive"`
+}
+
+// ReviewVerificationRequest is the body of an approval or rejection. The
+// reviewer is the operator whose API key made the request.
+type ReviewVerificationRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *KYCHandler) Submit(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req SubmitVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	documents := make([]models.VerificationDocument, len(req.Documents))
+	for i, doc := range req.Documents {
+		documents[i] = models.VerificationDocument{
+			Type:           doc.Type,
+			IssuingCountry: doc.IssuingCountry,
+			Reference:      doc.Reference,
+		}
+		if doc.ExpiresOn != "" {
+			expires, err := time.Parse("2006-01-02", doc.ExpiresOn)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expires_on, expected YYYY-MM-DD"})
+				return
+			}
+			documents[i].ExpiresOn = &expires
+		}
+	}
+
+	verificationCase, err := h.kycService.Submit(c.Request.Context(), uint(userID), req.Level, documents)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrVerificationPending):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, verificationCase)
+}
+
+func (h *KYCHandler) ListUserCases(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	cases, err := h.kycService.ListUserCases(c.Request.Context(), uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cases)
+}
+
+func (h *KYCHandler) ListCases(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.VerificationStatusPending &&
+		status != models.VerificationStatusApproved && status != models.VerificationStatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved or rejected"})
+		return
+	}
+
+	cases, err := h.kycService.ListCases(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cases)
+}
+
+func (h *KYCHandler) GetCase(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid verification case ID"})
+		return
+	}
+
+	verificationCase, err := h.kycService.GetCase(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "verification case not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, verificationCase)
+}
+
+func (h *KYCHandler) Approve(c *gin.Context) {
+	h.review(c, h.kycService.Approve)
+}
+
+func (h *KYCHandler) Reject(c *gin.Context) {
+	h.review(c, h.kycService.Reject)
+}
+
+func (h *KYCHandler) review(c *gin.Context, decide func(context.Context, uint, string, string) (*models.VerificationCase, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid verification case ID"})
+		return
+	}
+
+	var req ReviewVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviewer, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	verificationCase, err := decide(c.Request.Context(), uint(id), reviewer, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "verification case not found"})
+		case errors.Is(err, services.ErrVerificationReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, verificationCase)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock KYCService
+type MockKYCService struct {
+	mock.Mock
+}
+
+func (m *MockKYCService) Submit(ctx context.Context, userID uint, level models.KYCLevel, documents []models.VerificationDocument) (*models.VerificationCase, error) {
+	args := m.Called(userID, level, documents)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.VerificationCase), args.Error(1)
+}
+
+func (m *MockKYCService) GetCase(ctx context.Context, id uint) (*models.VerificationCase, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.VerificationCase), args.Error(1)
+}
+
+func (m *MockKYCService) ListCases(ctx context.Context, status string) ([]models.VerificationCase, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.VerificationCase), args.Error(1)
+}
+
+func (m *MockKYCService) ListUserCases(ctx context.Context, userID uint) ([]models.VerificationCase, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.VerificationCase), args.Error(1)
+}
+
+func (m *MockKYCService) Approve(ctx context.Context, id uint, reviewer, note string) (*models.VerificationCase, error) {
+	args := m.Called(id, reviewer, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.VerificationCase), args.Error(1)
+}
+
+func (m *MockKYCService) Reject(ctx context.Context, id uint, reviewer, note string) (*models.VerificationCase, error) {
+	args := m.Called(id, reviewer, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.VerificationCase), args.Error(1)
+}
+
+func TestKYCHandler_Submit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	submit := func(handler *KYCHandler, userID string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: userID}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+userID+"/verifications", bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		handler.Submit(c)
+		return w
+	}
+
+	t.Run("successful submission", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		expires := time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)
+		documents := []models.VerificationDocument{
+			{Type: models.DocumentTypePassport, IssuingCountry: "DE", Reference: "docs/1/passport.pdf", ExpiresOn: &expires},
+		}
+		verificationCase := &models.VerificationCase{ID: 1, UserID: 7, RequestedLevel: models.KYCLevelBasic, Status: models.VerificationStatusPending}
+		mockService.On("Submit", uint(7), models.KYCLevelBasic, documents).Return(verificationCase, nil)
+
+		w := submit(handler, "7", SubmitVerificationRequest{
+			Level: models.KYCLevelBasic,
+			Documents: []DocumentRequest{
+				{Type: "passport", IssuingCountry: "DE", Reference: "docs/1/passport.pdf", ExpiresOn: "2030-01-31"},
+			},
+		})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.VerificationCase
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.VerificationStatusPending, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing documents", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		w := submit(handler, "7", map[string]interface{}{"level": "basic", "documents": []interface{}{}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Submit")
+	})
+
+	t.Run("invalid expiry date", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		w := submit(handler, "7", SubmitVerificationRequest{
+			Level:     models.KYCLevelBasic,
+			Documents: []DocumentRequest{{Type: "passport", IssuingCountry: "DE", Reference: "x", ExpiresOn: "31/01/2030"}},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Submit")
+	})
+
+	t.Run("case already pending", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		mockService.On("Submit", uint(7), models.KYCLevelFull, mock.Anything).Return(nil, services.ErrVerificationPending)
+
+		w := submit(handler, "7", SubmitVerificationRequest{
+			Level:     models.KYCLevelFull,
+			Documents: []DocumentRequest{{Type: "passport", IssuingCountry: "DE", Reference: "x"}},
+		})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		mockService.On("Submit", uint(99), models.KYCLevelBasic, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+		w := submit(handler, "99", SubmitVerificationRequest{
+			Level:     models.KYCLevelBasic,
+			Documents: []DocumentRequest{{Type: "passport", IssuingCountry: "DE", Reference: "x"}},
+		})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestKYCHandler_ListCases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("review queue", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		cases := []models.VerificationCase{{ID: 1, UserID: 7, Status: models.VerificationStatusPending}}
+		mockService.On("ListCases", "pending").Return(cases, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/verifications?status=pending", nil)
+
+		handler.ListCases(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []models.VerificationCase
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response, 1)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/verifications?status=open", nil)
+
+		handler.ListCases(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListCases")
+	})
+}
+
+func TestKYCHandler_Review(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// review calls action as the named operator, or anonymously when
+	// reviewer is empty.
+	review := func(action func(*gin.Context), id, reviewer string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/verifications/"+id, bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		if reviewer != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+				auth.Principal{KeyID: 1, Name: reviewer, Role: models.APIKeyRoleOperator}))
+		}
+		action(c)
+		return w
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		approved := &models.VerificationCase{ID: 1, Status: models.VerificationStatusApproved, Reviewer: "alice"}
+		mockService.On("Approve", uint(1), "alice", "documents match").Return(approved, nil)
+
+		w := review(handler.Approve, "1", "alice", ReviewVerificationRequest{Note: "documents match"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reviewer required", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		w := review(handler.Approve, "1", "", ReviewVerificationRequest{Note: "ok"})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "Approve")
+	})
+
+	t.Run("reject without note", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		mockService.On("Reject", uint(1), "alice", "").Return(nil, errors.New("a note is required to reject a case"))
+
+		w := review(handler.Reject, "1", "alice", ReviewVerificationRequest{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already reviewed", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		mockService.On("Reject", uint(1), "bob", "blurry").Return(nil, services.ErrVerificationReviewed)
+
+		w := review(handler.Reject, "1", "bob", ReviewVerificationRequest{Note: "blurry"})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("case not found", func(t *testing.T) {
+		mockService := new(MockKYCService)
+		handler := NewKYCHandler(mockService)
+
+		mockService.On("Approve", uint(42), "alice", "").Return(nil, gorm.ErrRecordNotFound)
+
+		w := review(handler.Approve, "42", "alice", ReviewVerificationRequest{})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock EmailVerificationService
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) Resend(ctx context.Context, userID uint) (*models.EmailVerification, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EmailVerification), args.Error(1)
+}
+
+func (m *MockEmailVerificationService) Verify(ctx context.Context, token string) (*models.User, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func TestEmailVerificationHandler_Resend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	resend := func(handler *EmailVerificationHandler, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+id+"/email-verification", nil)
+		handler.Resend(c)
+		return w
+	}
+
+	t.Run("sent", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		verification := &models.EmailVerification{ID: 3, UserID: 1, Email: "john@example.com", TokenHash: "secret", ExpiresAt: time.Now().Add(time.Hour)}
+		mockService.On("Resend", uint(1)).Return(verification, nil)
+
+		w := resend(handler, "1")
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotContains(t, w.Body.String(), "secret")
+		var response models.EmailVerification
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "john@example.com", response.Email)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already verified", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		mockService.On("Resend", uint(1)).Return(nil, services.ErrEmailAlreadyVerified)
+
+		assert.Equal(t, http.StatusConflict, resend(handler, "1").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("too soon", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		mockService.On("Resend", uint(1)).Return(nil, services.ErrVerificationTooSoon)
+
+		assert.Equal(t, http.StatusTooManyRequests, resend(handler, "1").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		mockService.On("Resend", uint(9)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, resend(handler, "9").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, resend(handler, "abc").Code)
+		mockService.AssertNotCalled(t, "Resend")
+	})
+}
+
+func TestEmailVerificationHandler_Verify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	verify := func(handler *EmailVerificationHandler, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/email-verification", bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		handler.Verify(c)
+		return w
+	}
+
+	t.Run("verified", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		now := time.Now()
+		mockService.On("Verify", "tok").Return(&models.User{ID: 1, Email: "john@example.com", EmailVerifiedAt: &now}, nil)
+
+		w := verify(handler, VerifyEmailRequest{Token: "tok"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.User
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotNil(t, response.EmailVerifiedAt)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("token required", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, verify(handler, map[string]string{}).Code)
+		mockService.AssertNotCalled(t, "Verify")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		mockService.On("Verify", "bad").Return(nil, services.ErrVerificationInvalid)
+
+		assert.Equal(t, http.StatusBadRequest, verify(handler, VerifyEmailRequest{Token: "bad"}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mockService := new(MockEmailVerificationService)
+		handler := NewEmailVerificationHandler(mockService)
+
+		mockService.On("Verify", "old").Return(nil, services.ErrVerificationExpired)
+
+		assert.Equal(t, http.StatusGone, verify(handler, VerifyEmailRequest{Token: "old"}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MembershipHandler struct {
+	membershipService services.IMembershipService
+}
+
+func NewMembershipHandler(membershipService services.IMembershipService) *MembershipHandler {
+	return &MembershipHandler{membershipService: membershipService}
+}
+
+// AddMemberRequest shares a wallet with a user. SpendingCap limits what they
+// may send in any 24 hours.
+type AddMemberRequest struct {
+	UserID      uint              `json:"user_id" binding:"required"`
+	Role        models.WalletRole `json:"role" binding:"required"`
+	SpendingCap *int64            `json:"spending_cap"`
+}
+
+// UpdateMemberRequest changes a member. A spending_cap of 0 removes the cap.
+type UpdateMemberRequest struct {
+	Role        *models.WalletRole `json:"role"`
+	SpendingCap *int64             `json:"spending_cap"`
+}
+
+// ApprovalRuleRequest makes transfers above Threshold wait for
+// RequiredApprovals of the wallet's owners.
+type ApprovalRuleRequest struct {
+	Threshold         *int64 `json:"threshold" binding:"required"`
+	RequiredApprovals int    `json:"required_approvals" binding:"required,min=1"`
+}
+
+func (h *MembershipHandler) AddMember(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.membershipService.AddMember(c.Request.Context(), uint(walletID), services.MemberDetails{
+		UserID:      req.UserID,
+		Role:        req.Role,
+		SpendingCap: req.SpendingCap,
+	})
+	if err != nil {
+		respondMembershipError(c, err, "wallet or user not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *MembershipHandler) ListMembers(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	members, err := h.membershipService.ListMembers(c.Request.Context(), uint(walletID))
+	if err != nil {
+		respondMembershipError(c, err, "wallet not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *MembershipHandler) UpdateMember(c *gin.Context) {
+	walletID, userID, ok := memberParams(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == nil && req.SpendingCap == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	member, err := h.membershipService.UpdateMember(c.Request.Context(), walletID, userID, services.MemberUpdate{
+		Role:        req.Role,
+		SpendingCap: req.SpendingCap,
+	})
+	if err != nil {
+		respondMembershipError(c, err, "member not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *MembershipHandler) RemoveMember(c *gin.Context) {
+	walletID, userID, ok := memberParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.membershipService.RemoveMember(c.Request.Context(), walletID, userID); err != nil {
+		respondMembershipError(c, err, "member not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+func (h *MembershipHandler) SetApprovalRule(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	var req ApprovalRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, err := h.membershipService.SetApprovalRule(c.Request.Context(), uint(walletID), services.ApprovalRule{
+		Threshold:         *req.Threshold,
+		RequiredApprovals: req.RequiredApprovals,
+	})
+	if err != nil {
+		respondMembershipError(c, err, "wallet not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, wallet)
+}
+
+func (h *MembershipHandler) ClearApprovalRule(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	wallet, err := h.membershipService.ClearApprovalRule(c.Request.Context(), uint(walletID))
+	if err != nil {
+		respondMembershipError(c, err, "wallet not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, wallet)
+}
+
+// memberParams parses the wallet and user IDs of a member's path, writing a
+// 400 when either is malformed.
+func memberParams(c *gin.Context) (walletID, userID uint, ok bool) {
+	wallet, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return 0, 0, false
+	}
+	user, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return 0, 0, false
+	}
+	return uint(wallet), uint(user), true
+}
+
+// respondMembershipError writes the status for err. notFound is the message
+// when a record in the path does not exist.
+func respondMembershipError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, services.ErrInitiatorRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWalletRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrApprovalRuleUnmet):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock MembershipService
+type MockMembershipService struct {
+	mock.Mock
+}
+
+func (m *MockMembershipService) AddMember(ctx context.Context, walletID uint, details services.MemberDetails) (*models.WalletMember, error) {
+	args := m.Called(walletID, details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WalletMember), args.Error(1)
+}
+
+func (m *MockMembershipService) ListMembers(ctx context.Context, walletID uint) ([]models.WalletMember, error) {
+	args := m.Called(walletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WalletMember), args.Error(1)
+}
+
+func (m *MockMembershipService) UpdateMember(ctx context.Context, walletID, userID uint, update services.MemberUpdate) (*models.WalletMember, error) {
+	args := m.Called(walletID, userID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WalletMember), args.Error(1)
+}
+
+func (m *MockMembershipService) RemoveMember(ctx context.Context, walletID, userID uint) error {
+	args := m.Called(walletID, userID)
+	return args.Error(0)
+}
+
+func (m *MockMembershipService) SetApprovalRule(ctx context.Context, walletID uint, rule services.ApprovalRule) (*models.Wallet, error) {
+	args := m.Called(walletID, rule)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockMembershipService) ClearApprovalRule(ctx context.Context, walletID uint) (*models.Wallet, error) {
+	args := m.Called(walletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func membershipRequest(method, path string, params gin.Params, body interface{}) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = params
+	jsonData, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+	c.Request.Header.Add("Content-Type", "application/json")
+	return w, c
+}
+
+func TestMembershipHandler_AddMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	add := func(handler *MembershipHandler, body interface{}) *httptest.ResponseRecorder {
+		w, c := membershipRequest(http.MethodPost, "/api/v1/wallets/1/members", gin.Params{{Key: "id", Value: "1"}}, body)
+		handler.AddMember(c)
+		return w
+	}
+
+	t.Run("added", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		spendingCap := int64(5000)
+		details := services.MemberDetails{UserID: 2, Role: models.WalletRoleSpender, SpendingCap: &spendingCap}
+		mockService.On("AddMember", uint(1), details).Return(&models.WalletMember{ID: 1, WalletID: 1, UserID: 2, Role: models.WalletRoleSpender, SpendingCap: &spendingCap}, nil)
+
+		w := add(handler, AddMemberRequest{UserID: 2, Role: models.WalletRoleSpender, SpendingCap: &spendingCap})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.WalletMember
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.WalletRoleSpender, response.Role)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("role required", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, add(handler, map[string]interface{}{"user_id": 2}).Code)
+		mockService.AssertNotCalled(t, "AddMember")
+	})
+
+	t.Run("already a member", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		mockService.On("AddMember", uint(1), services.MemberDetails{UserID: 2, Role: models.WalletRoleOwner}).Return(nil, services.ErrAlreadyMember)
+
+		assert.Equal(t, http.StatusConflict, add(handler, AddMemberRequest{UserID: 2, Role: models.WalletRoleOwner}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		mockService.On("AddMember", uint(1), services.MemberDetails{UserID: 2, Role: models.WalletRoleViewer}).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, add(handler, AddMemberRequest{UserID: 2, Role: models.WalletRoleViewer}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not an owner", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		mockService.On("AddMember", uint(1), services.MemberDetails{UserID: 2, Role: models.WalletRoleOwner}).Return(nil, services.ErrWalletRoleForbidden)
+
+		assert.Equal(t, http.StatusForbidden, add(handler, AddMemberRequest{UserID: 2, Role: models.WalletRoleOwner}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("without a user key", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		mockService.On("AddMember", uint(1), services.MemberDetails{UserID: 2, Role: models.WalletRoleOwner}).Return(nil, services.ErrInitiatorRequired)
+
+		assert.Equal(t, http.StatusUnauthorized, add(handler, AddMemberRequest{UserID: 2, Role: models.WalletRoleOwner}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestMembershipHandler_UpdateMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	update := func(handler *MembershipHandler, userID string, body interface{}) *httptest.ResponseRecorder {
+		w, c := membershipRequest(http.MethodPatch, "/api/v1/wallets/1/members/"+userID,
+			gin.Params{{Key: "id", Value: "1"}, {Key: "user_id", Value: userID}}, body)
+		handler.UpdateMember(c)
+		return w
+	}
+
+	t.Run("demoting a needed owner", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		role := models.WalletRoleViewer
+		mockService.On("UpdateMember", uint(1), uint(2), services.MemberUpdate{Role: &role}).Return(nil, services.ErrApprovalRuleUnmet)
+
+		assert.Equal(t, http.StatusConflict, update(handler, "2", UpdateMemberRequest{Role: &role}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("nothing to update", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, update(handler, "2", map[string]interface{}{}).Code)
+		mockService.AssertNotCalled(t, "UpdateMember")
+	})
+
+	t.Run("invalid user ID", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, update(handler, "x", map[string]interface{}{"spending_cap": 0}).Code)
+		mockService.AssertNotCalled(t, "UpdateMember")
+	})
+}
+
+func TestMembershipHandler_RemoveMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("member not found", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		mockService.On("RemoveMember", uint(1), uint(5)).Return(gorm.ErrRecordNotFound)
+
+		w, c := membershipRequest(http.MethodDelete, "/api/v1/wallets/1/members/5",
+			gin.Params{{Key: "id", Value: "1"}, {Key: "user_id", Value: "5"}}, nil)
+		handler.RemoveMember(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestMembershipHandler_SetApprovalRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	set := func(handler *MembershipHandler, body interface{}) *httptest.ResponseRecorder {
+		w, c := membershipRequest(http.MethodPut, "/api/v1/wallets/1/approval-rule", gin.Params{{Key: "id", Value: "1"}}, body)
+		handler.SetApprovalRule(c)
+		return w
+	}
+
+	t.Run("set", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		threshold := int64(0)
+		mockService.On("SetApprovalRule", uint(1), services.ApprovalRule{Threshold: 0, RequiredApprovals: 2}).
+			Return(&models.Wallet{ID: 1, ApprovalThreshold: &threshold, RequiredApprovals: 2}, nil)
+
+		w := set(handler, ApprovalRuleRequest{Threshold: &threshold, RequiredApprovals: 2})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.Wallet
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.RequiredApprovals)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("threshold required", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, set(handler, map[string]interface{}{"required_approvals": 2}).Code)
+		mockService.AssertNotCalled(t, "SetApprovalRule")
+	})
+
+	t.Run("more approvals than owners", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		threshold := int64(1000)
+		mockService.On("SetApprovalRule", uint(1), services.ApprovalRule{Threshold: 1000, RequiredApprovals: 3}).Return(nil, services.ErrApprovalRuleUnmet)
+
+		assert.Equal(t, http.StatusConflict, set(handler, ApprovalRuleRequest{Threshold: &threshold, RequiredApprovals: 3}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not an owner", func(t *testing.T) {
+		mockService := new(MockMembershipService)
+		handler := NewMembershipHandler(mockService)
+
+		threshold := int64(1000)
+		mockService.On("SetApprovalRule", uint(1), services.ApprovalRule{Threshold: 1000, RequiredApprovals: 1}).Return(nil, services.ErrWalletRoleForbidden)
+
+		assert.Equal(t, http.StatusForbidden, set(handler, ApprovalRuleRequest{Threshold: &threshold, RequiredApprovals: 1}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/paylink"
+	"wallet-api/services"
+	"wallet-api/tenancy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PaymentLinkHandler struct {
+	linkService services.IPaymentLinkService
+}
+
+func NewPaymentLinkHandler(linkService services.IPaymentLinkService) *PaymentLinkHandler {
+	return &PaymentLinkHandler{linkService: linkService}
+}
+
+// CreatePaymentLinkRequest creates a link paying amount into a wallet. Mode
+// is single (the default) or multi.
+type CreatePaymentLinkRequest struct {
+	WalletID  uint       `json:"wallet_id" binding:"required"`
+	Amount    int64      `json:"amount" binding:"required,gt=0"`
+	Memo      string     `json:"memo"`
+	Mode      string     `json:"mode"`
+	MaxUses   *int       `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PayPaymentLinkRequest pays a link from a wallet. Like a transfer, the
+// payment is sent by the user of the API key.
+type PayPaymentLinkRequest struct {
+	PayerWalletID uint `json:"payer_wallet_id" binding:"required"`
+}
+
+func (h *PaymentLinkHandler) Create(c *gin.Context) {
+	var req CreatePaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.linkService.Create(c.Request.Context(), services.PaymentLinkDetails{
+		WalletID:  req.WalletID,
+		Amount:    req.Amount,
+		Memo:      req.Memo,
+		Mode:      req.Mode,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *PaymentLinkHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		return
+	}
+
+	link, err := h.linkService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment link not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+func (h *PaymentLinkHandler) ListByWallet(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	links, err := h.linkService.ListByWallet(c.Request.Context(), uint(walletID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// QR renders the link's URL as a QR code, a PNG by default.
+func (h *PaymentLinkHandler) QR(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		return
+	}
+	format := c.DefaultQuery("format", paylink.FormatPNG)
+	size, err := strconv.Atoi(c.DefaultQuery("size", "256"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid size"})
+		return
+	}
+
+	link, err := h.linkService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment link not found"})
+		return
+	}
+	image, err := paylink.Render(format, link.URL, size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, paylink.ContentType(format), image)
+}
+
+// Revoke revokes a link as the user of the API key, who must own its
+// wallet.
+func (h *PaymentLinkHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment link ID"})
+		return
+	}
+
+	link, err := h.linkService.Revoke(c.Request.Context(), uint(id))
+	if err != nil {
+		respondPaymentLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// Resolve shows what a link asks to be paid. It needs nothing but the
+// token, so it can be opened straight from a scanned QR code.
+func (h *PaymentLinkHandler) Resolve(c *gin.Context) {
+	link, err := h.linkService.Resolve(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondPaymentLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+func (h *PaymentLinkHandler) Pay(c *gin.Context) {
+	var req PayPaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.linkService.Pay(c.Request.Context(), c.Param("token"), req.PayerWalletID)
+	var held *services.HeldError
+	if errors.As(err, &held) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "transfer held for review", "transaction_id": held.TransactionID})
+		return
+	}
+	if err != nil {
+		respondPaymentLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+func respondPaymentLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, paylink.ErrInvalidToken):
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment link not found"})
+	case errors.Is(err, paylink.ErrExpiredToken):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInitiatorRequired), errors.Is(err, tenancy.ErrKeyRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, tenancy.ErrTenantForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotWalletMember), errors.Is(err, services.ErrWalletRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentLinkInactive), errors.Is(err, services.ErrPaymentNeedsApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
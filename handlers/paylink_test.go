@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"wallet-api/models"
+	"wallet-api/paylink"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock PaymentLinkService
+type MockPaymentLinkService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentLinkService) Create(ctx context.Context, details services.PaymentLinkDetails) (*models.PaymentLink, error) {
+	args := m.Called(details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkService) Get(ctx context.Context, id uint) (*models.PaymentLink, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkService) ListByWallet(ctx context.Context, walletID uint) ([]models.PaymentLink, error) {
+	args := m.Called(walletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkService) Revoke(ctx context.Context, id uint) (*models.PaymentLink, error) {
+	args := m.Called(id, keyUser(ctx))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkService) Resolve(ctx context.Context, token string) (*models.PaymentLink, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func (m *MockPaymentLinkService) Pay(ctx context.Context, token string, payerWalletID uint) (*models.PaymentLink, error) {
+	args := m.Called(token, payerWalletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentLink), args.Error(1)
+}
+
+func TestPaymentLinkHandler_Create(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPaymentLinkService)
+	handler := NewPaymentLinkHandler(mockService)
+
+	maxUses := 10
+	details := services.PaymentLinkDetails{WalletID: 42, Amount: 1500, Mode: models.PaymentLinkModeMulti, MaxUses: &maxUses}
+	mockService.On("Create", details).Return(&models.PaymentLink{
+		ID: 5, WalletID: 42, Amount: 1500, Mode: models.PaymentLinkModeMulti, MaxUses: &maxUses,
+		Status: models.PaymentLinkStatusActive, Token: "1.5.1748779200.sig", URL: "https://wallet.example/api/v1/pay/1.5.1748779200.sig",
+	}, nil)
+
+	w, c := tenantRequest(http.MethodPost, "/api/v1/payment-links", CreatePaymentLinkRequest{
+		WalletID: 42, Amount: 1500, Mode: models.PaymentLinkModeMulti, MaxUses: &maxUses,
+	})
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response models.PaymentLink
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "1.5.1748779200.sig", response.Token)
+	assert.Equal(t, 10, response.Limit())
+	mockService.AssertExpectations(t)
+}
+
+func TestPaymentLinkHandler_QR(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	link := &models.PaymentLink{ID: 5, URL: "https://wallet.example/api/v1/pay/1.5.1748779200.sig"}
+
+	t.Run("png", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Get", uint(5)).Return(link, nil)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/payment-links/5/qr", nil, gin.Param{Key: "id", Value: "5"})
+		handler.QR(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(w.Body.String(), "\x89PNG"))
+	})
+
+	t.Run("svg", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Get", uint(5)).Return(link, nil)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/payment-links/5/qr?format=svg&size=128", nil, gin.Param{Key: "id", Value: "5"})
+		handler.QR(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `width="128"`)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Get", uint(5)).Return(link, nil)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/payment-links/5/qr?format=gif", nil, gin.Param{Key: "id", Value: "5"})
+		handler.QR(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPaymentLinkHandler_Revoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "id", Value: "5"}
+
+	t.Run("as another user", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Revoke", uint(5), uint(7)).Return(nil, services.ErrWalletRoleForbidden)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-links/5/revoke", gin.H{"user_id": 8}, param)
+		asUser(c, 7)
+		handler.Revoke(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, "the user_id of the body is not the one acting")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("without a user key", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Revoke", uint(5), uint(0)).Return(nil, services.ErrInitiatorRequired)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-links/5/revoke", nil, param)
+		handler.Revoke(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPaymentLinkHandler_Resolve(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("expired", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Resolve", "1.5.1.sig").Return(nil, paylink.ErrExpiredToken)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/pay/1.5.1.sig", nil, gin.Param{Key: "token", Value: "1.5.1.sig"})
+		handler.Resolve(c)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("forged", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Resolve", "forged").Return(nil, paylink.ErrInvalidToken)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/pay/forged", nil, gin.Param{Key: "token", Value: "forged"})
+		handler.Resolve(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPaymentLinkHandler_Pay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "token", Value: "1.5.1748779200.sig"}
+
+	t.Run("used up", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+		mockService.On("Pay", param.Value, uint(7)).Return(nil, services.ErrPaymentLinkInactive)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/pay/"+param.Value, PayPaymentLinkRequest{PayerWalletID: 7}, param)
+		handler.Pay(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("payer required", func(t *testing.T) {
+		mockService := new(MockPaymentLinkService)
+		handler := NewPaymentLinkHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/pay/"+param.Value, map[string]interface{}{}, param)
+		handler.Pay(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Pay")
+	})
+}
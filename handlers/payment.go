@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PaymentRequestHandler struct {
+	paymentService services.IPaymentRequestService
+}
+
+func NewPaymentRequestHandler(paymentService services.IPaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{paymentService: paymentService}
+}
+
+// CreatePaymentRequestRequest asks for money to be paid into a wallet.
+// Without a payer_wallet_id any wallet may pay it.
+type CreatePaymentRequestRequest struct {
+	RequesterWalletID uint       `json:"requester_wallet_id" binding:"required"`
+	PayerWalletID     *uint      `json:"payer_wallet_id"`
+	Amount            int64      `json:"amount" binding:"required,gt=0"`
+	Memo              string     `json:"memo"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+}
+
+// AcceptPaymentRequestRequest pays a payment request. PayerWalletID is
+// required for open requests. Like a transfer, the payment is sent by the
+// user of the API key.
+type AcceptPaymentRequestRequest struct {
+	PayerWalletID uint `json:"payer_wallet_id"`
+}
+
+func (h *PaymentRequestHandler) Create(c *gin.Context) {
+	var req CreatePaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.paymentService.Create(c.Request.Context(), services.PaymentRequestDetails{
+		RequesterWalletID: req.RequesterWalletID,
+		PayerWalletID:     req.PayerWalletID,
+		Amount:            req.Amount,
+		Memo:              req.Memo,
+		ExpiresAt:         req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+func (h *PaymentRequestHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment request ID"})
+		return
+	}
+
+	request, err := h.paymentService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment request not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *PaymentRequestHandler) ListIncoming(c *gin.Context) {
+	h.list(c, h.paymentService.ListIncoming)
+}
+
+func (h *PaymentRequestHandler) ListOutgoing(c *gin.Context) {
+	h.list(c, h.paymentService.ListOutgoing)
+}
+
+func (h *PaymentRequestHandler) list(c *gin.Context, list func(context.Context, uint, string) ([]models.PaymentRequest, error)) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	requests, err := list(c.Request.Context(), uint(walletID), c.Query("status"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *PaymentRequestHandler) Accept(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment request ID"})
+		return
+	}
+
+	var req AcceptPaymentRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.paymentService.Accept(c.Request.Context(), uint(id), req.PayerWalletID)
+	var held *services.HeldError
+	if errors.As(err, &held) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "transfer held for review", "transaction_id": held.TransactionID})
+		return
+	}
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *PaymentRequestHandler) Decline(c *gin.Context) {
+	h.close(c, h.paymentService.Decline)
+}
+
+func (h *PaymentRequestHandler) Cancel(c *gin.Context) {
+	h.close(c, h.paymentService.Cancel)
+}
+
+// close declines or cancels a payment request as the user of the API key,
+// who must own the wallet concerned.
+func (h *PaymentRequestHandler) close(c *gin.Context, close func(context.Context, uint) (*models.PaymentRequest, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment request ID"})
+		return
+	}
+
+	request, err := close(c.Request.Context(), uint(id))
+	if err != nil {
+		respondPaymentRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func respondPaymentRequestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment request not found"})
+	case errors.Is(err, services.ErrInitiatorRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotWalletMember), errors.Is(err, services.ErrWalletRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentRequestClosed), errors.Is(err, services.ErrPaymentRequestExpired),
+		errors.Is(err, services.ErrPaymentNeedsApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock PaymentRequestService
+type MockPaymentRequestService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestService) Create(ctx context.Context, details services.PaymentRequestDetails) (*models.PaymentRequest, error) {
+	args := m.Called(details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Get(ctx context.Context, id uint) (*models.PaymentRequest, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) ListIncoming(ctx context.Context, walletID uint, status string) ([]models.PaymentRequest, error) {
+	args := m.Called(walletID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) ListOutgoing(ctx context.Context, walletID uint, status string) ([]models.PaymentRequest, error) {
+	args := m.Called(walletID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Accept(ctx context.Context, id, payerWalletID uint) (*models.PaymentRequest, error) {
+	args := m.Called(id, payerWalletID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Decline(ctx context.Context, id uint) (*models.PaymentRequest, error) {
+	args := m.Called(id, keyUser(ctx))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentRequest), args.Error(1)
+}
+
+func (m *MockPaymentRequestService) Cancel(ctx context.Context, id uint) (*models.PaymentRequest, error) {
+	args := m.Called(id, keyUser(ctx))
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PaymentRequest), args.Error(1)
+}
+
+func TestPaymentRequestHandler_Create(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("open request", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		details := services.PaymentRequestDetails{RequesterWalletID: 1, Amount: 1500, Memo: "dinner"}
+		mockService.On("Create", details).
+			Return(&models.PaymentRequest{ID: 3, RequesterWalletID: 1, Amount: 1500, Memo: "dinner", Status: models.PaymentRequestStatusPending}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests",
+			CreatePaymentRequestRequest{RequesterWalletID: 1, Amount: 1500, Memo: "dinner"})
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.PaymentRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, uint(3), response.ID)
+		assert.True(t, response.Open())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("amount required", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests", map[string]uint{"requester_wallet_id": 1})
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Create")
+	})
+}
+
+func TestPaymentRequestHandler_Accept(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	param := gin.Param{Key: "id", Value: "3"}
+
+	t.Run("paid", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		transactionID := uint(9)
+		mockService.On("Accept", uint(3), uint(0)).
+			Return(&models.PaymentRequest{ID: 3, Status: models.PaymentRequestStatusPaid, TransactionID: &transactionID}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/accept", map[string]interface{}{}, param)
+		handler.Accept(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.PaymentRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.PaymentRequestStatusPaid, response.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("held for review", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		mockService.On("Accept", uint(3), uint(2)).
+			Return(&models.PaymentRequest{ID: 3}, &services.HeldError{TransactionID: 9})
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/accept",
+			AcceptPaymentRequestRequest{PayerWalletID: 2}, param)
+		handler.Accept(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"transaction_id":9`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already paid", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		mockService.On("Accept", uint(3), uint(2)).Return(nil, services.ErrPaymentRequestClosed)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/accept",
+			AcceptPaymentRequestRequest{PayerWalletID: 2}, param)
+		handler.Accept(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPaymentRequestHandler_Decline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("not an owner", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		mockService.On("Decline", uint(3), uint(7)).Return(nil, services.ErrWalletRoleForbidden)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/decline",
+			gin.H{"user_id": 8}, gin.Param{Key: "id", Value: "3"})
+		asUser(c, 7)
+		handler.Decline(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, "the user_id of the body is not the one acting")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("without a user key", func(t *testing.T) {
+		mockService := new(MockPaymentRequestService)
+		handler := NewPaymentRequestHandler(mockService)
+
+		mockService.On("Decline", uint(3), uint(0)).Return(nil, services.ErrInitiatorRequired)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/decline", nil, gin.Param{Key: "id", Value: "3"})
+		handler.Decline(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPaymentRequestHandler_Cancel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockPaymentRequestService)
+	handler := NewPaymentRequestHandler(mockService)
+
+	mockService.On("Cancel", uint(3), uint(7)).Return(&models.PaymentRequest{ID: 3, Status: models.PaymentRequestStatusCancelled}, nil)
+
+	w, c := tenantRequest(http.MethodPost, "/api/v1/payment-requests/3/cancel", nil, gin.Param{Key: "id", Value: "3"})
+	asUser(c, 7)
+	handler.Cancel(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
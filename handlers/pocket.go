@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PocketHandler struct {
+	pocketService services.IPocketService
+}
+
+func NewPocketHandler(pocketService services.IPocketService) *PocketHandler {
+	return &PocketHandler{pocketService: pocketService}
+}
+
+// CreatePocketRequest opens a pocket. TargetDate is a plain date,
+// "2025-08-01".
+type CreatePocketRequest struct {
+	Name         string `json:"name" binding:"required"`
+	TargetAmount *int64 `json:"target_amount"`
+	TargetDate   string `json:"target_date"`
+}
+
+// UpdatePocketRequest changes a pocket. A target_amount of 0 or an empty
+// target_date removes that target.
+type UpdatePocketRequest struct {
+	Name         *string `json:"name"`
+	TargetAmount *int64  `json:"target_amount"`
+	TargetDate   *string `json:"target_date"`
+}
+
+// PocketMoveRequest moves money between a pocket and its parent wallet.
+type PocketMoveRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+func (h *PocketHandler) Create(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	var req CreatePocketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	details := services.PocketDetails{Name: req.Name, TargetAmount: req.TargetAmount}
+	if req.TargetDate != "" {
+		date, err := time.Parse("2006-01-02", req.TargetDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_date, want YYYY-MM-DD"})
+			return
+		}
+		details.TargetDate = &date
+	}
+
+	pocket, err := h.pocketService.Create(c.Request.Context(), uint(walletID), details)
+	if err != nil {
+		respondPocketError(c, err, "wallet not found")
+		return
+	}
+
+	c.JSON(http.StatusCreated, pocket)
+}
+
+func (h *PocketHandler) List(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	pockets, err := h.pocketService.List(c.Request.Context(), uint(walletID))
+	if err != nil {
+		respondPocketError(c, err, "wallet not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, pockets)
+}
+
+func (h *PocketHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pocket ID"})
+		return
+	}
+
+	var req UpdatePocketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil && req.TargetAmount == nil && req.TargetDate == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+	update := services.PocketUpdate{Name: req.Name, TargetAmount: req.TargetAmount}
+	if req.TargetDate != nil {
+		var date time.Time
+		if *req.TargetDate != "" {
+			date, err = time.Parse("2006-01-02", *req.TargetDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_date, want YYYY-MM-DD"})
+				return
+			}
+		}
+		update.TargetDate = &date
+	}
+
+	pocket, err := h.pocketService.Update(c.Request.Context(), uint(id), update)
+	if err != nil {
+		respondPocketError(c, err, "pocket not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, pocket)
+}
+
+// Fund moves money from the parent wallet into the pocket.
+func (h *PocketHandler) Fund(c *gin.Context) {
+	h.move(c, h.pocketService.Fund)
+}
+
+// Withdraw moves money from the pocket back to the parent wallet.
+func (h *PocketHandler) Withdraw(c *gin.Context) {
+	h.move(c, h.pocketService.Withdraw)
+}
+
+func (h *PocketHandler) move(c *gin.Context, move func(ctx context.Context, id uint, amount int64) (*models.Transaction, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pocket ID"})
+		return
+	}
+
+	var req PocketMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction, err := move(c.Request.Context(), uint(id), req.Amount)
+	if err != nil {
+		respondPocketError(c, err, "pocket not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// Close moves the pocket's balance back to its parent and deletes it.
+func (h *PocketHandler) Close(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pocket ID"})
+		return
+	}
+
+	pocket, err := h.pocketService.Close(c.Request.Context(), uint(id))
+	if err != nil {
+		respondPocketError(c, err, "pocket not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, pocket)
+}
+
+// respondPocketError writes the status for err. notFound is the message when
+// the wallet or pocket in the path does not exist.
+func respondPocketError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrNotPocket):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, services.ErrPocketNameTaken), errors.Is(err, services.ErrInsufficientBalance):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock PocketService
+type MockPocketService struct {
+	mock.Mock
+}
+
+func (m *MockPocketService) Create(ctx context.Context, parentID uint, details services.PocketDetails) (*models.Wallet, error) {
+	args := m.Called(parentID, details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockPocketService) List(ctx context.Context, parentID uint) ([]models.Wallet, error) {
+	args := m.Called(parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Wallet), args.Error(1)
+}
+
+func (m *MockPocketService) Update(ctx context.Context, id uint, update services.PocketUpdate) (*models.Wallet, error) {
+	args := m.Called(id, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockPocketService) Fund(ctx context.Context, id uint, amount int64) (*models.Transaction, error) {
+	args := m.Called(id, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transaction), args.Error(1)
+}
+
+func (m *MockPocketService) Withdraw(ctx context.Context, id uint, amount int64) (*models.Transaction, error) {
+	args := m.Called(id, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Transaction), args.Error(1)
+}
+
+func (m *MockPocketService) Close(ctx context.Context, id uint) (*models.Wallet, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func pocketRequest(method, path string, params gin.Params, body interface{}) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = params
+	jsonData, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+	c.Request.Header.Add("Content-Type", "application/json")
+	return w, c
+}
+
+func TestPocketHandler_Create(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	create := func(handler *PocketHandler, id string, body interface{}) *httptest.ResponseRecorder {
+		w, c := pocketRequest(http.MethodPost, "/api/v1/wallets/"+id+"/pockets", gin.Params{{Key: "id", Value: id}}, body)
+		handler.Create(c)
+		return w
+	}
+
+	t.Run("created", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		target := int64(50000)
+		date := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+		parentID := uint(1)
+		details := services.PocketDetails{Name: "Holiday", TargetAmount: &target, TargetDate: &date}
+		mockService.On("Create", uint(1), details).Return(&models.Wallet{ID: 2, ParentID: &parentID, Name: "Holiday", TargetAmount: &target, TargetDate: &date}, nil)
+
+		w := create(handler, "1", CreatePocketRequest{Name: "Holiday", TargetAmount: &target, TargetDate: "2025-08-01"})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.Wallet
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Holiday", response.Name)
+		assert.Equal(t, &parentID, response.ParentID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid target date", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		w := create(handler, "1", CreatePocketRequest{Name: "Holiday", TargetDate: "01/08/2025"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("name required", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, create(handler, "1", map[string]string{}).Code)
+		mockService.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		mockService.On("Create", uint(1), services.PocketDetails{Name: "Holiday"}).Return(nil, services.ErrPocketNameTaken)
+
+		assert.Equal(t, http.StatusConflict, create(handler, "1", CreatePocketRequest{Name: "Holiday"}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		mockService.On("Create", uint(9), services.PocketDetails{Name: "Holiday"}).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, create(handler, "9", CreatePocketRequest{Name: "Holiday"}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPocketHandler_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	update := func(handler *PocketHandler, id string, body interface{}) *httptest.ResponseRecorder {
+		w, c := pocketRequest(http.MethodPatch, "/api/v1/pockets/"+id, gin.Params{{Key: "id", Value: id}}, body)
+		handler.Update(c)
+		return w
+	}
+
+	t.Run("clears target date", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		empty := ""
+		mockService.On("Update", uint(2), services.PocketUpdate{TargetDate: &time.Time{}}).Return(&models.Wallet{ID: 2, Name: "Holiday"}, nil)
+
+		w := update(handler, "2", UpdatePocketRequest{TargetDate: &empty})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("nothing to update", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, update(handler, "2", map[string]string{}).Code)
+		mockService.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("not a pocket", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		name := "Rent"
+		mockService.On("Update", uint(1), services.PocketUpdate{Name: &name}).Return(nil, services.ErrNotPocket)
+
+		assert.Equal(t, http.StatusNotFound, update(handler, "1", UpdatePocketRequest{Name: &name}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPocketHandler_Move(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("fund", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		parentID := uint(1)
+		mockService.On("Fund", uint(2), int64(2500)).Return(&models.Transaction{ID: 7, SourceWalletID: &parentID, TargetWalletID: 2, Amount: 2500, Type: models.TransactionTypePocket}, nil)
+
+		w, c := pocketRequest(http.MethodPost, "/api/v1/pockets/2/fund", gin.Params{{Key: "id", Value: "2"}}, PocketMoveRequest{Amount: 2500})
+		handler.Fund(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.Transaction
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, models.TransactionTypePocket, response.Type)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("withdraw insufficient balance", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		mockService.On("Withdraw", uint(2), int64(2500)).Return(nil, services.ErrInsufficientBalance)
+
+		w, c := pocketRequest(http.MethodPost, "/api/v1/pockets/2/withdraw", gin.Params{{Key: "id", Value: "2"}}, PocketMoveRequest{Amount: 2500})
+		handler.Withdraw(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("amount must be positive", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		w, c := pocketRequest(http.MethodPost, "/api/v1/pockets/2/fund", gin.Params{{Key: "id", Value: "2"}}, map[string]int64{"amount": -5})
+		handler.Fund(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Fund")
+	})
+}
+
+func TestPocketHandler_Close(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("closed", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		mockService.On("Close", uint(2)).Return(&models.Wallet{ID: 2, Name: "Holiday"}, nil)
+
+		w, c := pocketRequest(http.MethodDelete, "/api/v1/pockets/2", gin.Params{{Key: "id", Value: "2"}}, nil)
+		handler.Close(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		mockService := new(MockPocketService)
+		handler := NewPocketHandler(mockService)
+
+		w, c := pocketRequest(http.MethodDelete, "/api/v1/pockets/x", gin.Params{{Key: "id", Value: "x"}}, nil)
+		handler.Close(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Close")
+	})
+}
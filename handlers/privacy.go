@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"wallet-api/export"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PrivacyHandler struct {
+	privacyService services.IPrivacyService
+}
+
+func NewPrivacyHandler(privacyService services.IPrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService}
+}
+
+// EraseUserRequest gives the reason for an erasure. The operator asking for
+// it is recorded from their API key.
+type EraseUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Export serves everything held about the user as a zip archive.
+func (h *PrivacyHandler) Export(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	data, err := h.privacyService.Export(c.Request.Context(), uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteUserData(&buf, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("user-%d-export.zip", userID)))
+	c.Data(http.StatusOK, export.UserDataContentType, buf.Bytes())
+}
+
+func (h *PrivacyHandler) Erase(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req EraseUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	requestedBy, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	erasure, err := h.privacyService.Erase(c.Request.Context(), uint(userID), requestedBy, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrErasureBalance), errors.Is(err, services.ErrAlreadyErased):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
+
+func (h *PrivacyHandler) GetErasure(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	erasure, err := h.privacyService.GetErasure(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "erasure not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
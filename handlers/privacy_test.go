@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock PrivacyService
+type MockPrivacyService struct {
+	mock.Mock
+}
+
+func (m *MockPrivacyService) Export(ctx context.Context, userID uint) (*models.UserExport, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserExport), args.Error(1)
+}
+
+func (m *MockPrivacyService) Erase(ctx context.Context, userID uint, requestedBy, reason string) (*models.Erasure, error) {
+	args := m.Called(userID, requestedBy, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Erasure), args.Error(1)
+}
+
+func (m *MockPrivacyService) GetErasure(ctx context.Context, userID uint) (*models.Erasure, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Erasure), args.Error(1)
+}
+
+func TestPrivacyHandler_Export(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	export := func(handler *PrivacyHandler, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/"+id+"/export", nil)
+		handler.Export(c)
+		return w
+	}
+
+	t.Run("archive", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		data := &models.UserExport{
+			ExportedAt: time.Now(),
+			User:       models.User{ID: 1, Name: "Jane Doe", Email: "jane@example.com"},
+		}
+		mockService.On("Export", uint(1)).Return(data, nil)
+
+		w := export(handler, "1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="user-1-export.zip"`, w.Header().Get("Content-Disposition"))
+		archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if assert.NoError(t, err) {
+			assert.Len(t, archive.File, 4)
+		}
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		mockService.On("Export", uint(2)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, export(handler, "2").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, export(handler, "abc").Code)
+		mockService.AssertNotCalled(t, "Export")
+	})
+}
+
+func TestPrivacyHandler_Erase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	erase := func(handler *PrivacyHandler, id string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+id+"/erasure", bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+			auth.Principal{KeyID: 2, Name: "dpo", Role: models.APIKeyRoleOperator}))
+		handler.Erase(c)
+		return w
+	}
+
+	t.Run("erased", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		erasure := &models.Erasure{ID: 1, UserID: 1, RequestedBy: "dpo", RetainUntil: time.Now().AddDate(10, 0, 0)}
+		mockService.On("Erase", uint(1), "dpo", "user request").Return(erasure, nil)
+
+		w := erase(handler, "1", map[string]string{"requested_by": "someone else", "reason": "user request"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.Erasure
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "dpo", response.RequestedBy)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("operator required", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/1/erasure", bytes.NewBufferString(`{"reason": "user request"}`))
+		c.Request.Header.Add("Content-Type", "application/json")
+		handler.Erase(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "Erase")
+	})
+
+	t.Run("balance left", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		err := fmt.Errorf("%w: wallet 4 holds 500", services.ErrErasureBalance)
+		mockService.On("Erase", uint(1), "dpo", "").Return(nil, err)
+
+		assert.Equal(t, http.StatusConflict, erase(handler, "1", EraseUserRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already erased", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		mockService.On("Erase", uint(1), "dpo", "").Return(nil, services.ErrAlreadyErased)
+
+		assert.Equal(t, http.StatusConflict, erase(handler, "1", EraseUserRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		mockService.On("Erase", uint(9), "dpo", "").Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, erase(handler, "9", EraseUserRequest{}).Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestPrivacyHandler_GetErasure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(handler *PrivacyHandler, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/"+id+"/erasure", nil)
+		handler.GetErasure(c)
+		return w
+	}
+
+	t.Run("found", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		mockService.On("GetErasure", uint(1)).Return(&models.Erasure{ID: 1, UserID: 1}, nil)
+
+		assert.Equal(t, http.StatusOK, get(handler, "1").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not erased", func(t *testing.T) {
+		mockService := new(MockPrivacyService)
+		handler := NewPrivacyHandler(mockService)
+
+		mockService.On("GetErasure", uint(2)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, get(handler, "2").Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService services.IReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService services.IReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+type ReconciliationRequest struct {
+	// Date is the UTC day to reconcile (YYYY-MM-DD); defaults to yesterday.
+	Date string `json:"date"`
+}
+
+// ResolveDiscrepancyRequest explains a discrepancy. The operator resolving
+// it is recorded from their API key.
+type ResolveDiscrepancyRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+func (h *ReconciliationHandler) Run(c *gin.Context) {
+	var req ReconciliationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	day := time.Now().UTC().AddDate(0, 0, -1)
+	if req.Date != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	report, err := h.reconciliationService.RunEndOfDay(c.Request.Context(), day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ReconciliationHandler) ListDiscrepancies(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.DiscrepancyStatusOpen && status != models.DiscrepancyStatusResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open or resolved"})
+		return
+	}
+
+	discrepancies, err := h.reconciliationService.ListDiscrepancies(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, discrepancies)
+}
+
+func (h *ReconciliationHandler) GetDiscrepancy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discrepancy ID"})
+		return
+	}
+
+	discrepancy, err := h.reconciliationService.GetDiscrepancy(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "discrepancy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, discrepancy)
+}
+
+func (h *ReconciliationHandler) ResolveDiscrepancy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid discrepancy ID"})
+		return
+	}
+
+	var req ResolveDiscrepancyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resolvedBy, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	discrepancy, err := h.reconciliationService.ResolveDiscrepancy(c.Request.Context(), uint(id), resolvedBy, req.Note)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "discrepancy not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, discrepancy)
+}
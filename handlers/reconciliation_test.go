@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock ReconciliationService
+type MockReconciliationService struct {
+	mock.Mock
+}
+
+func (m *MockReconciliationService) RunEndOfDay(ctx context.Context, day time.Time) (*models.ReconciliationReport, error) {
+	args := m.Called(day)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockReconciliationService) ListDiscrepancies(ctx context.Context, status string) ([]models.Discrepancy, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Discrepancy), args.Error(1)
+}
+
+func (m *MockReconciliationService) GetDiscrepancy(ctx context.Context, id uint) (*models.Discrepancy, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discrepancy), args.Error(1)
+}
+
+func (m *MockReconciliationService) ResolveDiscrepancy(ctx context.Context, id uint, resolvedBy, note string) (*models.Discrepancy, error) {
+	args := m.Called(id, resolvedBy, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Discrepancy), args.Error(1)
+}
+
+func TestReconciliationHandler_Run(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("reconcile given date", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		day := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+		report := &models.ReconciliationReport{Date: day, WalletsChecked: 3, Discrepancies: []models.Discrepancy{}}
+		mockService.On("RunEndOfDay", day).Return(report, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body, _ := json.Marshal(ReconciliationRequest{Date: "2025-03-31"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/reconciliations", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Run(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.ReconciliationReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 3, response.WalletsChecked)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body, _ := json.Marshal(ReconciliationRequest{Date: "31/03/2025"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/reconciliations", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Run(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "RunEndOfDay")
+	})
+}
+
+func TestReconciliationHandler_ListDiscrepancies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("filter by status", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		discrepancies := []models.Discrepancy{{ID: 1, WalletID: 17, Difference: 50, Status: models.DiscrepancyStatusOpen}}
+		mockService.On("ListDiscrepancies", "open").Return(discrepancies, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/discrepancies?status=open", nil)
+
+		handler.ListDiscrepancies(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []models.Discrepancy
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response, 1)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/discrepancies?status=ignored", nil)
+
+		handler.ListDiscrepancies(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListDiscrepancies")
+	})
+}
+
+func TestReconciliationHandler_ResolveDiscrepancy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	asOperator := func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+			auth.Principal{KeyID: 2, Name: "ops@example.com", Role: models.APIKeyRoleOperator}))
+	}
+
+	t.Run("successful resolution", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		resolved := &models.Discrepancy{ID: 1, Status: models.DiscrepancyStatusResolved, ResolutionNote: "manual correction", ResolvedBy: "ops@example.com"}
+		mockService.On("ResolveDiscrepancy", uint(1), "ops@example.com", "manual correction").Return(resolved, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		body, _ := json.Marshal(ResolveDiscrepancyRequest{Note: "manual correction"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/discrepancies/1/resolve", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+		asOperator(c)
+
+		handler.ResolveDiscrepancy(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("operator required", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		body, _ := json.Marshal(ResolveDiscrepancyRequest{Note: "manual correction"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/discrepancies/1/resolve", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.ResolveDiscrepancy(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "ResolveDiscrepancy")
+	})
+
+	t.Run("missing note", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/discrepancies/1/resolve", bytes.NewBufferString("{}"))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.ResolveDiscrepancy(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ResolveDiscrepancy")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		mockService.On("ResolveDiscrepancy", uint(9), "ops@example.com", "n/a").Return(nil, gorm.ErrRecordNotFound)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "9"}}
+		body, _ := json.Marshal(ResolveDiscrepancyRequest{Note: "n/a"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/discrepancies/9/resolve", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+		asOperator(c)
+
+		handler.ResolveDiscrepancy(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already resolved", func(t *testing.T) {
+		mockService := new(MockReconciliationService)
+		handler := NewReconciliationHandler(mockService)
+
+		mockService.On("ResolveDiscrepancy", uint(1), "ops@example.com", "again").Return(nil, errors.New("discrepancy already resolved"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		body, _ := json.Marshal(ResolveDiscrepancyRequest{Note: "again"})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/discrepancies/1/resolve", bytes.NewBuffer(body))
+		c.Request.Header.Add("Content-Type", "application/json")
+		asOperator(c)
+
+		handler.ResolveDiscrepancy(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/ratelimit"
+	"wallet-api/tenancy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers groups the HTTP handlers that make up the public API.
+type Handlers struct {
+	User           *UserHandler
+	Wallet         *WalletHandler
+	Pocket         *PocketHandler
+	Membership     *MembershipHandler
+	Approval       *ApprovalHandler
+	Transfer       *TransferHandler
+	PaymentRequest *PaymentRequestHandler
+	PaymentLink    *PaymentLinkHandler
+	Escrow         *EscrowHandler
+	Statement      *StatementHandler
+	Reconciliation *ReconciliationHandler
+	KYC            *KYCHandler
+	AML            *AMLHandler
+	Screening      *ScreeningHandler
+	Privacy        *PrivacyHandler
+	Email          *EmailVerificationHandler
+	Tenant         *TenantHandler
+
+	// Auth, when set, authenticates the API key each request presents, so
+	// that the routes below it can tell callers apart.
+	Auth *auth.Authenticator
+	// RateLimit, when set, limits each route under its class's policy, per
+	// authenticated caller or else per client IP.
+	RateLimit *ratelimit.Limiter
+	// Tenancy, when set, scopes every route but the tenant routes to the
+	// tenant of the caller's API key, or the one an admin key names in the
+	// X-Tenant-ID header.
+	Tenancy *tenancy.Resolver
+	// ExportTimeout, when set, replaces the server's write timeout on
+	// statement exports, which stream for as long as a wallet's history
+	// takes to write.
+	ExportTimeout time.Duration
+}
+
+// RegisterRoutes mounts every API route on the given group. It is shared by
+// cmd/main.go and the tests so the served router and the OpenAPI spec can be
+// checked against the same route table.
+func RegisterRoutes(rg *gin.RouterGroup, h Handlers) {
+	// Callers are authenticated before they are limited, so each key gets
+	// its own buckets.
+	rg.Use(h.authenticate())
+	reads := rg.Group("", h.limit(ratelimit.ClassRead), h.tenant())
+	writes := rg.Group("", h.limit(ratelimit.ClassWrite), h.tenant())
+	money := rg.Group("", h.limit(ratelimit.ClassMoney), h.tenant())
+	signups := rg.Group("", h.limit(ratelimit.ClassSignup), h.tenant())
+	// Reviews are made by operators, who are named by their API key.
+	operators := rg.Group("", auth.Require(models.APIKeyRoleOperator), h.limit(ratelimit.ClassWrite), h.tenant())
+
+	// Tenant routes are not scoped to a tenant: they manage all of them, so
+	// only admins may use them.
+	admins := rg.Group("", auth.Require(models.APIKeyRoleAdmin))
+	admins.POST("/tenants", h.limit(ratelimit.ClassWrite), h.Tenant.Create)
+	admins.GET("/tenants", h.limit(ratelimit.ClassRead), h.Tenant.List)
+	admins.GET("/tenants/:id", h.limit(ratelimit.ClassRead), h.Tenant.GetByID)
+	admins.PATCH("/tenants/:id", h.limit(ratelimit.ClassWrite), h.Tenant.Update)
+
+	// Payment link tokens name their own tenant, so anyone holding one can
+	// open it without naming a tenant. Paying it takes a key of that tenant.
+	rg.GET("/pay/:token", h.limit(ratelimit.ClassRead), h.PaymentLink.Resolve)
+	rg.POST("/pay/:token", h.limit(ratelimit.ClassMoney), h.PaymentLink.Pay)
+
+	// User routes
+	signups.POST("/users", h.User.Create)
+	reads.GET("/users", h.User.List)
+	reads.GET("/users/:id", h.User.GetByID)
+	writes.PATCH("/users/:id", h.User.Update)
+	operators.POST("/users/:id/deactivate", h.User.Deactivate)
+	operators.POST("/users/:id/reactivate", h.User.Reactivate)
+
+	// Email verification routes
+	writes.POST("/users/:id/email-verification", h.Email.Resend)
+	writes.POST("/email-verification", h.Email.Verify)
+
+	// Privacy routes
+	reads.GET("/users/:id/export", h.Privacy.Export)
+	operators.POST("/users/:id/erasure", h.Privacy.Erase)
+	reads.GET("/users/:id/erasure", h.Privacy.GetErasure)
+
+	// KYC routes
+	writes.POST("/users/:id/verifications", h.KYC.Submit)
+	reads.GET("/users/:id/verifications", h.KYC.ListUserCases)
+	reads.GET("/verifications", h.KYC.ListCases)
+	reads.GET("/verifications/:id", h.KYC.GetCase)
+	operators.POST("/verifications/:id/approve", h.KYC.Approve)
+	operators.POST("/verifications/:id/reject", h.KYC.Reject)
+
+	// Wallet routes
+	writes.POST("/wallets", h.Wallet.Create)
+	reads.GET("/wallets/:id", h.Wallet.GetByID)
+	reads.GET("/users/:id/wallets", h.Wallet.GetByUserID)
+
+	// Pocket routes
+	writes.POST("/wallets/:id/pockets", h.Pocket.Create)
+	reads.GET("/wallets/:id/pockets", h.Pocket.List)
+	writes.PATCH("/pockets/:id", h.Pocket.Update)
+	writes.DELETE("/pockets/:id", h.Pocket.Close)
+	money.POST("/pockets/:id/fund", h.Pocket.Fund)
+	money.POST("/pockets/:id/withdraw", h.Pocket.Withdraw)
+
+	// Joint wallet routes
+	writes.POST("/wallets/:id/members", h.Membership.AddMember)
+	reads.GET("/wallets/:id/members", h.Membership.ListMembers)
+	writes.PATCH("/wallets/:id/members/:user_id", h.Membership.UpdateMember)
+	writes.DELETE("/wallets/:id/members/:user_id", h.Membership.RemoveMember)
+	writes.PUT("/wallets/:id/approval-rule", h.Membership.SetApprovalRule)
+	writes.DELETE("/wallets/:id/approval-rule", h.Membership.ClearApprovalRule)
+	reads.GET("/wallets/:id/approvals", h.Approval.ListByWallet)
+	reads.GET("/approvals/:id", h.Approval.Get)
+	money.POST("/approvals/:id/approve", h.Approval.Approve)
+	writes.POST("/approvals/:id/reject", h.Approval.Reject)
+
+	// Transfer routes
+	money.POST("/transfers", h.Transfer.Transfer)
+	money.POST("/deposits", h.Transfer.Deposit)
+	reads.GET("/wallets/:id/transactions", h.Transfer.GetTransactions)
+
+	// Payment request routes
+	writes.POST("/payment-requests", h.PaymentRequest.Create)
+	reads.GET("/payment-requests/:id", h.PaymentRequest.Get)
+	money.POST("/payment-requests/:id/accept", h.PaymentRequest.Accept)
+	writes.POST("/payment-requests/:id/decline", h.PaymentRequest.Decline)
+	writes.POST("/payment-requests/:id/cancel", h.PaymentRequest.Cancel)
+	reads.GET("/wallets/:id/payment-requests/incoming", h.PaymentRequest.ListIncoming)
+	reads.GET("/wallets/:id/payment-requests/outgoing", h.PaymentRequest.ListOutgoing)
+
+	// Payment link routes
+	writes.POST("/payment-links", h.PaymentLink.Create)
+	reads.GET("/payment-links/:id", h.PaymentLink.Get)
+	reads.GET("/payment-links/:id/qr", h.PaymentLink.QR)
+	writes.POST("/payment-links/:id/revoke", h.PaymentLink.Revoke)
+	reads.GET("/wallets/:id/payment-links", h.PaymentLink.ListByWallet)
+
+	// Escrow routes
+	money.POST("/escrows", h.Escrow.Create)
+	reads.GET("/escrows/:id", h.Escrow.Get)
+	money.POST("/escrows/:id/confirm", h.Escrow.Confirm)
+	writes.POST("/escrows/:id/dispute", h.Escrow.Dispute)
+	operators.POST("/escrows/:id/resolve", h.Escrow.Resolve)
+	reads.GET("/wallets/:id/escrows", h.Escrow.ListByWallet)
+
+	// Statement routes
+	reads.GET("/wallets/:id/balance", h.Statement.GetBalance)
+	reads.GET("/wallets/:id/statement", h.Statement.GetStatement)
+	reads.GET("/wallets/:id/statement/export", h.exportDeadline(), h.Statement.ExportStatement)
+
+	// Reconciliation routes
+	operators.POST("/reconciliations", h.Reconciliation.Run)
+	reads.GET("/discrepancies", h.Reconciliation.ListDiscrepancies)
+	reads.GET("/discrepancies/:id", h.Reconciliation.GetDiscrepancy)
+	operators.POST("/discrepancies/:id/resolve", h.Reconciliation.ResolveDiscrepancy)
+
+	// AML routes
+	reads.GET("/aml/cases", h.AML.ListCases)
+	reads.GET("/aml/cases/:id", h.AML.GetCase)
+	operators.POST("/aml/cases/:id/clear", h.AML.Clear)
+	operators.POST("/aml/cases/:id/confirm", h.AML.Confirm)
+
+	// Sanctions screening routes
+	reads.GET("/screening/hits", h.Screening.ListHits)
+	reads.GET("/screening/hits/:id", h.Screening.GetHit)
+	operators.POST("/screening/hits/:id/clear", h.Screening.Clear)
+	operators.POST("/screening/hits/:id/confirm", h.Screening.Confirm)
+}
+
+func (h Handlers) authenticate() gin.HandlerFunc {
+	if h.Auth == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return h.Auth.Middleware()
+}
+
+func (h Handlers) limit(class ratelimit.Class) gin.HandlerFunc {
+	if h.RateLimit == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return h.RateLimit.Middleware(class)
+}
+
+func (h Handlers) tenant() gin.HandlerFunc {
+	if h.Tenancy == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return h.Tenancy.Middleware()
+}
+
+func (h Handlers) exportDeadline() gin.HandlerFunc {
+	if h.ExportTimeout == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		// Test recorders cannot set deadlines; real connections can.
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(h.ExportTimeout))
+		c.Next()
+	}
+}
+
+// operatorName returns the name of the API key an operator route was called
+// with, answering 401 when there is none.
+func operatorName(c *gin.Context) (string, bool) {
+	p, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "an API key is required"})
+		return "", false
+	}
+	return p.Name, true
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRoutes mounts every route like cmd/main.go does, with wallet as the
+// only working service.
+func setupRoutes(wallet *MockWalletService, keys auth.StaticKeys, limiter *ratelimit.Limiter) *gin.Engine {
+	return setupRoutesWith(Handlers{Wallet: NewWalletHandler(wallet), RateLimit: limiter}, keys)
+}
+
+// setupRoutesWith mounts every route with the handlers set in h and
+// handlers without a service for the others.
+func setupRoutesWith(h Handlers, keys auth.StaticKeys) *gin.Engine {
+	defaults := Handlers{
+		User:           NewUserHandler(nil),
+		Wallet:         NewWalletHandler(nil),
+		Pocket:         NewPocketHandler(nil),
+		Membership:     NewMembershipHandler(nil),
+		Approval:       NewApprovalHandler(nil),
+		Transfer:       NewTransferHandler(nil),
+		PaymentRequest: NewPaymentRequestHandler(nil),
+		PaymentLink:    NewPaymentLinkHandler(nil),
+		Escrow:         NewEscrowHandler(nil),
+		Statement:      NewStatementHandler(nil),
+		Reconciliation: NewReconciliationHandler(nil),
+		KYC:            NewKYCHandler(nil),
+		AML:            NewAMLHandler(nil),
+		Screening:      NewScreeningHandler(nil),
+		Privacy:        NewPrivacyHandler(nil),
+		Email:          NewEmailVerificationHandler(nil),
+		Tenant:         NewTenantHandler(nil),
+	}
+	if h.Wallet != nil {
+		defaults.Wallet = h.Wallet
+	}
+	if h.KYC != nil {
+		defaults.KYC = h.KYC
+	}
+	if h.AML != nil {
+		defaults.AML = h.AML
+	}
+	if h.Screening != nil {
+		defaults.Screening = h.Screening
+	}
+	if h.Tenant != nil {
+		defaults.Tenant = h.Tenant
+	}
+	defaults.Auth = auth.NewAuthenticator(keys)
+	defaults.RateLimit = h.RateLimit
+	defaults.Tenancy = h.Tenancy
+
+	router := gin.New()
+	RegisterRoutes(router.Group("/api/v1"), defaults)
+	return router
+}
+
+// testKeys are the API keys the route tests authenticate with.
+var testKeys = auth.StaticKeys{
+	"wk_alice": {ID: 1, TenantID: models.DefaultTenantID, Name: "alice", Role: models.APIKeyRoleUser, UserID: &testUserID},
+	"wk_bob":   {ID: 2, TenantID: models.DefaultTenantID, Name: "bob", Role: models.APIKeyRoleOperator},
+	"wk_root":  {ID: 3, TenantID: models.DefaultTenantID, Name: "root", Role: models.APIKeyRoleAdmin},
+}
+
+var testUserID = uint(7)
+
+// asUser authenticates the request of c with a user API key of userID.
+func asUser(c *gin.Context, userID uint) {
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+		auth.Principal{KeyID: 9, TenantID: models.DefaultTenantID, Name: "user", Role: models.APIKeyRoleUser, UserID: &userID}))
+}
+
+// keyUser returns the user of the API key ctx was authenticated with, or 0
+// without one, so that mocks can be set up for the acting user.
+func keyUser(ctx context.Context) uint {
+	if p, ok := auth.FromContext(ctx); ok && p.UserID != nil {
+		return *p.UserID
+	}
+	return 0
+}
+
+func TestRegisterRoutes_RateLimitByPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func() *gin.Engine {
+		wallet := new(MockWalletService)
+		wallet.On("GetByID", uint(1)).Return(&models.Wallet{ID: 1, UserID: testUserID}, nil)
+		limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[ratelimit.Class]ratelimit.Policy{
+			ratelimit.ClassRead: {Limit: 1, Period: time.Minute},
+		})
+		return setupRoutes(wallet, testKeys, limiter)
+	}
+	get := func(router *gin.Engine, key, addr string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/wallets/1", nil)
+		req.RemoteAddr = addr
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("each key has its own bucket", func(t *testing.T) {
+		router := newRouter()
+		assert.Equal(t, http.StatusOK, get(router, "wk_alice", "203.0.113.7:1234"))
+		assert.Equal(t, http.StatusOK, get(router, "wk_bob", "203.0.113.7:1234"), "a second key from the same address")
+		assert.Equal(t, http.StatusOK, get(router, "", "203.0.113.7:1234"), "anonymous requests are limited by address")
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "wk_alice", "198.51.100.1:1234"), "the bucket follows the key across addresses")
+	})
+
+	t.Run("anonymous requests fall back to the client IP", func(t *testing.T) {
+		router := newRouter()
+		assert.Equal(t, http.StatusOK, get(router, "", "203.0.113.7:1234"))
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "", "203.0.113.7:5678"))
+		assert.Equal(t, http.StatusOK, get(router, "", "198.51.100.1:1234"))
+	})
+
+	t.Run("invalid keys are refused before they are limited", func(t *testing.T) {
+		router := newRouter()
+		assert.Equal(t, http.StatusUnauthorized, get(router, "wk_unknown", "203.0.113.7:1234"))
+		assert.Equal(t, http.StatusOK, get(router, "", "203.0.113.7:1234"))
+	})
+}
+
+func TestRegisterRoutes_OperatorRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	kyc := new(MockKYCService)
+	kyc.On("Approve", uint(1), "bob", "").Return(&models.VerificationCase{ID: 1}, nil)
+	kyc.On("Approve", uint(1), "root", "").Return(&models.VerificationCase{ID: 1}, nil)
+	amlService := new(MockAMLService)
+	amlService.On("Clear", uint(1), "bob", "").Return(&models.AMLCase{ID: 1}, nil)
+	screening := new(MockScreeningService)
+	screening.On("Clear", uint(1), "bob", "").Return(&models.ScreeningHit{ID: 1}, nil)
+	router := setupRoutesWith(Handlers{
+		KYC:       NewKYCHandler(kyc),
+		AML:       NewAMLHandler(amlService),
+		Screening: NewScreeningHandler(screening),
+	}, testKeys)
+
+	post := func(path, key string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, path := range []string{
+		"/api/v1/verifications/1/approve",
+		"/api/v1/verifications/1/reject",
+		"/api/v1/aml/cases/1/clear",
+		"/api/v1/aml/cases/1/confirm",
+		"/api/v1/screening/hits/1/clear",
+		"/api/v1/screening/hits/1/confirm",
+		"/api/v1/escrows/1/resolve",
+		"/api/v1/users/1/deactivate",
+		"/api/v1/users/1/reactivate",
+		"/api/v1/users/1/erasure",
+		"/api/v1/reconciliations",
+		"/api/v1/discrepancies/1/resolve",
+	} {
+		assert.Equal(t, http.StatusUnauthorized, post(path, ""), path)
+		assert.Equal(t, http.StatusForbidden, post(path, "wk_alice"), path)
+	}
+
+	assert.Equal(t, http.StatusOK, post("/api/v1/verifications/1/approve", "wk_bob"))
+	assert.Equal(t, http.StatusOK, post("/api/v1/verifications/1/approve", "wk_root"))
+	assert.Equal(t, http.StatusOK, post("/api/v1/aml/cases/1/clear", "wk_bob"))
+	assert.Equal(t, http.StatusOK, post("/api/v1/screening/hits/1/clear", "wk_bob"))
+	kyc.AssertExpectations(t)
+	amlService.AssertExpectations(t)
+	screening.AssertExpectations(t)
+}
+
+func TestRegisterRoutes_TenantRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tenantService := new(MockTenantService)
+	tenantService.On("List").Return([]models.Tenant{{ID: 1, Name: "default"}}, nil)
+	router := setupRoutesWith(Handlers{Tenant: NewTenantHandler(tenantService)}, testKeys)
+
+	get := func(path, key string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, path := range []string{"/api/v1/tenants", "/api/v1/tenants/1"} {
+		assert.Equal(t, http.StatusUnauthorized, get(path, ""), path)
+		assert.Equal(t, http.StatusForbidden, get(path, "wk_alice"), path)
+		assert.Equal(t, http.StatusForbidden, get(path, "wk_bob"), path)
+	}
+
+	assert.Equal(t, http.StatusOK, get("/api/v1/tenants", "wk_root"))
+	tenantService.AssertExpectations(t)
+}
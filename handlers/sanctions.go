@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ScreeningHandler struct {
+	screeningService services.IScreeningService
+}
+
+func NewScreeningHandler(screeningService services.IScreeningService) *ScreeningHandler {
+	return &ScreeningHandler{screeningService: screeningService}
+}
+
+// ReviewScreeningHitRequest is the body of a review of a hit. The reviewer
+// is the operator whose API key made the request.
+type ReviewScreeningHitRequest struct {
+	Note string `json:"note"`
+}
+
+func (h *ScreeningHandler) ListHits(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && status != models.ScreeningHitStatusPending &&
+		status != models.ScreeningHitStatusCleared && status != models.ScreeningHitStatusConfirmed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, cleared or confirmed"})
+		return
+	}
+
+	hits, err := h.screeningService.ListHits(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, hits)
+}
+
+func (h *ScreeningHandler) GetHit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid screening hit ID"})
+		return
+	}
+
+	hit, err := h.screeningService.GetHit(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "screening hit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hit)
+}
+
+func (h *ScreeningHandler) Clear(c *gin.Context) {
+	h.review(c, h.screeningService.Clear)
+}
+
+func (h *ScreeningHandler) Confirm(c *gin.Context) {
+	h.review(c, h.screeningService.Confirm)
+}
+
+func (h *ScreeningHandler) review(c *gin.Context, review func(context.Context, uint, string, string) (*models.ScreeningHit, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid screening hit ID"})
+		return
+	}
+
+	var req ReviewScreeningHitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviewer, ok := operatorName(c)
+	if !ok {
+		return
+	}
+
+	hit, err := review(c.Request.Context(), uint(id), reviewer, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "screening hit not found"})
+		case errors.Is(err, services.ErrScreeningHitReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, hit)
+}
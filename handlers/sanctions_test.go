@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock ScreeningService
+type MockScreeningService struct {
+	mock.Mock
+}
+
+func (m *MockScreeningService) ListHits(ctx context.Context, status string) ([]models.ScreeningHit, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningService) GetHit(ctx context.Context, id uint) (*models.ScreeningHit, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningService) Clear(ctx context.Context, id uint, reviewer, note string) (*models.ScreeningHit, error) {
+	args := m.Called(id, reviewer, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningHit), args.Error(1)
+}
+
+func (m *MockScreeningService) Confirm(ctx context.Context, id uint, reviewer, note string) (*models.ScreeningHit, error) {
+	args := m.Called(id, reviewer, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScreeningHit), args.Error(1)
+}
+
+func TestScreeningHandler_ListHits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list := func(handler *ScreeningHandler, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/screening/hits"+query, nil)
+		handler.ListHits(c)
+		return w
+	}
+
+	t.Run("pending hits", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		hits := []models.ScreeningHit{{
+			ID: 1, Subject: models.ScreeningSubjectUser, UserID: 3, ScreenedName: "Ivan Petrof",
+			List: "sdn", EntryID: "SDN-1001", EntryName: "Ivan Petroff", Score: 0.97,
+			Status: models.ScreeningHitStatusPending,
+		}}
+		mockService.On("ListHits", "pending").Return(hits, nil)
+
+		w := list(handler, "?status=pending")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response []models.ScreeningHit
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response, 1) {
+			assert.Equal(t, "SDN-1001", response[0].EntryID)
+		}
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		w := list(handler, "?status=open")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "ListHits")
+	})
+}
+
+func TestScreeningHandler_GetHit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	get := func(handler *ScreeningHandler, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/screening/hits/"+id, nil)
+		handler.GetHit(c)
+		return w
+	}
+
+	t.Run("found", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		mockService.On("GetHit", uint(1)).Return(&models.ScreeningHit{ID: 1}, nil)
+
+		assert.Equal(t, http.StatusOK, get(handler, "1").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		mockService.On("GetHit", uint(2)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, get(handler, "2").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		assert.Equal(t, http.StatusBadRequest, get(handler, "abc").Code)
+		mockService.AssertNotCalled(t, "GetHit")
+	})
+}
+
+func TestScreeningHandler_Review(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// review calls action as the named reviewer, or anonymously when
+	// reviewer is empty.
+	review := func(action func(*gin.Context), id, reviewer string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/screening/hits/"+id, bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		if reviewer != "" {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(),
+				auth.Principal{KeyID: 1, Name: reviewer, Role: models.APIKeyRoleOperator}))
+		}
+		action(c)
+		return w
+	}
+
+	t.Run("clear", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		cleared := &models.ScreeningHit{ID: 1, Status: models.ScreeningHitStatusCleared, Reviewer: "grace"}
+		mockService.On("Clear", uint(1), "grace", "different date of birth").Return(cleared, nil)
+
+		w := review(handler.Clear, "1", "grace", ReviewScreeningHitRequest{Note: "different date of birth"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reviewer required", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		w := review(handler.Confirm, "1", "", ReviewScreeningHitRequest{Note: "passport matches"})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "Confirm")
+	})
+
+	t.Run("confirm without note", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		mockService.On("Confirm", uint(1), "grace", "").Return(nil, errors.New("a note is required to confirm a hit"))
+
+		w := review(handler.Confirm, "1", "grace", ReviewScreeningHitRequest{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("already reviewed", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		mockService.On("Clear", uint(1), "heidi", "").Return(nil, services.ErrScreeningHitReviewed)
+
+		w := review(handler.Clear, "1", "heidi", ReviewScreeningHitRequest{})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("hit not found", func(t *testing.T) {
+		mockService := new(MockScreeningService)
+		handler := NewScreeningHandler(mockService)
+
+		mockService.On("Confirm", uint(42), "grace", "match").Return(nil, gorm.ErrRecordNotFound)
+
+		w := review(handler.Confirm, "42", "grace", ReviewScreeningHitRequest{Note: "match"})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
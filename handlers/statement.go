@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/export"
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StatementHandler struct {
+	statementService services.IStatementService
+}
+
+func NewStatementHandler(statementService services.IStatementService) *StatementHandler {
+	return &StatementHandler{statementService: statementService}
+}
+
+// parseTime accepts RFC 3339 timestamps ("2025-03-31T23:59:00Z") or plain
+// dates ("2025-03-31", meaning midnight UTC).
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseEnd is parseTime for the inclusive end of a range, where a plain date
+// covers that whole day: it ends just before the next midnight, at the last
+// microsecond Postgres can store.
+func parseEnd(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond), nil
+}
+
+func (h *StatementHandler) GetBalance(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	asOf := time.Now().UTC()
+	if value := c.Query("as_of"); value != "" {
+		asOf, err = parseTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid as_of, expected RFC 3339 timestamp or YYYY-MM-DD"})
+			return
+		}
+	}
+
+	balance, err := h.statementService.BalanceAsOf(c.Request.Context(), uint(walletID), asOf)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BalanceResponse{
+		WalletID: uint(walletID),
+		Balance:  balance,
+		AsOf:     asOf,
+	})
+}
+
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	statement, err := h.statementService.Statement(c.Request.Context(), uint(walletID), from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// ExportStatement streams the statement for a date range as CSV, OFX or
+// camt.053, selected with ?format=. Rows are rendered as they are read from
+// the database.
+func (h *StatementHandler) ExportStatement(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet ID"})
+		return
+	}
+
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	out := &attachmentWriter{
+		c:           c,
+		contentType: export.ContentType(format),
+		filename: fmt.Sprintf("statement-%d-%s-%s.%s",
+			walletID, from.UTC().Format("20060102"), to.UTC().Format("20060102"), export.Extension(format)),
+	}
+	writer, err := export.NewWriter(format, out, export.Options{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.statementService.StreamStatement(c.Request.Context(), uint(walletID), from, to, writer)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// The status line is gone; record the error for the logger and cut
+		// the response short so the client sees a truncated body.
+		c.Error(err)
+		c.Abort()
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// attachmentWriter sets the download headers on the first write, so a
+// request that fails before any output can still answer with a JSON error.
+type attachmentWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+// parseRange reads the required from/to query parameters, writing a 400
+// response and returning false when they are missing or malformed.
+func parseRange(c *gin.Context) (time.Time, time.Time, bool) {
+	if c.Query("from") == "" || c.Query("to") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, expected RFC 3339 timestamp or YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+	to, err := parseEnd(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, expected RFC 3339 timestamp or YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock StatementService
+type MockStatementService struct {
+	mock.Mock
+}
+
+func (m *MockStatementService) BalanceAsOf(ctx context.Context, walletID uint, asOf time.Time) (int64, error) {
+	args := m.Called(walletID, asOf)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStatementService) Statement(ctx context.Context, walletID uint, from, to time.Time) (*models.Statement, error) {
+	args := m.Called(walletID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Statement), args.Error(1)
+}
+
+func (m *MockStatementService) StreamStatement(ctx context.Context, walletID uint, from, to time.Time, w services.StatementWriter) error {
+	args := m.Called(walletID, from, to, w)
+	return args.Error(0)
+}
+
+func TestStatementHandler_GetBalance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	asOf := time.Date(2025, 3, 31, 23, 59, 0, 0, time.UTC)
+
+	t.Run("balance as of timestamp", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		mockService.On("BalanceAsOf", uint(17), asOf).Return(int64(1500), nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "17"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/17/balance?as_of=2025-03-31T23:59:00Z", nil)
+
+		handler.GetBalance(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.BalanceResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(17), response.WalletID)
+		assert.Equal(t, int64(1500), response.Balance)
+		assert.True(t, asOf.Equal(response.AsOf))
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid as_of", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "17"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/17/balance?as_of=yesterday", nil)
+
+		handler.GetBalance(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "BalanceAsOf")
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		mockService.On("BalanceAsOf", uint(17), mock.AnythingOfType("time.Time")).Return(int64(0), gorm.ErrRecordNotFound)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "17"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/17/balance", nil)
+
+		handler.GetBalance(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestStatementHandler_GetStatement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	t.Run("successful statement", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		statement := &models.Statement{
+			WalletID:       1,
+			From:           from,
+			To:             to,
+			OpeningBalance: 100,
+			Lines: []models.StatementLine{
+				{TransactionID: 1, Amount: 50, RunningBalance: 150},
+				{TransactionID: 2, Amount: -30, RunningBalance: 120},
+			},
+			ClosingBalance: 120,
+		}
+		mockService.On("Statement", uint(1), from, to).Return(statement, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-03-01&to=2025-03-31T23:59:59Z", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.Statement
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(100), response.OpeningBalance)
+		assert.Equal(t, 2, len(response.Lines))
+		assert.Equal(t, int64(120), response.ClosingBalance)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("a plain to date covers the whole day", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		endOfDay := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+		mockService.On("Statement", uint(1), from, endOfDay).Return(&models.Statement{WalletID: 1}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-03-01&to=2025-03-31", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("a single day", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		day := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+		mockService.On("Statement", uint(1), day, day.AddDate(0, 0, 1).Add(-time.Microsecond)).Return(&models.Statement{WalletID: 1}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-03-31&to=2025-03-31", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing range", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-03-01", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Statement")
+	})
+
+	t.Run("inverted range", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-04-01&to=2025-03-01", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Statement")
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		mockService.On("Statement", uint(1), from, to).Return(nil, errors.New("boom"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement?from=2025-03-01&to=2025-03-31T23:59:59Z", nil)
+
+		handler.GetStatement(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestStatementHandler_ExportStatement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)
+
+	t.Run("streams csv", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		mockService.On("StreamStatement", uint(1), from, to, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			w := args.Get(3).(services.StatementWriter)
+			w.Begin(models.StatementHeader{WalletID: 1, From: from, To: to, ClosingBalance: 250})
+			w.Line(models.StatementLine{TransactionID: 7, Type: models.TransactionTypeDeposit, Amount: 250, RunningBalance: 250, CreatedAt: from})
+			w.End()
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement/export?from=2025-03-01&to=2025-03-31", nil)
+
+		handler.ExportStatement(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "statement-1-20250301-20250331.csv")
+		assert.Contains(t, w.Body.String(), "2025-03-01T00:00:00Z,7,,deposit,,2.50,2.50,USD")
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement/export?from=2025-03-01&to=2025-03-31&format=qif", nil)
+
+		handler.ExportStatement(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "StreamStatement")
+	})
+
+	t.Run("wallet not found", func(t *testing.T) {
+		mockService := new(MockStatementService)
+		handler := NewStatementHandler(mockService)
+
+		mockService.On("StreamStatement", uint(1), from, to, mock.Anything).Return(gorm.ErrRecordNotFound)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/statement/export?from=2025-03-01&to=2025-03-31&format=ofx", nil)
+
+		handler.ExportStatement(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		mockService.AssertExpectations(t)
+	})
+}
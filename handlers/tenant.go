@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TenantHandler struct {
+	tenantService services.ITenantService
+}
+
+func NewTenantHandler(tenantService services.ITenantService) *TenantHandler {
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// CreateTenantRequest creates a tenant. Without currencies it offers only
+// USD. Limits are keyed by KYC level.
+type CreateTenantRequest struct {
+	Name       string                               `json:"name" binding:"required"`
+	Slug       string                               `json:"slug" binding:"required"`
+	Currencies []string                             `json:"currencies"`
+	Limits     map[models.KYCLevel]models.TierLimit `json:"limits"`
+}
+
+// UpdateTenantRequest changes a tenant. Omitted fields are left alone; an
+// empty fees, limits or escrow_wallets object removes them all.
+type UpdateTenantRequest struct {
+	Name          *string                              `json:"name"`
+	Currencies    []string                             `json:"currencies"`
+	Fees          map[string]models.TransferFee        `json:"fees"`
+	Limits        map[models.KYCLevel]models.TierLimit `json:"limits"`
+	EscrowWallets map[string]uint                      `json:"escrow_wallets"`
+}
+
+func (h *TenantHandler) Create(c *gin.Context) {
+	var req CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenant, err := h.tenantService.Create(c.Request.Context(), services.TenantDetails{
+		Name:       req.Name,
+		Slug:       req.Slug,
+		Currencies: req.Currencies,
+		Limits:     req.Limits,
+	})
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenant)
+}
+
+func (h *TenantHandler) List(c *gin.Context) {
+	tenants, err := h.tenantService.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tenants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenants)
+}
+
+func (h *TenantHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant ID"})
+		return
+	}
+
+	tenant, err := h.tenantService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+func (h *TenantHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant ID"})
+		return
+	}
+
+	var req UpdateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil && req.Currencies == nil && req.Fees == nil && req.Limits == nil && req.EscrowWallets == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	tenant, err := h.tenantService.Update(c.Request.Context(), uint(id), services.TenantUpdate{
+		Name:          req.Name,
+		Currencies:    req.Currencies,
+		Fees:          req.Fees,
+		Limits:        req.Limits,
+		EscrowWallets: req.EscrowWallets,
+	})
+	if err != nil {
+		respondTenantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+func respondTenantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+	case errors.Is(err, services.ErrTenantSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/models"
+	"wallet-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// Mock TenantService
+type MockTenantService struct {
+	mock.Mock
+}
+
+func (m *MockTenantService) Create(ctx context.Context, details services.TenantDetails) (*models.Tenant, error) {
+	args := m.Called(details)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tenant), args.Error(1)
+}
+
+func (m *MockTenantService) Get(ctx context.Context, id uint) (*models.Tenant, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tenant), args.Error(1)
+}
+
+func (m *MockTenantService) List(ctx context.Context) ([]models.Tenant, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Tenant), args.Error(1)
+}
+
+func (m *MockTenantService) Update(ctx context.Context, id uint, update services.TenantUpdate) (*models.Tenant, error) {
+	args := m.Called(id, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tenant), args.Error(1)
+}
+
+func tenantRequest(method, path string, body interface{}, params ...gin.Param) (*httptest.ResponseRecorder, *gin.Context) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = params
+	jsonData, _ := json.Marshal(body)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+	c.Request.Header.Add("Content-Type", "application/json")
+	return w, c
+}
+
+func TestTenantHandler_Create(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("successful creation", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		details := services.TenantDetails{Name: "Acme", Slug: "acme", Currencies: []string{"EUR"}}
+		mockService.On("Create", details).
+			Return(&models.Tenant{ID: 2, Name: "Acme", Slug: "acme", Currencies: []string{"EUR"}}, nil)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/tenants",
+			CreateTenantRequest{Name: "Acme", Slug: "acme", Currencies: []string{"EUR"}})
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response models.Tenant
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, uint(2), response.ID)
+		assert.Equal(t, []string{"EUR"}, response.Currencies)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("slug required", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/tenants", map[string]string{"name": "Acme"})
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("slug taken", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		mockService.On("Create", mock.Anything).Return(nil, services.ErrTenantSlugTaken)
+
+		w, c := tenantRequest(http.MethodPost, "/api/v1/tenants", CreateTenantRequest{Name: "Acme", Slug: "acme"})
+		handler.Create(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestTenantHandler_GetByID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		mockService.On("Get", uint(9)).Return(nil, gorm.ErrRecordNotFound)
+
+		w, c := tenantRequest(http.MethodGet, "/api/v1/tenants/9", nil, gin.Param{Key: "id", Value: "9"})
+		handler.GetByID(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestTenantHandler_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("set fees", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		fees := map[string]models.TransferFee{"EUR": {Fixed: 25, BasisPoints: 100, WalletID: 4}}
+		mockService.On("Update", uint(2), services.TenantUpdate{Fees: fees}).
+			Return(&models.Tenant{ID: 2, Fees: fees}, nil)
+
+		w, c := tenantRequest(http.MethodPatch, "/api/v1/tenants/2", UpdateTenantRequest{Fees: fees},
+			gin.Param{Key: "id", Value: "2"})
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.Tenant
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, fees, response.Fees)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("nothing to update", func(t *testing.T) {
+		mockService := new(MockTenantService)
+		handler := NewTenantHandler(mockService)
+
+		w, c := tenantRequest(http.MethodPatch, "/api/v1/tenants/2", map[string]string{},
+			gin.Param{Key: "id", Value: "2"})
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Update")
+	})
+}
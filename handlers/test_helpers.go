@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 	"wallet-api/models"
+	"wallet-api/repositories"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
@@ -45,12 +46,38 @@ func setupTestDB(t *testing.T) *gorm.DB {
 
 	// Run migrations for User, Wallet, and Transaction models
 	err = gormDB.AutoMigrate(
+		&models.Tenant{},
 		&models.User{},
 		&models.Wallet{},
 		&models.Transaction{},
+		&models.BalanceSnapshot{},
+		&models.Discrepancy{},
+		&models.RateLimitBucket{},
+		&models.VerificationCase{},
+		&models.VerificationDocument{},
+		&models.VerificationEvent{},
+		&models.AMLCase{},
+		&models.AMLAlert{},
+		&models.ScreeningHit{},
+		&models.Erasure{},
+		&models.EmailVerification{},
+		&models.WalletMember{},
+		&models.TransferApproval{},
+		&models.ApprovalVote{},
+		&models.PaymentRequest{},
+		&models.PaymentLink{},
+		&models.PaymentLinkUse{},
+		&models.EscrowAgreement{},
+		&models.APIKey{},
 	)
 	assert.NoError(t, err)
 
+	// Emails are unique per tenant, no longer across the database
+	if gormDB.Migrator().HasIndex(&models.User{}, "idx_users_email") {
+		assert.NoError(t, gormDB.Migrator().DropIndex(&models.User{}, "idx_users_email"))
+	}
+	assert.NoError(t, repositories.NewTenantRepository(gormDB).EnsureDefault())
+
 	// Run migrations
 	// TODO: Add your migrations here
 
@@ -64,9 +91,12 @@ func teardownTestDB(t *testing.T, db *gorm.DB) {
 }
 
 func truncateTables(t *testing.T, db *gorm.DB) {
-	tables := []string{"transactions", "wallets", "users"}
+	tables := []string{"api_keys", "escrow_agreements", "payment_link_uses", "payment_links", "payment_requests", "approval_votes", "transfer_approvals", "wallet_members", "email_verifications", "erasures", "screening_hits", "aml_alerts", "aml_cases", "verification_events", "verification_documents", "verification_cases", "rate_limit_buckets", "discrepancies", "balance_snapshots", "transactions", "wallets", "users"}
 	for _, table := range tables {
 		err := db.Exec(fmt.Sprintf("TRUNCATE TABLE %s CASCADE", table)).Error
 		assert.NoError(t, err)
 	}
+	// The default tenant is kept: every tenant_id column defaults to it
+	err := db.Exec("DELETE FROM tenants WHERE id <> ?", models.DefaultTenantID).Error
+	assert.NoError(t, err)
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -33,7 +34,27 @@ func (h *TransferHandler) Transfer(c *gin.Context) {
 		return
 	}
 
-	err := h.transferService.Transfer(req.SourceWalletID, req.TargetWalletID, req.Amount)
+	// The transfer is sent by the user of the API key: the wallet's owner or
+	// one of its members.
+	err := h.transferService.Transfer(c.Request.Context(), req.SourceWalletID, req.TargetWalletID, req.Amount)
+	var held *services.HeldError
+	if errors.As(err, &held) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "transfer held for review", "transaction_id": held.TransactionID})
+		return
+	}
+	var pending *services.ApprovalRequiredError
+	if errors.As(err, &pending) {
+		c.JSON(http.StatusAccepted, gin.H{"message": "transfer awaiting approval", "approval_id": pending.ApprovalID})
+		return
+	}
+	if errors.Is(err, services.ErrInitiatorRequired) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrNotWalletMember) || errors.Is(err, services.ErrWalletRoleForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -54,7 +75,7 @@ func (h *TransferHandler) Deposit(c *gin.Context) {
 		return
 	}
 
-	err := h.transferService.Deposit(req.WalletID, req.Amount)
+	err := h.transferService.Deposit(c.Request.Context(), req.WalletID, req.Amount)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -70,7 +91,7 @@ func (h *TransferHandler) GetTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions, err := h.transferService.GetTransactionsByWalletID(uint(walletID))
+	transactions, err := h.transferService.GetTransactionsByWalletID(c.Request.Context(), uint(walletID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "transactions not found"})
 		return
@@ -83,9 +104,11 @@ func (h *TransferHandler) GetTransactions(c *gin.Context) {
 			SourceWalletID:  t.SourceWalletID,
 			TargetWalletID:  t.TargetWalletID,
 			Amount:          t.Amount,
+			Fee:             t.Fee,
 			Type:            t.Type,
 			ReferenceNumber: t.ReferenceNumber,
 			Status:          t.Status,
+			Description:     t.Description,
 			CreatedAt:       t.CreatedAt,
 			UpdatedAt:       t.UpdatedAt,
 		})
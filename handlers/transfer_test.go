@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"wallet-api/models"
+	"wallet-api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,17 +22,17 @@ type MockTransferService struct {
 	mock.Mock
 }
 
-func (m *MockTransferService) Transfer(sourceWalletID, targetWalletID uint, amount int64) error {
+func (m *MockTransferService) Transfer(ctx context.Context, sourceWalletID, targetWalletID uint, amount int64) error {
 	args := m.Called(sourceWalletID, targetWalletID, amount)
 	return args.Error(0)
 }
 
-func (m *MockTransferService) Deposit(walletID uint, amount int64) error {
+func (m *MockTransferService) Deposit(ctx context.Context, walletID uint, amount int64) error {
 	args := m.Called(walletID, amount)
 	return args.Error(0)
 }
 
-func (m *MockTransferService) GetTransactionsByWalletID(walletID uint) ([]models.Transaction, error) {
+func (m *MockTransferService) GetTransactionsByWalletID(ctx context.Context, walletID uint) ([]models.Transaction, error) {
 	args := m.Called(walletID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -72,6 +74,90 @@ func TestTransferHandler_Transfer(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("held for review", func(t *testing.T) {
+		mockService := new(MockTransferService)
+		handler := NewTransferHandler(mockService)
+
+		mockService.On("Transfer", uint(1), uint(2), int64(100)).Return(&services.HeldError{TransactionID: 42})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		jsonReq, _ := json.Marshal(TransferRequest{SourceWalletID: 1, TargetWalletID: 2, Amount: 100})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonReq))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Transfer(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "transfer held for review", response["message"])
+		assert.Equal(t, float64(42), response["transaction_id"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("awaiting approval", func(t *testing.T) {
+		mockService := new(MockTransferService)
+		handler := NewTransferHandler(mockService)
+
+		mockService.On("Transfer", uint(1), uint(2), int64(100)).Return(&services.ApprovalRequiredError{ApprovalID: 7})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		jsonReq, _ := json.Marshal(TransferRequest{SourceWalletID: 1, TargetWalletID: 2, Amount: 100})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonReq))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Transfer(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "transfer awaiting approval", response["message"])
+		assert.Equal(t, float64(7), response["approval_id"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		mockService := new(MockTransferService)
+		handler := NewTransferHandler(mockService)
+
+		mockService.On("Transfer", uint(1), uint(2), int64(100)).Return(services.ErrNotWalletMember)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		jsonReq, _ := json.Marshal(TransferRequest{SourceWalletID: 1, TargetWalletID: 2, Amount: 100})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonReq))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Transfer(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("without a user key", func(t *testing.T) {
+		mockService := new(MockTransferService)
+		handler := NewTransferHandler(mockService)
+
+		mockService.On("Transfer", uint(1), uint(2), int64(100)).Return(services.ErrInitiatorRequired)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		jsonReq, _ := json.Marshal(TransferRequest{SourceWalletID: 1, TargetWalletID: 2, Amount: 100})
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewBuffer(jsonReq))
+		c.Request.Header.Add("Content-Type", "application/json")
+
+		handler.Transfer(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
 	t.Run("invalid request body", func(t *testing.T) {
 		mockService := new(MockTransferService)
 		handler := NewTransferHandler(mockService)
@@ -208,6 +294,7 @@ func TestTransferHandler_GetTransactions(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/transactions", nil)
 
 		handler.GetTransactions(c)
 
@@ -230,6 +317,7 @@ func TestTransferHandler_GetTransactions(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "invalid"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/invalid/transactions", nil)
 
 		handler.GetTransactions(c)
 
@@ -246,6 +334,7 @@ func TestTransferHandler_GetTransactions(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1/transactions", nil)
 
 		handler.GetTransactions(c)
 
@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"wallet-api/models"
+	"wallet-api/repositories"
 	"wallet-api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Basic email format validation
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
 type UserHandler struct {
 	userService services.UserServiceInterface
 }
@@ -36,14 +43,12 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Basic email format validation
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	if !emailRegex.MatchString(user.Email) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email format"})
 		return
 	}
 
-	err := h.userService.Create(&user)
+	err := h.userService.Create(c.Request.Context(), &user)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -59,7 +64,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetByID(uint(id))
+	user, err := h.userService.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
@@ -67,3 +72,163 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// UpdateUserRequest changes a user's profile. Omitted fields are left alone.
+type UpdateUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+func (h *UserHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil && req.Email == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name or email is required"})
+		return
+	}
+	if req.Email != nil && !emailRegex.MatchString(*req.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email format"})
+		return
+	}
+
+	user, err := h.userService.Update(c.Request.Context(), uint(id), services.UserUpdate{Name: req.Name, Email: req.Email})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrEmailInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// List serves a page of users, optionally filtered by name or email prefix,
+// creation time and status.
+func (h *UserHandler) List(c *gin.Context) {
+	filter := repositories.UserFilter{
+		NamePrefix:  c.Query("name"),
+		EmailPrefix: c.Query("email"),
+	}
+
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.param + ", expected RFC 3339 timestamp or YYYY-MM-DD"})
+			return
+		}
+		*bound.dst = &t
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "active":
+		active := true
+		filter.Active = &active
+	case "deactivated":
+		active := false
+		filter.Active = &active
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or deactivated"})
+		return
+	}
+
+	page, pageSize := 1, services.DefaultPageSize
+	for _, p := range []struct {
+		param string
+		dst   *int
+	}{
+		{"page", &page},
+		{"page_size", &pageSize},
+	} {
+		value := c.Query(p.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.param + " must be a positive integer"})
+			return
+		}
+		*p.dst = n
+	}
+
+	result, err := h.userService.List(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+type DeactivateUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req DeactivateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.Deactivate(c.Request.Context(), uint(id), req.Reason)
+	h.respondActivation(c, user, err)
+}
+
+func (h *UserHandler) Reactivate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.userService.Reactivate(c.Request.Context(), uint(id))
+	h.respondActivation(c, user, err)
+}
+
+func (h *UserHandler) respondActivation(c *gin.Context, user *models.User, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrUserDeactivated), errors.Is(err, services.ErrUserActive):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
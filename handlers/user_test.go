@@ -2,18 +2,23 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // Mock UserService
@@ -21,12 +26,44 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) Create(user *models.User) error {
+func (m *MockUserService) Create(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserService) GetByID(id uint) (*models.User, error) {
+func (m *MockUserService) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) Update(ctx context.Context, id uint, update services.UserUpdate) (*models.User, error) {
+	args := m.Called(id, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) List(ctx context.Context, filter repositories.UserFilter, page, pageSize int) (*models.UserPage, error) {
+	args := m.Called(filter, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
+func (m *MockUserService) Deactivate(ctx context.Context, id uint, reason string) (*models.User, error) {
+	args := m.Called(id, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) Reactivate(ctx context.Context, id uint) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -133,6 +170,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
 
 		handler.GetByID(c)
 
@@ -155,6 +193,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "invalid"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/invalid", nil)
 
 		handler.GetByID(c)
 
@@ -171,6 +210,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
 
 		handler.GetByID(c)
 
@@ -179,6 +219,221 @@ func TestUserHandler_GetByID(t *testing.T) {
 	})
 }
 
+func TestUserHandler_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	update := func(handler *UserHandler, id string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPatch, "/api/v1/users/"+id, bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		handler.Update(c)
+		return w
+	}
+
+	t.Run("email change", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		email := "jane@example.org"
+		updated := &models.User{ID: 1, Name: "Jane Doe", Email: email}
+		mockService.On("Update", uint(1), services.UserUpdate{Email: &email}).Return(updated, nil)
+
+		w := update(handler, "1", map[string]string{"email": email})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.User
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, email, response.Email)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		w := update(handler, "1", map[string]string{"email": "not-an-email"})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("nothing to change", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		w := update(handler, "1", map[string]string{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("email in use", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		email := "taken@example.com"
+		mockService.On("Update", uint(1), services.UserUpdate{Email: &email}).Return(nil, services.ErrEmailInUse)
+
+		w := update(handler, "1", map[string]string{"email": email})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		name := "Jane"
+		mockService.On("Update", uint(9), services.UserUpdate{Name: &name}).Return(nil, gorm.ErrRecordNotFound)
+
+		w := update(handler, "9", map[string]string{"name": name})
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUserHandler_List(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	list := func(handler *UserHandler, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users"+query, nil)
+		handler.List(c)
+		return w
+	}
+
+	t.Run("filters and page", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		active := false
+		filter := repositories.UserFilter{NamePrefix: "jo", EmailPrefix: "john@", CreatedFrom: &from, Active: &active}
+		page := &models.UserPage{Users: []models.User{{ID: 4, Name: "John"}}, Total: 3, Page: 2, PageSize: 1}
+		mockService.On("List", filter, 2, 1).Return(page, nil)
+
+		w := list(handler, "?name=jo&email=john@&created_from=2025-01-01&status=deactivated&page=2&page_size=1")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response models.UserPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(3), response.Total)
+		assert.Len(t, response.Users, 1)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		mockService.On("List", repositories.UserFilter{}, 1, services.DefaultPageSize).
+			Return(&models.UserPage{Users: []models.User{}, Page: 1, PageSize: services.DefaultPageSize}, nil)
+
+		assert.Equal(t, http.StatusOK, list(handler, "").Code)
+		mockService.AssertExpectations(t)
+	})
+
+	for name, query := range map[string]string{
+		"invalid date":   "?created_to=yesterday",
+		"invalid status": "?status=frozen",
+		"invalid page":   "?page=0",
+		"invalid size":   "?page_size=x",
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			handler := NewUserHandler(mockService)
+
+			assert.Equal(t, http.StatusBadRequest, list(handler, query).Code)
+			mockService.AssertNotCalled(t, "List")
+		})
+	}
+}
+
+func TestUserHandler_Deactivation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(action func(*gin.Context), id string, body interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: id}}
+		jsonData, _ := json.Marshal(body)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+id, bytes.NewBuffer(jsonData))
+		c.Request.Header.Add("Content-Type", "application/json")
+		action(c)
+		return w
+	}
+
+	t.Run("deactivate", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		now := time.Now()
+		user := &models.User{ID: 1, DeactivatedAt: &now, DeactivationReason: "account closed"}
+		mockService.On("Deactivate", uint(1), "account closed").Return(user, nil)
+
+		w := post(handler.Deactivate, "1", DeactivateUserRequest{Reason: "account closed"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reason required", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		w := post(handler.Deactivate, "1", map[string]string{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "Deactivate")
+	})
+
+	t.Run("already deactivated", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		mockService.On("Deactivate", uint(1), "again").Return(nil, services.ErrUserDeactivated)
+
+		assert.Equal(t, http.StatusConflict, post(handler.Deactivate, "1", DeactivateUserRequest{Reason: "again"}).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reactivate", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		mockService.On("Reactivate", uint(1)).Return(&models.User{ID: 1}, nil)
+
+		assert.Equal(t, http.StatusOK, post(handler.Reactivate, "1", nil).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reactivate active user", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		mockService.On("Reactivate", uint(1)).Return(nil, services.ErrUserActive)
+
+		assert.Equal(t, http.StatusConflict, post(handler.Reactivate, "1", nil).Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService := new(MockUserService)
+		handler := NewUserHandler(mockService)
+
+		mockService.On("Reactivate", uint(9)).Return(nil, gorm.ErrRecordNotFound)
+
+		assert.Equal(t, http.StatusNotFound, post(handler.Reactivate, "9", nil).Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
 func toString(v interface{}) string {
 	switch v := v.(type) {
 	case float64:
@@ -30,7 +30,7 @@ func (h *WalletHandler) Create(c *gin.Context) {
 		return
 	}
 
-	err := h.walletService.Create(&wallet)
+	err := h.walletService.Create(c.Request.Context(), &wallet)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -39,6 +39,7 @@ func (h *WalletHandler) Create(c *gin.Context) {
 		"id":         wallet.ID,
 		"user_id":    wallet.UserID,
 		"balance":    wallet.Balance,
+		"currency":   wallet.Currency,
 		"created_at": wallet.CreatedAt,
 		"updated_at": wallet.UpdatedAt,
 	}
@@ -53,7 +54,7 @@ func (h *WalletHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	wallet, err := h.walletService.GetByID(uint(id))
+	wallet, err := h.walletService.GetByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
 		return
@@ -69,7 +70,7 @@ func (h *WalletHandler) GetByUserID(c *gin.Context) {
 		return
 	}
 
-	wallets, err := h.walletService.GetByUserID(uint(userID))
+	wallets, err := h.walletService.GetByUserID(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "wallets not found"})
 		return
@@ -81,6 +82,10 @@ func (h *WalletHandler) GetByUserID(c *gin.Context) {
 			ID:        w.ID,
 			UserID:    w.UserID,
 			Balance:   w.Balance,
+			Currency:  w.Currency,
+			FrozenAt:  w.FrozenAt,
+			ParentID:  w.ParentID,
+			Name:      w.Name,
 			CreatedAt: w.CreatedAt,
 			UpdatedAt: w.UpdatedAt,
 		})
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -20,12 +21,12 @@ type MockWalletService struct {
 	mock.Mock
 }
 
-func (m *MockWalletService) Create(wallet *models.Wallet) error {
+func (m *MockWalletService) Create(ctx context.Context, wallet *models.Wallet) error {
 	args := m.Called(wallet)
 	return args.Error(0)
 }
 
-func (m *MockWalletService) GetByID(id uint) (*models.Wallet, error) {
+func (m *MockWalletService) GetByID(ctx context.Context, id uint) (*models.Wallet, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -33,7 +34,7 @@ func (m *MockWalletService) GetByID(id uint) (*models.Wallet, error) {
 	return args.Get(0).(*models.Wallet), args.Error(1)
 }
 
-func (m *MockWalletService) GetByUserID(userID uint) ([]models.Wallet, error) {
+func (m *MockWalletService) GetByUserID(ctx context.Context, userID uint) ([]models.Wallet, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -139,6 +140,7 @@ func TestWalletHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1", nil)
 
 		handler.GetByID(c)
 
@@ -161,6 +163,7 @@ func TestWalletHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "invalid"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/invalid", nil)
 
 		handler.GetByID(c)
 
@@ -177,6 +180,7 @@ func TestWalletHandler_GetByID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/wallets/1", nil)
 
 		handler.GetByID(c)
 
@@ -210,6 +214,7 @@ func TestWalletHandler_GetByUserID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1/wallets", nil)
 
 		handler.GetByUserID(c)
 
@@ -236,6 +241,7 @@ func TestWalletHandler_GetByUserID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "invalid"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/invalid/wallets", nil)
 
 		handler.GetByUserID(c)
 
@@ -252,6 +258,7 @@ func TestWalletHandler_GetByUserID(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/users/1/wallets", nil)
 
 		handler.GetByUserID(c)
 
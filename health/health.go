@@ -0,0 +1,105 @@
+// Package health serves the liveness and readiness endpoints used by
+// docker-compose and orchestrators to gate traffic.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Check is a named readiness probe. It should return quickly and honour ctx.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Handler serves /healthz and /readyz.
+type Handler struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHandler(timeout time.Duration, checks ...Check) *Handler {
+	return &Handler{checks: checks, timeout: timeout}
+}
+
+// SetShuttingDown makes /readyz fail so load balancers stop routing new
+// requests while in-flight ones drain.
+func (h *Handler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is up and serving requests. It has no
+// dependencies, so a database outage never gets the container restarted.
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness runs every check and answers 503 if any fails or the server is
+// shutting down.
+func (h *Handler) Readiness(c *gin.Context) {
+	if h.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := gin.H{}
+	for _, check := range h.checks {
+		if err := check.Check(ctx); err != nil {
+			status = http.StatusServiceUnavailable
+			results[check.Name] = err.Error()
+			continue
+		}
+		results[check.Name] = "ok"
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	c.JSON(status, gin.H{"status": overall, "checks": results})
+}
+
+// Database pings the connection pool.
+func Database(db *gorm.DB) Check {
+	return Check{Name: "database", Check: func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}}
+}
+
+// Migrations verifies that the table of every model exists.
+func Migrations(db *gorm.DB, models ...interface{}) Check {
+	return Check{Name: "migrations", Check: func(ctx context.Context) error {
+		migrator := db.WithContext(ctx).Migrator()
+		var missing []error
+		for _, model := range models {
+			if !migrator.HasTable(model) {
+				missing = append(missing, fmt.Errorf("table for %T is missing", model))
+			}
+		}
+		return errors.Join(missing...)
+	}}
+}
+
+// Worker wraps a background worker's own health report.
+func Worker(name string, healthy func() error) Check {
+	return Check{Name: "worker:" + name, Check: func(context.Context) error {
+		return healthy()
+	}}
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h *Handler, path string) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", h.Liveness)
+	router.GET("/readyz", h.Readiness)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func ok(context.Context) error { return nil }
+
+func TestHandler_Liveness(t *testing.T) {
+	h := NewHandler(time.Second, Check{Name: "database", Check: func(context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	code, body := serve(h, "/healthz")
+	assert.Equal(t, http.StatusOK, code, "liveness ignores dependencies")
+	assert.Equal(t, "ok", body["status"])
+}
+
+func TestHandler_Readiness(t *testing.T) {
+	t.Run("all checks pass", func(t *testing.T) {
+		h := NewHandler(time.Second, Check{Name: "database", Check: ok}, Worker("eod", func() error { return nil }))
+
+		code, body := serve(h, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]interface{}{"database": "ok", "worker:eod": "ok"}, body["checks"])
+	})
+
+	t.Run("failing check", func(t *testing.T) {
+		h := NewHandler(time.Second,
+			Check{Name: "database", Check: ok},
+			Check{Name: "migrations", Check: func(context.Context) error { return errors.New("table for *models.User is missing") }},
+		)
+
+		code, body := serve(h, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unavailable", body["status"])
+		assert.Equal(t, "table for *models.User is missing", body["checks"].(map[string]interface{})["migrations"])
+	})
+
+	t.Run("check timeout", func(t *testing.T) {
+		h := NewHandler(10*time.Millisecond, Check{Name: "database", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+		code, _ := serve(h, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		h := NewHandler(time.Second, Check{Name: "database", Check: ok})
+		h.SetShuttingDown()
+
+		code, body := serve(h, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "shutting down", body["status"])
+
+		code, _ = serve(h, "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
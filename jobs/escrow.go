@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"wallet-api/logging"
+)
+
+// EscrowRefunder refunds escrow agreements past their expiry.
+// services.EscrowService implements it.
+type EscrowRefunder interface {
+	RefundExpired(ctx context.Context) (int, error)
+}
+
+// EscrowTimeouts refunds expired escrow agreements every Interval.
+type EscrowTimeouts struct {
+	service  EscrowRefunder
+	Interval time.Duration
+}
+
+func NewEscrowTimeouts(service EscrowRefunder, interval time.Duration) *EscrowTimeouts {
+	return &EscrowTimeouts{service: service, Interval: interval}
+}
+
+// Run blocks until ctx is cancelled, refunding expired agreements on every
+// tick.
+func (j *EscrowTimeouts) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		j.sweep(ctx)
+	}
+}
+
+func (j *EscrowTimeouts) sweep(ctx context.Context) {
+	refunded, err := j.service.RefundExpired(ctx)
+	logger := logging.For("jobs").With("job", "escrow-timeouts")
+	if err != nil {
+		logger.ErrorContext(ctx, "refunding expired escrow agreements failed", "refunded", refunded, "error", err)
+		return
+	}
+	if refunded > 0 {
+		logger.InfoContext(ctx, "refunded expired escrow agreements", "refunded", refunded)
+	}
+}
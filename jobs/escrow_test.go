@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRefunder struct {
+	calls atomic.Int32
+}
+
+func (r *countingRefunder) RefundExpired(ctx context.Context) (int, error) {
+	r.calls.Add(1)
+	return 1, nil
+}
+
+func TestEscrowTimeouts_Run(t *testing.T) {
+	refunder := &countingRefunder{}
+	job := NewEscrowTimeouts(refunder, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return refunder.calls.Load() >= 2 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
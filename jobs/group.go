@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"wallet-api/logging"
+)
+
+// Worker is a background job that runs until its context is cancelled.
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+type runningWorker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Group runs background workers and stops them one at a time, in the reverse
+// of the order they were started, so later workers that depend on earlier
+// ones are shut down first.
+type Group struct {
+	mu      sync.Mutex
+	workers []*runningWorker
+}
+
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// Go starts w in its own goroutine.
+func (g *Group) Go(name string, w Worker) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rw := &runningWorker{name: name, cancel: cancel, done: make(chan struct{})}
+
+	g.mu.Lock()
+	g.workers = append(g.workers, rw)
+	g.mu.Unlock()
+
+	go func() {
+		defer close(rw.done)
+		w.Run(ctx)
+	}()
+}
+
+// Stop cancels each worker and waits for it to return before moving on to
+// the next. It gives up once ctx is done, reporting the workers still running.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	workers := g.workers
+	g.workers = nil
+	g.mu.Unlock()
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		w.cancel()
+
+		start := time.Now()
+		select {
+		case <-w.done:
+			logging.For("jobs").Info("stopped worker", "worker", w.name, "elapsed", time.Since(start).Round(time.Millisecond))
+		case <-ctx.Done():
+			var pending []string
+			for j := i; j >= 0; j-- {
+				workers[j].cancel()
+				pending = append(pending, workers[j].name)
+			}
+			return fmt.Errorf("workers did not stop in time: %v", pending)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingWorker struct {
+	name  string
+	mu    *sync.Mutex
+	order *[]string
+	block time.Duration
+}
+
+func (w recordingWorker) Run(ctx context.Context) {
+	<-ctx.Done()
+	time.Sleep(w.block)
+	w.mu.Lock()
+	*w.order = append(*w.order, w.name)
+	w.mu.Unlock()
+}
+
+func TestGroup_StopsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	g := NewGroup()
+	g.Go("first", recordingWorker{name: "first", mu: &mu, order: &order})
+	g.Go("second", recordingWorker{name: "second", mu: &mu, order: &order, block: 10 * time.Millisecond})
+
+	err := g.Stop(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, order)
+}
+
+func TestGroup_StopTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	g := NewGroup()
+	g.Go("slow", recordingWorker{name: "slow", mu: &mu, order: &order, block: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := g.Stop(ctx)
+	assert.ErrorContains(t, err, "slow")
+}
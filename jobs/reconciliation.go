@@ -0,0 +1,76 @@
+// Package jobs holds background workers that run inside the server process.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/services"
+)
+
+// EndOfDayReconciliation reconciles the previous UTC day once a day, Delay
+// after midnight.
+type EndOfDayReconciliation struct {
+	service services.IReconciliationService
+	Delay   time.Duration
+	now     func() time.Time
+	running atomic.Bool
+}
+
+func NewEndOfDayReconciliation(service services.IReconciliationService, delay time.Duration) *EndOfDayReconciliation {
+	return &EndOfDayReconciliation{
+		service: service,
+		Delay:   delay,
+		now:     time.Now,
+	}
+}
+
+// Run blocks until ctx is cancelled, reconciling each day as it closes.
+func (j *EndOfDayReconciliation) Run(ctx context.Context) {
+	j.running.Store(true)
+	defer j.running.Store(false)
+
+	for {
+		next := nextRun(j.now(), j.Delay)
+		timer := time.NewTimer(next.Sub(j.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		day := services.StartOfDay(next).AddDate(0, 0, -1)
+		report, err := j.service.RunEndOfDay(ctx, day)
+		logger := logging.For("jobs").With("job", "eod-reconciliation", "date", day.Format("2006-01-02"))
+		if err != nil {
+			logger.ErrorContext(ctx, "end-of-day reconciliation failed", "error", err)
+			continue
+		}
+		logger.InfoContext(ctx, "end-of-day reconciliation finished",
+			"wallets_checked", report.WalletsChecked,
+			"discrepancies", len(report.Discrepancies),
+		)
+	}
+}
+
+// nextRun returns the first midnight-plus-delay strictly after now.
+func nextRun(now time.Time, delay time.Duration) time.Time {
+	next := services.StartOfDay(now).Add(delay)
+	for !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Healthy reports whether the job loop is running.
+func (j *EndOfDayReconciliation) Healthy() error {
+	if !j.running.Load() {
+		return errors.New("end-of-day reconciliation job is not running")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextRun(t *testing.T) {
+	delay := 15 * time.Minute
+
+	t.Run("before todays run", func(t *testing.T) {
+		now := time.Date(2025, 3, 31, 0, 5, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2025, 3, 31, 0, 15, 0, 0, time.UTC), nextRun(now, delay))
+	})
+
+	t.Run("after todays run", func(t *testing.T) {
+		now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2025, 4, 1, 0, 15, 0, 0, time.UTC), nextRun(now, delay))
+	})
+
+	t.Run("exactly at run time", func(t *testing.T) {
+		now := time.Date(2025, 3, 31, 0, 15, 0, 0, time.UTC)
+		assert.Equal(t, time.Date(2025, 4, 1, 0, 15, 0, 0, time.UTC), nextRun(now, delay))
+	})
+
+	t.Run("non-UTC clock", func(t *testing.T) {
+		now := time.Date(2025, 3, 31, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		assert.True(t, time.Date(2025, 3, 31, 0, 15, 0, 0, time.UTC).Equal(nextRun(now, delay)))
+	})
+}
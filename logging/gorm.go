@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-api/tracing"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's logs to slog under the "gorm" component. Every
+// statement is logged at debug level (info after db.Debug()), slow ones at
+// warn and failed ones at error. SQL is logged with its literals removed, so
+// interpolated bind values such as e-mail addresses never reach the logs.
+type GormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	mode          gormlogger.LogLevel
+}
+
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		logger:        logger.With(ComponentKey, "gorm"),
+		slowThreshold: slowThreshold,
+		mode:          gormlogger.Warn,
+	}
+}
+
+var _ gormlogger.Interface = &GormLogger{}
+
+func (l *GormLogger) LogMode(mode gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.mode = mode
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, gormlogger.Info, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, gormlogger.Warn, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, gormlogger.Error, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, required gormlogger.LogLevel, msg string) {
+	if l.mode < required {
+		return
+	}
+	l.logger.Log(ctx, level, msg)
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.mode == gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	level := slog.LevelDebug
+	msg := "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	case l.mode >= gormlogger.Info:
+		level = slog.LevelInfo
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", tracing.SanitizeSQL(sql)),
+		slog.Int64("rows", rows),
+		slog.Duration("elapsed", elapsed),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
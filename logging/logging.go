@@ -0,0 +1,146 @@
+// Package logging configures structured JSON logging with log/slog. Records
+// carry the request ID and trace context of the ctx they are logged with,
+// sensitive fields are redacted, and levels can be set per component.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ComponentKey is the attribute naming the part of the program that logged a
+// record. Per-component levels are keyed on it.
+const ComponentKey = "component"
+
+// Options configure the logger built by New.
+type Options struct {
+	// Level is the minimum level of components without a level of their own.
+	Level slog.Level
+	// Levels overrides Level per component.
+	Levels map[string]slog.Level
+	// Format is "json" (default) or "text".
+	Format string
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, opts Options) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{
+		// The wrapping handler filters by component, so let everything through.
+		Level:       slog.Level(-1 << 10),
+		ReplaceAttr: redact,
+	}
+
+	var inner slog.Handler
+	if opts.Format == "text" {
+		inner = slog.NewTextHandler(w, handlerOpts)
+	} else {
+		inner = slog.NewJSONHandler(w, handlerOpts)
+	}
+
+	return slog.New(&handler{
+		inner:  inner,
+		level:  opts.Level,
+		levels: opts.Levels,
+	})
+}
+
+// For returns the default logger tagged with component.
+func For(component string) *slog.Logger {
+	return slog.Default().With(ComponentKey, component)
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// ParseLevels parses "component=level" pairs such as "gorm=warn".
+func ParseLevels(pairs []string) (map[string]slog.Level, error) {
+	levels := map[string]slog.Level{}
+	for _, pair := range pairs {
+		component, raw, ok := strings.Cut(pair, "=")
+		if !ok || component == "" {
+			return nil, fmt.Errorf("log level %q is not component=level", pair)
+		}
+		level, err := ParseLevel(raw)
+		if err != nil {
+			return nil, err
+		}
+		levels[component] = level
+	}
+	return levels, nil
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying who is acting, such as
+// "cli:alice" for an operator command. Records logged with the context carry
+// it as "actor", so money movements can be attributed in the audit trail.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor stored in ctx, or "".
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// handler applies per-component levels and adds the actor and the request
+// and trace IDs from the context to every record.
+type handler struct {
+	inner     slog.Handler
+	level     slog.Level
+	levels    map[string]slog.Level
+	component string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	min := h.level
+	if l, ok := h.levels[h.component]; ok {
+		min = l
+	}
+	return level >= min
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if actor := Actor(ctx); actor != "" {
+		r.AddAttrs(slog.String("actor", actor))
+	}
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			clone.component = a.Value.String()
+		}
+	}
+	clone.inner = h.inner.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.inner = h.inner.WithGroup(name)
+	return &clone
+}
@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// records decodes the JSON lines written to buf.
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestNew_ComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{
+		Level:  slog.LevelInfo,
+		Levels: map[string]slog.Level{"gorm": slog.LevelDebug, "http": slog.LevelWarn},
+	})
+
+	logger.Debug("hidden")
+	logger.Info("shown")
+	logger.With(ComponentKey, "gorm").Debug("query")
+	logger.With(ComponentKey, "http").Info("request")
+	logger.With(ComponentKey, "http").Warn("client error")
+
+	var messages []string
+	for _, r := range records(t, &buf) {
+		messages = append(messages, r["msg"].(string))
+	}
+	assert.Equal(t, []string{"shown", "query", "client error"}, messages)
+}
+
+func TestNew_Redaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: slog.LevelInfo})
+
+	logger.Info("user created by john@example.com",
+		"email", "john@example.com",
+		"access_token", "eyJhbGciOi",
+		"Authorization", "Bearer abc",
+		"password", "hunter2",
+		slog.Group("user", "email", "jane@example.com", "name", "Jane"),
+		"error", `duplicate key: Key (email)=(jane@example.com) already exists`,
+		"wallet_id", 7,
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"john@example.com", "jane@example.com", "eyJhbGciOi", "Bearer abc", "hunter2"} {
+		assert.NotContains(t, out, secret)
+	}
+
+	r := records(t, &buf)[0]
+	assert.Equal(t, "user created by [REDACTED]", r["msg"])
+	assert.Equal(t, Redacted, r["email"])
+	assert.Equal(t, Redacted, r["access_token"])
+	assert.Equal(t, Redacted, r["user"].(map[string]interface{})["email"])
+	assert.Equal(t, "Jane", r["user"].(map[string]interface{})["name"])
+	assert.Equal(t, float64(7), r["wallet_id"])
+}
+
+func TestNew_ContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: slog.LevelInfo})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = WithRequestID(ctx, "req-123")
+	ctx = WithActor(ctx, "cli:alice")
+
+	logger.InfoContext(ctx, "transfer committed")
+	logger.Info("no context")
+
+	r := records(t, &buf)
+	assert.Equal(t, "req-123", r[0]["request_id"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r[0]["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", r[0]["span_id"])
+	assert.Equal(t, "cli:alice", r[0]["actor"])
+	assert.NotContains(t, r[1], "request_id")
+	assert.NotContains(t, r[1], "actor")
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels([]string{"gorm=debug", "http=WARN"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]slog.Level{"gorm": slog.LevelDebug, "http": slog.LevelWarn}, levels)
+
+	_, err = ParseLevels([]string{"gorm"})
+	assert.Error(t, err)
+	_, err = ParseLevels([]string{"gorm=loud"})
+	assert.Error(t, err)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: slog.LevelInfo})
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), AccessLog(logger.With(ComponentKey, "http")))
+	router.GET("/wallets/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, RequestID(c.Request.Context()))
+	})
+
+	serve := func(header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/wallets/7", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("propagates incoming ID", func(t *testing.T) {
+		buf.Reset()
+		w := serve("b7c1e6f0-5b5e-4a43-9d3f-2f3d6c0c4a11")
+		assert.Equal(t, "b7c1e6f0-5b5e-4a43-9d3f-2f3d6c0c4a11", w.Header().Get(RequestIDHeader))
+		assert.Equal(t, "b7c1e6f0-5b5e-4a43-9d3f-2f3d6c0c4a11", w.Body.String(), "handlers see the ID in the request context")
+
+		r := records(t, &buf)[0]
+		assert.Equal(t, "request", r["msg"])
+		assert.Equal(t, "b7c1e6f0-5b5e-4a43-9d3f-2f3d6c0c4a11", r["request_id"])
+		assert.Equal(t, "/wallets/:id", r["route"])
+		assert.Equal(t, float64(http.StatusOK), r["status"])
+	})
+
+	t.Run("generates missing ID", func(t *testing.T) {
+		w := serve("")
+		assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+		assert.NotEqual(t, serve("").Header().Get(RequestIDHeader), w.Header().Get(RequestIDHeader))
+	})
+
+	t.Run("replaces malformed ID", func(t *testing.T) {
+		w := serve("abc\" injected=\"1")
+		assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+		w = serve(strings.Repeat("a", 129))
+		assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+	})
+}
+
+func TestGormLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: slog.LevelInfo, Levels: map[string]slog.Level{"gorm": slog.LevelDebug}})
+	gl := NewGormLogger(logger, 100*time.Millisecond)
+	ctx := WithRequestID(context.Background(), "req-9")
+
+	sql := func() (string, int64) {
+		return `SELECT * FROM "users" WHERE email = 'john@example.com' LIMIT 1`, 1
+	}
+	gl.Trace(ctx, time.Now(), sql, nil)
+	gl.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	gl.Trace(ctx, time.Now(), sql, errors.New("connection reset"))
+	gl.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	gl.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), sql, errors.New("ignored"))
+
+	assert.NotContains(t, buf.String(), "john@example.com")
+
+	r := records(t, &buf)
+	require.Len(t, r, 4)
+	assert.Equal(t, "DEBUG", r[0]["level"])
+	assert.Equal(t, `SELECT * FROM "users" WHERE email = ? LIMIT ?`, r[0]["sql"])
+	assert.Equal(t, "gorm", r[0][ComponentKey])
+	assert.Equal(t, "req-9", r[0]["request_id"])
+	assert.Equal(t, "slow query", r[1]["msg"])
+	assert.Equal(t, "WARN", r[1]["level"])
+	assert.Equal(t, "query failed", r[2]["msg"])
+	assert.Equal(t, "connection reset", r[2]["error"])
+	assert.Equal(t, "DEBUG", r[3]["level"], "not found is not an error")
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of sensitive attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively against the end of an
+// attribute key, so "user_email" and "refresh_token" are covered too.
+var sensitiveKeys = []string{
+	"email",
+	"token",
+	"password",
+	"secret",
+	"authorization",
+	"api_key",
+	"cookie",
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// redact is the slog ReplaceAttr hook. Sensitive keys lose their value
+// entirely; e-mail addresses embedded in any other string are masked.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		if s := a.Value.String(); strings.Contains(s, "@") {
+			return slog.String(a.Key, emailPattern.ReplaceAllString(s, Redacted))
+		}
+	}
+	return a
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.HasSuffix(key, k) {
+			return true
+		}
+	}
+	return false
+}
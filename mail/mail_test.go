@@ -0,0 +1,243 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestTemplates_Golden(t *testing.T) {
+	templates, err := NewTemplates()
+	require.NoError(t, err)
+
+	at := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	samples := map[string]any{
+		TemplateVerifyEmail: VerifyEmailData{
+			Name:      "Jane Doe",
+			Link:      "https://wallet.example.com/verify-email?token=abc123&source=email",
+			ExpiresAt: at.Add(24 * time.Hour),
+		},
+		TemplateTransferReceived: TransferData{
+			Name: "Ada <Lovelace>", WalletID: 7, Currency: "USD", CounterpartyWalletID: 3,
+			Amount: 2550, Balance: 10075, Reference: "TRF-1741944600000000000", At: at,
+		},
+		TemplateLargeDebit: TransferData{
+			Name: "Jane Doe", WalletID: 3, Currency: "USD", CounterpartyWalletID: 7,
+			Amount: 150000, Balance: 500, Reference: "TRF-1741944600000000000", At: at,
+		},
+		TemplateLowBalance: LowBalanceData{Name: "Jane Doe", WalletID: 3, Currency: "EUR", Balance: 500, Threshold: 1000},
+	}
+	require.Len(t, samples, len(templateNames))
+
+	for _, name := range templateNames {
+		t.Run(name, func(t *testing.T) {
+			msg, err := templates.Render(name, "jane@example.com", samples[name])
+			require.NoError(t, err)
+			assert.Equal(t, "jane@example.com", msg.To)
+
+			got := "Subject: " + msg.Subject + "\n\n" + msg.Text + "\n----\n\n" + msg.HTML
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got)
+		})
+	}
+
+	t.Run("unknown template", func(t *testing.T) {
+		_, err := templates.Render("welcome", "jane@example.com", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestTemplates_MinorUnits(t *testing.T) {
+	templates, err := NewTemplates()
+	require.NoError(t, err)
+
+	for currency, expected := range map[string]string{"JPY": "500 JPY", "KWD": "0.500 KWD", "EUR": "5.00 EUR"} {
+		msg, err := templates.Render(TemplateLowBalance, "jane@example.com",
+			LowBalanceData{Name: "Jane Doe", WalletID: 3, Currency: currency, Balance: 500, Threshold: 1000})
+		require.NoError(t, err)
+		assert.Contains(t, msg.Text, expected)
+		assert.Contains(t, msg.HTML, expected)
+	}
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := Message{To: "jane@example.com", Subject: "Grüße", Text: "Hello\n", HTML: "<p>Hello</p>"}
+	data, err := msg.Bytes("Wallet <no-reply@example.com>", time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", parsed.Header.Get("To"))
+	assert.Equal(t, "=?utf-8?q?Gr=C3=BC=C3=9Fe?=", parsed.Header.Get("Subject"))
+	assert.Contains(t, parsed.Header.Get("Content-Type"), "multipart/alternative")
+	body, err := io.ReadAll(parsed.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "text/plain; charset=utf-8")
+	assert.Contains(t, string(body), "text/html; charset=utf-8")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer, err := NewFileMailer(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "jane@example.com", Subject: "One", Text: "1"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "jane@example.com", Subject: "Two", Text: "2"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-jane@example.com.eml"))
+}
+
+// recorder is a Mailer that keeps what it is asked to send.
+type recorder struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (r *recorder) Send(_ context.Context, msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("inline without a worker", func(t *testing.T) {
+		rec := &recorder{}
+		q := NewQueue(rec)
+		require.NoError(t, q.Send(context.Background(), Message{To: "a@example.com"}))
+		assert.Equal(t, 1, rec.count())
+	})
+
+	t.Run("background worker drains on stop", func(t *testing.T) {
+		rec := &recorder{}
+		q := NewQueue(rec)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			q.Run(ctx)
+			close(done)
+		}()
+		require.Eventually(t, func() bool {
+			q.mu.RLock()
+			defer q.mu.RUnlock()
+			return q.running
+		}, time.Second, time.Millisecond)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, q.Send(context.Background(), Message{To: fmt.Sprintf("u%d@example.com", i)}))
+		}
+		cancel()
+		<-done
+		assert.Equal(t, 10, rec.count())
+	})
+}
+
+// fakeSMTP accepts one message the way an SMTP relay without TLS or
+// authentication would, and returns the commands and data it received.
+func fakeSMTP(t *testing.T) (addr string, received <-chan []string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	out := make(chan []string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				out <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						out <- lines
+						return
+					}
+					data = strings.TrimRight(data, "\r\n")
+					if data == "." {
+						break
+					}
+					lines = append(lines, data)
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				out <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return lis.Addr().String(), out
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	var portNum int
+	fmt.Sscan(port, &portNum)
+
+	mailer, err := NewSMTPMailer(host, portNum, "", "", "Wallet <no-reply@example.com>")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mailer.Send(ctx, Message{To: "jane@example.com", Subject: "Hello", Text: "Hi Jane\n"}))
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<jane@example.com>")
+	assert.Contains(t, lines, "Subject: Hello")
+	assert.Contains(t, lines, "Hi Jane")
+
+	_, err = NewSMTPMailer(host, portNum, "", "", "not an address")
+	assert.Error(t, err)
+}
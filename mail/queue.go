@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"wallet-api/logging"
+)
+
+// DefaultQueueSize bounds the messages waiting to be sent.
+const DefaultQueueSize = 256
+
+// Queue sends messages through a Mailer in the background once Run has
+// started, so requests do not wait on the mail server. Without a running
+// worker, or when the queue is full, Send delivers before returning. Either
+// way delivery errors are logged rather than returned: emails are
+// notifications and never fail the operation that triggered them.
+type Queue struct {
+	mailer Mailer
+	queue  chan queued
+
+	// mu guards running, so Send never queues a message after Run has
+	// drained the queue for the last time.
+	mu      sync.RWMutex
+	running bool
+}
+
+type queued struct {
+	ctx context.Context
+	msg Message
+}
+
+func NewQueue(mailer Mailer) *Queue {
+	return &Queue{mailer: mailer, queue: make(chan queued, DefaultQueueSize)}
+}
+
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	ctx = context.WithoutCancel(ctx)
+
+	q.mu.RLock()
+	if q.running {
+		select {
+		case q.queue <- queued{ctx: ctx, msg: msg}:
+			q.mu.RUnlock()
+			return nil
+		default:
+		}
+	}
+	q.mu.RUnlock()
+	q.deliver(ctx, msg)
+	return nil
+}
+
+// Run sends queued messages until ctx is cancelled, then works off whatever
+// is still queued.
+func (q *Queue) Run(ctx context.Context) {
+	q.mu.Lock()
+	q.running = true
+	q.mu.Unlock()
+
+	for {
+		select {
+		case m := <-q.queue:
+			q.deliver(m.ctx, m.msg)
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.running = false
+			q.mu.Unlock()
+			for {
+				select {
+				case m := <-q.queue:
+					q.deliver(m.ctx, m.msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, msg Message) {
+	if err := q.mailer.Send(ctx, msg); err != nil {
+		logging.For("mail").ErrorContext(ctx, "email not sent",
+			slog.String("to", msg.To),
+			slog.String("subject", msg.Subject),
+			slog.String("error", err.Error()),
+		)
+	}
+}
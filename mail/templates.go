@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"wallet-api/export"
+)
+
+// Template names.
+const (
+	TemplateVerifyEmail      = "verify_email"
+	TemplateTransferReceived = "transfer_received"
+	TemplateLargeDebit       = "large_debit"
+	TemplateLowBalance       = "low_balance"
+)
+
+// templateNames lists every template, in the order they are documented.
+var templateNames = []string{TemplateVerifyEmail, TemplateTransferReceived, TemplateLargeDebit, TemplateLowBalance}
+
+// VerifyEmailData fills TemplateVerifyEmail.
+type VerifyEmailData struct {
+	Name      string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// TransferData fills TemplateTransferReceived and TemplateLargeDebit. The
+// wallet is the recipient's or the sender's respectively, and amounts are
+// in its currency.
+type TransferData struct {
+	Name                 string
+	WalletID             uint
+	Currency             string
+	CounterpartyWalletID uint
+	Amount               int64
+	Balance              int64
+	Reference            string
+	At                   time.Time
+}
+
+// LowBalanceData fills TemplateLowBalance. Amounts are in the wallet's
+// currency.
+type LowBalanceData struct {
+	Name      string
+	WalletID  uint
+	Currency  string
+	Balance   int64
+	Threshold int64
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Templates renders the emails. Each template is a pair of files in
+// templates/: <name>.txt defines "subject" and "text", <name>.html defines
+// "content" for the shared HTML layout.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewTemplates parses the templates. Amounts are in minor units and are
+// rendered with the decimal places of the currency each message names,
+// followed by that currency.
+func NewTemplates() (*Templates, error) {
+	funcs := map[string]any{
+		"amount": func(amount int64, currency string) string {
+			return export.FormatAmount(amount, export.MinorUnits(currency)) + " " + currency
+		},
+		"date": func(t time.Time) string {
+			return t.UTC().Format("2 Jan 2006 15:04 MST")
+		},
+	}
+
+	t := &Templates{text: map[string]*texttemplate.Template{}, html: map[string]*htmltemplate.Template{}}
+	for _, name := range templateNames {
+		text, err := texttemplate.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/footer.txt", "templates/"+name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("mail: template %s: %w", name, err)
+		}
+		html, err := htmltemplate.New(name).Funcs(funcs).
+			ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("mail: template %s: %w", name, err)
+		}
+		t.text[name], t.html[name] = text, html
+	}
+	return t, nil
+}
+
+// Render fills the named template with data into a message to to.
+func (t *Templates) Render(name, to string, data any) (Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("mail: unknown template %q", name)
+	}
+
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
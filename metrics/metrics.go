@@ -0,0 +1,162 @@
+// Package metrics defines the Prometheus collectors exposed on /metrics and
+// the helpers the HTTP layer and services use to record them.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wallet"
+
+// Outcomes recorded for transfers and deposits.
+const (
+	OutcomeSuccess             = "success"
+	OutcomeInsufficientBalance = "insufficient_balance"
+	OutcomeNotFound            = "not_found"
+	OutcomeInvalid             = "invalid"
+	OutcomeLimitExceeded       = "limit_exceeded"
+	OutcomeHeld                = "held"
+	OutcomeAwaitingApproval    = "awaiting_approval"
+	OutcomeDenied              = "denied"
+	OutcomeError               = "error"
+)
+
+// Registry holds every collector of the process. A dedicated registry keeps
+// tests independent of whatever else registers on the global default one.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	transfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Transfers attempted, by outcome.",
+	}, []string{"outcome"})
+
+	deposits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposits_total",
+		Help:      "Deposits attempted, by outcome.",
+	}, []string{"outcome"})
+
+	insufficientBalance = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_balance_rejections_total",
+		Help:      "Debits rejected because the source wallet balance was too low.",
+	})
+
+	amountMoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amount_moved_minor_units_total",
+		Help:      "Sum of completed transaction amounts in minor units, by type and currency.",
+	}, []string{"type", "currency"})
+
+	amountSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transaction_amount_minor_units",
+		Help:      "Distribution of completed transaction amounts in minor units, by type and currency.",
+		Buckets:   prometheus.ExponentialBuckets(100, 10, 7),
+	}, []string{"type", "currency"})
+
+	lockWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent acquiring wallet row locks inside a transaction, by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		httpRequestsInFlight,
+		transfers,
+		deposits,
+		insufficientBalance,
+		amountMoved,
+		amountSize,
+		lockWait,
+	)
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	return gin.WrapH(h)
+}
+
+// Middleware records latency for every request. Requests that match no route
+// share the "unmatched" label so scanners cannot blow up the cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveTransfer records the outcome of a transfer and, when it succeeded,
+// the amount moved.
+func ObserveTransfer(outcome string, amount int64, currency string) {
+	observe(transfers, "transfer", outcome, amount, currency)
+}
+
+// ObserveDeposit records the outcome of a deposit and, when it succeeded,
+// the amount moved.
+func ObserveDeposit(outcome string, amount int64, currency string) {
+	observe(deposits, "deposit", outcome, amount, currency)
+}
+
+func observe(counter *prometheus.CounterVec, typ, outcome string, amount int64, currency string) {
+	counter.WithLabelValues(outcome).Inc()
+	switch outcome {
+	case OutcomeSuccess:
+		amountMoved.WithLabelValues(typ, currency).Add(float64(amount))
+		amountSize.WithLabelValues(typ, currency).Observe(float64(amount))
+	case OutcomeInsufficientBalance:
+		insufficientBalance.Inc()
+	}
+}
+
+// ObserveLockWait records how long an operation waited for its row locks.
+func ObserveLockWait(operation string, d time.Duration) {
+	lockWait.WithLabelValues(operation).Observe(d.Seconds())
+}
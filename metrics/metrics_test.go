@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/metrics", Handler())
+	router.GET("/wallets/:id", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "wallet not found"})
+	})
+	return router
+}
+
+// scrape fetches /metrics and returns the parsed metric families.
+func scrape(t *testing.T, router *gin.Engine) map[string]*dto.MetricFamily {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(w.Body.String()))
+	require.NoError(t, err)
+	return families
+}
+
+// find returns the metric of the family whose labels include labels.
+func find(families map[string]*dto.MetricFamily, name string, labels map[string]string) *dto.Metric {
+	family, ok := families[name]
+	if !ok {
+		return nil
+	}
+	for _, m := range family.GetMetric() {
+		matched := 0
+		for _, lp := range m.GetLabel() {
+			if v, ok := labels[lp.GetName()]; ok && v == lp.GetValue() {
+				matched++
+			}
+		}
+		if matched == len(labels) {
+			return m
+		}
+	}
+	return nil
+}
+
+func counter(families map[string]*dto.MetricFamily, name string, labels map[string]string) float64 {
+	if m := find(families, name, labels); m != nil {
+		return m.GetCounter().GetValue()
+	}
+	return 0
+}
+
+func TestMiddleware(t *testing.T) {
+	router := setupRouter()
+
+	for _, path := range []string{"/wallets/1", "/wallets/2", "/wp-login.php"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	families := scrape(t, router)
+
+	t.Run("labels by route template", func(t *testing.T) {
+		m := find(families, "wallet_http_request_duration_seconds", map[string]string{
+			"method": "GET", "route": "/wallets/:id", "status": "404",
+		})
+		require.NotNil(t, m)
+		assert.GreaterOrEqual(t, m.GetHistogram().GetSampleCount(), uint64(2))
+	})
+
+	t.Run("unmatched routes share a label", func(t *testing.T) {
+		m := find(families, "wallet_http_request_duration_seconds", map[string]string{"route": "unmatched"})
+		require.NotNil(t, m)
+		assert.Nil(t, find(families, "wallet_http_request_duration_seconds", map[string]string{"route": "/wp-login.php"}))
+	})
+
+	t.Run("runtime collectors", func(t *testing.T) {
+		assert.Contains(t, families, "go_goroutines")
+	})
+}
+
+func TestObserveTransfer(t *testing.T) {
+	router := setupRouter()
+	before := scrape(t, router)
+
+	ObserveTransfer(OutcomeSuccess, 250, "USD")
+	ObserveTransfer(OutcomeInsufficientBalance, 10000, "USD")
+	ObserveDeposit(OutcomeSuccess, 1000, "USD")
+	ObserveDeposit(OutcomeNotFound, 1000, "USD")
+	ObserveLockWait("transfer", 3*time.Millisecond)
+
+	after := scrape(t, router)
+	delta := func(name string, labels map[string]string) float64 {
+		return counter(after, name, labels) - counter(before, name, labels)
+	}
+
+	assert.Equal(t, 1.0, delta("wallet_transfers_total", map[string]string{"outcome": "success"}))
+	assert.Equal(t, 1.0, delta("wallet_transfers_total", map[string]string{"outcome": "insufficient_balance"}))
+	assert.Equal(t, 1.0, delta("wallet_deposits_total", map[string]string{"outcome": "success"}))
+	assert.Equal(t, 1.0, delta("wallet_deposits_total", map[string]string{"outcome": "not_found"}))
+	assert.Equal(t, 1.0, delta("wallet_insufficient_balance_rejections_total", nil))
+
+	// Only successful operations move money.
+	assert.Equal(t, 250.0, delta("wallet_amount_moved_minor_units_total", map[string]string{"type": "transfer", "currency": "USD"}))
+	assert.Equal(t, 1000.0, delta("wallet_amount_moved_minor_units_total", map[string]string{"type": "deposit", "currency": "USD"}))
+
+	lock := find(after, "wallet_lock_wait_seconds", map[string]string{"operation": "transfer"})
+	require.NotNil(t, lock)
+	assert.NotZero(t, lock.GetHistogram().GetSampleCount())
+}
+
+type nopDriver struct{}
+
+func (nopDriver) Open(string) (driver.Conn, error) { return nil, errors.New("not connected") }
+
+func TestRegisterDB(t *testing.T) {
+	sql.Register("metrics-nop", nopDriver{})
+	db, err := sql.Open("metrics-nop", "")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	require.NoError(t, RegisterDB(db, "test"))
+	assert.Error(t, RegisterDB(db, "test"), "a pool is registered once per name")
+
+	families := scrape(t, setupRouter())
+	m := find(families, "go_sql_max_open_connections", map[string]string{"db_name": "test"})
+	require.NotNil(t, m)
+	assert.Equal(t, 7.0, m.GetGauge().GetValue())
+}
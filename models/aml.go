@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AMLCaseStatusOpen      = "open"
+	AMLCaseStatusCleared   = "cleared"
+	AMLCaseStatusConfirmed = "confirmed"
+)
+
+const (
+	AMLSeverityLow    = "low"
+	AMLSeverityMedium = "medium"
+	AMLSeverityHigh   = "high"
+)
+
+// AMLCase groups the alerts raised on one transaction for an analyst to
+// work. When Held is set the transaction was stopped before it moved any
+// money and stays pending until the case is closed: clearing the case books
+// the transfer, confirming it fails the transfer.
+type AMLCase struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	TenantID      uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	TransactionID uint       `json:"transaction_id" gorm:"not null;uniqueIndex"`
+	WalletID      uint       `json:"wallet_id" gorm:"not null;index"`
+	Status        string     `json:"status" gorm:"size:20;default:'open';index"`
+	Held          bool       `json:"held" gorm:"not null;default:false"`
+	Analyst       string     `json:"analyst,omitempty" gorm:"size:100"`
+	Note          string     `json:"note,omitempty" gorm:"size:500"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	Alerts        []AMLAlert `json:"alerts" gorm:"foreignKey:CaseID"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// AMLAlert is one rule that fired on a case's transaction.
+type AMLAlert struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TenantID  uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	CaseID    uint      `json:"case_id" gorm:"not null;index"`
+	Rule      string    `json:"rule" gorm:"size:50;not null"`
+	Severity  string    `json:"severity" gorm:"size:10;not null"`
+	Details   string    `json:"details" gorm:"size:500"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// API key roles. A user key acts for one user; operator keys review KYC
+// cases, AML cases and screening hits; admin keys may also manage tenants
+// and act on any of them.
+const (
+	APIKeyRoleUser     = "user"
+	APIKeyRoleOperator = "operator"
+	APIKeyRoleAdmin    = "admin"
+)
+
+// APIKey authenticates callers of the API. Only a hash of the secret is
+// kept; the secret itself is shown once, when the key is issued. A key
+// belongs to its tenant, which for admin keys is only the tenant used when
+// a request names none.
+type APIKey struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID uint   `json:"tenant_id" gorm:"not null;default:1;index"`
+	Name     string `json:"name" gorm:"size:100;not null"`
+	Role     string `json:"role" gorm:"size:20;not null"`
+	// UserID is the user a user key acts for. Unset for other roles.
+	UserID *uint `json:"user_id,omitempty" gorm:"index"`
+	// Prefix is the start of the secret, enough to tell keys apart.
+	Prefix    string     `json:"prefix" gorm:"size:16;not null"`
+	Hash      string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Revoked reports whether the key may no longer be used.
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// EscrowStatusPending is an agreement whose funding transfer is held
+	// for review.
+	EscrowStatusPending  = "pending"
+	EscrowStatusFunded   = "funded"
+	EscrowStatusDisputed = "disputed"
+	EscrowStatusReleased = "released"
+	EscrowStatusRefunded = "refunded"
+	// EscrowStatusFailed is an agreement whose held funding transfer was
+	// refused on review, so nothing was ever held.
+	EscrowStatusFailed = "failed"
+)
+
+// EscrowAgreement holds Amount from BuyerWalletID in the tenant's escrow
+// wallet for SellerWalletID. The buyer confirming delivery releases it to
+// the seller; a dispute waits for an operator to release or refund it, and
+// an agreement neither confirmed nor disputed by ExpiresAt is refunded to
+// the buyer. Each movement is a transaction the agreement links to.
+type EscrowAgreement struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	TenantID       uint   `json:"tenant_id" gorm:"not null;default:1;index"`
+	BuyerWalletID  uint   `json:"buyer_wallet_id" gorm:"not null;index"`
+	SellerWalletID uint   `json:"seller_wallet_id" gorm:"not null;index"`
+	EscrowWalletID uint   `json:"escrow_wallet_id" gorm:"not null"`
+	Amount         int64  `json:"amount" gorm:"not null"`
+	Memo           string `json:"memo,omitempty" gorm:"size:255"`
+	Status         string `json:"status" gorm:"size:20;not null;index"`
+	// ExpiresAt is when a funded agreement is refunded unless confirmed or
+	// disputed first.
+	ExpiresAt     time.Time `json:"expires_at" gorm:"not null;index"`
+	DisputedBy    *uint     `json:"disputed_by,omitempty"`
+	DisputeReason string    `json:"dispute_reason,omitempty" gorm:"size:255"`
+	// ResolvedBy names the operator who settled a dispute.
+	ResolvedBy     string `json:"resolved_by,omitempty" gorm:"size:100"`
+	ResolutionNote string `json:"resolution_note,omitempty" gorm:"size:255"`
+	// FundingTransactionID is the transfer from the buyer into escrow;
+	// ReleaseTransactionID or RefundTransactionID the payout that closed
+	// the agreement.
+	FundingTransactionID uint       `json:"funding_transaction_id" gorm:"not null"`
+	ReleaseTransactionID *uint      `json:"release_transaction_id,omitempty"`
+	RefundTransactionID  *uint      `json:"refund_transaction_id,omitempty"`
+	ClosedAt             *time.Time `json:"closed_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// Closed reports whether the agreement's money has left escrow, or never
+// arrived.
+func (a *EscrowAgreement) Closed() bool {
+	switch a.Status {
+	case EscrowStatusReleased, EscrowStatusRefunded, EscrowStatusFailed:
+		return true
+	}
+	return false
+}
+
+// Expired reports whether the agreement was still funded, undisputed, when
+// it expired.
+func (a *EscrowAgreement) Expired(now time.Time) bool {
+	return a.Status == EscrowStatusFunded && !now.Before(a.ExpiresAt)
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// KYCLevel is how thoroughly a user's identity has been verified. Higher
+// levels unlock larger balances and outgoing limits.
+type KYCLevel string
+
+const (
+	KYCLevelNone  KYCLevel = "none"
+	KYCLevelBasic KYCLevel = "basic"
+	KYCLevelFull  KYCLevel = "full"
+)
+
+// Rank orders the levels; it is -1 for unknown levels.
+func (l KYCLevel) Rank() int {
+	switch l {
+	case KYCLevelNone, "":
+		return 0
+	case KYCLevelBasic:
+		return 1
+	case KYCLevelFull:
+		return 2
+	default:
+		return -1
+	}
+}
+
+const (
+	VerificationStatusPending  = "pending"
+	VerificationStatusApproved = "approved"
+	VerificationStatusRejected = "rejected"
+)
+
+const (
+	DocumentTypePassport       = "passport"
+	DocumentTypeNationalID     = "national_id"
+	DocumentTypeDrivingLicence = "driving_licence"
+	DocumentTypeProofOfAddress = "proof_of_address"
+)
+
+// VerificationCase is a user's request to be verified at RequestedLevel,
+// reviewed manually by an operator.
+type VerificationCase struct {
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	TenantID       uint                   `json:"tenant_id" gorm:"not null;default:1;index"`
+	UserID         uint                   `json:"user_id" gorm:"not null;index"`
+	RequestedLevel KYCLevel               `json:"requested_level" gorm:"size:10;not null"`
+	Status         string                 `json:"status" gorm:"size:20;default:'pending';index"`
+	Reviewer       string                 `json:"reviewer,omitempty" gorm:"size:100"`
+	ReviewNote     string                 `json:"review_note,omitempty" gorm:"size:500"`
+	ReviewedAt     *time.Time             `json:"reviewed_at,omitempty"`
+	Documents      []VerificationDocument `json:"documents" gorm:"foreignKey:CaseID"`
+	History        []VerificationEvent    `json:"history" gorm:"foreignKey:CaseID"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// VerificationDocument is the metadata of a submitted identity document. The
+// file itself lives in document storage under Reference.
+type VerificationDocument struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	TenantID       uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	CaseID         uint       `json:"case_id" gorm:"not null;index"`
+	Type           string     `json:"type" gorm:"size:30;not null"`
+	IssuingCountry string     `json:"issuing_country" gorm:"size:2;not null"`
+	Reference      string     `json:"reference" gorm:"size:255;not null"`
+	ExpiresOn      *time.Time `json:"expires_on,omitempty" gorm:"type:date"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// VerificationEvent is one entry in a case's status history.
+type VerificationEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TenantID  uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	CaseID    uint      `json:"case_id" gorm:"not null;index"`
+	Status    string    `json:"status" gorm:"size:20;not null"`
+	Actor     string    `json:"actor" gorm:"size:100;not null"`
+	Note      string    `json:"note,omitempty" gorm:"size:500"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerification is a token sent to confirm that a user owns an email
+// address. Only a hash of the token is stored. Sending a new token or
+// changing the address supersedes the outstanding ones.
+type EmailVerification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TenantID  uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	Email     string     `json:"email" gorm:"size:100;not null"`
+	TokenHash string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+)
+
+// WalletRole is what a member may do with a joint wallet.
+type WalletRole string
+
+const (
+	// WalletRoleOwner may spend without a cap, unless given one, and
+	// approves transfers that need approval.
+	WalletRoleOwner WalletRole = "owner"
+	// WalletRoleSpender may send transfers, up to their spending cap.
+	WalletRoleSpender WalletRole = "spender"
+	// WalletRoleViewer may see the wallet but not spend from it.
+	WalletRoleViewer WalletRole = "viewer"
+)
+
+// Valid reports whether r is a known role.
+func (r WalletRole) Valid() bool {
+	switch r {
+	case WalletRoleOwner, WalletRoleSpender, WalletRoleViewer:
+		return true
+	}
+	return false
+}
+
+// WalletMember gives a user other than the wallet's owner a role in it,
+// making it a joint wallet. The user the wallet was opened for stays its
+// primary owner, an owner without a cap who needs no membership.
+type WalletMember struct {
+	ID       uint       `json:"id" gorm:"primaryKey"`
+	TenantID uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	WalletID uint       `json:"wallet_id" gorm:"not null;uniqueIndex:idx_wallet_members_wallet_user"`
+	UserID   uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_wallet_members_wallet_user;index"`
+	Role     WalletRole `json:"role" gorm:"size:20;not null"`
+	// SpendingCap is the most the member may send from the wallet in any
+	// 24 hours, in minor units. Nil means no cap.
+	SpendingCap *int64    `json:"spending_cap,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+	TransferApprovalStatusPending  = "pending"
+	TransferApprovalStatusApproved = "approved"
+	TransferApprovalStatusRejected = "rejected"
+	TransferApprovalStatusFailed   = "failed"
+)
+
+// TransferApproval is a transfer from a wallet with an approval rule that
+// waits for its owners. It is executed once RequiredApprovals owners have
+// approved, and rejected once too many have rejected it for that to happen.
+// Failed records an approved transfer that could not be made, with the
+// reason.
+type TransferApproval struct {
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	TenantID          uint           `json:"tenant_id" gorm:"not null;default:1;index"`
+	SourceWalletID    uint           `json:"source_wallet_id" gorm:"not null;index"`
+	TargetWalletID    uint           `json:"target_wallet_id" gorm:"not null"`
+	Amount            int64          `json:"amount" gorm:"not null"`
+	RequestedBy       uint           `json:"requested_by" gorm:"not null"`
+	RequiredApprovals int            `json:"required_approvals" gorm:"not null"`
+	Status            string         `json:"status" gorm:"size:20;default:'pending';index"`
+	TransactionID     *uint          `json:"transaction_id,omitempty"`
+	FailureReason     string         `json:"failure_reason,omitempty" gorm:"size:255"`
+	DecidedAt         *time.Time     `json:"decided_at,omitempty"`
+	Votes             []ApprovalVote `json:"votes" gorm:"foreignKey:ApprovalID"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// Approvals counts the votes for the transfer and against it.
+func (a *TransferApproval) Approvals() (approve, reject int) {
+	for _, vote := range a.Votes {
+		if vote.Approve {
+			approve++
+		} else {
+			reject++
+		}
+	}
+	return approve, reject
+}
+
+// ApprovalVote is one owner's decision on a transfer approval.
+type ApprovalVote struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	TenantID   uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	ApprovalID uint      `json:"approval_id" gorm:"not null;uniqueIndex:idx_approval_votes_approval_user"`
+	UserID     uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_approval_votes_approval_user"`
+	Approve    bool      `json:"approve"`
+	Note       string    `json:"note,omitempty" gorm:"size:500"`
+	CreatedAt  time.Time `json:"created_at"`
+}
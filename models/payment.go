@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	PaymentRequestStatusPending   = "pending"
+	PaymentRequestStatusPaid      = "paid"
+	PaymentRequestStatusDeclined  = "declined"
+	PaymentRequestStatusCancelled = "cancelled"
+	PaymentRequestStatusExpired   = "expired"
+)
+
+// PaymentRequest asks for Amount to be paid into RequesterWalletID. A
+// request names the wallet expected to pay it, or none for an open request
+// that any wallet of the tenant may pay. Paying it makes a transfer, which
+// the request links to; a pending request past ExpiresAt can no longer be
+// paid and is reported as expired.
+type PaymentRequest struct {
+	ID                uint   `json:"id" gorm:"primaryKey"`
+	TenantID          uint   `json:"tenant_id" gorm:"not null;default:1;index"`
+	RequesterWalletID uint   `json:"requester_wallet_id" gorm:"not null;index"`
+	PayerWalletID     *uint  `json:"payer_wallet_id,omitempty" gorm:"index"`
+	Amount            int64  `json:"amount" gorm:"not null"`
+	Memo              string `json:"memo,omitempty" gorm:"size:255"`
+	Status            string `json:"status" gorm:"size:20;default:'pending';index"`
+	// PaidFromWalletID is the wallet that paid the request, which for an
+	// open request is only known then.
+	PaidFromWalletID *uint      `json:"paid_from_wallet_id,omitempty"`
+	TransactionID    *uint      `json:"transaction_id,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	// ClosedAt is when the request was paid, declined or cancelled.
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Open reports whether any wallet may pay the request.
+func (r *PaymentRequest) Open() bool {
+	return r.PayerWalletID == nil
+}
+
+// Expired reports whether the request was still pending when it expired.
+func (r *PaymentRequest) Expired(now time.Time) bool {
+	return r.Status == PaymentRequestStatusPending && r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+const (
+	PaymentLinkModeSingle = "single"
+	PaymentLinkModeMulti  = "multi"
+
+	PaymentLinkStatusActive  = "active"
+	PaymentLinkStatusUsed    = "used"
+	PaymentLinkStatusRevoked = "revoked"
+	PaymentLinkStatusExpired = "expired"
+)
+
+// PaymentLink is a shareable request for Amount to be paid into WalletID,
+// handed out as a signed token, typically in a QR code. A single-use link
+// takes one payment; a multi-use link takes up to MaxUses, or any number
+// without it, and is then used up. Links stop working at ExpiresAt.
+type PaymentLink struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID uint   `json:"tenant_id" gorm:"not null;default:1;index"`
+	WalletID uint   `json:"wallet_id" gorm:"not null;index"`
+	Amount   int64  `json:"amount" gorm:"not null"`
+	Memo     string `json:"memo,omitempty" gorm:"size:255"`
+	Mode     string `json:"mode" gorm:"size:10;not null"`
+	MaxUses  *int   `json:"max_uses,omitempty"`
+	UseCount int    `json:"use_count" gorm:"not null;default:0"`
+	Status   string `json:"status" gorm:"size:20;default:'active';index"`
+	// ExpiresAt is whole seconds, as signed into the token.
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Token and URL are derived from the link rather than stored.
+	Token string           `json:"token,omitempty" gorm:"-"`
+	URL   string           `json:"url,omitempty" gorm:"-"`
+	Uses  []PaymentLinkUse `json:"uses,omitempty" gorm:"foreignKey:LinkID"`
+}
+
+// PaymentLinkUse is one payment made through a link.
+type PaymentLinkUse struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	TenantID      uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	LinkID        uint      `json:"link_id" gorm:"not null;index"`
+	PayerWalletID uint      `json:"payer_wallet_id" gorm:"not null"`
+	TransactionID uint      `json:"transaction_id" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Limit returns how many payments the link takes in all, 0 for no limit.
+func (l *PaymentLink) Limit() int {
+	switch {
+	case l.Mode == PaymentLinkModeSingle:
+		return 1
+	case l.MaxUses != nil:
+		return *l.MaxUses
+	}
+	return 0
+}
+
+// Expired reports whether the link was still active when it expired.
+func (l *PaymentLink) Expired(now time.Time) bool {
+	return l.Status == PaymentLinkStatusActive && !now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Erasure records that a user's personal data was erased at their request.
+// The user row is pseudonymized and soft-deleted; their wallets and
+// transactions are kept, linked only to the user ID, until RetainUntil.
+type Erasure struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TenantID    uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	RequestedBy string    `json:"requested_by" gorm:"size:100;not null"`
+	Reason      string    `json:"reason,omitempty" gorm:"size:500"`
+	RetainUntil time.Time `json:"retain_until" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserExport is everything held about a user, as handed over on a data
+// subject access request.
+type UserExport struct {
+	ExportedAt    time.Time
+	User          User
+	Wallets       []Wallet
+	Transactions  []Transaction
+	Verifications []VerificationCase
+}
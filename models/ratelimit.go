@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the persisted state of one token bucket, shared by every
+// instance when rate limits are kept in Postgres.
+type RateLimitBucket struct {
+	Key        string    `json:"key" gorm:"primaryKey;size:255"`
+	Tokens     float64   `json:"tokens" gorm:"not null"`
+	RefilledAt time.Time `json:"refilled_at" gorm:"not null;index"`
+}
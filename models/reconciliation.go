@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// BalanceSnapshot is a wallet's closing balance at the end of a UTC day.
+type BalanceSnapshot struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	TenantID        uint      `json:"tenant_id" gorm:"not null;default:1;index"`
+	WalletID        uint      `json:"wallet_id" gorm:"not null;uniqueIndex:idx_snapshot_wallet_date"`
+	Date            time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_snapshot_wallet_date"`
+	Balance         int64     `json:"balance" gorm:"not null"`
+	ExpectedBalance int64     `json:"expected_balance" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+const (
+	DiscrepancyStatusOpen     = "open"
+	DiscrepancyStatusResolved = "resolved"
+)
+
+// Discrepancy records a day on which a wallet's balance was not explained by
+// its previous snapshot plus that day's transactions.
+type Discrepancy struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	TenantID        uint       `json:"tenant_id" gorm:"not null;default:1;index"`
+	WalletID        uint       `json:"wallet_id" gorm:"not null;uniqueIndex:idx_discrepancy_wallet_date"`
+	Date            time.Time  `json:"date" gorm:"type:date;not null;uniqueIndex:idx_discrepancy_wallet_date"`
+	ExpectedBalance int64      `json:"expected_balance" gorm:"not null"`
+	ActualBalance   int64      `json:"actual_balance" gorm:"not null"`
+	Difference      int64      `json:"difference" gorm:"not null"` // actual - expected
+	Status          string     `json:"status" gorm:"size:20;default:'open';index"`
+	ResolutionNote  string     `json:"resolution_note" gorm:"size:500"`
+	ResolvedBy      string     `json:"resolved_by,omitempty" gorm:"size:100"` // operator's API key name
+	ResolvedAt      *time.Time `json:"resolved_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+//DTO
+type ReconciliationReport struct {
+	Date           time.Time     `json:"date"`
+	WalletsChecked int           `json:"wallets_checked"`
+	Discrepancies  []Discrepancy `json:"discrepancies"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// ScreeningStatus says whether a user may use the service as far as
+// sanctions screening is concerned.
+type ScreeningStatus string
+
+const (
+	ScreeningStatusClear   ScreeningStatus = "clear"
+	ScreeningStatusPending ScreeningStatus = "pending"
+	ScreeningStatusBlocked ScreeningStatus = "blocked"
+)
+
+const (
+	ScreeningSubjectUser     = "user"
+	ScreeningSubjectTransfer = "transfer"
+)
+
+const (
+	ScreeningHitStatusPending   = "pending"
+	ScreeningHitStatusCleared   = "cleared"
+	ScreeningHitStatusConfirmed = "confirmed"
+)
+
+// ScreeningHit is a name that resembled a sanctions list entry. Until an
+// operator reviews it, it blocks its subject: a user hit keeps the user
+// pending, a transfer hit keeps the transfer held without moving money.
+// Clearing every hit on the subject lets it go ahead; confirming one blocks
+// the user or fails the transfer.
+type ScreeningHit struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID uint   `json:"tenant_id" gorm:"not null;default:1;index"`
+	Subject  string `json:"subject" gorm:"size:10;not null"`
+	// UserID is the user whose name was screened, also for transfer hits.
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	TransactionID *uint      `json:"transaction_id,omitempty" gorm:"index"`
+	ScreenedName  string     `json:"screened_name" gorm:"size:100;not null"`
+	List          string     `json:"list" gorm:"size:100;not null"`
+	EntryID       string     `json:"entry_id" gorm:"size:100;not null"`
+	EntryName     string     `json:"entry_name" gorm:"size:255;not null"`
+	Score         float64    `json:"score" gorm:"not null"`
+	Status        string     `json:"status" gorm:"size:20;default:'pending';index"`
+	Reviewer      string     `json:"reviewer,omitempty" gorm:"size:100"`
+	Note          string     `json:"note,omitempty" gorm:"size:500"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+//DTO
+type BalanceResponse struct {
+	WalletID uint      `json:"wallet_id"`
+	Balance  int64     `json:"balance"`
+	AsOf     time.Time `json:"as_of"`
+}
+
+// StatementLine is one completed transaction as seen from a single wallet.
+// Amount is signed: credits are positive and debits negative.
+type StatementLine struct {
+	TransactionID   uint            `json:"transaction_id"`
+	Type            TransactionType `json:"type"`
+	ReferenceNumber string          `json:"reference_number"`
+	CounterpartyID  *uint           `json:"counterparty_wallet_id"`
+	Amount          int64           `json:"amount"`
+	RunningBalance  int64           `json:"running_balance"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+//DTO
+type Statement struct {
+	WalletID       uint            `json:"wallet_id"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance int64           `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	ClosingBalance int64           `json:"closing_balance"`
+}
+
+// StatementHeader carries everything a statement export needs before the
+// first line is written.
+type StatementHeader struct {
+	WalletID       uint
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// NewStatementLine converts a transaction into a line for walletID.
+// runningBalance is the balance before the transaction is applied.
+func NewStatementLine(walletID uint, t Transaction, runningBalance int64) StatementLine {
+	line := StatementLine{
+		TransactionID:   t.ID,
+		Type:            t.Type,
+		ReferenceNumber: t.ReferenceNumber,
+		Amount:          t.Amount,
+		CreatedAt:       t.CreatedAt,
+	}
+	if t.TargetWalletID == walletID {
+		line.CounterpartyID = t.SourceWalletID
+	} else {
+		line.Amount = -t.Amount
+		target := t.TargetWalletID
+		line.CounterpartyID = &target
+	}
+	line.RunningBalance = runningBalance + line.Amount
+	return line
+}
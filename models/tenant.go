@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	// DefaultTenantID is the tenant of data created before tenants existed
+	// and of requests that name no tenant. The tenant_id columns default to
+	// it.
+	DefaultTenantID uint = 1
+	// DefaultTenantSlug is the slug of the default tenant.
+	DefaultTenantSlug = "default"
+	// DefaultTenantCurrency is the default tenant's only currency, matching
+	// the currency wallets had before tenants existed.
+	DefaultTenantCurrency = "USD"
+)
+
+// Tenant is an organization the API is run for. Every other model belongs to
+// exactly one tenant, and a request only ever sees the data of the tenant
+// it names.
+type Tenant struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"size:100;not null"`
+	Slug string `json:"slug" gorm:"size:50;not null;uniqueIndex"`
+	// Currencies lists the ISO 4217 codes the tenant's wallets may hold. New
+	// wallets hold the first unless they ask for another.
+	Currencies []string `json:"currencies" gorm:"type:text;serializer:json"`
+	// Fees are charged on transfers, by currency. Currencies without an
+	// entry are free.
+	Fees map[string]TransferFee `json:"fees,omitempty" gorm:"type:text;serializer:json"`
+	// Limits replace the configured KYC limits for the levels they name.
+	Limits map[KYCLevel]TierLimit `json:"limits,omitempty" gorm:"type:text;serializer:json"`
+	// EscrowWallets hold the money of escrow agreements, by currency.
+	// Agreements cannot be made in currencies without one.
+	EscrowWallets map[string]uint `json:"escrow_wallets,omitempty" gorm:"type:text;serializer:json"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// DefaultCurrency is the currency new wallets hold unless they ask for
+// another.
+func (t *Tenant) DefaultCurrency() string {
+	if len(t.Currencies) == 0 {
+		return DefaultTenantCurrency
+	}
+	return t.Currencies[0]
+}
+
+// Supports reports whether the tenant's wallets may hold currency.
+func (t *Tenant) Supports(currency string) bool {
+	for _, c := range t.Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// TransferFee is charged to the sender of a transfer on top of the amount
+// and credited to WalletID, a wallet of the tenant in the same currency.
+// Amounts are in minor units; BasisPoints are hundredths of a percent of the
+// amount, rounded down.
+type TransferFee struct {
+	Fixed       int64 `json:"fixed"`
+	BasisPoints int   `json:"basis_points"`
+	WalletID    uint  `json:"wallet_id"`
+}
+
+// For returns the fee on a transfer of amount.
+func (f TransferFee) For(amount int64) int64 {
+	return f.Fixed + amount*int64(f.BasisPoints)/10_000
+}
+
+// TierLimit caps what a user at one KYC level may hold in a wallet and send
+// in any 24 hours, in minor units. Zero means unlimited.
+type TierLimit struct {
+	MaxBalance    int64 `json:"max_balance"`
+	DailyOutgoing int64 `json:"daily_outgoing"`
+}
@@ -12,9 +12,24 @@ const (
 	TransactionTypeDeposit  TransactionType = "deposit"
 	TransactionTypeWithdraw TransactionType = "withdraw"
 	TransactionTypeTransfer TransactionType = "transfer"
+	// TransactionTypePocket moves money between a wallet and one of its
+	// pockets.
+	TransactionTypePocket TransactionType = "pocket"
+	// TransactionTypeFee charges a tenant's transfer fee to the sender.
+	TransactionTypeFee TransactionType = "fee"
+	// TransactionTypeEscrow pays money held in escrow out to the seller or
+	// back to the buyer.
+	TransactionTypeEscrow TransactionType = "escrow"
+)
+
+const (
+	TransactionStatusPending   = "pending"
+	TransactionStatusCompleted = "completed"
+	TransactionStatusFailed    = "failed"
 )
 type Transaction struct {
 	ID              uint            `json:"id" gorm:"primaryKey"`
+	TenantID        uint            `json:"tenant_id" gorm:"not null;default:1;index"`
 	SourceWalletID  *uint           `json:"source_wallet_id"`
 	SourceWallet    *Wallet         `json:"source_wallet" gorm:"foreignKey:SourceWalletID"`
 	TargetWalletID  uint            `json:"target_wallet_id" gorm:"not null"`
@@ -23,6 +38,18 @@ type Transaction struct {
 	Type            TransactionType `json:"type" gorm:"not null"`
 	ReferenceNumber string          `json:"reference_number" gorm:"size:50;index"`
 	Status          string          `json:"status" gorm:"size:20;default:'completed'"` // pending, completed, failed
+	Description     string          `json:"description,omitempty" gorm:"size:255"`
+	DeviceID        string          `json:"device_id,omitempty" gorm:"size:128"`
+	// Fee is what the sender paid on top of Amount for a transfer; it is
+	// booked as a separate transaction of type fee.
+	Fee             int64           `json:"fee,omitempty" gorm:"not null;default:0"`
+	// InitiatedBy is the user who sent a transfer: the wallet's owner or
+	// one of its members.
+	InitiatedBy     *uint           `json:"initiated_by,omitempty" gorm:"index"`
+	// RiskScore and RiskReasons record the fraud risk assessment of a
+	// transfer, when one was made.
+	RiskScore       *int            `json:"risk_score,omitempty"`
+	RiskReasons     []string        `json:"risk_reasons,omitempty" gorm:"type:text;serializer:json"`
 	CreatedAt       time.Time       `json:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt  `json:"deleted_at" gorm:"index"`
@@ -35,9 +62,11 @@ type TransferResponse struct {
 	SourceWalletID  *uint      `json:"source_wallet_id"`  // Nullable
 	TargetWalletID  uint      `json:"target_wallet_id"`  // Nullable
 	Amount          int64      `json:"amount"`
+	Fee             int64      `json:"fee,omitempty"`
 	Type            TransactionType     `json:"type"`
 	ReferenceNumber string     `json:"reference_number"`
 	Status          string     `json:"status"`
+	Description     string     `json:"description,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
\ No newline at end of file
@@ -7,10 +7,33 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"size:100;not null"`
-	Email     string         `json:"email" gorm:"size:100;uniqueIndex;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-}
\ No newline at end of file
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	TenantID        uint            `json:"tenant_id" gorm:"not null;default:1;index;uniqueIndex:idx_users_tenant_email"`
+	Name            string          `json:"name" gorm:"size:100;not null"`
+	Email           string          `json:"email" gorm:"size:100;uniqueIndex:idx_users_tenant_email;not null"`
+	KYCLevel        KYCLevel        `json:"kyc_level" gorm:"size:10;not null;default:'none'"`
+	ScreeningStatus ScreeningStatus `json:"screening_status" gorm:"size:10;not null;default:'clear'"`
+	ErasedAt        *time.Time      `json:"erased_at,omitempty"`
+	EmailVerifiedAt *time.Time      `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt  `json:"deleted_at" gorm:"index"`
+
+	// DeactivatedAt is set while an operator has deactivated the user. The
+	// user's wallets cannot send or receive money until reactivation.
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty" gorm:"index"`
+	DeactivationReason string     `json:"deactivation_reason,omitempty" gorm:"size:255"`
+}
+
+// Active reports whether the user may transact.
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// UserPage is one page of a user listing.
+type UserPage struct {
+	Users    []User `json:"users"`
+	Total    int64  `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
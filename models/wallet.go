@@ -8,20 +8,60 @@ import (
 
 type Wallet struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
+	TenantID  uint           `json:"tenant_id" gorm:"not null;default:1;index"`
 	UserID    uint           `json:"user_id" gorm:"not null"`
 	User      User           `json:"user" gorm:"foreignKey:UserID;references:ID"`
 	Balance   int64          `json:"balance" gorm:"default:0"`
+	Currency  string         `json:"currency" gorm:"size:3;not null;default:'USD'"`
+	FrozenAt  *time.Time     `json:"frozen_at,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// FrozenReason records why an operator froze the wallet. Frozen wallets
+	// cannot send or receive money.
+	FrozenReason string `json:"frozen_reason,omitempty" gorm:"size:255"`
+
+	// ParentID is set on pockets: named sub-wallets that set money aside
+	// within their parent wallet, optionally towards a target amount by a
+	// target date. Pockets only move money to and from their parent.
+	ParentID     *uint      `json:"parent_id,omitempty" gorm:"index"`
+	Name         string     `json:"name,omitempty" gorm:"size:100"`
+	TargetAmount *int64     `json:"target_amount,omitempty"`
+	TargetDate   *time.Time `json:"target_date,omitempty" gorm:"type:date"`
+
+	// ApprovalThreshold and RequiredApprovals make up a joint wallet's
+	// approval rule: transfers above the threshold wait until that many
+	// owners approve them. A nil threshold means no rule.
+	ApprovalThreshold *int64 `json:"approval_threshold,omitempty"`
+	RequiredApprovals int    `json:"required_approvals,omitempty" gorm:"not null;default:0"`
+
+	// Pockets and TotalBalance are filled in when a parent wallet is read:
+	// its pockets, and its balance together with theirs.
+	Pockets      []Wallet `json:"pockets,omitempty" gorm:"-"`
+	TotalBalance *int64   `json:"total_balance,omitempty" gorm:"-"`
+}
+
+// Frozen reports whether the wallet is frozen.
+func (w *Wallet) Frozen() bool {
+	return w.FrozenAt != nil
+}
+
+// IsPocket reports whether the wallet is a pocket of another wallet.
+func (w *Wallet) IsPocket() bool {
+	return w.ParentID != nil
 }
 
 
 //DTO
 type WalletResponse struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"user_id"`
-	Balance   int64     `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	Balance   int64      `json:"balance"`
+	Currency  string     `json:"currency"`
+	FrozenAt  *time.Time `json:"frozen_at,omitempty"`
+	ParentID  *uint      `json:"parent_id,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
\ No newline at end of file
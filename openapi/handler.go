@@ -0,0 +1,31 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Spec returns the document for the API served by this binary.
+func Spec() *Document {
+	return Build("Wallet API", "1.0.0", Operations)
+}
+
+// Handler serves the generated spec as JSON. The document is built once.
+func Handler() gin.HandlerFunc {
+	doc := Spec()
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// DocsHandler serves a self-contained HTML page that renders /openapi.json.
+func DocsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+	}
+}
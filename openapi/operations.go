@@ -0,0 +1,687 @@
+package openapi
+
+import (
+	"net/http"
+
+	"wallet-api/handlers"
+	"wallet-api/models"
+)
+
+// Operation describes one route of the API. Path uses gin syntax and is
+// relative to BasePath.
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	Tag         string
+	Query       []QueryParam
+	Request     interface{}
+	Response    interface{}
+	ContentType string
+	Status      int
+	// Alternates are further success responses, by status, with their bodies.
+	Alternates map[int]interface{}
+	Errors     []int
+	// Role, when set, is the API key role the route requires. Other routes
+	// may be called anonymously.
+	Role string
+}
+
+// QueryParam describes an optional or required query string parameter.
+type QueryParam struct {
+	Name        string
+	Required    bool
+	Format      string
+	Description string
+}
+
+func (op Operation) contentType() string {
+	if op.ContentType != "" {
+		return op.ContentType
+	}
+	return "application/json"
+}
+
+// BasePath is the prefix every operation is mounted under.
+const BasePath = "/api/v1"
+
+// ErrorResponse is the body of every non-2xx JSON response.
+type ErrorResponse struct {
+	Error string `json:"error" binding:"required"`
+}
+
+// MessageResponse is the body returned by endpoints that only acknowledge.
+type MessageResponse struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// AcceptedTransferResponse is returned with 202 when an AML rule or a
+// sanctions screening hit holds a transfer for review, with the held
+// transaction, or when it waits for approval by the owners of a joint
+// wallet, with the approval.
+type AcceptedTransferResponse struct {
+	Message       string `json:"message" binding:"required"`
+	TransactionID uint   `json:"transaction_id,omitempty"`
+	ApprovalID    uint   `json:"approval_id,omitempty"`
+}
+
+// Operations is the route table documented by the spec. It must list exactly
+// the routes mounted by handlers.RegisterRoutes.
+var Operations = []Operation{
+	// Tenants
+	{
+		ID: "createTenant", Method: http.MethodPost, Path: "/tenants", Tag: "tenants",
+		Summary:  "Create a tenant",
+		Request:  handlers.CreateTenantRequest{},
+		Response: models.Tenant{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+		Role:     models.APIKeyRoleAdmin,
+	},
+	{
+		ID: "listTenants", Method: http.MethodGet, Path: "/tenants", Tag: "tenants",
+		Summary:  "List tenants",
+		Response: []models.Tenant{},
+		Role:     models.APIKeyRoleAdmin,
+	},
+	{
+		ID: "getTenant", Method: http.MethodGet, Path: "/tenants/:id", Tag: "tenants",
+		Summary:  "Get a tenant",
+		Response: models.Tenant{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		Role:     models.APIKeyRoleAdmin,
+	},
+	{
+		ID: "updateTenant", Method: http.MethodPatch, Path: "/tenants/:id", Tag: "tenants",
+		Summary:  "Change a tenant's name, currencies, transfer fees, KYC limits or escrow wallets",
+		Request:  handlers.UpdateTenantRequest{},
+		Response: models.Tenant{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		Role:     models.APIKeyRoleAdmin,
+	},
+
+	// Users
+	{
+		ID: "createUser", Method: http.MethodPost, Path: "/users", Tag: "users",
+		Summary:  "Create a user",
+		Request:  models.User{},
+		Response: models.User{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "listUsers", Method: http.MethodGet, Path: "/users", Tag: "users",
+		Summary: "List users, oldest first, a page at a time",
+		Query: []QueryParam{
+			{Name: "name", Description: "name prefix, case-insensitive"},
+			{Name: "email", Description: "email prefix, case-insensitive"},
+			{Name: "created_from", Format: "date-time", Description: "created at or after, RFC 3339 timestamp or YYYY-MM-DD"},
+			{Name: "created_to", Format: "date-time", Description: "created before, RFC 3339 timestamp or YYYY-MM-DD"},
+			{Name: "status", Description: "active or deactivated"},
+			{Name: "page", Description: "page number, from 1"},
+			{Name: "page_size", Description: "users per page, default 50, at most 200"},
+		},
+		Response: models.UserPage{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getUser", Method: http.MethodGet, Path: "/users/:id", Tag: "users",
+		Summary:  "Get a user by ID",
+		Response: models.User{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "updateUser", Method: http.MethodPatch, Path: "/users/:id", Tag: "users",
+		Summary:  "Change a user's name or email",
+		Request:  handlers.UpdateUserRequest{},
+		Response: models.User{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		ID: "deactivateUser", Method: http.MethodPost, Path: "/users/:id/deactivate", Tag: "users",
+		Summary:  "Deactivate a user, stopping their wallets from moving money",
+		Request:  handlers.DeactivateUserRequest{},
+		Response: models.User{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "reactivateUser", Method: http.MethodPost, Path: "/users/:id/reactivate", Tag: "users",
+		Summary:  "Reactivate a deactivated user",
+		Response: models.User{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+
+	// Email verification
+	{
+		ID: "resendEmailVerification", Method: http.MethodPost, Path: "/users/:id/email-verification", Tag: "email",
+		Summary:  "Email the user a new link to verify their address",
+		Response: models.EmailVerification{},
+		Status:   http.StatusAccepted,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests},
+	},
+	{
+		ID: "verifyEmail", Method: http.MethodPost, Path: "/email-verification", Tag: "email",
+		Summary:  "Verify an email address with the token from a verification link",
+		Request:  handlers.VerifyEmailRequest{},
+		Response: models.User{},
+		Errors:   []int{http.StatusBadRequest, http.StatusGone},
+	},
+
+	// Privacy
+	{
+		ID: "exportUserData", Method: http.MethodGet, Path: "/users/:id/export", Tag: "privacy",
+		Summary:     "Download everything held about a user as a zip of JSON files",
+		Response:    "",
+		ContentType: "application/zip",
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "eraseUser", Method: http.MethodPost, Path: "/users/:id/erasure", Tag: "privacy",
+		Summary:  "Erase a user's personal data, keeping their financial records for the retention period",
+		Request:  handlers.EraseUserRequest{},
+		Response: models.Erasure{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "getUserErasure", Method: http.MethodGet, Path: "/users/:id/erasure", Tag: "privacy",
+		Summary:  "Get the record of a user's erasure",
+		Response: models.Erasure{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// KYC
+	{
+		ID: "submitVerification", Method: http.MethodPost, Path: "/users/:id/verifications", Tag: "kyc",
+		Summary:  "Submit identity documents to be verified at a KYC level",
+		Request:  handlers.SubmitVerificationRequest{},
+		Response: models.VerificationCase{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		ID: "listUserVerifications", Method: http.MethodGet, Path: "/users/:id/verifications", Tag: "kyc",
+		Summary:  "List the verification cases of a user",
+		Response: []models.VerificationCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "listVerifications", Method: http.MethodGet, Path: "/verifications", Tag: "kyc",
+		Summary:  "List verification cases, oldest first, for review",
+		Query:    []QueryParam{{Name: "status", Description: "pending, approved or rejected"}},
+		Response: []models.VerificationCase{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getVerification", Method: http.MethodGet, Path: "/verifications/:id", Tag: "kyc",
+		Summary:  "Get a verification case with its documents and history",
+		Response: models.VerificationCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "approveVerification", Method: http.MethodPost, Path: "/verifications/:id/approve", Tag: "kyc",
+		Summary:  "Approve a case and raise the user's KYC level",
+		Request:  handlers.ReviewVerificationRequest{},
+		Response: models.VerificationCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "rejectVerification", Method: http.MethodPost, Path: "/verifications/:id/reject", Tag: "kyc",
+		Summary:  "Reject a case with a note",
+		Request:  handlers.ReviewVerificationRequest{},
+		Response: models.VerificationCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+
+	// Wallets
+	{
+		ID: "createWallet", Method: http.MethodPost, Path: "/wallets", Tag: "wallets",
+		Summary:  "Create a wallet",
+		Request:  models.Wallet{},
+		Response: models.WalletResponse{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getWallet", Method: http.MethodGet, Path: "/wallets/:id", Tag: "wallets",
+		Summary:  "Get a wallet by ID",
+		Response: models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "listUserWallets", Method: http.MethodGet, Path: "/users/:id/wallets", Tag: "wallets",
+		Summary:  "List the wallets of a user",
+		Response: []models.WalletResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Pockets
+	{
+		ID: "createPocket", Method: http.MethodPost, Path: "/wallets/:id/pockets", Tag: "pockets",
+		Summary:  "Open a savings pocket under a wallet",
+		Request:  handlers.CreatePocketRequest{},
+		Response: models.Wallet{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		ID: "listPockets", Method: http.MethodGet, Path: "/wallets/:id/pockets", Tag: "pockets",
+		Summary:  "List the pockets of a wallet",
+		Response: []models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "updatePocket", Method: http.MethodPatch, Path: "/pockets/:id", Tag: "pockets",
+		Summary:  "Rename a pocket or change its target",
+		Request:  handlers.UpdatePocketRequest{},
+		Response: models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		ID: "closePocket", Method: http.MethodDelete, Path: "/pockets/:id", Tag: "pockets",
+		Summary:  "Close a pocket, moving its balance back to the parent wallet",
+		Response: models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "fundPocket", Method: http.MethodPost, Path: "/pockets/:id/fund", Tag: "pockets",
+		Summary:  "Move money from the parent wallet into a pocket",
+		Request:  handlers.PocketMoveRequest{},
+		Response: models.Transaction{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+	{
+		ID: "withdrawFromPocket", Method: http.MethodPost, Path: "/pockets/:id/withdraw", Tag: "pockets",
+		Summary:  "Move money from a pocket back to the parent wallet",
+		Request:  handlers.PocketMoveRequest{},
+		Response: models.Transaction{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+	},
+
+	// Joint wallets
+	{
+		ID: "addWalletMember", Method: http.MethodPost, Path: "/wallets/:id/members", Tag: "joint-wallets",
+		Summary:  "Share a wallet with a user as owner, spender or viewer",
+		Request:  handlers.AddMemberRequest{},
+		Response: models.WalletMember{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "listWalletMembers", Method: http.MethodGet, Path: "/wallets/:id/members", Tag: "joint-wallets",
+		Summary:  "List the members of a wallet, not including its primary owner",
+		Response: []models.WalletMember{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "updateWalletMember", Method: http.MethodPatch, Path: "/wallets/:id/members/:user_id", Tag: "joint-wallets",
+		Summary:  "Change a member's role or spending cap",
+		Request:  handlers.UpdateMemberRequest{},
+		Response: models.WalletMember{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "removeWalletMember", Method: http.MethodDelete, Path: "/wallets/:id/members/:user_id", Tag: "joint-wallets",
+		Summary:  "Remove a member from a wallet",
+		Response: MessageResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "setApprovalRule", Method: http.MethodPut, Path: "/wallets/:id/approval-rule", Tag: "joint-wallets",
+		Summary:  "Make transfers above a threshold wait for approval by N owners",
+		Request:  handlers.ApprovalRuleRequest{},
+		Response: models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "clearApprovalRule", Method: http.MethodDelete, Path: "/wallets/:id/approval-rule", Tag: "joint-wallets",
+		Summary:  "Remove a wallet's approval rule",
+		Response: models.Wallet{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "listTransferApprovals", Method: http.MethodGet, Path: "/wallets/:id/approvals", Tag: "joint-wallets",
+		Summary:  "List the approvals of transfers from a wallet, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "pending, approved, rejected or failed"}},
+		Response: []models.TransferApproval{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "getTransferApproval", Method: http.MethodGet, Path: "/approvals/:id", Tag: "joint-wallets",
+		Summary:  "Get a transfer approval with its votes",
+		Response: models.TransferApproval{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "approveTransfer", Method: http.MethodPost, Path: "/approvals/:id/approve", Tag: "joint-wallets",
+		Summary:  "Approve a transfer as an owner, making it once enough owners have",
+		Request:  handlers.DecideApprovalRequest{},
+		Response: models.TransferApproval{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "rejectTransfer", Method: http.MethodPost, Path: "/approvals/:id/reject", Tag: "joint-wallets",
+		Summary:  "Reject a transfer as an owner",
+		Request:  handlers.DecideApprovalRequest{},
+		Response: models.TransferApproval{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+
+	// Transfers
+	{
+		ID: "createTransfer", Method: http.MethodPost, Path: "/transfers", Tag: "transfers",
+		Summary:    "Transfer funds between wallets",
+		Request:    handlers.TransferRequest{},
+		Response:   MessageResponse{},
+		Alternates: map[int]interface{}{http.StatusAccepted: AcceptedTransferResponse{}},
+		Errors:     []int{http.StatusBadRequest, http.StatusForbidden},
+		Role:       models.APIKeyRoleUser,
+	},
+	{
+		ID: "createDeposit", Method: http.MethodPost, Path: "/deposits", Tag: "transfers",
+		Summary:  "Deposit funds into a wallet",
+		Request:  handlers.DepositRequest{},
+		Response: MessageResponse{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "listWalletTransactions", Method: http.MethodGet, Path: "/wallets/:id/transactions", Tag: "transfers",
+		Summary:  "List the transactions of a wallet",
+		Response: []models.TransferResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Payment requests
+	{
+		ID: "createPaymentRequest", Method: http.MethodPost, Path: "/payment-requests", Tag: "payment-requests",
+		Summary:  "Ask a wallet, or anyone for an open request, to pay into a wallet",
+		Request:  handlers.CreatePaymentRequestRequest{},
+		Response: models.PaymentRequest{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "getPaymentRequest", Method: http.MethodGet, Path: "/payment-requests/:id", Tag: "payment-requests",
+		Summary:  "Get a payment request",
+		Response: models.PaymentRequest{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "acceptPaymentRequest", Method: http.MethodPost, Path: "/payment-requests/:id/accept", Tag: "payment-requests",
+		Summary:    "Pay a payment request by a transfer to the requester",
+		Request:    handlers.AcceptPaymentRequestRequest{},
+		Response:   models.PaymentRequest{},
+		Alternates: map[int]interface{}{http.StatusAccepted: AcceptedTransferResponse{}},
+		Errors:     []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:       models.APIKeyRoleUser,
+	},
+	{
+		ID: "declinePaymentRequest", Method: http.MethodPost, Path: "/payment-requests/:id/decline", Tag: "payment-requests",
+		Summary:  "Decline a payment request as an owner of the paying wallet",
+		Response: models.PaymentRequest{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "cancelPaymentRequest", Method: http.MethodPost, Path: "/payment-requests/:id/cancel", Tag: "payment-requests",
+		Summary:  "Cancel a payment request as an owner of the requesting wallet",
+		Response: models.PaymentRequest{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "listIncomingPaymentRequests", Method: http.MethodGet, Path: "/wallets/:id/payment-requests/incoming", Tag: "payment-requests",
+		Summary:  "List the payment requests a wallet is asked to pay, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "pending, paid, declined, cancelled or expired"}},
+		Response: []models.PaymentRequest{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "listOutgoingPaymentRequests", Method: http.MethodGet, Path: "/wallets/:id/payment-requests/outgoing", Tag: "payment-requests",
+		Summary:  "List the payment requests made by a wallet, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "pending, paid, declined, cancelled or expired"}},
+		Response: []models.PaymentRequest{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Payment links
+	{
+		ID: "createPaymentLink", Method: http.MethodPost, Path: "/payment-links", Tag: "payment-links",
+		Summary:  "Create a single- or multi-use link paying a fixed amount into a wallet",
+		Request:  handlers.CreatePaymentLinkRequest{},
+		Response: models.PaymentLink{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "getPaymentLink", Method: http.MethodGet, Path: "/payment-links/:id", Tag: "payment-links",
+		Summary:  "Get a payment link with its token, URL and payments",
+		Response: models.PaymentLink{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "getPaymentLinkQR", Method: http.MethodGet, Path: "/payment-links/:id/qr", Tag: "payment-links",
+		Summary: "Render a payment link's URL as a QR code, PNG or SVG",
+		Query: []QueryParam{
+			{Name: "format", Description: "png (default) or svg"},
+			{Name: "size", Description: "width in pixels, 64 to 2048, default 256"},
+		},
+		Response:    "",
+		ContentType: "image/png",
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "revokePaymentLink", Method: http.MethodPost, Path: "/payment-links/:id/revoke", Tag: "payment-links",
+		Summary:  "Revoke a payment link as an owner of its wallet",
+		Response: models.PaymentLink{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "listWalletPaymentLinks", Method: http.MethodGet, Path: "/wallets/:id/payment-links", Tag: "payment-links",
+		Summary:  "List the payment links paying into a wallet, oldest first",
+		Response: []models.PaymentLink{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "resolvePaymentLink", Method: http.MethodGet, Path: "/pay/:token", Tag: "payment-links",
+		Summary:  "Show what a payment link token asks to be paid; needs no tenant",
+		Response: models.PaymentLink{},
+		Errors:   []int{http.StatusNotFound, http.StatusGone},
+	},
+	{
+		ID: "payPaymentLink", Method: http.MethodPost, Path: "/pay/:token", Tag: "payment-links",
+		Summary:    "Pay a payment link by a transfer to its wallet; needs no tenant",
+		Request:    handlers.PayPaymentLinkRequest{},
+		Response:   models.PaymentLink{},
+		Alternates: map[int]interface{}{http.StatusAccepted: AcceptedTransferResponse{}},
+		Errors:     []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusGone},
+		Role:       models.APIKeyRoleUser,
+	},
+
+	// Escrow
+	{
+		ID: "createEscrow", Method: http.MethodPost, Path: "/escrows", Tag: "escrow",
+		Summary:    "Pay from a buyer wallet into escrow for a seller",
+		Request:    handlers.CreateEscrowRequest{},
+		Response:   models.EscrowAgreement{},
+		Status:     http.StatusCreated,
+		Alternates: map[int]interface{}{http.StatusAccepted: models.EscrowAgreement{}},
+		Errors:     []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:       models.APIKeyRoleUser,
+	},
+	{
+		ID: "getEscrow", Method: http.MethodGet, Path: "/escrows/:id", Tag: "escrow",
+		Summary:  "Get an escrow agreement",
+		Response: models.EscrowAgreement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "confirmEscrow", Method: http.MethodPost, Path: "/escrows/:id/confirm", Tag: "escrow",
+		Summary:  "Confirm delivery as an owner of the buyer wallet, releasing the money to the seller",
+		Response: models.EscrowAgreement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "disputeEscrow", Method: http.MethodPost, Path: "/escrows/:id/dispute", Tag: "escrow",
+		Summary:  "Dispute an escrow agreement as an owner of the buyer or seller wallet",
+		Request:  handlers.DisputeEscrowRequest{},
+		Response: models.EscrowAgreement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleUser,
+	},
+	{
+		ID: "resolveEscrow", Method: http.MethodPost, Path: "/escrows/:id/resolve", Tag: "escrow",
+		Summary:  "Settle a dispute by releasing the money to the seller or refunding the buyer",
+		Request:  handlers.ResolveEscrowRequest{},
+		Response: models.EscrowAgreement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "listWalletEscrows", Method: http.MethodGet, Path: "/wallets/:id/escrows", Tag: "escrow",
+		Summary:  "List the escrow agreements a wallet buys or sells in, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "pending, funded, disputed, released, refunded or failed"}},
+		Response: []models.EscrowAgreement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Statements
+	{
+		ID: "getWalletBalance", Method: http.MethodGet, Path: "/wallets/:id/balance", Tag: "statements",
+		Summary: "Get the balance of a wallet at a point in time",
+		Query: []QueryParam{
+			{Name: "as_of", Format: "date-time", Description: "RFC 3339 timestamp or YYYY-MM-DD; defaults to now"},
+		},
+		Response: models.BalanceResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "getWalletStatement", Method: http.MethodGet, Path: "/wallets/:id/statement", Tag: "statements",
+		Summary: "Get a statement with opening, running and closing balances",
+		Query: []QueryParam{
+			{Name: "from", Required: true, Format: "date-time", Description: "RFC 3339 timestamp or YYYY-MM-DD"},
+			{Name: "to", Required: true, Format: "date-time", Description: "RFC 3339 timestamp or YYYY-MM-DD"},
+		},
+		Response: models.Statement{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "exportWalletStatement", Method: http.MethodGet, Path: "/wallets/:id/statement/export", Tag: "statements",
+		Summary: "Download a statement as CSV, OFX 2.x or ISO 20022 camt.053",
+		Query: []QueryParam{
+			{Name: "from", Required: true, Format: "date-time", Description: "RFC 3339 timestamp or YYYY-MM-DD"},
+			{Name: "to", Required: true, Format: "date-time", Description: "RFC 3339 timestamp or YYYY-MM-DD"},
+			{Name: "format", Description: "csv (default), ofx or camt053"},
+		},
+		Response:    "",
+		ContentType: "application/octet-stream",
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Reconciliation
+	{
+		ID: "runReconciliation", Method: http.MethodPost, Path: "/reconciliations", Tag: "reconciliation",
+		Summary:  "Snapshot balances and reconcile a UTC day",
+		Request:  handlers.ReconciliationRequest{},
+		Response: models.ReconciliationReport{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "listDiscrepancies", Method: http.MethodGet, Path: "/discrepancies", Tag: "reconciliation",
+		Summary:  "List reconciliation discrepancies",
+		Query:    []QueryParam{{Name: "status", Description: "open or resolved"}},
+		Response: []models.Discrepancy{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getDiscrepancy", Method: http.MethodGet, Path: "/discrepancies/:id", Tag: "reconciliation",
+		Summary:  "Get a discrepancy by ID",
+		Response: models.Discrepancy{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "resolveDiscrepancy", Method: http.MethodPost, Path: "/discrepancies/:id/resolve", Tag: "reconciliation",
+		Summary:  "Resolve a discrepancy with a note",
+		Request:  handlers.ResolveDiscrepancyRequest{},
+		Response: models.Discrepancy{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		Role:     models.APIKeyRoleOperator,
+	},
+
+	// AML
+	{
+		ID: "listAMLCases", Method: http.MethodGet, Path: "/aml/cases", Tag: "aml",
+		Summary:  "List AML cases, held transfers first, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "open, cleared or confirmed"}},
+		Response: []models.AMLCase{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getAMLCase", Method: http.MethodGet, Path: "/aml/cases/:id", Tag: "aml",
+		Summary:  "Get an AML case with its alerts",
+		Response: models.AMLCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "clearAMLCase", Method: http.MethodPost, Path: "/aml/cases/:id/clear", Tag: "aml",
+		Summary:  "Close a case as a false positive, booking a held transfer",
+		Request:  handlers.DecideAMLCaseRequest{},
+		Response: models.AMLCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "confirmAMLCase", Method: http.MethodPost, Path: "/aml/cases/:id/confirm", Tag: "aml",
+		Summary:  "Close a case as suspicious with a note, failing a held transfer",
+		Request:  handlers.DecideAMLCaseRequest{},
+		Response: models.AMLCase{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+
+	// Sanctions screening
+	{
+		ID: "listScreeningHits", Method: http.MethodGet, Path: "/screening/hits", Tag: "screening",
+		Summary:  "List sanctions screening hits, oldest first",
+		Query:    []QueryParam{{Name: "status", Description: "pending, cleared or confirmed"}},
+		Response: []models.ScreeningHit{},
+		Errors:   []int{http.StatusBadRequest},
+	},
+	{
+		ID: "getScreeningHit", Method: http.MethodGet, Path: "/screening/hits/:id", Tag: "screening",
+		Summary:  "Get a sanctions screening hit",
+		Response: models.ScreeningHit{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		ID: "clearScreeningHit", Method: http.MethodPost, Path: "/screening/hits/:id/clear", Tag: "screening",
+		Summary:  "Mark a hit a false positive, releasing its user or held transfer once no hits remain",
+		Request:  handlers.ReviewScreeningHitRequest{},
+		Response: models.ScreeningHit{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+	{
+		ID: "confirmScreeningHit", Method: http.MethodPost, Path: "/screening/hits/:id/confirm", Tag: "screening",
+		Summary:  "Confirm a hit with a note, blocking the user and failing a held transfer",
+		Request:  handlers.ReviewScreeningHitRequest{},
+		Response: models.ScreeningHit{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict},
+		Role:     models.APIKeyRoleOperator,
+	},
+}
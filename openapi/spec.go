@@ -0,0 +1,306 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version is the OpenAPI version emitted by Build.
+const Version = "3.1.0"
+
+// Document is the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps a lower-case HTTP method to its operation.
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	// Security lists the alternative ways to authenticate; an empty
+	// requirement allows anonymous calls.
+	Security []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+// apiKeyScheme names the API key security scheme.
+const apiKeyScheme = "apiKey"
+
+// Schema is the subset of JSON Schema used by the API.
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       interface{}        `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// Build generates the OpenAPI document for the given operations. Request and
+// response schemas are derived from the Go types by reflecting over their
+// json and binding tags.
+func Build(title, version string, ops []Operation) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{
+				apiKeyScheme: {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "An API key issued with `wallet-api api-key create`.",
+				},
+			},
+		},
+	}
+	errorSchema := doc.schemaFor(reflect.TypeOf(ErrorResponse{}))
+
+	for _, op := range ops {
+		path := specPath(BasePath + op.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+
+		obj := &OperationObject{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Tags:        []string{op.Tag},
+			Responses:   map[string]Response{},
+			Security:    []map[string][]string{{}, {apiKeyScheme: {}}},
+		}
+		errorCodes := append(append([]int{}, op.Errors...), http.StatusUnauthorized, http.StatusTooManyRequests)
+		if op.Role != "" {
+			obj.Security = []map[string][]string{{apiKeyScheme: {}}}
+			errorCodes = append(errorCodes, http.StatusForbidden)
+		}
+		for _, name := range pathParams(op.Path) {
+			obj.Parameters = append(obj.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer", Minimum: zero()},
+			})
+		}
+		for _, q := range op.Query {
+			obj.Parameters = append(obj.Parameters, Parameter{
+				Name:        q.Name,
+				In:          "query",
+				Required:    q.Required,
+				Description: q.Description,
+				Schema:      &Schema{Type: "string", Format: q.Format},
+			})
+		}
+		if op.Request != nil {
+			obj.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					"application/json": {Schema: doc.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		obj.Responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status),
+			Content: map[string]MediaType{
+				op.contentType(): {Schema: doc.schemaFor(reflect.TypeOf(op.Response))},
+			},
+		}
+		for code, body := range op.Alternates {
+			obj.Responses[strconv.Itoa(code)] = Response{
+				Description: http.StatusText(code),
+				Content: map[string]MediaType{
+					op.contentType(): {Schema: doc.schemaFor(reflect.TypeOf(body))},
+				},
+			}
+		}
+		// Every route sits behind the authenticator, which refuses unknown
+		// keys, and the rate limiter.
+		for _, code := range errorCodes {
+			obj.Responses[strconv.Itoa(code)] = Response{
+				Description: http.StatusText(code),
+				Content: map[string]MediaType{
+					"application/json": {Schema: errorSchema},
+				},
+			}
+		}
+
+		item[strings.ToLower(op.Method)] = obj
+	}
+
+	return doc
+}
+
+// schemaFor returns the schema for t, registering named structs as
+// components and referencing them.
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: []string{"string", "null"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := d.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: zero()}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Register a placeholder first so self-referencing types terminate.
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = d.schemaFor(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// specPath converts a gin path ("/wallets/:id") to an OpenAPI path
+// ("/wallets/{id}").
+func specPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, p := range strings.Split(path, "/") {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			params = append(params, p[1:])
+		}
+	}
+	return params
+}
+
+func zero() *float64 {
+	z := 0.0
+	return &z
+}
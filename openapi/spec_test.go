@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"wallet-api/handlers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers.RegisterRoutes(router.Group(BasePath), handlers.Handlers{
+		User:           handlers.NewUserHandler(nil),
+		Wallet:         handlers.NewWalletHandler(nil),
+		Pocket:         handlers.NewPocketHandler(nil),
+		Membership:     handlers.NewMembershipHandler(nil),
+		Approval:       handlers.NewApprovalHandler(nil),
+		Transfer:       handlers.NewTransferHandler(nil),
+		PaymentRequest: handlers.NewPaymentRequestHandler(nil),
+		PaymentLink:    handlers.NewPaymentLinkHandler(nil),
+		Escrow:         handlers.NewEscrowHandler(nil),
+		Statement:      handlers.NewStatementHandler(nil),
+		Reconciliation: handlers.NewReconciliationHandler(nil),
+		KYC:            handlers.NewKYCHandler(nil),
+		AML:            handlers.NewAMLHandler(nil),
+		Screening:      handlers.NewScreeningHandler(nil),
+		Privacy:        handlers.NewPrivacyHandler(nil),
+		Email:          handlers.NewEmailVerificationHandler(nil),
+		Tenant:         handlers.NewTenantHandler(nil),
+	})
+	return router
+}
+
+func TestSpec_MatchesRouter(t *testing.T) {
+	router := setupRouter()
+
+	var routed []string
+	for _, r := range router.Routes() {
+		routed = append(routed, strings.ToLower(r.Method)+" "+specPath(r.Path))
+	}
+
+	var documented []string
+	for path, item := range Spec().Paths {
+		for method := range item {
+			documented = append(documented, method+" "+path)
+		}
+	}
+
+	sort.Strings(routed)
+	sort.Strings(documented)
+	assert.Equal(t, routed, documented, "routes registered by handlers.RegisterRoutes and openapi.Operations disagree")
+}
+
+func TestSpec_Schemas(t *testing.T) {
+	doc := Spec()
+
+	t.Run("request types", func(t *testing.T) {
+		transfer := doc.Components.Schemas["TransferRequest"]
+		if assert.NotNil(t, transfer) {
+			assert.Equal(t, []string{"amount", "source_wallet_id", "target_wallet_id"}, transfer.Required)
+			assert.Equal(t, "int64", transfer.Properties["amount"].Format)
+		}
+
+		deposit := doc.Components.Schemas["DepositRequest"]
+		if assert.NotNil(t, deposit) {
+			assert.Equal(t, []string{"amount", "wallet_id"}, deposit.Required)
+		}
+	})
+
+	t.Run("response types", func(t *testing.T) {
+		for _, name := range []string{"WalletResponse", "TransferResponse", "ErrorResponse"} {
+			assert.Contains(t, doc.Components.Schemas, name)
+		}
+
+		transfer := doc.Components.Schemas["TransferResponse"]
+		assert.Equal(t, []string{"integer", "null"}, transfer.Properties["source_wallet_id"].Type)
+		assert.Equal(t, "date-time", transfer.Properties["created_at"].Format)
+	})
+
+	t.Run("path parameters", func(t *testing.T) {
+		op := doc.Paths["/api/v1/wallets/{id}"]["get"]
+		if assert.NotNil(t, op) && assert.Len(t, op.Parameters, 1) {
+			assert.Equal(t, "id", op.Parameters[0].Name)
+			assert.Equal(t, "path", op.Parameters[0].In)
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/openapi.json", Handler())
+	router.GET("/docs", DocsHandler())
+
+	t.Run("spec", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var doc map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, Version, doc["openapi"])
+	})
+
+	t.Run("docs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), "/openapi.json")
+	})
+}
@@ -0,0 +1,86 @@
+// Package paylink signs the tokens payment links are shared by and renders
+// them as QR codes.
+package paylink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for a token that is malformed or was not
+// signed with the signer's secret.
+var ErrInvalidToken = errors.New("invalid payment link token")
+
+// ErrExpiredToken is returned for a correctly signed token past its expiry.
+var ErrExpiredToken = errors.New("payment link expired")
+
+// Claims are what a token vouches for: the link, the tenant it belongs to
+// and until when it may be used.
+type Claims struct {
+	LinkID    uint
+	TenantID  uint
+	ExpiresAt time.Time
+}
+
+// Signer issues and checks tokens with HMAC-SHA256. A token is the claims in
+// plain decimal followed by the signature, "<tenant>.<link>.<expiry>.<mac>",
+// so it is short enough for a small QR code and safe in a URL path.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a Signer using secret, which should be at least 32
+// random bytes.
+func NewSigner(secret []byte) *Signer {
+	return &Signer{key: secret}
+}
+
+// Sign returns the token for claims. Expiry is kept to the second.
+func (s *Signer) Sign(claims Claims) string {
+	payload := fmt.Sprintf("%d.%d.%d", claims.TenantID, claims.LinkID, claims.ExpiresAt.Unix())
+	return payload + "." + s.mac(payload)
+}
+
+// Verify returns the claims of token if it is correctly signed and not
+// expired at now.
+func (s *Signer) Verify(token string, now time.Time) (Claims, error) {
+	cut := strings.LastIndexByte(token, '.')
+	if cut < 0 || !hmac.Equal([]byte(token[cut+1:]), []byte(s.mac(token[:cut]))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	parts := strings.Split(token[:cut], ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+	var values [3]uint64
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 63)
+		if err != nil {
+			return Claims{}, ErrInvalidToken
+		}
+		values[i] = value
+	}
+
+	claims := Claims{
+		TenantID:  uint(values[0]),
+		LinkID:    uint(values[1]),
+		ExpiresAt: time.Unix(int64(values[2]), 0),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return claims, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (s *Signer) mac(payload string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
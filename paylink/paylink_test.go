@@ -0,0 +1,83 @@
+package paylink
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	claims := Claims{LinkID: 42, TenantID: 3, ExpiresAt: now.Add(time.Hour)}
+	token := signer.Sign(claims)
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := signer.Verify(token, now)
+		require.NoError(t, err)
+		assert.Equal(t, claims.LinkID, got.LinkID)
+		assert.Equal(t, claims.TenantID, got.TenantID)
+		assert.True(t, claims.ExpiresAt.Equal(got.ExpiresAt))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Verify(token, now.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrExpiredToken)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		forged := strings.Replace(token, "3.42.", "3.43.", 1)
+		_, err := signer.Verify(forged, now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("another secret", func(t *testing.T) {
+		_, err := NewSigner([]byte("another secret")).Verify(token, now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "1.2.sig", "a.b.c.sig"} {
+			_, err := signer.Verify(token, now)
+			assert.ErrorIs(t, err, ErrInvalidToken, token)
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	content := "https://wallet.example/api/v1/pay/1.42.1748779200.signature"
+
+	t.Run("png", func(t *testing.T) {
+		out, err := Render(FormatPNG, content, 256)
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(out))
+		require.NoError(t, err)
+		assert.Equal(t, 256, img.Bounds().Dx())
+	})
+
+	t.Run("svg", func(t *testing.T) {
+		out, err := Render(FormatSVG, content, 256)
+		require.NoError(t, err)
+		var doc struct {
+			XMLName xml.Name `xml:"svg"`
+			Width   int      `xml:"width,attr"`
+			ViewBox string   `xml:"viewBox,attr"`
+		}
+		require.NoError(t, xml.Unmarshal(out, &doc))
+		assert.Equal(t, 256, doc.Width)
+		assert.True(t, strings.HasPrefix(doc.ViewBox, "0 0 "))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Render("gif", content, 256)
+		assert.Error(t, err)
+		_, err = Render(FormatPNG, content, 10)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,77 @@
+package paylink
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// QR code formats.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// MinSize and MaxSize bound the width of PNG QR codes, in pixels.
+const (
+	MinSize = 64
+	MaxSize = 2048
+)
+
+// ContentType returns the MIME type of a QR code format.
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes content as a QR code in format. PNGs are size pixels wide;
+// SVGs scale to whatever size they are shown at, so size only sets their
+// width and height attributes.
+func Render(format, content string, size int) ([]byte, error) {
+	if size < MinSize || size > MaxSize {
+		return nil, fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	// Medium recovery survives some smudging on a printed code while keeping
+	// it small enough to scan from a phone screen.
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatPNG:
+		return code.PNG(size)
+	case FormatSVG:
+		return svg(code.Bitmap(), size), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q: use png or svg", format)
+}
+
+// svg draws bitmap, quiet zone included, one unit per module. Each run of
+// dark modules in a row is a single rectangle in the path.
+func svg(bitmap [][]bool, size int) []byte {
+	var path bytes.Buffer
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, len(bitmap), len(bitmap))
+	out.WriteString(`<rect width="100%" height="100%" fill="#fff"/>`)
+	fmt.Fprintf(&out, `<path fill="#000" d="%s"/></svg>`, path.String())
+	out.WriteByte('\n')
+	return out.Bytes()
+}
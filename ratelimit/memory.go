@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// use PostgresStore when several instances serve the same clients.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens     float64
+	refilledAt time.Time
+	period     time.Duration
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+var _ Store = &MemoryStore{}
+
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(policy.Limit), refilledAt: now}
+		s.buckets[key] = b
+	}
+	var result Result
+	b.tokens, result = take(b.tokens, b.refilledAt, now, policy)
+	b.refilledAt = now
+	b.period = policy.Period
+	return result, nil
+}
+
+// sweep drops buckets that have had a full period to refill; they are
+// indistinguishable from new ones. It runs at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.refilledAt) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
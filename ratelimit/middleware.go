@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-api/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller, for
+// example "api_key:<id>" or "user:<id>". Authentication middleware sets it;
+// requests without one are limited by client IP.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the principal stored in ctx, or "".
+func Principal(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
+
+// Limiter applies a Policy per route class.
+type Limiter struct {
+	store    Store
+	policies map[Class]Policy
+	now      func() time.Time
+}
+
+func NewLimiter(store Store, policies map[Class]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies, now: time.Now}
+}
+
+// Middleware limits the routes it is attached to under the policy for class.
+// Routes whose class has no policy are not limited. It sets the RateLimit-*
+// headers from draft-ietf-httpapi-ratelimit-headers on every response and
+// answers 429 with Retry-After once the bucket is empty. If the store fails
+// the request is let through: an outage of the limiter should not become an
+// outage of the API.
+func (l *Limiter) Middleware(class Class) gin.HandlerFunc {
+	policy, ok := l.policies[class]
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	policyHeader := strconv.Itoa(policy.Limit) + ";w=" + strconv.Itoa(int(policy.Period.Seconds()))
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		result, err := l.store.Take(ctx, Key(c, class), policy, l.now())
+		if err != nil {
+			logging.For("ratelimit").ErrorContext(ctx, "rate limit store failed; allowing request",
+				"class", string(class), "error", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", seconds(result.Reset))
+		h.Set("RateLimit-Policy", policyHeader)
+
+		if !result.Allowed {
+			h.Set("Retry-After", seconds(result.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Key identifies the bucket for a request: the principal if the request is
+// authenticated, otherwise the client IP.
+func Key(c *gin.Context, class Class) string {
+	if p := Principal(c.Request.Context()); p != "" {
+		return string(class) + ":" + p
+	}
+	return string(class) + ":ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance enforces the same limits. Each Take locks the bucket's row for
+// the duration of a short transaction.
+type PostgresStore struct {
+	db *gorm.DB
+	// PruneInterval is how often Run deletes buckets idle for PruneAfter.
+	PruneInterval time.Duration
+	PruneAfter    time.Duration
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{
+		db:            db,
+		PruneInterval: time.Hour,
+		PruneAfter:    24 * time.Hour,
+	}
+}
+
+var _ Store = &PostgresStore{}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bucket := models.RateLimitBucket{Key: key, Tokens: float64(policy.Limit), RefilledAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&bucket, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		bucket.Tokens, result = take(bucket.Tokens, bucket.RefilledAt, now, policy)
+		bucket.RefilledAt = now
+		return tx.Save(&bucket).Error
+	})
+	return result, err
+}
+
+// Prune deletes buckets not touched since before. They would be full again
+// by now, so dropping them changes no decision.
+func (s *PostgresStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).Where("refilled_at < ?", before).Delete(&models.RateLimitBucket{})
+	return res.RowsAffected, res.Error
+}
+
+// Run prunes idle buckets every PruneInterval until ctx is cancelled.
+func (s *PostgresStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := s.Prune(ctx, time.Now().Add(-s.PruneAfter))
+		logger := logging.For("ratelimit")
+		if err != nil {
+			logger.ErrorContext(ctx, "pruning rate limit buckets failed", "error", err)
+			continue
+		}
+		logger.DebugContext(ctx, "pruned rate limit buckets", "deleted", deleted)
+	}
+}
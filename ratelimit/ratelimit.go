@@ -0,0 +1,72 @@
+// Package ratelimit limits requests with token buckets kept in a pluggable
+// store, one bucket per route class and client.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Class groups routes that share a policy.
+type Class string
+
+const (
+	ClassRead   Class = "read"
+	ClassWrite  Class = "write"
+	ClassMoney  Class = "money"
+	ClassSignup Class = "signup"
+)
+
+// Policy allows Limit requests per Period. The bucket holds at most Limit
+// tokens and refills continuously, so a client may burst up to Limit and
+// then sustain Limit/Period.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (p Policy) perSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets. Take refills the bucket for key as of now and removes
+// one token if there is one. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// take applies the token bucket algorithm to a bucket that held tokens at
+// refilledAt and returns the tokens left after this request.
+func take(tokens float64, refilledAt, now time.Time, policy Policy) (float64, Result) {
+	capacity := float64(policy.Limit)
+	if elapsed := now.Sub(refilledAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*policy.perSecond())
+	}
+
+	result := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / policy.perSecond())
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((capacity - tokens) / policy.perSecond())
+	return tokens, result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTake(t *testing.T) {
+	policy := Policy{Limit: 10, Period: 10 * time.Second}
+
+	t.Run("allows a full burst", func(t *testing.T) {
+		tokens, result := take(10, start, start, policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 9.0, tokens)
+		assert.Equal(t, 9, result.Remaining)
+		assert.Equal(t, time.Second, result.Reset)
+	})
+
+	t.Run("rejects an empty bucket", func(t *testing.T) {
+		tokens, result := take(0.25, start, start, policy)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0.25, tokens, "a rejected request costs nothing")
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 750*time.Millisecond, result.RetryAfter)
+		assert.Equal(t, 9750*time.Millisecond, result.Reset)
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		tokens, result := take(0, start, start.Add(2500*time.Millisecond), policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1.5, tokens)
+	})
+
+	t.Run("never exceeds the limit", func(t *testing.T) {
+		tokens, _ := take(5, start, start.Add(time.Hour), policy)
+		assert.Equal(t, 9.0, tokens)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Limit: 2, Period: time.Minute}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "a", policy, start)
+		require.NoError(t, err)
+		assert.Equal(t, want, result.Allowed, "request %d", i)
+	}
+
+	result, _ := store.Take(ctx, "b", policy, start)
+	assert.True(t, result.Allowed, "keys have separate buckets")
+
+	result, _ = store.Take(ctx, "a", policy, start.Add(30*time.Second))
+	assert.True(t, result.Allowed, "one token is back after half the period")
+
+	// Buckets idle for a full period are swept
+	store.Take(ctx, "c", policy, start.Add(2*time.Minute))
+	assert.Len(t, store.buckets, 1)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Policy, time.Time) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func serve(l *Limiter, class Class, principal, remoteAddr string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if principal != "" {
+			c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+		}
+	})
+	router.POST("/transfers", l.Middleware(class), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"key": Key(c, class)})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/transfers", nil)
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	newLimiter := func() *Limiter {
+		l := NewLimiter(NewMemoryStore(), map[Class]Policy{ClassMoney: {Limit: 2, Period: time.Minute}})
+		l.now = func() time.Time { return start }
+		return l
+	}
+
+	t.Run("headers and 429", func(t *testing.T) {
+		l := newLimiter()
+
+		w := serve(l, ClassMoney, "", "203.0.113.7:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+		assert.JSONEq(t, `{"key":"money:ip:203.0.113.7"}`, w.Body.String())
+
+		serve(l, ClassMoney, "", "203.0.113.7:1234")
+		w = serve(l, ClassMoney, "", "203.0.113.7:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"rate limit exceeded"}`, w.Body.String())
+	})
+
+	t.Run("principal is preferred over ip", func(t *testing.T) {
+		l := newLimiter()
+
+		w := serve(l, ClassMoney, "api_key:k1", "203.0.113.7:1234")
+		assert.JSONEq(t, `{"key":"money:api_key:k1"}`, w.Body.String())
+
+		serve(l, ClassMoney, "api_key:k1", "198.51.100.1:1234")
+		w = serve(l, ClassMoney, "api_key:k1", "192.0.2.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "the key follows the principal across addresses")
+	})
+
+	t.Run("class without policy", func(t *testing.T) {
+		w := serve(newLimiter(), ClassRead, "", "203.0.113.7:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("store failure lets requests through", func(t *testing.T) {
+		l := NewLimiter(failingStore{}, map[Class]Policy{ClassMoney: {Limit: 1, Period: time.Minute}})
+
+		w := serve(l, ClassMoney, "", "203.0.113.7:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AMLCaseRepository struct {
+	DB *gorm.DB
+}
+
+func NewAMLCaseRepository(db *gorm.DB) *AMLCaseRepository {
+	return &AMLCaseRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *AMLCaseRepository) WithContext(ctx context.Context) *AMLCaseRepository {
+	return &AMLCaseRepository{DB: r.DB.WithContext(ctx)}
+}
+
+// Create inserts the case together with its alerts.
+func (r *AMLCaseRepository) Create(amlCase *models.AMLCase) error {
+	return r.DB.Create(amlCase).Error
+}
+
+// GetByID returns the case with its alerts.
+func (r *AMLCaseRepository) GetByID(id uint) (*models.AMLCase, error) {
+	var amlCase models.AMLCase
+	err := r.withAlerts().First(&amlCase, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &amlCase, nil
+}
+
+// GetForUpdate locks the case row until the surrounding transaction ends.
+func (r *AMLCaseRepository) GetForUpdate(id uint) (*models.AMLCase, error) {
+	var amlCase models.AMLCase
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&amlCase, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &amlCase, nil
+}
+
+// List returns cases oldest first, held ones ahead of the rest since they
+// block a customer's transfer, optionally filtered by status.
+func (r *AMLCaseRepository) List(status string) ([]models.AMLCase, error) {
+	var cases []models.AMLCase
+	query := r.withAlerts().Order("held DESC").Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&cases).Error; err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+func (r *AMLCaseRepository) Save(amlCase *models.AMLCase) error {
+	return r.DB.Omit(clause.Associations).Save(amlCase).Error
+}
+
+func (r *AMLCaseRepository) withAlerts() *gorm.DB {
+	return r.DB.Preload("Alerts", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
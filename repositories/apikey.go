@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *APIKeyRepository) WithContext(ctx context.Context) *APIKeyRepository {
+	return &APIKeyRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	return r.DB.Create(key).Error
+}
+
+func (r *APIKeyRepository) GetByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash finds the key whose secret hashes to hash.
+func (r *APIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.Where("hash = ?", hash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepository) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.DB.Order("id").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Save(key *models.APIKey) error {
+	return r.DB.Save(key).Error
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EscrowRepository struct {
+	DB *gorm.DB
+}
+
+func NewEscrowRepository(db *gorm.DB) *EscrowRepository {
+	return &EscrowRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *EscrowRepository) WithContext(ctx context.Context) *EscrowRepository {
+	return &EscrowRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *EscrowRepository) Create(agreement *models.EscrowAgreement) error {
+	return r.DB.Create(agreement).Error
+}
+
+func (r *EscrowRepository) GetByID(id uint) (*models.EscrowAgreement, error) {
+	var agreement models.EscrowAgreement
+	err := r.DB.First(&agreement, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &agreement, nil
+}
+
+// GetForUpdate locks the agreement row until the surrounding transaction
+// ends.
+func (r *EscrowRepository) GetForUpdate(id uint) (*models.EscrowAgreement, error) {
+	var agreement models.EscrowAgreement
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&agreement, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &agreement, nil
+}
+
+// ListByWallet returns the agreements the wallet buys or sells in, oldest
+// first, optionally filtered by status.
+func (r *EscrowRepository) ListByWallet(walletID uint, status string) ([]models.EscrowAgreement, error) {
+	agreements := []models.EscrowAgreement{}
+	query := r.DB.Where("(buyer_wallet_id = ? OR seller_wallet_id = ?)", walletID, walletID).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&agreements).Error; err != nil {
+		return nil, err
+	}
+	return agreements, nil
+}
+
+// ListDue returns the agreements the timeout sweep has to look at: those
+// still funded at now, and those whose funding is held for review.
+func (r *EscrowRepository) ListDue(now time.Time) ([]models.EscrowAgreement, error) {
+	var agreements []models.EscrowAgreement
+	err := r.DB.Where("(status = ? AND expires_at <= ?) OR status = ?",
+		models.EscrowStatusFunded, now, models.EscrowStatusPending).
+		Order("id").
+		Find(&agreements).Error
+	return agreements, err
+}
+
+func (r *EscrowRepository) Save(agreement *models.EscrowAgreement) error {
+	return r.DB.Save(agreement).Error
+}
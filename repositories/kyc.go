@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VerificationCaseRepository struct {
+	DB *gorm.DB
+}
+
+func NewVerificationCaseRepository(db *gorm.DB) *VerificationCaseRepository {
+	return &VerificationCaseRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *VerificationCaseRepository) WithContext(ctx context.Context) *VerificationCaseRepository {
+	return &VerificationCaseRepository{DB: r.DB.WithContext(ctx)}
+}
+
+// Create inserts the case together with its documents and history.
+func (r *VerificationCaseRepository) Create(verificationCase *models.VerificationCase) error {
+	return r.DB.Create(verificationCase).Error
+}
+
+// GetByID returns the case with its documents and history, oldest first.
+func (r *VerificationCaseRepository) GetByID(id uint) (*models.VerificationCase, error) {
+	var verificationCase models.VerificationCase
+	err := r.withDetails().First(&verificationCase, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verificationCase, nil
+}
+
+// GetForUpdate locks the case row until the surrounding transaction ends.
+func (r *VerificationCaseRepository) GetForUpdate(id uint) (*models.VerificationCase, error) {
+	var verificationCase models.VerificationCase
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&verificationCase, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verificationCase, nil
+}
+
+// List returns cases oldest first, so the review queue is worked in order,
+// optionally filtered by status.
+func (r *VerificationCaseRepository) List(status string) ([]models.VerificationCase, error) {
+	var cases []models.VerificationCase
+	query := r.withDetails().Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&cases).Error; err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// ListByUserID returns a user's cases newest first.
+func (r *VerificationCaseRepository) ListByUserID(userID uint) ([]models.VerificationCase, error) {
+	var cases []models.VerificationCase
+	err := r.withDetails().Where("user_id = ?", userID).Order("id DESC").Find(&cases).Error
+	if err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// HasPending reports whether the user has a case awaiting review.
+func (r *VerificationCaseRepository) HasPending(userID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.VerificationCase{}).
+		Where("user_id = ? AND status = ?", userID, models.VerificationStatusPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *VerificationCaseRepository) Save(verificationCase *models.VerificationCase) error {
+	return r.DB.Omit(clause.Associations).Save(verificationCase).Error
+}
+
+func (r *VerificationCaseRepository) AddEvent(event *models.VerificationEvent) error {
+	return r.DB.Create(event).Error
+}
+
+// Pseudonymize blanks the notes on the user's cases and their history and
+// the document references, keeping the cases and their outcomes.
+func (r *VerificationCaseRepository) Pseudonymize(userID uint) error {
+	cases := r.DB.Model(&models.VerificationCase{}).Select("id").Where("user_id = ?", userID)
+	if err := r.DB.Model(&models.VerificationCase{}).Where("user_id = ?", userID).
+		Update("review_note", "").Error; err != nil {
+		return err
+	}
+	if err := r.DB.Model(&models.VerificationEvent{}).Where("case_id IN (?)", cases).
+		Update("note", "").Error; err != nil {
+		return err
+	}
+	return r.DB.Model(&models.VerificationDocument{}).Where("case_id IN (?)", cases).
+		Update("reference", "erased").Error
+}
+
+func (r *VerificationCaseRepository) withDetails() *gorm.DB {
+	return r.DB.
+		Preload("Documents", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmailVerificationRepository struct {
+	DB *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *EmailVerificationRepository) WithContext(ctx context.Context) *EmailVerificationRepository {
+	return &EmailVerificationRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *EmailVerificationRepository) Create(verification *models.EmailVerification) error {
+	return r.DB.Create(verification).Error
+}
+
+// GetByTokenHashForUpdate loads the verification with the given token hash
+// and locks its row until the surrounding transaction ends.
+func (r *EmailVerificationRepository) GetByTokenHashForUpdate(tokenHash string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Latest returns the most recently issued verification for the user.
+func (r *EmailVerificationRepository) Latest(userID uint) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC, id DESC").First(&verification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// ExpireOutstanding expires every unused, unexpired verification for the
+// user at now, so only the next token issued can be redeemed.
+func (r *EmailVerificationRepository) ExpireOutstanding(userID uint, now time.Time) error {
+	return r.DB.Model(&models.EmailVerification{}).
+		Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, now).
+		Update("expires_at", now).Error
+}
+
+// DeleteByUserID deletes every verification sent to the user, with the
+// addresses they were sent to.
+func (r *EmailVerificationRepository) DeleteByUserID(userID uint) error {
+	return r.DB.Where("user_id = ?", userID).Delete(&models.EmailVerification{}).Error
+}
+
+// MarkUsed records that the verification was redeemed at usedAt.
+func (r *EmailVerificationRepository) MarkUsed(id uint, usedAt time.Time) error {
+	result := r.DB.Model(&models.EmailVerification{}).Where("id = ?", id).Update("used_at", usedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WalletMemberRepository struct {
+	DB *gorm.DB
+}
+
+func NewWalletMemberRepository(db *gorm.DB) *WalletMemberRepository {
+	return &WalletMemberRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *WalletMemberRepository) WithContext(ctx context.Context) *WalletMemberRepository {
+	return &WalletMemberRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *WalletMemberRepository) Create(member *models.WalletMember) error {
+	return r.DB.Create(member).Error
+}
+
+// Get returns the user's membership of the wallet.
+func (r *WalletMemberRepository) Get(walletID, userID uint) (*models.WalletMember, error) {
+	var member models.WalletMember
+	err := r.DB.Where("wallet_id = ? AND user_id = ?", walletID, userID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListByWallet returns the members of a wallet in the order they joined.
+func (r *WalletMemberRepository) ListByWallet(walletID uint) ([]models.WalletMember, error) {
+	members := []models.WalletMember{}
+	err := r.DB.Where("wallet_id = ?", walletID).Order("id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// CountOwners counts the members of a wallet with the owner role, not
+// including its primary owner.
+func (r *WalletMemberRepository) CountOwners(walletID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.WalletMember{}).
+		Where("wallet_id = ? AND role = ?", walletID, models.WalletRoleOwner).
+		Count(&count).Error
+	return count, err
+}
+
+// Update saves the member's role and spending cap.
+func (r *WalletMemberRepository) Update(member *models.WalletMember) error {
+	return r.DB.Model(member).Select("role", "spending_cap").Updates(member).Error
+}
+
+func (r *WalletMemberRepository) Delete(walletID, userID uint) error {
+	result := r.DB.Where("wallet_id = ? AND user_id = ?", walletID, userID).Delete(&models.WalletMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes the user from every wallet they are a member of.
+func (r *WalletMemberRepository) DeleteByUserID(userID uint) error {
+	return r.DB.Where("user_id = ?", userID).Delete(&models.WalletMember{}).Error
+}
+
+type TransferApprovalRepository struct {
+	DB *gorm.DB
+}
+
+func NewTransferApprovalRepository(db *gorm.DB) *TransferApprovalRepository {
+	return &TransferApprovalRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *TransferApprovalRepository) WithContext(ctx context.Context) *TransferApprovalRepository {
+	return &TransferApprovalRepository{DB: r.DB.WithContext(ctx)}
+}
+
+// Create inserts the approval together with its votes.
+func (r *TransferApprovalRepository) Create(approval *models.TransferApproval) error {
+	return r.DB.Create(approval).Error
+}
+
+// GetByID returns the approval with its votes.
+func (r *TransferApprovalRepository) GetByID(id uint) (*models.TransferApproval, error) {
+	var approval models.TransferApproval
+	err := r.withVotes().First(&approval, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// GetForUpdate locks the approval row until the surrounding transaction
+// ends and returns it with its votes.
+func (r *TransferApprovalRepository) GetForUpdate(id uint) (*models.TransferApproval, error) {
+	var approval models.TransferApproval
+	err := r.withVotes().Clauses(clause.Locking{Strength: "UPDATE"}).First(&approval, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// ListByWallet returns the approvals of transfers from a wallet, oldest
+// first, optionally filtered by status.
+func (r *TransferApprovalRepository) ListByWallet(walletID uint, status string) ([]models.TransferApproval, error) {
+	approvals := []models.TransferApproval{}
+	query := r.withVotes().Where("source_wallet_id = ?", walletID).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+func (r *TransferApprovalRepository) AddVote(vote *models.ApprovalVote) error {
+	return r.DB.Create(vote).Error
+}
+
+func (r *TransferApprovalRepository) Save(approval *models.TransferApproval) error {
+	return r.DB.Omit(clause.Associations).Save(approval).Error
+}
+
+func (r *TransferApprovalRepository) withVotes() *gorm.DB {
+	return r.DB.Preload("Votes", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
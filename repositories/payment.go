@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRequestRepository struct {
+	DB *gorm.DB
+}
+
+func NewPaymentRequestRepository(db *gorm.DB) *PaymentRequestRepository {
+	return &PaymentRequestRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *PaymentRequestRepository) WithContext(ctx context.Context) *PaymentRequestRepository {
+	return &PaymentRequestRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *PaymentRequestRepository) Create(request *models.PaymentRequest) error {
+	return r.DB.Create(request).Error
+}
+
+func (r *PaymentRequestRepository) GetByID(id uint) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := r.DB.First(&request, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetForUpdate locks the request row until the surrounding transaction
+// ends.
+func (r *PaymentRequestRepository) GetForUpdate(id uint) (*models.PaymentRequest, error) {
+	var request models.PaymentRequest
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ListIncoming returns the requests a wallet is asked to pay, oldest first,
+// optionally filtered by stored status.
+func (r *PaymentRequestRepository) ListIncoming(walletID uint, status string) ([]models.PaymentRequest, error) {
+	return r.list("payer_wallet_id = ?", walletID, status)
+}
+
+// ListOutgoing returns the requests for payment into a wallet, oldest
+// first, optionally filtered by stored status.
+func (r *PaymentRequestRepository) ListOutgoing(walletID uint, status string) ([]models.PaymentRequest, error) {
+	return r.list("requester_wallet_id = ?", walletID, status)
+}
+
+func (r *PaymentRequestRepository) list(condition string, walletID uint, status string) ([]models.PaymentRequest, error) {
+	requests := []models.PaymentRequest{}
+	query := r.DB.Where(condition, walletID).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *PaymentRequestRepository) Save(request *models.PaymentRequest) error {
+	return r.DB.Save(request).Error
+}
+
+type PaymentLinkRepository struct {
+	DB *gorm.DB
+}
+
+func NewPaymentLinkRepository(db *gorm.DB) *PaymentLinkRepository {
+	return &PaymentLinkRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *PaymentLinkRepository) WithContext(ctx context.Context) *PaymentLinkRepository {
+	return &PaymentLinkRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *PaymentLinkRepository) Create(link *models.PaymentLink) error {
+	return r.DB.Create(link).Error
+}
+
+func (r *PaymentLinkRepository) GetByID(id uint) (*models.PaymentLink, error) {
+	var link models.PaymentLink
+	err := r.DB.First(&link, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetWithUses returns the link with its payments, oldest first.
+func (r *PaymentLinkRepository) GetWithUses(id uint) (*models.PaymentLink, error) {
+	var link models.PaymentLink
+	err := r.DB.Preload("Uses", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&link, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetForUpdate locks the link row until the surrounding transaction ends.
+func (r *PaymentLinkRepository) GetForUpdate(id uint) (*models.PaymentLink, error) {
+	var link models.PaymentLink
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&link, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListByWallet returns the links paying into a wallet, oldest first.
+func (r *PaymentLinkRepository) ListByWallet(walletID uint) ([]models.PaymentLink, error) {
+	links := []models.PaymentLink{}
+	if err := r.DB.Where("wallet_id = ?", walletID).Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Save updates the link, without touching its uses.
+func (r *PaymentLinkRepository) Save(link *models.PaymentLink) error {
+	return r.DB.Omit("Uses").Save(link).Error
+}
+
+func (r *PaymentLinkRepository) CreateUse(use *models.PaymentLinkUse) error {
+	return r.DB.Create(use).Error
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+)
+
+type ErasureRepository struct {
+	DB *gorm.DB
+}
+
+func NewErasureRepository(db *gorm.DB) *ErasureRepository {
+	return &ErasureRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *ErasureRepository) WithContext(ctx context.Context) *ErasureRepository {
+	return &ErasureRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *ErasureRepository) Create(erasure *models.Erasure) error {
+	return r.DB.Create(erasure).Error
+}
+
+func (r *ErasureRepository) GetByUserID(userID uint) (*models.Erasure, error) {
+	var erasure models.Erasure
+	err := r.DB.Where("user_id = ?", userID).First(&erasure).Error
+	if err != nil {
+		return nil, err
+	}
+	return &erasure, nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BalanceSnapshotRepository struct {
+	DB *gorm.DB
+}
+
+func NewBalanceSnapshotRepository(db *gorm.DB) *BalanceSnapshotRepository {
+	return &BalanceSnapshotRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *BalanceSnapshotRepository) WithContext(ctx context.Context) *BalanceSnapshotRepository {
+	return &BalanceSnapshotRepository{DB: r.DB.WithContext(ctx)}
+}
+
+// Upsert creates the snapshot or replaces the one for the same wallet and
+// day, so a day can be reconciled more than once.
+func (r *BalanceSnapshotRepository) Upsert(snapshot *models.BalanceSnapshot) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "wallet_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"balance", "expected_balance", "updated_at"}),
+	}).Create(snapshot).Error
+}
+
+// GetLatestBefore returns the most recent snapshot of a wallet dated strictly
+// before day, or gorm.ErrRecordNotFound if there is none.
+func (r *BalanceSnapshotRepository) GetLatestBefore(walletID uint, day time.Time) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	err := r.DB.Where("wallet_id = ? AND date < ?", walletID, day).
+		Order("date DESC").
+		First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *BalanceSnapshotRepository) GetByWalletID(walletID uint) ([]models.BalanceSnapshot, error) {
+	var snapshots []models.BalanceSnapshot
+	err := r.DB.Where("wallet_id = ?", walletID).Order("date").Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+type DiscrepancyRepository struct {
+	DB *gorm.DB
+}
+
+func NewDiscrepancyRepository(db *gorm.DB) *DiscrepancyRepository {
+	return &DiscrepancyRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *DiscrepancyRepository) WithContext(ctx context.Context) *DiscrepancyRepository {
+	return &DiscrepancyRepository{DB: r.DB.WithContext(ctx)}
+}
+
+// Upsert records a discrepancy, refreshing the amounts and reopening it if
+// the same wallet and day were already flagged. A reopened discrepancy loses
+// its earlier resolution.
+func (r *DiscrepancyRepository) Upsert(discrepancy *models.Discrepancy) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "wallet_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"expected_balance": discrepancy.ExpectedBalance,
+			"actual_balance":   discrepancy.ActualBalance,
+			"difference":       discrepancy.Difference,
+			"status":           models.DiscrepancyStatusOpen,
+			"resolution_note":  "",
+			"resolved_by":      "",
+			"resolved_at":      nil,
+			"updated_at":       time.Now(),
+		}),
+	}).Create(discrepancy).Error
+}
+
+func (r *DiscrepancyRepository) GetByID(id uint) (*models.Discrepancy, error) {
+	var discrepancy models.Discrepancy
+	err := r.DB.First(&discrepancy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &discrepancy, nil
+}
+
+// List returns discrepancies newest first, optionally filtered by status.
+func (r *DiscrepancyRepository) List(status string) ([]models.Discrepancy, error) {
+	var discrepancies []models.Discrepancy
+	query := r.DB.Order("date DESC, wallet_id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&discrepancies).Error; err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+func (r *DiscrepancyRepository) Save(discrepancy *models.Discrepancy) error {
+	return r.DB.Save(discrepancy).Error
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScreeningHitRepository struct {
+	DB *gorm.DB
+}
+
+func NewScreeningHitRepository(db *gorm.DB) *ScreeningHitRepository {
+	return &ScreeningHitRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *ScreeningHitRepository) WithContext(ctx context.Context) *ScreeningHitRepository {
+	return &ScreeningHitRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *ScreeningHitRepository) Create(hits []models.ScreeningHit) error {
+	return r.DB.Create(&hits).Error
+}
+
+func (r *ScreeningHitRepository) GetByID(id uint) (*models.ScreeningHit, error) {
+	var hit models.ScreeningHit
+	err := r.DB.First(&hit, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hit, nil
+}
+
+// GetForUpdate locks the hit row until the surrounding transaction ends.
+func (r *ScreeningHitRepository) GetForUpdate(id uint) (*models.ScreeningHit, error) {
+	var hit models.ScreeningHit
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hit, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hit, nil
+}
+
+// List returns hits oldest first, optionally filtered by status.
+func (r *ScreeningHitRepository) List(status string) ([]models.ScreeningHit, error) {
+	var hits []models.ScreeningHit
+	query := r.DB.Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// ClearedEntries returns the list entries already cleared for the user, as
+// "list/entry ID" keys, so a reviewed false positive is not raised again.
+func (r *ScreeningHitRepository) ClearedEntries(userID uint) (map[string]bool, error) {
+	var hits []models.ScreeningHit
+	err := r.DB.Select("list", "entry_id").
+		Where("user_id = ? AND status = ?", userID, models.ScreeningHitStatusCleared).
+		Find(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	cleared := make(map[string]bool, len(hits))
+	for _, hit := range hits {
+		cleared[hit.List+"/"+hit.EntryID] = true
+	}
+	return cleared, nil
+}
+
+// CountUncleared counts the other hits on the same subject as hit that have
+// not been cleared.
+func (r *ScreeningHitRepository) CountUncleared(hit *models.ScreeningHit) (int64, error) {
+	query := r.DB.Model(&models.ScreeningHit{}).
+		Where("id <> ? AND subject = ? AND status <> ?", hit.ID, hit.Subject, models.ScreeningHitStatusCleared)
+	if hit.Subject == models.ScreeningSubjectTransfer {
+		query = query.Where("transaction_id = ?", hit.TransactionID)
+	} else {
+		query = query.Where("user_id = ?", hit.UserID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// Pseudonymize replaces the name screened in the user's hits, keeping the
+// hits and the entries they matched.
+func (r *ScreeningHitRepository) Pseudonymize(userID uint) error {
+	return r.DB.Model(&models.ScreeningHit{}).Where("user_id = ?", userID).
+		Update("screened_name", ErasedName).Error
+}
+
+func (r *ScreeningHitRepository) Save(hit *models.ScreeningHit) error {
+	return r.DB.Save(hit).Error
+}
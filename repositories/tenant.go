@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"wallet-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TenantRepository struct {
+	DB *gorm.DB
+}
+
+func NewTenantRepository(db *gorm.DB) *TenantRepository {
+	return &TenantRepository{DB: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *TenantRepository) WithContext(ctx context.Context) *TenantRepository {
+	return &TenantRepository{DB: r.DB.WithContext(ctx)}
+}
+
+func (r *TenantRepository) Create(tenant *models.Tenant) error {
+	return r.DB.Create(tenant).Error
+}
+
+func (r *TenantRepository) GetByID(id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := r.DB.First(&tenant, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetForUpdate locks the tenant row until the surrounding transaction ends.
+func (r *TenantRepository) GetForUpdate(id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tenant, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (r *TenantRepository) List() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	err := r.DB.Order("id").Find(&tenants).Error
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// SlugTaken reports whether a tenant other than exceptID uses slug.
+func (r *TenantRepository) SlugTaken(slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Tenant{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *TenantRepository) Save(tenant *models.Tenant) error {
+	return r.DB.Save(tenant).Error
+}
+
+// EnsureDefault creates the default tenant in a new database. The tenant_id
+// columns default to its ID, so it must be the first tenant created.
+func (r *TenantRepository) EnsureDefault() error {
+	tenant := models.Tenant{
+		Name:       "Default",
+		Slug:       models.DefaultTenantSlug,
+		Currencies: []string{models.DefaultTenantCurrency},
+	}
+	err := r.DB.Where(models.Tenant{Slug: models.DefaultTenantSlug}).FirstOrCreate(&tenant).Error
+	if err != nil {
+		return err
+	}
+	if tenant.ID != models.DefaultTenantID {
+		return fmt.Errorf("default tenant has ID %d, want %d", tenant.ID, models.DefaultTenantID)
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"wallet-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepository struct {
@@ -13,6 +17,11 @@ func NewTransactionRepository(db *gorm.DB) *TransactionRepository {
 	return &TransactionRepository{DB: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *TransactionRepository) WithContext(ctx context.Context) *TransactionRepository {
+	return &TransactionRepository{DB: r.DB.WithContext(ctx)}
+}
+
 func (r *TransactionRepository) Create(transaction *models.Transaction) error {
 	return r.DB.Create(transaction).Error
 }
@@ -33,4 +42,139 @@ func (r *TransactionRepository) GetByWalletID(walletID uint) ([]models.Transacti
 		return nil, err
 	}
 	return transactions, nil
-}
\ No newline at end of file
+}
+
+// GetByUserID returns the transactions into or out of any of the user's
+// wallets, oldest first. Transfers between two of them are listed once.
+func (r *TransactionRepository) GetByUserID(userID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	wallets := r.DB.Model(&models.Wallet{}).Unscoped().Select("id").Where("user_id = ?", userID)
+	err := r.DB.Where("source_wallet_id IN (?) OR target_wallet_id IN (?)", wallets, wallets).
+		Order("created_at, id").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// OutgoingSince sums the completed transfers sent from any of the user's
+// wallets at or after since.
+func (r *TransactionRepository) OutgoingSince(userID uint, since time.Time) (int64, error) {
+	var total int64
+	err := r.DB.Model(&models.Transaction{}).
+		Joins("JOIN wallets ON wallets.id = transactions.source_wallet_id").
+		Where("wallets.user_id = ? AND transactions.type = ? AND transactions.status = ? AND transactions.created_at >= ?",
+			userID, models.TransactionTypeTransfer, models.TransactionStatusCompleted, since).
+		Select("COALESCE(SUM(transactions.amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// InitiatedSince sums the completed transfers the user sent from a wallet at
+// or after since.
+func (r *TransactionRepository) InitiatedSince(walletID, userID uint, since time.Time) (int64, error) {
+	var total int64
+	err := r.DB.Model(&models.Transaction{}).
+		Where("source_wallet_id = ? AND initiated_by = ? AND type = ? AND status = ? AND created_at >= ?",
+			walletID, userID, models.TransactionTypeTransfer, models.TransactionStatusCompleted, since).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// ActivitySince returns the completed transactions into or out of a wallet
+// created at or after since, oldest first.
+func (r *TransactionRepository) ActivitySince(walletID uint, since time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.DB.
+		Where("(source_wallet_id = ? OR target_wallet_id = ?) AND status = ? AND created_at >= ?",
+			walletID, walletID, models.TransactionStatusCompleted, since).
+		Order("created_at").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetForUpdate locks the transaction row until the surrounding transaction
+// ends.
+func (r *TransactionRepository) GetForUpdate(id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// BalanceAt returns the balance of a wallet as of the given time (inclusive),
+// computed from completed transactions.
+func (r *TransactionRepository) BalanceAt(walletID uint, asOf time.Time) (int64, error) {
+	return r.sumBalance(walletID, "created_at <= ?", asOf)
+}
+
+// BalanceBefore returns the balance of a wallet from completed transactions
+// strictly before the given time.
+func (r *TransactionRepository) BalanceBefore(walletID uint, before time.Time) (int64, error) {
+	return r.sumBalance(walletID, "created_at < ?", before)
+}
+
+// BalanceSince returns the net movement of a wallet from completed
+// transactions at or after the given time.
+func (r *TransactionRepository) BalanceSince(walletID uint, since time.Time) (int64, error) {
+	return r.sumBalance(walletID, "created_at >= ?", since)
+}
+
+func (r *TransactionRepository) sumBalance(walletID uint, cond string, at time.Time) (int64, error) {
+	var balance int64
+	err := r.DB.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN target_wallet_id = ? THEN amount ELSE -amount END), 0)", walletID).
+		Where("(source_wallet_id = ? OR target_wallet_id = ?) AND status = ?", walletID, walletID, models.TransactionStatusCompleted).
+		Where(cond, at).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// GetCompletedByWalletIDBetween returns the completed transactions of a wallet
+// created within [from, to], oldest first.
+func (r *TransactionRepository) GetCompletedByWalletIDBetween(walletID uint, from, to time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.completedBetween(walletID, from, to).Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// EachCompletedByWalletIDBetween is GetCompletedByWalletIDBetween without
+// loading the whole range into memory: fn is called once per row, and
+// iteration stops at the first error it returns.
+func (r *TransactionRepository) EachCompletedByWalletIDBetween(walletID uint, from, to time.Time, fn func(models.Transaction) error) error {
+	rows, err := r.completedBetween(walletID, from, to).Model(&models.Transaction{}).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := r.DB.ScanRows(rows, &transaction); err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *TransactionRepository) completedBetween(walletID uint, from, to time.Time) *gorm.DB {
+	return r.DB.Where("(source_wallet_id = ? OR target_wallet_id = ?) AND status = ?", walletID, walletID, models.TransactionStatusCompleted).
+		Where("created_at >= ? AND created_at <= ?", from, to).
+		Order("created_at, id")
+}
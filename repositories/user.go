@@ -1,8 +1,14 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"wallet-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -13,6 +19,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
+	return &UserRepository{DB: r.DB.WithContext(ctx)}
+}
+
 func (r *UserRepository) Create(user *models.User) error {
 	return r.DB.Create(user).Error
 }
@@ -26,6 +37,16 @@ func (r *UserRepository) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// GetForUpdate locks the user row until the surrounding transaction ends.
+func (r *UserRepository) GetForUpdate(id uint) (*models.User, error) {
+	var user models.User
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.DB.Where("email = ?", email).First(&user).Error
@@ -33,4 +54,147 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// EmailTaken reports whether a user other than exceptID holds email. Erased
+// and deleted users count, as the unique index still covers their rows.
+func (r *UserRepository) EmailTaken(email string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.DB.Unscoped().Model(&models.User{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UserFilter narrows a user listing. Zero fields do not filter.
+type UserFilter struct {
+	// NamePrefix and EmailPrefix match case-insensitively.
+	NamePrefix  string
+	EmailPrefix string
+	// CreatedFrom is inclusive, CreatedTo exclusive.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Active, when set, keeps only active or only deactivated users.
+	Active *bool
+}
+
+// List returns the users matching filter, oldest first, skipping offset and
+// returning at most limit, along with the number of matches in total.
+func (r *UserRepository) List(filter UserFilter, offset, limit int) ([]models.User, int64, error) {
+	query := r.DB.Model(&models.User{})
+	if filter.NamePrefix != "" {
+		query = query.Where("LOWER(name) LIKE ?", likePrefix(filter.NamePrefix))
+	}
+	if filter.EmailPrefix != "" {
+		query = query.Where("LOWER(email) LIKE ?", likePrefix(filter.EmailPrefix))
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("deactivated_at IS NULL")
+		} else {
+			query = query.Where("deactivated_at IS NOT NULL")
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if err := query.Order("created_at, id").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// likePrefix turns prefix into a lower-case LIKE pattern matching it
+// literally at the start of a value.
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	return escaped + "%"
+}
+
+// Update saves the user's name, email, email verification and screening
+// status, the fields a profile change can touch.
+func (r *UserRepository) Update(user *models.User) error {
+	return r.DB.Model(user).Select("name", "email", "email_verified_at", "screening_status").Updates(user).Error
+}
+
+// SetEmailVerified records when the user's current email was verified.
+func (r *UserRepository) SetEmailVerified(id uint, verifiedAt time.Time) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", id).Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetDeactivated deactivates the user at deactivatedAt with reason, or
+// reactivates them when deactivatedAt is nil.
+func (r *UserRepository) SetDeactivated(id uint, deactivatedAt *time.Time, reason string) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deactivated_at":      deactivatedAt,
+		"deactivation_reason": reason,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetKYCLevel records the level a user has been verified at.
+func (r *UserRepository) SetKYCLevel(id uint, level models.KYCLevel) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", id).Update("kyc_level", level)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetScreeningStatus records the outcome of the user's sanctions review.
+func (r *UserRepository) SetScreeningStatus(id uint, status models.ScreeningStatus) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", id).Update("screening_status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Pseudonymize replaces the user's personal data with placeholders derived
+// from their ID, marks them erased at erasedAt and soft-deletes them.
+// ErasedName replaces the name of an erased user wherever it was stored.
+const ErasedName = "Erased user"
+
+func (r *UserRepository) Pseudonymize(id uint, erasedAt time.Time) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":       ErasedName,
+		"email":      fmt.Sprintf("erased-%d@erased.invalid", id),
+		"erased_at":  erasedAt,
+		"deleted_at": erasedAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
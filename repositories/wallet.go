@@ -1,10 +1,13 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"wallet-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WalletRepository struct {
@@ -15,6 +18,11 @@ func NewWalletRepository(db *gorm.DB) *WalletRepository {
 	return &WalletRepository{DB: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx.
+func (r *WalletRepository) WithContext(ctx context.Context) *WalletRepository {
+	return &WalletRepository{DB: r.DB.WithContext(ctx)}
+}
+
 func (r *WalletRepository) Create(wallet *models.Wallet) error {
 	return r.DB.Create(wallet).Error
 }
@@ -38,6 +46,17 @@ func (r *WalletRepository) GetByUserID(userID uint) ([]models.Wallet, error) {
 	return wallets, nil
 }
 
+// GetByUserIDForUpdate locks the user's wallets until the surrounding
+// transaction ends.
+func (r *WalletRepository) GetByUserIDForUpdate(userID uint) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Order("id").Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
 func (r *WalletRepository) UpdateBalance(id uint, amount int64) error {
 	result := r.DB.Model(&models.Wallet{}).Where("id = ?", id).Update("balance", gorm.Expr("balance + ?", amount))
 	if result.Error != nil {
@@ -48,3 +67,121 @@ func (r *WalletRepository) UpdateBalance(id uint, amount int64) error {
 	}
 	return nil
 }
+
+// SetFrozen freezes the wallet at frozenAt with reason, or unfreezes it when
+// frozenAt is nil.
+func (r *WalletRepository) SetFrozen(id uint, frozenAt *time.Time, reason string) error {
+	result := r.DB.Model(&models.Wallet{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"frozen_at":     frozenAt,
+		"frozen_reason": reason,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetIDsCreatedBefore returns the IDs of all wallets created before t.
+func (r *WalletRepository) GetIDsCreatedBefore(t time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.DB.Model(&models.Wallet{}).Where("created_at < ?", t).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetForUpdate locks the wallet row until the surrounding transaction ends.
+func (r *WalletRepository) GetForUpdate(id uint) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// GetPockets returns the pockets of a wallet, oldest first.
+func (r *WalletRepository) GetPockets(parentID uint) ([]models.Wallet, error) {
+	var pockets []models.Wallet
+	err := r.DB.Where("parent_id = ?", parentID).Order("id").Find(&pockets).Error
+	if err != nil {
+		return nil, err
+	}
+	return pockets, nil
+}
+
+// GetWithPockets returns the wallet and its pockets, oldest first. They are
+// read in one statement so their balances are consistent with each other.
+func (r *WalletRepository) GetWithPockets(id uint) (*models.Wallet, []models.Wallet, error) {
+	var wallets []models.Wallet
+	err := r.DB.Preload("User").Where("id = ? OR parent_id = ?", id, id).Order("id").Find(&wallets).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var wallet *models.Wallet
+	pockets := []models.Wallet{}
+	for i := range wallets {
+		if wallets[i].ID == id {
+			wallet = &wallets[i]
+		} else {
+			pockets = append(pockets, wallets[i])
+		}
+	}
+	if wallet == nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return wallet, pockets, nil
+}
+
+// PocketsBalance sums the balances of a wallet's pockets.
+func (r *WalletRepository) PocketsBalance(parentID uint) (int64, error) {
+	var total int64
+	err := r.DB.Model(&models.Wallet{}).Where("parent_id = ?", parentID).
+		Select("COALESCE(SUM(balance), 0)").Scan(&total).Error
+	return total, err
+}
+
+// PocketNameTaken reports whether a pocket of the parent other than exceptID
+// is called name, ignoring case.
+func (r *WalletRepository) PocketNameTaken(parentID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Wallet{}).
+		Where("parent_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", parentID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdatePocket saves the pocket's name and targets.
+func (r *WalletRepository) UpdatePocket(pocket *models.Wallet) error {
+	return r.DB.Model(pocket).Select("name", "target_amount", "target_date").Updates(pocket).Error
+}
+
+// SetApprovalRule saves the wallet's approval rule. A nil threshold removes
+// it.
+func (r *WalletRepository) SetApprovalRule(id uint, threshold *int64, required int) error {
+	result := r.DB.Model(&models.Wallet{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"approval_threshold": threshold, "required_approvals": required})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete soft-deletes the wallet.
+func (r *WalletRepository) Delete(id uint) error {
+	result := r.DB.Delete(&models.Wallet{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
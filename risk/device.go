@@ -0,0 +1,39 @@
+package risk
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceHeader identifies the client device a request comes from, as
+// reported by the app.
+const DeviceHeader = "X-Device-ID"
+
+type deviceKey struct{}
+
+// WithDevice returns a copy of ctx carrying the device ID.
+func WithDevice(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, deviceKey{}, id)
+}
+
+// Device returns the device ID stored in ctx, or "".
+func Device(ctx context.Context) string {
+	id, _ := ctx.Value(deviceKey{}).(string)
+	return id
+}
+
+var validDevice = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// DeviceMiddleware stores a well-formed X-Device-ID in the request context.
+// Malformed IDs are ignored rather than rejected, so the transfer is scored
+// as coming from an unknown device.
+func DeviceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id := c.GetHeader(DeviceHeader); validDevice.MatchString(id) {
+			c.Request = c.Request.WithContext(WithDevice(c.Request.Context(), id))
+		}
+		c.Next()
+	}
+}
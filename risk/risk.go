@@ -0,0 +1,185 @@
+// Package risk scores transfers for fraud risk before they commit. A scorer
+// weighs signals such as an unfamiliar device or counterparty, an unusual
+// amount or the time of day into a score from 0 to 100 and decides whether
+// the transfer goes ahead, waits for review or is refused.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Decision is what happens to a scored transfer.
+type Decision string
+
+const (
+	Allow  Decision = "allow"
+	Review Decision = "review"
+	Deny   Decision = "deny"
+)
+
+// Signal names, as they prefix the reasons of an assessment.
+const (
+	SignalNewDevice       = "new_device"
+	SignalNewCounterparty = "new_counterparty"
+	SignalUnusualAmount   = "unusual_amount"
+	SignalNightTime       = "night_time"
+)
+
+// AlertRule names the AML alert that holds a transfer for review.
+const AlertRule = "risk_score"
+
+// Points each signal adds to the score.
+const (
+	NewDevicePoints       = 25
+	NewCounterpartyPoints = 20
+	UnusualAmountPoints   = 35
+	NightTimePoints       = 15
+)
+
+// Assessment is the outcome of scoring one transfer.
+type Assessment struct {
+	Score    int
+	Decision Decision
+	// Reasons explains the score, one "signal: details" entry per signal
+	// that contributed.
+	Reasons []string
+}
+
+// History is what the heuristic looks back at.
+type History interface {
+	// Activity returns the completed transactions into or out of a wallet
+	// created at or after since, oldest first.
+	Activity(walletID uint, since time.Time) ([]models.Transaction, error)
+}
+
+// Heuristic scores a transfer against the sending wallet's outgoing
+// transfers within Window:
+//
+//   - new_device: the transfer comes from a device the wallet has not sent
+//     from before, while it has sent from others;
+//   - new_counterparty: the wallet has not paid the target wallet before;
+//   - unusual_amount: the amount exceeds AmountMultiple times the wallet's
+//     average transfer;
+//   - night_time: it is sent between NightStart and NightEnd, hours in UTC.
+//
+// Scores from ReviewScore hold the transfer for review and scores from
+// DenyScore refuse it.
+type Heuristic struct {
+	Window         time.Duration
+	AmountMultiple float64
+	NightStart     int
+	NightEnd       int
+	ReviewScore    int
+	DenyScore      int
+}
+
+// Score assesses t before it is committed, reading history through tx.
+func (h Heuristic) Score(ctx context.Context, tx *gorm.DB, t *models.Transaction) (_ Assessment, err error) {
+	_, span := tracing.Start(ctx, "risk.Heuristic.Score")
+	defer tracing.End(span, &err)
+
+	a, err := h.Assess(activity{repositories.NewTransactionRepository(tx)}, t, time.Now())
+	span.SetAttributes(attribute.Int("risk.score", a.Score), attribute.String("risk.decision", string(a.Decision)))
+	return a, err
+}
+
+// Assess scores the transfer t as of now.
+func (h Heuristic) Assess(history History, t *models.Transaction, now time.Time) (Assessment, error) {
+	var a Assessment
+	if t.SourceWalletID == nil {
+		return Assessment{Decision: Allow}, nil
+	}
+	walletID := *t.SourceWalletID
+
+	prior, err := history.Activity(walletID, now.Add(-h.Window))
+	if err != nil {
+		return a, err
+	}
+	// Only transfers to another wallet say whom the sender pays and how
+	// much; fees, pocket moves and the like would skew both.
+	var sent []models.Transaction
+	for _, p := range prior {
+		if p.Type != models.TransactionTypeTransfer || p.TargetWalletID == 0 {
+			continue
+		}
+		if p.SourceWalletID != nil && *p.SourceWalletID == walletID && p.ID != t.ID {
+			sent = append(sent, p)
+		}
+	}
+
+	add := func(points int, signal, format string, args ...interface{}) {
+		a.Score += points
+		a.Reasons = append(a.Reasons, signal+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t.DeviceID != "" {
+		known, others := false, false
+		for _, p := range sent {
+			known = known || p.DeviceID == t.DeviceID
+			others = others || p.DeviceID != ""
+		}
+		if others && !known {
+			add(NewDevicePoints, SignalNewDevice, "wallet %d has not sent from device %s within %s", walletID, t.DeviceID, h.Window)
+		}
+	}
+
+	paid := false
+	var total int64
+	for _, p := range sent {
+		paid = paid || p.TargetWalletID == t.TargetWalletID
+		total += p.Amount
+	}
+	if !paid {
+		add(NewCounterpartyPoints, SignalNewCounterparty, "wallet %d has not paid wallet %d within %s", walletID, t.TargetWalletID, h.Window)
+	}
+	if len(sent) > 0 {
+		average := float64(total) / float64(len(sent))
+		if float64(t.Amount) > h.AmountMultiple*average {
+			add(UnusualAmountPoints, SignalUnusualAmount, "%d is more than %g times the average of %.0f over %d transfers",
+				t.Amount, h.AmountMultiple, average, len(sent))
+		}
+	}
+
+	if hour := now.UTC().Hour(); h.night(hour) {
+		add(NightTimePoints, SignalNightTime, "sent at %02d:%02d UTC", hour, now.UTC().Minute())
+	}
+
+	if a.Score > 100 {
+		a.Score = 100
+	}
+	switch {
+	case a.Score >= h.DenyScore:
+		a.Decision = Deny
+	case a.Score >= h.ReviewScore:
+		a.Decision = Review
+	default:
+		a.Decision = Allow
+	}
+	return a, nil
+}
+
+// night reports whether hour falls between NightStart and NightEnd, which
+// may wrap around midnight. Equal hours mean no night at all.
+func (h Heuristic) night(hour int) bool {
+	if h.NightStart <= h.NightEnd {
+		return hour >= h.NightStart && hour < h.NightEnd
+	}
+	return hour >= h.NightStart || hour < h.NightEnd
+}
+
+type activity struct {
+	transactions *repositories.TransactionRepository
+}
+
+func (a activity) Activity(walletID uint, since time.Time) ([]models.Transaction, error) {
+	return a.transactions.ActivitySince(walletID, since)
+}
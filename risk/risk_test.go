@@ -0,0 +1,154 @@
+package risk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHistory serves the heuristic from memory.
+type fakeHistory []models.Transaction
+
+func (h fakeHistory) Activity(walletID uint, since time.Time) ([]models.Transaction, error) {
+	var out []models.Transaction
+	for _, t := range h {
+		involved := t.TargetWalletID == walletID || (t.SourceWalletID != nil && *t.SourceWalletID == walletID)
+		if involved && !t.CreatedAt.Before(since) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+var now = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func transfer(id, source, target uint, amount int64, device string, at time.Time) models.Transaction {
+	return models.Transaction{
+		ID: id, SourceWalletID: &source, TargetWalletID: target, Amount: amount,
+		Type: models.TransactionTypeTransfer, Status: models.TransactionStatusCompleted,
+		DeviceID: device, CreatedAt: at,
+	}
+}
+
+func TestHeuristic_Assess(t *testing.T) {
+	h := Heuristic{Window: 30 * 24 * time.Hour, AmountMultiple: 5, NightStart: 23, NightEnd: 5, ReviewScore: 50, DenyScore: 80}
+
+	history := fakeHistory{
+		transfer(1, 1, 2, 1_000, "phone", now.Add(-72*time.Hour)),
+		transfer(2, 1, 2, 3_000, "phone", now.Add(-48*time.Hour)),
+		transfer(3, 1, 3, 2_000, "", now.Add(-24*time.Hour)),
+		transfer(4, 4, 1, 50_000, "", now.Add(-time.Hour)),
+		transfer(5, 1, 9, 1_000, "tablet", now.Add(-60*24*time.Hour)),
+	}
+
+	assess := func(target uint, amount int64, device string, at time.Time) Assessment {
+		next := transfer(0, 1, target, amount, device, time.Time{})
+		a, err := h.Assess(history, &next, at)
+		require.NoError(t, err)
+		return a
+	}
+
+	t.Run("familiar", func(t *testing.T) {
+		a := assess(2, 2_000, "phone", now)
+		assert.Equal(t, Assessment{Score: 0, Decision: Allow}, a)
+	})
+
+	t.Run("new counterparty", func(t *testing.T) {
+		a := assess(5, 2_000, "phone", now)
+		assert.Equal(t, NewCounterpartyPoints, a.Score)
+		assert.Equal(t, Allow, a.Decision)
+		assert.Equal(t, []string{"new_counterparty: wallet 1 has not paid wallet 5 within 720h0m0s"}, a.Reasons)
+	})
+
+	t.Run("incoming transfers and old history do not count", func(t *testing.T) {
+		a := assess(9, 2_000, "tablet", now)
+		assert.Equal(t, NewCounterpartyPoints+NewDevicePoints, a.Score)
+	})
+
+	t.Run("unusual amount to a new counterparty is reviewed", func(t *testing.T) {
+		a := assess(5, 10_001, "phone", now)
+		assert.Equal(t, NewCounterpartyPoints+UnusualAmountPoints, a.Score)
+		assert.Equal(t, Review, a.Decision)
+		assert.Contains(t, a.Reasons, "unusual_amount: 10001 is more than 5 times the average of 2000 over 3 transfers")
+	})
+
+	t.Run("everything at once is denied", func(t *testing.T) {
+		a := assess(5, 20_000, "laptop", now.Add(-11*time.Hour))
+		assert.Equal(t, 95, a.Score)
+		assert.Equal(t, Deny, a.Decision)
+		assert.Len(t, a.Reasons, 4)
+		assert.Contains(t, a.Reasons, "night_time: sent at 01:00 UTC")
+	})
+
+	t.Run("unknown device", func(t *testing.T) {
+		a := assess(2, 2_000, "", now)
+		assert.Zero(t, a.Score, "transfers without a device ID are not penalised")
+	})
+
+	t.Run("first transfer", func(t *testing.T) {
+		next := transfer(0, 7, 2, 1_000_000, "phone", time.Time{})
+		a, err := h.Assess(fakeHistory{}, &next, now)
+		require.NoError(t, err)
+		assert.Equal(t, NewCounterpartyPoints, a.Score, "no history to compare amount or device with")
+	})
+
+	t.Run("only transfers count as history", func(t *testing.T) {
+		fee := transfer(6, 1, 5, 100, "phone", now.Add(-time.Hour))
+		fee.Type = models.TransactionTypeFee
+		a, err := h.Assess(append(fakeHistory{fee}, history...), &models.Transaction{
+			SourceWalletID: fee.SourceWalletID, TargetWalletID: 5, Amount: 2_000, DeviceID: "phone",
+			Type: models.TransactionTypeTransfer,
+		}, now)
+		require.NoError(t, err)
+		assert.Equal(t, NewCounterpartyPoints, a.Score, "paying the fee wallet does not make it a known counterparty")
+	})
+
+	t.Run("deposits are not scored", func(t *testing.T) {
+		deposit := models.Transaction{TargetWalletID: 1, Amount: 1_000_000, Type: models.TransactionTypeDeposit}
+		a, err := h.Assess(history, &deposit, now)
+		require.NoError(t, err)
+		assert.Equal(t, Assessment{Decision: Allow}, a)
+	})
+}
+
+func TestHeuristic_Night(t *testing.T) {
+	wrapping := Heuristic{NightStart: 22, NightEnd: 6}
+	assert.True(t, wrapping.night(23))
+	assert.True(t, wrapping.night(0))
+	assert.False(t, wrapping.night(6))
+	assert.False(t, wrapping.night(12))
+
+	early := Heuristic{NightStart: 0, NightEnd: 5}
+	assert.True(t, early.night(4))
+	assert.False(t, early.night(5))
+
+	assert.False(t, Heuristic{NightStart: 3, NightEnd: 3}.night(3), "equal hours mean no night")
+}
+
+func TestDeviceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	device := func(header string) string {
+		var got string
+		router := gin.New()
+		router.Use(DeviceMiddleware())
+		router.GET("/", func(c *gin.Context) { got = Device(c.Request.Context()) })
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DeviceHeader, header)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	assert.Equal(t, "ios:4F2A-91", device("ios:4F2A-91"))
+	assert.Empty(t, device("bad\nid"))
+	assert.Empty(t, device(""))
+	assert.Empty(t, Device(context.Background()))
+}
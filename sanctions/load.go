@@ -0,0 +1,134 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadFiles reads every list file. The format follows the extension: .csv
+// or .xml.
+func LoadFiles(paths []string) ([]Entry, error) {
+	var entries []Entry
+	for _, path := range paths {
+		loaded, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, loaded...)
+	}
+	return entries, nil
+}
+
+// LoadFile reads one list file. CSV lists are named after the file.
+func LoadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		entries, err = ReadCSV(f, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	case ".xml":
+		entries, err = ReadXML(f)
+	default:
+		return nil, fmt.Errorf("sanctions list %s: unsupported format %q, expected .csv or .xml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sanctions list %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ReadCSV reads a list with the header "id,name,aliases". Aliases are
+// separated by "|" and may be empty.
+//
+//	id,name,aliases
+//	SDN-1001,Ivan Petrov,Ivan Petroff|I. Petrov
+func ReadCSV(r io.Reader, list string) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if strings.Join(header, ",") != "id,name,aliases" {
+		return nil, fmt.Errorf("header must be id,name,aliases, got %s", strings.Join(header, ","))
+	}
+
+	var entries []Entry
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := Entry{List: list, ID: record[0], Name: record[1]}
+		if entry.ID == "" || entry.Name == "" {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("line %d: id and name are required", line)
+		}
+		for _, alias := range strings.Split(record[2], "|") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type xmlList struct {
+	Name    string     `xml:"name,attr"`
+	Entries []xmlEntry `xml:"entry"`
+}
+
+type xmlEntry struct {
+	ID      string   `xml:"id,attr"`
+	Name    string   `xml:"name"`
+	Aliases []string `xml:"alias"`
+}
+
+// ReadXML reads a list of the form
+//
+//	<list name="EU-CONSOLIDATED">
+//	  <entry id="EU-77">
+//	    <name>Ivan Petrov</name>
+//	    <alias>Ivan Petroff</alias>
+//	  </entry>
+//	</list>
+func ReadXML(r io.Reader) ([]Entry, error) {
+	var list xmlList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	if list.Name == "" {
+		return nil, errors.New("list name attribute is required")
+	}
+
+	entries := make([]Entry, 0, len(list.Entries))
+	for i, e := range list.Entries {
+		entry := Entry{List: list.Name, ID: strings.TrimSpace(e.ID), Name: strings.TrimSpace(e.Name)}
+		if entry.ID == "" || entry.Name == "" {
+			return nil, fmt.Errorf("entry %d: id and name are required", i+1)
+		}
+		for _, alias := range e.Aliases {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,209 @@
+// Package sanctions screens names against sanctions and watch lists. Lists
+// are loaded from local files; names are normalized and compared token by
+// token with Jaro-Winkler similarity, so transliteration variants, reordered
+// names and small typos still match.
+package sanctions
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultThreshold is the score from which a name counts as a match.
+const DefaultThreshold = 0.88
+
+// Entry is one listed person or organisation.
+type Entry struct {
+	List    string
+	ID      string
+	Name    string
+	Aliases []string
+}
+
+// Match is an entry a screened name resembles.
+type Match struct {
+	Entry Entry
+	// Name is the entry name or alias that matched best.
+	Name  string
+	Score float64
+}
+
+// Screener matches names against a fixed set of entries. It is safe for
+// concurrent use.
+type Screener struct {
+	threshold float64
+	entries   []indexedEntry
+}
+
+type indexedEntry struct {
+	entry Entry
+	names []indexedName
+}
+
+type indexedName struct {
+	name   string
+	tokens []string
+}
+
+// NewScreener returns a screener reporting matches scoring at least
+// threshold, between 0 and 1.
+func NewScreener(threshold float64, entries []Entry) *Screener {
+	s := &Screener{threshold: threshold}
+	for _, e := range entries {
+		indexed := indexedEntry{entry: e}
+		for _, name := range append([]string{e.Name}, e.Aliases...) {
+			if tokens := Tokens(name); len(tokens) > 0 {
+				indexed.names = append(indexed.names, indexedName{name: name, tokens: tokens})
+			}
+		}
+		s.entries = append(s.entries, indexed)
+	}
+	return s
+}
+
+// Len returns the number of entries loaded.
+func (s *Screener) Len() int {
+	return len(s.entries)
+}
+
+// Screen returns the entries name matches, best first, each with the entry
+// name or alias it matched best.
+func (s *Screener) Screen(name string) []Match {
+	tokens := Tokens(name)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for _, e := range s.entries {
+		best := Match{Entry: e.entry}
+		for _, n := range e.names {
+			if score := Score(tokens, n.tokens); score > best.Score {
+				best.Name, best.Score = n.name, score
+			}
+		}
+		if best.Score >= s.threshold {
+			matches = append(matches, best)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// folds spells out Latin letters that do not decompose into a base letter
+// and an accent.
+var folds = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// Tokens normalizes name into lower-case ASCII-folded words: accents are
+// removed, punctuation separates words and apostrophes are dropped, so
+// "O'Brien, José" becomes ["obrien", "jose"].
+func Tokens(name string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		r = unicode.ToLower(r)
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+		case folds[r] != "":
+			b.WriteString(folds[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// Score compares two tokenized names. Each token of the shorter name is
+// paired with its most similar unused token of the longer one; the score is
+// the sum of those similarities divided by the shorter name's length plus
+// half the tokens left over, so a missing middle name costs less than a
+// wrong one.
+func Score(a, b []string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return 0
+	}
+
+	used := make([]bool, len(b))
+	var total float64
+	for _, ta := range a {
+		best, bestIdx := 0.0, -1
+		for i, tb := range b {
+			if used[i] {
+				continue
+			}
+			if sim := JaroWinkler(ta, tb); sim > best {
+				best, bestIdx = sim, i
+			}
+		}
+		if bestIdx >= 0 {
+			used[bestIdx] = true
+			total += best
+		}
+	}
+	return total / (float64(len(a)) + float64(len(b)-len(a))/2)
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of a and b, from 0 for
+// nothing in common to 1 for equal strings.
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo, hi := max(0, i-window), min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,101 @@
+package sanctions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	assert.Equal(t, []string{"obrien", "jose"}, Tokens("O'Brien, José"))
+	assert.Equal(t, []string{"mohammed", "al", "rashid"}, Tokens("  MOHAMMED Al-Rashid "))
+	assert.Equal(t, []string{"muller", "strasse", "12"}, Tokens("Müller-Straße 12"))
+	assert.Equal(t, []string{"lukasz", "sorensen"}, Tokens("Łukasz Sørensen"))
+	assert.Empty(t, Tokens(" -- "))
+}
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, JaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, JaroWinkler("dwayne", "duane"), 0.001)
+	assert.InDelta(t, 0.813, JaroWinkler("dixon", "dicksonx"), 0.001)
+	assert.Equal(t, 1.0, JaroWinkler("petrov", "petrov"))
+	assert.Equal(t, 0.0, JaroWinkler("abc", "xyz"))
+	assert.Equal(t, 0.0, JaroWinkler("", "abc"))
+}
+
+func TestScore(t *testing.T) {
+	score := func(a, b string) float64 { return Score(Tokens(a), Tokens(b)) }
+
+	assert.Equal(t, 1.0, score("Ivan Petrov", "Petrov Ivan"), "order does not matter")
+	assert.Greater(t, score("Ivan Petrov", "Ivan Petrof"), 0.95)
+	assert.InDelta(t, 0.8, score("Ivan Petrov", "Ivan Sergeyevich Petrov"), 0.001, "a missing middle name costs half a token")
+	assert.Less(t, score("Ivan", "Ivan Petrov"), 0.7, "a first name alone is not a match")
+	assert.Less(t, score("Anna Schmidt", "Ivan Petrov"), 0.6)
+}
+
+func TestScreener(t *testing.T) {
+	entries, err := LoadFiles([]string{"testdata/sdn.csv", "testdata/eu.xml"})
+	require.NoError(t, err)
+	screener := NewScreener(DefaultThreshold, entries)
+	assert.Equal(t, 5, screener.Len())
+
+	t.Run("alias with a typo", func(t *testing.T) {
+		matches := screener.Screen("Ivan Petrof")
+		require.Len(t, matches, 1)
+		assert.Equal(t, "SDN-1001", matches[0].Entry.ID)
+		assert.Equal(t, "sdn", matches[0].Entry.List)
+		assert.Equal(t, "Ivan Petroff", matches[0].Name)
+		assert.Greater(t, matches[0].Score, 0.95)
+	})
+
+	t.Run("transliteration", func(t *testing.T) {
+		matches := screener.Screen("Muhammad Al Rashid")
+		require.NotEmpty(t, matches)
+		assert.Equal(t, "EU-77", matches[0].Entry.ID)
+		assert.Equal(t, "EU-CONSOLIDATED", matches[0].Entry.List)
+	})
+
+	t.Run("accents", func(t *testing.T) {
+		matches := screener.Screen("jose alvarez ortega")
+		require.Len(t, matches, 1)
+		assert.Equal(t, "SDN-1003", matches[0].Entry.ID)
+		assert.Equal(t, 1.0, matches[0].Score)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		assert.Empty(t, screener.Screen("Jane Doe"))
+		assert.Empty(t, screener.Screen("Ivan"))
+		assert.Empty(t, screener.Screen(""))
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		assert.Empty(t, NewScreener(0.99, entries).Screen("Ivan Petrof"))
+	})
+}
+
+func TestReadCSV(t *testing.T) {
+	t.Run("bad header", func(t *testing.T) {
+		_, err := ReadCSV(strings.NewReader("name,id,aliases\n"), "x")
+		assert.ErrorContains(t, err, "header must be id,name,aliases")
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, err := ReadCSV(strings.NewReader("id,name,aliases\nA-1,,\n"), "x")
+		assert.ErrorContains(t, err, "line 2: id and name are required")
+	})
+}
+
+func TestReadXML(t *testing.T) {
+	_, err := ReadXML(strings.NewReader(`<list><entry id="1"><name>X</name></entry></list>`))
+	assert.ErrorContains(t, err, "list name attribute is required")
+
+	_, err = ReadXML(strings.NewReader(`<list name="L"><entry><name>X</name></entry></list>`))
+	assert.ErrorContains(t, err, "entry 1: id and name are required")
+}
+
+func TestLoadFile(t *testing.T) {
+	_, err := LoadFile("testdata/list.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrAMLCaseClosed is returned when a case is decided twice.
+var ErrAMLCaseClosed = errors.New("AML case already closed")
+
+// TransactionMonitor screens money movements against the AML rules.
+// aml.Engine implements it.
+type TransactionMonitor interface {
+	// Screen runs the blocking rules on a transfer about to be committed in
+	// tx. Any alerts returned hold the transfer for review.
+	Screen(ctx context.Context, tx *gorm.DB, t *models.Transaction) ([]models.AMLAlert, error)
+	// Observe hands a committed transaction to the remaining rules.
+	Observe(ctx context.Context, t models.Transaction)
+}
+
+// IAMLService is the analysts' side of transaction monitoring: working the
+// queue of cases the rules raised and deciding them.
+type IAMLService interface {
+	ListCases(ctx context.Context, status string) ([]models.AMLCase, error)
+	GetCase(ctx context.Context, id uint) (*models.AMLCase, error)
+	Clear(ctx context.Context, id uint, analyst, note string) (*models.AMLCase, error)
+	Confirm(ctx context.Context, id uint, analyst, note string) (*models.AMLCase, error)
+}
+
+type AMLService struct {
+	caseRepo  *repositories.AMLCaseRepository
+	transfers *TransferService
+	db        *gorm.DB
+}
+
+var _ IAMLService = &AMLService{}
+
+func NewAMLService(
+	caseRepo *repositories.AMLCaseRepository,
+	transfers *TransferService,
+	db *gorm.DB,
+) *AMLService {
+	return &AMLService{
+		caseRepo:  caseRepo,
+		transfers: transfers,
+		db:        db,
+	}
+}
+
+func (s *AMLService) ListCases(ctx context.Context, status string) (_ []models.AMLCase, err error) {
+	ctx, span := tracing.Start(ctx, "AMLService.ListCases")
+	defer tracing.End(span, &err)
+
+	return s.caseRepo.WithContext(ctx).List(status)
+}
+
+func (s *AMLService) GetCase(ctx context.Context, id uint) (_ *models.AMLCase, err error) {
+	ctx, span := tracing.Start(ctx, "AMLService.GetCase")
+	defer tracing.End(span, &err)
+
+	return s.caseRepo.WithContext(ctx).GetByID(id)
+}
+
+// Clear closes the case as a false positive. A held transfer is booked, and
+// the case stays open if it no longer can be, e.g. for lack of funds.
+func (s *AMLService) Clear(ctx context.Context, id uint, analyst, note string) (_ *models.AMLCase, err error) {
+	ctx, span := tracing.Start(ctx, "AMLService.Clear", attribute.Int64("aml.case_id", int64(id)))
+	defer tracing.End(span, &err)
+
+	return s.close(ctx, id, models.AMLCaseStatusCleared, analyst, note)
+}
+
+// Confirm closes the case as suspicious. A held transfer fails. A note
+// recording the grounds is required.
+func (s *AMLService) Confirm(ctx context.Context, id uint, analyst, note string) (_ *models.AMLCase, err error) {
+	ctx, span := tracing.Start(ctx, "AMLService.Confirm", attribute.Int64("aml.case_id", int64(id)))
+	defer tracing.End(span, &err)
+
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("a note is required to confirm a case")
+	}
+	return s.close(ctx, id, models.AMLCaseStatusConfirmed, analyst, note)
+}
+
+func (s *AMLService) close(ctx context.Context, id uint, status, analyst, note string) (*models.AMLCase, error) {
+	analyst = strings.TrimSpace(analyst)
+	if analyst == "" {
+		return nil, errors.New("analyst is required")
+	}
+
+	var held bool
+	var transactionID uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		caseRepo := repositories.NewAMLCaseRepository(tx)
+
+		amlCase, err := caseRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if amlCase.Status != models.AMLCaseStatusOpen {
+			return ErrAMLCaseClosed
+		}
+		held, transactionID = amlCase.Held, amlCase.TransactionID
+
+		if held {
+			if status == models.AMLCaseStatusCleared {
+				err = s.transfers.releaseHeld(tx, amlCase.TransactionID)
+			} else {
+				err = failHeld(tx, amlCase.TransactionID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		amlCase.Status = status
+		amlCase.Analyst = analyst
+		amlCase.Note = note
+		amlCase.ClosedAt = &now
+		return caseRepo.Save(amlCase)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "aml case "+status,
+		slog.Uint64("case_id", uint64(id)),
+		slog.Uint64("transaction_id", uint64(transactionID)),
+		slog.Bool("held", held),
+		slog.String("analyst", analyst),
+	)
+	if held && status == models.AMLCaseStatusCleared {
+		s.transfers.finishReleased(ctx, transactionID)
+	}
+	return s.caseRepo.WithContext(ctx).GetByID(id)
+}
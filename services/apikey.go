@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrAPIKeyRevoked is returned when a key is revoked twice.
+var ErrAPIKeyRevoked = errors.New("API key already revoked")
+
+// apiKeyPrefix starts every secret so leaked keys are easy to search for.
+const apiKeyPrefix = "wk_"
+
+// maxAPIKeyNameLength matches the size of the models.APIKey column.
+const maxAPIKeyNameLength = 100
+
+// IAPIKeyService issues, lists and revokes API keys and authenticates the
+// secrets callers present.
+type IAPIKeyService interface {
+	Issue(ctx context.Context, details APIKeyDetails) (string, *models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id uint) (*models.APIKey, error)
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+// APIKeyDetails describes a new key. UserID is required for user keys and
+// not allowed for the others.
+type APIKeyDetails struct {
+	Name   string
+	Role   string
+	UserID *uint
+}
+
+type APIKeyService struct {
+	keyRepo  *repositories.APIKeyRepository
+	userRepo *repositories.UserRepository
+	db       *gorm.DB
+}
+
+var _ IAPIKeyService = &APIKeyService{}
+
+func NewAPIKeyService(keyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository, db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		keyRepo:  keyRepo,
+		userRepo: userRepo,
+		db:       db,
+	}
+}
+
+// Issue creates a key in the tenant of ctx and returns its secret, which is
+// not stored and cannot be shown again.
+func (s *APIKeyService) Issue(ctx context.Context, details APIKeyDetails) (_ string, _ *models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Issue", attribute.String("api_key.role", details.Role))
+	defer tracing.End(span, &err)
+
+	name := strings.TrimSpace(details.Name)
+	if name == "" {
+		return "", nil, errInvalid("name must not be empty")
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return "", nil, errInvalid(fmt.Sprintf("name must be at most %d characters", maxAPIKeyNameLength))
+	}
+	switch details.Role {
+	case models.APIKeyRoleUser:
+		if details.UserID == nil {
+			return "", nil, errInvalid("a user key needs a user")
+		}
+	case models.APIKeyRoleOperator, models.APIKeyRoleAdmin:
+		if details.UserID != nil {
+			return "", nil, errInvalid("only user keys act for a user")
+		}
+	default:
+		return "", nil, errInvalid("role must be user, operator or admin")
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return "", nil, err
+	}
+	key := &models.APIKey{
+		Name:   name,
+		Role:   details.Role,
+		UserID: details.UserID,
+		Prefix: secret[:len(apiKeyPrefix)+8],
+		Hash:   hashAPIKey(secret),
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if key.UserID != nil {
+			if _, err := repositories.NewUserRepository(tx).GetByID(*key.UserID); err != nil {
+				return err
+			}
+		}
+		return repositories.NewAPIKeyRepository(tx).Create(key)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "API key issued",
+		slog.Uint64("api_key_id", uint64(key.ID)),
+		slog.String("role", key.Role),
+	)
+	return secret, key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) (_ []models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.List")
+	defer tracing.End(span, &err)
+
+	return s.keyRepo.WithContext(ctx).List()
+}
+
+// Revoke stops the key from authenticating any further requests.
+func (s *APIKeyService) Revoke(ctx context.Context, id uint) (_ *models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Revoke", attribute.Int64("api_key.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	keyRepo := s.keyRepo.WithContext(ctx)
+	key, err := keyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked() {
+		return nil, ErrAPIKeyRevoked
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := keyRepo.Save(key); err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "API key revoked",
+		slog.Uint64("api_key_id", uint64(key.ID)),
+	)
+	return key, nil
+}
+
+// Authenticate returns the key whose secret the caller presented, or
+// auth.ErrInvalidKey. It runs before the request's tenant is known, so keys
+// of every tenant are searched.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (_ *models.APIKey, err error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer tracing.End(span, &err)
+
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, auth.ErrInvalidKey
+	}
+	key, err := s.keyRepo.WithContext(ctx).GetByHash(hashAPIKey(secret))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, auth.ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked() {
+		return nil, auth.ErrInvalidKey
+	}
+	return key, nil
+}
+
+// newAPIKeySecret returns apiKeyPrefix followed by 32 random bytes.
+func newAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey is what is stored for a secret. The secrets are random, so an
+// unsalted hash is enough.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/metrics"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrApprovalDecided is returned when a vote is cast on an approval that is
+// no longer pending.
+var ErrApprovalDecided = errors.New("approval already decided")
+
+// ErrAlreadyVoted is returned when an owner votes twice on an approval.
+var ErrAlreadyVoted = errors.New("owner has already voted on this approval")
+
+// IApprovalService lets the owners of joint wallets decide transfers that
+// wait for their approval.
+type IApprovalService interface {
+	Get(ctx context.Context, id uint) (*models.TransferApproval, error)
+	ListByWallet(ctx context.Context, walletID uint, status string) ([]models.TransferApproval, error)
+	Approve(ctx context.Context, id uint, note string) (*models.TransferApproval, error)
+	Reject(ctx context.Context, id uint, note string) (*models.TransferApproval, error)
+}
+
+type ApprovalService struct {
+	approvalRepo *repositories.TransferApprovalRepository
+	walletRepo   *repositories.WalletRepository
+	transfers    *TransferService
+	db           *gorm.DB
+}
+
+var _ IApprovalService = &ApprovalService{}
+
+// NewApprovalService returns an ApprovalService making approved transfers
+// through transfers.
+func NewApprovalService(approvalRepo *repositories.TransferApprovalRepository, walletRepo *repositories.WalletRepository, transfers *TransferService, db *gorm.DB) *ApprovalService {
+	return &ApprovalService{approvalRepo: approvalRepo, walletRepo: walletRepo, transfers: transfers, db: db}
+}
+
+func (s *ApprovalService) Get(ctx context.Context, id uint) (_ *models.TransferApproval, err error) {
+	ctx, span := tracing.Start(ctx, "ApprovalService.Get")
+	defer tracing.End(span, &err)
+
+	return s.approvalRepo.WithContext(ctx).GetByID(id)
+}
+
+// ListByWallet returns the approvals of transfers from the wallet, oldest
+// first, optionally filtered by status.
+func (s *ApprovalService) ListByWallet(ctx context.Context, walletID uint, status string) (_ []models.TransferApproval, err error) {
+	ctx, span := tracing.Start(ctx, "ApprovalService.ListByWallet", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	switch status {
+	case "", models.TransferApprovalStatusPending, models.TransferApprovalStatusApproved,
+		models.TransferApprovalStatusRejected, models.TransferApprovalStatusFailed:
+	default:
+		return nil, errInvalid("status must be pending, approved, rejected or failed")
+	}
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return nil, err
+	}
+	return s.approvalRepo.WithContext(ctx).ListByWallet(walletID, status)
+}
+
+// Approve records the approval of the owner whose user API key ctx was
+// authenticated with. The vote that brings the approvals to the number
+// required makes the transfer, with every check a transfer gets; if it
+// cannot be made the approval fails with the reason.
+func (s *ApprovalService) Approve(ctx context.Context, id uint, note string) (*models.TransferApproval, error) {
+	return s.decide(ctx, "ApprovalService.Approve", id, true, note)
+}
+
+// Reject records the rejection of the owner whose user API key ctx was
+// authenticated with. The approval is rejected once so many owners have
+// rejected it that the rest cannot approve it.
+func (s *ApprovalService) Reject(ctx context.Context, id uint, note string) (*models.TransferApproval, error) {
+	return s.decide(ctx, "ApprovalService.Reject", id, false, note)
+}
+
+func (s *ApprovalService) decide(ctx context.Context, name string, id uint, approve bool, note string) (_ *models.TransferApproval, err error) {
+	ctx, span := tracing.Start(ctx, name,
+		attribute.Int64("approval.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	var approval *models.TransferApproval
+	var result transferResult
+	var executed bool
+	var transferErr error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		approvalRepo := repositories.NewTransferApprovalRepository(tx)
+		approval, err = approvalRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if approval.Status != models.TransferApprovalStatusPending {
+			return ErrApprovalDecided
+		}
+		// The wallet is locked before its owners are looked up, as it is by
+		// membership changes.
+		wallet, err := repositories.NewWalletRepository(tx).GetForUpdate(approval.SourceWalletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, userID); err != nil {
+			return err
+		}
+		for _, vote := range approval.Votes {
+			if vote.UserID == userID {
+				return ErrAlreadyVoted
+			}
+		}
+
+		vote := models.ApprovalVote{ApprovalID: approval.ID, UserID: userID, Approve: approve, Note: note}
+		if err := approvalRepo.AddVote(&vote); err != nil {
+			return err
+		}
+		approval.Votes = append(approval.Votes, vote)
+
+		owners, err := countOwners(tx, wallet.ID)
+		if err != nil {
+			return err
+		}
+		approvals, rejections := approval.Approvals()
+		now := time.Now()
+		switch {
+		case approvals >= approval.RequiredApprovals:
+			executed = true
+			approval.DecidedAt = &now
+			// The transfer runs in a savepoint so that when it cannot be
+			// made the vote is kept and the approval marked failed.
+			transferErr = tx.Transaction(func(tx *gorm.DB) error {
+				var err error
+				result, err = s.transfers.transferIn(ctx, tx, approval.SourceWalletID, approval.TargetWalletID, approval.Amount, approval)
+				return err
+			})
+			switch {
+			case transferErr != nil && outcome(transferErr) == metrics.OutcomeError:
+				return transferErr
+			case transferErr != nil:
+				approval.Status = models.TransferApprovalStatusFailed
+				approval.FailureReason = transferErr.Error()
+			case result.denied:
+				approval.Status = models.TransferApprovalStatusFailed
+				approval.FailureReason = ErrTransferDenied.Error()
+				approval.TransactionID = &result.transaction.ID
+			default:
+				approval.Status = models.TransferApprovalStatusApproved
+				approval.TransactionID = &result.transaction.ID
+			}
+		case rejections > owners-approval.RequiredApprovals:
+			approval.Status = models.TransferApprovalStatusRejected
+			approval.DecidedAt = &now
+		}
+		return approvalRepo.Save(approval)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "transfer approval vote",
+		slog.Uint64("approval_id", uint64(approval.ID)),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.Bool("approve", approve),
+		slog.String("status", approval.Status),
+	)
+	if executed {
+		if transferErr == nil {
+			transferErr = s.transfers.finish(ctx, result)
+		}
+		metrics.ObserveTransfer(outcome(transferErr), approval.Amount, result.currency)
+		logResult(ctx, "transfer", transferErr,
+			slog.Uint64("source_wallet_id", uint64(approval.SourceWalletID)),
+			slog.Uint64("target_wallet_id", uint64(approval.TargetWalletID)),
+			slog.Int64("amount", approval.Amount),
+			slog.Uint64("approval_id", uint64(approval.ID)),
+		)
+	}
+	return approval, nil
+}
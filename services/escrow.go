@@ -0,0 +1,573 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/metrics"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tenancy"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrEscrowClosed is returned when an agreement that was released, refunded
+// or never funded is acted on.
+var ErrEscrowClosed = errors.New("escrow agreement is closed")
+
+// ErrEscrowPending is returned when an agreement is acted on while its
+// funding transfer is held for review.
+var ErrEscrowPending = errors.New("escrow agreement is not funded yet: its funding transfer is held for review")
+
+// ErrEscrowDisputed is returned when a disputed agreement is confirmed or
+// disputed again. Only an operator's resolution closes it.
+var ErrEscrowDisputed = errors.New("escrow agreement is disputed and awaits resolution")
+
+// ErrEscrowNotDisputed is returned when an agreement without a dispute is
+// resolved.
+var ErrEscrowNotDisputed = errors.New("escrow agreement is not disputed")
+
+// ErrEscrowExpired is returned when a funded agreement is acted on after it
+// expired. It has been refunded to the buyer by then.
+var ErrEscrowExpired = errors.New("escrow agreement expired and was refunded to the buyer")
+
+// ErrEscrowNeedsApproval is returned when an agreement is funded from a
+// joint wallet whose approval rule covers the amount.
+var ErrEscrowNeedsApproval = errors.New("the buyer wallet's approval rule covers this amount; escrow cannot wait for approval")
+
+// Escrow resolutions an operator can settle a dispute with.
+const (
+	EscrowResolveRelease = "release"
+	EscrowResolveRefund  = "refund"
+)
+
+// maxEscrowTextLength and maxEscrowResolverLength match the sizes of the
+// models.EscrowAgreement columns.
+const (
+	maxEscrowTextLength     = 255
+	maxEscrowResolverLength = 100
+)
+
+// IEscrowService holds money between a buyer and a seller until delivery.
+// Funding an agreement is a transfer from the buyer into the tenant's
+// escrow wallet, with every check a transfer gets; the money leaves escrow
+// by a payout to the seller or back to the buyer.
+type IEscrowService interface {
+	Create(ctx context.Context, details EscrowDetails) (*models.EscrowAgreement, error)
+	Get(ctx context.Context, id uint) (*models.EscrowAgreement, error)
+	ListByWallet(ctx context.Context, walletID uint, status string) ([]models.EscrowAgreement, error)
+	Confirm(ctx context.Context, id uint) (*models.EscrowAgreement, error)
+	Dispute(ctx context.Context, id uint, reason string) (*models.EscrowAgreement, error)
+	Resolve(ctx context.Context, id uint, resolution EscrowResolution) (*models.EscrowAgreement, error)
+	RefundExpired(ctx context.Context) (int, error)
+}
+
+// EscrowDetails describes a new agreement. Without ExpiresAt it expires
+// after the configured timeout.
+type EscrowDetails struct {
+	BuyerWalletID  uint
+	SellerWalletID uint
+	Amount         int64
+	Memo           string
+	ExpiresAt      *time.Time
+}
+
+// EscrowResolution settles a dispute: Outcome is EscrowResolveRelease or
+// EscrowResolveRefund, decided by the operator ResolvedBy.
+type EscrowResolution struct {
+	Outcome    string
+	ResolvedBy string
+	Note       string
+}
+
+// EscrowConfig governs escrow agreements.
+type EscrowConfig struct {
+	// Timeout is how long agreements created without an expiry last.
+	Timeout time.Duration
+}
+
+type EscrowService struct {
+	escrowRepo *repositories.EscrowRepository
+	walletRepo *repositories.WalletRepository
+	transfers  *TransferService
+	db         *gorm.DB
+	cfg        EscrowConfig
+	now        func() time.Time
+}
+
+var _ IEscrowService = &EscrowService{}
+
+// NewEscrowService returns an EscrowService moving money through
+// transfers.
+func NewEscrowService(escrowRepo *repositories.EscrowRepository, walletRepo *repositories.WalletRepository, transfers *TransferService, db *gorm.DB, cfg EscrowConfig) *EscrowService {
+	return &EscrowService{
+		escrowRepo: escrowRepo,
+		walletRepo: walletRepo,
+		transfers:  transfers,
+		db:         db,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// Create funds a new agreement by a transfer from the buyer, sent by the
+// initiator in ctx, into the escrow wallet of the buyer's currency. A
+// transfer held for review creates the agreement as pending, together with
+// a *HeldError; one denied by risk checks creates none.
+func (s *EscrowService) Create(ctx context.Context, details EscrowDetails) (_ *models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.Create",
+		attribute.Int64("wallet.source_id", int64(details.BuyerWalletID)),
+		attribute.Int64("wallet.target_id", int64(details.SellerWalletID)),
+		attribute.Int64("transaction.amount", details.Amount),
+	)
+	defer tracing.End(span, &err)
+
+	if details.Amount <= 0 {
+		return nil, errInvalid("amount must be positive")
+	}
+	if details.BuyerWalletID == details.SellerWalletID {
+		return nil, errInvalid("buyer and seller wallets cannot be the same")
+	}
+	memo := strings.TrimSpace(details.Memo)
+	if len(memo) > maxEscrowTextLength {
+		return nil, errInvalid(fmt.Sprintf("memo must be at most %d characters", maxEscrowTextLength))
+	}
+	now := s.now()
+	expiresAt := now.Add(s.cfg.Timeout)
+	if details.ExpiresAt != nil {
+		if !details.ExpiresAt.After(now) {
+			return nil, errInvalid("expires_at must be in the future")
+		}
+		expiresAt = *details.ExpiresAt
+	}
+
+	var agreement *models.EscrowAgreement
+	var result transferResult
+	var escrowWalletID uint
+	var executed bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+		buyer, err := walletRepo.GetByID(details.BuyerWalletID)
+		if err != nil {
+			return err
+		}
+		seller, err := walletRepo.GetByID(details.SellerWalletID)
+		if err != nil {
+			return err
+		}
+		if seller.IsPocket() {
+			return ErrPocketWallet
+		}
+		if seller.Currency != buyer.Currency {
+			return errInvalid("buyer and seller wallets hold different currencies")
+		}
+		if err := checkUser(&seller.User); err != nil {
+			return err
+		}
+		escrowWalletID, err = escrowWalletFor(tx, buyer)
+		if err != nil {
+			return err
+		}
+		if buyer.ID == escrowWalletID || seller.ID == escrowWalletID {
+			return errInvalid("the escrow wallet cannot buy or sell")
+		}
+
+		executed = true
+		result, err = s.transfers.transferIn(ctx, tx, buyer.ID, escrowWalletID, details.Amount, nil)
+		switch {
+		case err != nil:
+			return err
+		case result.approval != nil:
+			executed = false
+			return ErrEscrowNeedsApproval
+		case result.denied:
+			// The denied transfer is kept on record, without an agreement.
+			return nil
+		}
+
+		agreement = &models.EscrowAgreement{
+			BuyerWalletID:        buyer.ID,
+			SellerWalletID:       seller.ID,
+			EscrowWalletID:       escrowWalletID,
+			Amount:               details.Amount,
+			Memo:                 memo,
+			Status:               models.EscrowStatusFunded,
+			ExpiresAt:            expiresAt,
+			FundingTransactionID: result.transaction.ID,
+		}
+		if result.held {
+			agreement.Status = models.EscrowStatusPending
+		}
+		return repositories.NewEscrowRepository(tx).Create(agreement)
+	})
+
+	var transferErr error
+	if executed {
+		transferErr = err
+		if err == nil {
+			transferErr = s.transfers.finish(ctx, result)
+		}
+		metrics.ObserveTransfer(outcome(transferErr), details.Amount, result.currency)
+		attrs := []slog.Attr{
+			slog.Uint64("source_wallet_id", uint64(details.BuyerWalletID)),
+			slog.Uint64("target_wallet_id", uint64(escrowWalletID)),
+			slog.Int64("amount", details.Amount),
+		}
+		if agreement != nil {
+			attrs = append(attrs, slog.Uint64("escrow_agreement_id", uint64(agreement.ID)))
+		}
+		logResult(ctx, "transfer", transferErr, attrs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return agreement, transferErr
+}
+
+// Get returns the agreement. A pending agreement is reported as funded, or
+// failed, once the review of its funding transfer has decided it.
+func (s *EscrowService) Get(ctx context.Context, id uint) (_ *models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.Get", attribute.Int64("escrow_agreement.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	agreement, err := s.escrowRepo.WithContext(ctx).GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syncFunding(s.db.WithContext(ctx), agreement, s.now()); err != nil {
+		return nil, err
+	}
+	return agreement, nil
+}
+
+// ListByWallet returns the agreements the wallet buys or sells in, oldest
+// first, optionally filtered by stored status.
+func (s *EscrowService) ListByWallet(ctx context.Context, walletID uint, status string) (_ []models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.ListByWallet", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	switch status {
+	case "", models.EscrowStatusPending, models.EscrowStatusFunded, models.EscrowStatusDisputed,
+		models.EscrowStatusReleased, models.EscrowStatusRefunded, models.EscrowStatusFailed:
+	default:
+		return nil, errInvalid("status must be pending, funded, disputed, released, refunded or failed")
+	}
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return nil, err
+	}
+	return s.escrowRepo.WithContext(ctx).ListByWallet(walletID, status)
+}
+
+// Confirm records that the buyer received what they paid for and releases
+// the money to the seller. Only an owner of the buyer wallet may confirm,
+// with their user API key.
+func (s *EscrowService) Confirm(ctx context.Context, id uint) (_ *models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.Confirm",
+		attribute.Int64("escrow_agreement.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	return s.settle(ctx, id, func(tx *gorm.DB, agreement *models.EscrowAgreement) (string, error) {
+		if err := checkEscrowParty(tx, userID, agreement.BuyerWalletID); err != nil {
+			return "", err
+		}
+		if err := checkFunded(agreement); err != nil {
+			return "", err
+		}
+		return EscrowResolveRelease, nil
+	}, &userID)
+}
+
+// Dispute stops the agreement from being released or timing out until an
+// operator resolves it. An owner of either wallet may dispute, with their
+// user API key.
+func (s *EscrowService) Dispute(ctx context.Context, id uint, reason string) (_ *models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.Dispute",
+		attribute.Int64("escrow_agreement.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errInvalid("a reason is required to dispute an agreement")
+	}
+	if len(reason) > maxEscrowTextLength {
+		return nil, errInvalid(fmt.Sprintf("reason must be at most %d characters", maxEscrowTextLength))
+	}
+
+	return s.settle(ctx, id, func(tx *gorm.DB, agreement *models.EscrowAgreement) (string, error) {
+		if err := checkEscrowParty(tx, userID, agreement.BuyerWalletID, agreement.SellerWalletID); err != nil {
+			return "", err
+		}
+		if err := checkFunded(agreement); err != nil {
+			return "", err
+		}
+		agreement.Status = models.EscrowStatusDisputed
+		agreement.DisputedBy = &userID
+		agreement.DisputeReason = reason
+		return "", nil
+	}, &userID)
+}
+
+// Resolve settles a dispute on an operator's decision, releasing the money
+// to the seller or refunding it to the buyer.
+func (s *EscrowService) Resolve(ctx context.Context, id uint, resolution EscrowResolution) (_ *models.EscrowAgreement, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.Resolve",
+		attribute.Int64("escrow_agreement.id", int64(id)),
+		attribute.String("escrow.outcome", resolution.Outcome),
+	)
+	defer tracing.End(span, &err)
+
+	if resolution.Outcome != EscrowResolveRelease && resolution.Outcome != EscrowResolveRefund {
+		return nil, errInvalid("outcome must be release or refund")
+	}
+	resolvedBy := strings.TrimSpace(resolution.ResolvedBy)
+	if resolvedBy == "" {
+		return nil, errInvalid("resolved_by is required")
+	}
+	if len(resolvedBy) > maxEscrowResolverLength {
+		return nil, errInvalid(fmt.Sprintf("resolved_by must be at most %d characters", maxEscrowResolverLength))
+	}
+	note := strings.TrimSpace(resolution.Note)
+	if len(note) > maxEscrowTextLength {
+		return nil, errInvalid(fmt.Sprintf("note must be at most %d characters", maxEscrowTextLength))
+	}
+
+	return s.settle(ctx, id, func(tx *gorm.DB, agreement *models.EscrowAgreement) (string, error) {
+		switch {
+		case agreement.Closed():
+			return "", ErrEscrowClosed
+		case agreement.Status != models.EscrowStatusDisputed:
+			return "", ErrEscrowNotDisputed
+		}
+		agreement.ResolvedBy = resolvedBy
+		agreement.ResolutionNote = note
+		return resolution.Outcome, nil
+	}, nil)
+}
+
+// RefundExpired refunds every funded agreement past its expiry, across all
+// tenants, and moves on pending agreements whose funding has been decided.
+// Each agreement is settled in a transaction of its own, so one that
+// cannot be refunded, such as to a frozen wallet, is tried again on the
+// next run without holding up the rest. It returns how many were refunded.
+func (s *EscrowService) RefundExpired(ctx context.Context) (refunded int, err error) {
+	ctx, span := tracing.Start(ctx, "EscrowService.RefundExpired")
+	defer tracing.End(span, &err)
+
+	due, err := s.escrowRepo.WithContext(ctx).ListDue(s.now())
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for _, a := range due {
+		// The sweep sees every tenant; each refund is booked in the
+		// agreement's own.
+		agreement, err := s.settle(tenancy.WithTenant(ctx, a.TenantID), a.ID, func(*gorm.DB, *models.EscrowAgreement) (string, error) {
+			return "", nil
+		}, nil)
+		if err != nil && !errors.Is(err, ErrEscrowExpired) {
+			errs = append(errs, fmt.Errorf("escrow agreement %d: %w", a.ID, err))
+			continue
+		}
+		if agreement.Status == models.EscrowStatusRefunded {
+			refunded++
+		}
+	}
+	return refunded, errors.Join(errs...)
+}
+
+// settle locks the agreement and applies decide to it. A funded agreement
+// past its expiry is refunded instead, and ErrEscrowExpired returned with
+// it. decide returns the payout to make, if any, or changes the agreement
+// itself. initiatedBy is the user acting on the agreement.
+func (s *EscrowService) settle(ctx context.Context, id uint, decide func(tx *gorm.DB, agreement *models.EscrowAgreement) (string, error), initiatedBy *uint) (*models.EscrowAgreement, error) {
+	var agreement *models.EscrowAgreement
+	var result transferResult
+	var payout, before string
+	var expired bool
+	var decideErr error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		escrowRepo := repositories.NewEscrowRepository(tx)
+		var err error
+		agreement, err = escrowRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		before = agreement.Status
+		now := s.now()
+		synced, err := syncFunding(tx, agreement, now)
+		if err != nil {
+			return err
+		}
+
+		if expired = agreement.Expired(now); expired {
+			payout = EscrowResolveRefund
+			initiatedBy = nil
+		} else if payout, decideErr = decide(tx, agreement); decideErr != nil {
+			if synced {
+				// Keep what the review decided even though the action
+				// was refused.
+				return escrowRepo.Save(agreement)
+			}
+			return decideErr
+		}
+
+		if payout != "" {
+			if result, err = s.payOut(tx, agreement, payout, initiatedBy, now); err != nil {
+				return err
+			}
+		}
+		if agreement.Status == before {
+			return nil
+		}
+		return escrowRepo.Save(agreement)
+	})
+	if payout != "" {
+		payoutErr := err
+		if err == nil {
+			payoutErr = s.transfers.finish(ctx, result)
+		}
+		target := agreement.SellerWalletID
+		if payout == EscrowResolveRefund {
+			target = agreement.BuyerWalletID
+		}
+		logResult(ctx, "escrow "+payout, payoutErr,
+			slog.Uint64("escrow_agreement_id", uint64(id)),
+			slog.Uint64("source_wallet_id", uint64(agreement.EscrowWalletID)),
+			slog.Uint64("target_wallet_id", uint64(target)),
+			slog.Int64("amount", agreement.Amount),
+		)
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case decideErr != nil:
+		return nil, decideErr
+	}
+
+	if agreement.Status != before {
+		logging.For("services").InfoContext(ctx, "escrow agreement updated",
+			slog.Uint64("escrow_agreement_id", uint64(agreement.ID)),
+			slog.String("from", before),
+			slog.String("status", agreement.Status),
+		)
+	}
+	if expired {
+		return agreement, ErrEscrowExpired
+	}
+	return agreement, nil
+}
+
+// payOut pays the agreement's money out of escrow, to the seller on
+// release and to the buyer on refund, and closes the agreement.
+func (s *EscrowService) payOut(tx *gorm.DB, agreement *models.EscrowAgreement, payout string, initiatedBy *uint, now time.Time) (transferResult, error) {
+	target, status := agreement.SellerWalletID, models.EscrowStatusReleased
+	if payout == EscrowResolveRefund {
+		target, status = agreement.BuyerWalletID, models.EscrowStatusRefunded
+	}
+	result, err := s.transfers.payOutEscrow(tx, agreement.EscrowWalletID, target, agreement.Amount,
+		fmt.Sprintf("%s of escrow agreement %d", payout, agreement.ID), initiatedBy)
+	if err != nil {
+		return result, err
+	}
+	agreement.Status = status
+	agreement.ClosedAt = &now
+	if payout == EscrowResolveRefund {
+		agreement.RefundTransactionID = &result.transaction.ID
+	} else {
+		agreement.ReleaseTransactionID = &result.transaction.ID
+	}
+	return result, nil
+}
+
+// syncFunding moves a pending agreement on once the review of its funding
+// transfer has decided it: to funded if the transfer was booked, to failed
+// if it was refused. It reports whether the agreement changed; saving it is
+// up to the caller.
+func syncFunding(tx *gorm.DB, agreement *models.EscrowAgreement, now time.Time) (bool, error) {
+	if agreement.Status != models.EscrowStatusPending {
+		return false, nil
+	}
+	funding, err := repositories.NewTransactionRepository(tx).GetByID(agreement.FundingTransactionID)
+	if err != nil {
+		return false, err
+	}
+	switch funding.Status {
+	case models.TransactionStatusCompleted:
+		agreement.Status = models.EscrowStatusFunded
+	case models.TransactionStatusFailed:
+		agreement.Status = models.EscrowStatusFailed
+		agreement.ClosedAt = &now
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkFunded rejects acting on an agreement that is not funded and
+// undisputed.
+func checkFunded(agreement *models.EscrowAgreement) error {
+	switch {
+	case agreement.Closed():
+		return ErrEscrowClosed
+	case agreement.Status == models.EscrowStatusPending:
+		return ErrEscrowPending
+	case agreement.Status == models.EscrowStatusDisputed:
+		return ErrEscrowDisputed
+	}
+	return nil
+}
+
+// checkEscrowParty rejects a user who owns none of the wallets.
+func checkEscrowParty(tx *gorm.DB, userID uint, walletIDs ...uint) error {
+	walletRepo := repositories.NewWalletRepository(tx)
+	for _, id := range walletIDs {
+		wallet, err := walletRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		owner, err := isOwner(tx, wallet, userID)
+		if err != nil {
+			return err
+		}
+		if owner {
+			return nil
+		}
+	}
+	return ErrWalletRoleForbidden
+}
+
+// escrowWalletFor returns the escrow wallet the tenant of wallet keeps in
+// its currency.
+func escrowWalletFor(tx *gorm.DB, wallet *models.Wallet) (uint, error) {
+	tenant, err := repositories.NewTenantRepository(tx).GetByID(wallet.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	id, ok := tenant.EscrowWallets[wallet.Currency]
+	if !ok || id == 0 {
+		return 0, errInvalid(fmt.Sprintf("no escrow wallet is set up for %s", wallet.Currency))
+	}
+	return id, nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVerificationPending is returned when a user submits a case while
+	// another is still awaiting review.
+	ErrVerificationPending = errors.New("user already has a pending verification case")
+	// ErrVerificationReviewed is returned when a case is reviewed twice.
+	ErrVerificationReviewed = errors.New("verification case already reviewed")
+)
+
+// IKYCService runs the identity verification workflow: users submit
+// document metadata, operators approve or reject, and approval raises the
+// user's KYC level.
+type IKYCService interface {
+	Submit(ctx context.Context, userID uint, level models.KYCLevel, documents []models.VerificationDocument) (*models.VerificationCase, error)
+	GetCase(ctx context.Context, id uint) (*models.VerificationCase, error)
+	ListCases(ctx context.Context, status string) ([]models.VerificationCase, error)
+	ListUserCases(ctx context.Context, userID uint) ([]models.VerificationCase, error)
+	Approve(ctx context.Context, id uint, reviewer, note string) (*models.VerificationCase, error)
+	Reject(ctx context.Context, id uint, reviewer, note string) (*models.VerificationCase, error)
+}
+
+type KYCService struct {
+	caseRepo *repositories.VerificationCaseRepository
+	userRepo *repositories.UserRepository
+	db       *gorm.DB
+}
+
+var _ IKYCService = &KYCService{}
+
+func NewKYCService(
+	caseRepo *repositories.VerificationCaseRepository,
+	userRepo *repositories.UserRepository,
+	db *gorm.DB,
+) *KYCService {
+	return &KYCService{
+		caseRepo: caseRepo,
+		userRepo: userRepo,
+		db:       db,
+	}
+}
+
+var identityDocuments = map[string]bool{
+	models.DocumentTypePassport:       true,
+	models.DocumentTypeNationalID:     true,
+	models.DocumentTypeDrivingLicence: true,
+}
+
+// validateDocuments checks that documents support level: basic needs an
+// identity document, full additionally a proof of address. Expired
+// documents are refused.
+func validateDocuments(level models.KYCLevel, documents []models.VerificationDocument, today time.Time) error {
+	var identity, address bool
+	for _, doc := range documents {
+		switch {
+		case identityDocuments[doc.Type]:
+			identity = true
+		case doc.Type == models.DocumentTypeProofOfAddress:
+			address = true
+		default:
+			return fmt.Errorf("unknown document type %q", doc.Type)
+		}
+		if doc.ExpiresOn != nil && doc.ExpiresOn.Before(today) {
+			return fmt.Errorf("%s document has expired", doc.Type)
+		}
+	}
+
+	if !identity {
+		return errors.New("an identity document (passport, national_id or driving_licence) is required")
+	}
+	if level == models.KYCLevelFull && !address {
+		return errors.New("full verification also requires a proof_of_address document")
+	}
+	return nil
+}
+
+// Submit opens a case asking for the user to be verified at level.
+func (s *KYCService) Submit(ctx context.Context, userID uint, level models.KYCLevel, documents []models.VerificationDocument) (_ *models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.Submit",
+		attribute.Int64("user.id", int64(userID)),
+		attribute.String("kyc.level", string(level)),
+	)
+	defer tracing.End(span, &err)
+
+	if level != models.KYCLevelBasic && level != models.KYCLevelFull {
+		return nil, errors.New("level must be basic or full")
+	}
+	if err := validateDocuments(level, documents, StartOfDay(time.Now())); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.WithContext(ctx).GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.KYCLevel.Rank() >= level.Rank() {
+		return nil, fmt.Errorf("user is already verified at level %s", user.KYCLevel)
+	}
+
+	verificationCase := &models.VerificationCase{
+		UserID:         userID,
+		RequestedLevel: level,
+		Status:         models.VerificationStatusPending,
+		Documents:      documents,
+		History: []models.VerificationEvent{{
+			Status: models.VerificationStatusPending,
+			Actor:  actor(ctx, fmt.Sprintf("user:%d", userID)),
+			Note:   "submitted",
+		}},
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		caseRepo := repositories.NewVerificationCaseRepository(tx)
+
+		// Lock the user so two submissions cannot both see no pending case
+		if _, err := repositories.NewUserRepository(tx).GetForUpdate(userID); err != nil {
+			return err
+		}
+		pending, err := caseRepo.HasPending(userID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return ErrVerificationPending
+		}
+		return caseRepo.Create(verificationCase)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "verification submitted",
+		slog.Uint64("case_id", uint64(verificationCase.ID)),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.String("level", string(level)),
+	)
+	return verificationCase, nil
+}
+
+func (s *KYCService) GetCase(ctx context.Context, id uint) (_ *models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.GetCase")
+	defer tracing.End(span, &err)
+
+	return s.caseRepo.WithContext(ctx).GetByID(id)
+}
+
+func (s *KYCService) ListCases(ctx context.Context, status string) (_ []models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.ListCases")
+	defer tracing.End(span, &err)
+
+	return s.caseRepo.WithContext(ctx).List(status)
+}
+
+func (s *KYCService) ListUserCases(ctx context.Context, userID uint) (_ []models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.ListUserCases")
+	defer tracing.End(span, &err)
+
+	if _, err := s.userRepo.WithContext(ctx).GetByID(userID); err != nil {
+		return nil, err
+	}
+	return s.caseRepo.WithContext(ctx).ListByUserID(userID)
+}
+
+// Approve closes the case and raises the user to the requested level.
+func (s *KYCService) Approve(ctx context.Context, id uint, reviewer, note string) (_ *models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.Approve")
+	defer tracing.End(span, &err)
+
+	return s.review(ctx, id, models.VerificationStatusApproved, reviewer, note)
+}
+
+// Reject closes the case without changing the user's level. A note telling
+// the user what to fix is required.
+func (s *KYCService) Reject(ctx context.Context, id uint, reviewer, note string) (_ *models.VerificationCase, err error) {
+	ctx, span := tracing.Start(ctx, "KYCService.Reject")
+	defer tracing.End(span, &err)
+
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("a note is required to reject a case")
+	}
+	return s.review(ctx, id, models.VerificationStatusRejected, reviewer, note)
+}
+
+func (s *KYCService) review(ctx context.Context, id uint, status, reviewer, note string) (*models.VerificationCase, error) {
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		return nil, errors.New("reviewer is required")
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		caseRepo := repositories.NewVerificationCaseRepository(tx)
+
+		verificationCase, err := caseRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if verificationCase.Status != models.VerificationStatusPending {
+			return ErrVerificationReviewed
+		}
+
+		now := time.Now()
+		verificationCase.Status = status
+		verificationCase.Reviewer = reviewer
+		verificationCase.ReviewNote = note
+		verificationCase.ReviewedAt = &now
+		if err := caseRepo.Save(verificationCase); err != nil {
+			return err
+		}
+		if err := caseRepo.AddEvent(&models.VerificationEvent{
+			CaseID: id,
+			Status: status,
+			Actor:  reviewer,
+			Note:   note,
+		}); err != nil {
+			return err
+		}
+
+		if status == models.VerificationStatusApproved {
+			return repositories.NewUserRepository(tx).SetKYCLevel(verificationCase.UserID, verificationCase.RequestedLevel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "verification "+status,
+		slog.Uint64("case_id", uint64(id)),
+		slog.String("reviewer", reviewer),
+	)
+	return s.caseRepo.WithContext(ctx).GetByID(id)
+}
+
+// actor returns who is acting according to ctx, or fallback.
+func actor(ctx context.Context, fallback string) string {
+	if a := logging.Actor(ctx); a != "" {
+		return a
+	}
+	return fallback
+}
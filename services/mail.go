@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/mail"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrVerificationInvalid is returned for a verification token that was never
+// issued, has been used, or was issued for an address the user no longer
+// has.
+var ErrVerificationInvalid = errors.New("invalid verification token")
+
+// ErrVerificationExpired is returned for a verification token past its expiry
+// or superseded by a newer one.
+var ErrVerificationExpired = errors.New("verification token expired")
+
+// ErrEmailAlreadyVerified is returned when a verification is requested for
+// an address that is already verified.
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// ErrVerificationTooSoon is returned when a verification is resent before
+// the cooldown since the last one has passed.
+var ErrVerificationTooSoon = errors.New("verification sent too recently")
+
+// EmailVerifier sends a user a link to confirm their email address.
+// UserService calls it for new users and changed addresses.
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, user *models.User) error
+}
+
+// IEmailVerificationService issues and redeems email verification tokens.
+type IEmailVerificationService interface {
+	Resend(ctx context.Context, userID uint) (*models.EmailVerification, error)
+	Verify(ctx context.Context, token string) (*models.User, error)
+}
+
+// EmailVerificationConfig controls the verification emails. Tokens are
+// appended to URL as the token query parameter, are valid for TTL, and can
+// be resent once Cooldown has passed since the last one.
+type EmailVerificationConfig struct {
+	URL      string
+	TTL      time.Duration
+	Cooldown time.Duration
+}
+
+type EmailVerificationService struct {
+	verificationRepo *repositories.EmailVerificationRepository
+	userRepo         *repositories.UserRepository
+	db               *gorm.DB
+	mailer           mail.Mailer
+	templates        *mail.Templates
+	config           EmailVerificationConfig
+}
+
+var (
+	_ IEmailVerificationService = &EmailVerificationService{}
+	_ EmailVerifier             = &EmailVerificationService{}
+)
+
+// NewEmailVerificationService returns an EmailVerificationService sending
+// its emails through mailer.
+func NewEmailVerificationService(
+	verificationRepo *repositories.EmailVerificationRepository,
+	userRepo *repositories.UserRepository,
+	db *gorm.DB,
+	mailer mail.Mailer,
+	templates *mail.Templates,
+	config EmailVerificationConfig,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		verificationRepo: verificationRepo,
+		userRepo:         userRepo,
+		db:               db,
+		mailer:           mailer,
+		templates:        templates,
+		config:           config,
+	}
+}
+
+// SendVerification issues a token for the user's current address and
+// emails them the link, expiring the tokens sent before it.
+func (s *EmailVerificationService) SendVerification(ctx context.Context, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.SendVerification", attribute.Int64("user.id", int64(user.ID)))
+	defer tracing.End(span, &err)
+
+	_, err = s.issue(ctx, user.ID, false)
+	return err
+}
+
+// Resend issues a new token for the user, refusing while their address is
+// verified or within the cooldown of the last one.
+func (s *EmailVerificationService) Resend(ctx context.Context, userID uint) (_ *models.EmailVerification, err error) {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.Resend", attribute.Int64("user.id", int64(userID)))
+	defer tracing.End(span, &err)
+
+	return s.issue(ctx, userID, true)
+}
+
+func (s *EmailVerificationService) issue(ctx context.Context, userID uint, resend bool) (*models.EmailVerification, error) {
+	token, err := newVerificationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	var verification *models.EmailVerification
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		verificationRepo := repositories.NewEmailVerificationRepository(tx)
+
+		// Locking the user serializes resends, so the cooldown holds
+		// under concurrent requests.
+		var err error
+		user, err = repositories.NewUserRepository(tx).GetForUpdate(userID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if resend {
+			if user.EmailVerifiedAt != nil {
+				return ErrEmailAlreadyVerified
+			}
+			latest, err := verificationRepo.Latest(userID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if latest != nil && latest.Email == user.Email && now.Sub(latest.CreatedAt) < s.config.Cooldown {
+				return ErrVerificationTooSoon
+			}
+		}
+
+		if err := verificationRepo.ExpireOutstanding(userID, now); err != nil {
+			return err
+		}
+		verification = &models.EmailVerification{
+			UserID:    userID,
+			Email:     user.Email,
+			TokenHash: hashVerificationToken(token),
+			ExpiresAt: now.Add(s.config.TTL),
+		}
+		return verificationRepo.Create(verification)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.link(token)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.templates.Render(mail.TemplateVerifyEmail, user.Email, mail.VerifyEmailData{
+		Name:      user.Name,
+		Link:      link,
+		ExpiresAt: verification.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "email verification sent",
+		slog.Uint64("user_id", uint64(userID)),
+		slog.Uint64("verification_id", uint64(verification.ID)),
+		slog.Time("expires_at", verification.ExpiresAt),
+	)
+	return verification, nil
+}
+
+// Verify redeems token, marking the address it was issued for verified.
+func (s *EmailVerificationService) Verify(ctx context.Context, token string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "EmailVerificationService.Verify")
+	defer tracing.End(span, &err)
+
+	if token == "" {
+		return nil, ErrVerificationInvalid
+	}
+
+	var user *models.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		verificationRepo := repositories.NewEmailVerificationRepository(tx)
+		userRepo := repositories.NewUserRepository(tx)
+
+		verification, err := verificationRepo.GetByTokenHashForUpdate(hashVerificationToken(token))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if verification.UsedAt != nil {
+			return ErrVerificationInvalid
+		}
+		now := time.Now()
+		if !now.Before(verification.ExpiresAt) {
+			return ErrVerificationExpired
+		}
+
+		user, err = userRepo.GetForUpdate(verification.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if user.Email != verification.Email {
+			return ErrVerificationInvalid
+		}
+
+		if err := verificationRepo.MarkUsed(verification.ID, now); err != nil {
+			return err
+		}
+		if err := userRepo.SetEmailVerified(user.ID, now); err != nil {
+			return err
+		}
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "email verified",
+		slog.Uint64("user_id", uint64(user.ID)),
+	)
+	return user, nil
+}
+
+// link returns the verification URL carrying token.
+func (s *EmailVerificationService) link(token string) (string, error) {
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// newVerificationToken returns 32 random bytes, base64url encoded.
+func newVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
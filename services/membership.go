@@ -0,0 +1,516 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-api/auth"
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrNotWalletMember is returned when someone other than a wallet's owner
+// or members acts on it.
+var ErrNotWalletMember = errors.New("user is not a member of the wallet")
+
+// ErrWalletRoleForbidden is returned when a member's role does not allow
+// what they asked for: viewers spending, or anyone but an owner deciding an
+// approval or changing the wallet's members and approval rule.
+var ErrWalletRoleForbidden = errors.New("the member's role does not allow this")
+
+// ErrSpendingCapExceeded is returned when a transfer would take a member
+// past their spending cap.
+var ErrSpendingCapExceeded = errors.New("spending cap exceeded")
+
+// ErrAlreadyMember is returned when a user is added to a wallet they
+// already own or are a member of.
+var ErrAlreadyMember = errors.New("user is already a member of the wallet")
+
+// ErrApprovalRuleUnmet is returned when a change would leave a wallet with
+// fewer owners than its approval rule requires approvals.
+var ErrApprovalRuleUnmet = errors.New("the wallet's approval rule needs more owners")
+
+// ErrApprovalRequired is returned, wrapped in an *ApprovalRequiredError,
+// when a transfer waits for the owners of its wallet.
+var ErrApprovalRequired = errors.New("transfer awaiting approval")
+
+// ApprovalRequiredError reports a transfer recorded as a pending approval
+// instead of made. It is made once enough owners approve it.
+type ApprovalRequiredError struct {
+	ApprovalID uint
+}
+
+func (e *ApprovalRequiredError) Error() string { return ErrApprovalRequired.Error() }
+
+func (e *ApprovalRequiredError) Is(target error) bool { return target == ErrApprovalRequired }
+
+// ErrInitiatorRequired is returned when a transfer, or another action taken
+// as a user, is not sent with a user API key, so there is no user to take
+// it.
+var ErrInitiatorRequired = errors.New("a user API key is required")
+
+// initiator returns the user acting with ctx, such as the sender of a
+// transfer: the user whose API key authenticated it.
+func initiator(ctx context.Context) (uint, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Role != models.APIKeyRoleUser || p.UserID == nil {
+		return 0, ErrInitiatorRequired
+	}
+	return *p.UserID, nil
+}
+
+// checkSpender rejects a transfer of amount from the locked wallet that the
+// user may not send: users without a membership, viewers, deactivated
+// members and members over their spending cap. The wallet's primary owner
+// may always send.
+func checkSpender(tx *gorm.DB, wallet *models.Wallet, userID uint, amount int64) error {
+	if userID == wallet.UserID {
+		return nil
+	}
+	member, err := repositories.NewWalletMemberRepository(tx).Get(wallet.ID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotWalletMember
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role == models.WalletRoleViewer {
+		return fmt.Errorf("%w: viewers cannot send transfers", ErrWalletRoleForbidden)
+	}
+	user, err := repositories.NewUserRepository(tx).GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkUser(user); err != nil {
+		return err
+	}
+	if member.SpendingCap == nil {
+		return nil
+	}
+	// The wallet row is locked, so transfers by the same member are counted
+	// one at a time.
+	sent, err := repositories.NewTransactionRepository(tx).InitiatedSince(wallet.ID, userID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent+amount > *member.SpendingCap {
+		return fmt.Errorf("%w: %d of %d allowed per 24 hours already sent", ErrSpendingCapExceeded, sent, *member.SpendingCap)
+	}
+	return nil
+}
+
+// isOwner reports whether the user owns the wallet, as its primary owner or
+// a member with the owner role.
+func isOwner(tx *gorm.DB, wallet *models.Wallet, userID uint) (bool, error) {
+	if userID == wallet.UserID {
+		return true, nil
+	}
+	member, err := repositories.NewWalletMemberRepository(tx).Get(wallet.ID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.Role == models.WalletRoleOwner, nil
+}
+
+// checkOwner rejects a user who does not own the locked wallet.
+func checkOwner(tx *gorm.DB, wallet *models.Wallet, userID uint) error {
+	owner, err := isOwner(tx, wallet, userID)
+	if err != nil {
+		return err
+	}
+	if !owner {
+		return ErrWalletRoleForbidden
+	}
+	return nil
+}
+
+// countOwners counts the owners of the wallet, its primary owner included.
+func countOwners(tx *gorm.DB, walletID uint) (int, error) {
+	members, err := repositories.NewWalletMemberRepository(tx).CountOwners(walletID)
+	return int(members) + 1, err
+}
+
+// requestApproval records a pending approval for a transfer of amount from
+// the locked wallet when its approval rule covers it. Asking for the
+// transfer counts as the approval of a requester who owns the wallet, so
+// nil is returned when that is all the rule requires.
+func requestApproval(tx *gorm.DB, wallet *models.Wallet, targetWalletID, requestedBy uint, amount int64) (*models.TransferApproval, error) {
+	if wallet.ApprovalThreshold == nil || amount <= *wallet.ApprovalThreshold {
+		return nil, nil
+	}
+
+	approval := &models.TransferApproval{
+		SourceWalletID:    wallet.ID,
+		TargetWalletID:    targetWalletID,
+		Amount:            amount,
+		RequestedBy:       requestedBy,
+		RequiredApprovals: wallet.RequiredApprovals,
+		Status:            models.TransferApprovalStatusPending,
+	}
+	owner, err := isOwner(tx, wallet, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+	if owner {
+		if wallet.RequiredApprovals <= 1 {
+			return nil, nil
+		}
+		approval.Votes = []models.ApprovalVote{{UserID: requestedBy, Approve: true}}
+	}
+	return approval, repositories.NewTransferApprovalRepository(tx).Create(approval)
+}
+
+// IMembershipService manages who shares a wallet and the approval rule for
+// its large transfers. Changes are made by an owner of the wallet, the user
+// of the API key.
+type IMembershipService interface {
+	AddMember(ctx context.Context, walletID uint, details MemberDetails) (*models.WalletMember, error)
+	ListMembers(ctx context.Context, walletID uint) ([]models.WalletMember, error)
+	UpdateMember(ctx context.Context, walletID, userID uint, update MemberUpdate) (*models.WalletMember, error)
+	RemoveMember(ctx context.Context, walletID, userID uint) error
+	SetApprovalRule(ctx context.Context, walletID uint, rule ApprovalRule) (*models.Wallet, error)
+	ClearApprovalRule(ctx context.Context, walletID uint) (*models.Wallet, error)
+}
+
+// MemberDetails describes a new member of a wallet.
+type MemberDetails struct {
+	UserID      uint
+	Role        models.WalletRole
+	SpendingCap *int64
+}
+
+// MemberUpdate changes a member. Nil fields are left alone; a SpendingCap
+// of 0 removes the cap.
+type MemberUpdate struct {
+	Role        *models.WalletRole
+	SpendingCap *int64
+}
+
+// ApprovalRule makes transfers above Threshold wait for RequiredApprovals
+// owners.
+type ApprovalRule struct {
+	Threshold         int64
+	RequiredApprovals int
+}
+
+type MembershipService struct {
+	memberRepo *repositories.WalletMemberRepository
+	walletRepo *repositories.WalletRepository
+	db         *gorm.DB
+}
+
+var _ IMembershipService = &MembershipService{}
+
+func NewMembershipService(memberRepo *repositories.WalletMemberRepository, walletRepo *repositories.WalletRepository, db *gorm.DB) *MembershipService {
+	return &MembershipService{memberRepo: memberRepo, walletRepo: walletRepo, db: db}
+}
+
+// AddMember gives a user a role in the wallet. The wallet row is locked
+// while memberships change, as it is by transfers from it, so a transfer is
+// checked against either the old members or the new ones.
+func (s *MembershipService) AddMember(ctx context.Context, walletID uint, details MemberDetails) (_ *models.WalletMember, err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.AddMember",
+		attribute.Int64("wallet.id", int64(walletID)),
+		attribute.Int64("user.id", int64(details.UserID)),
+	)
+	defer tracing.End(span, &err)
+
+	if !details.Role.Valid() {
+		return nil, errInvalid("role must be owner, spender or viewer")
+	}
+	if err := checkSpendingCap(details.Role, details.SpendingCap); err != nil {
+		return nil, err
+	}
+	ownerID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.WalletMember{
+		WalletID:    walletID,
+		UserID:      details.UserID,
+		Role:        details.Role,
+		SpendingCap: details.SpendingCap,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := repositories.NewWalletRepository(tx).GetForUpdate(walletID)
+		if err != nil {
+			return err
+		}
+		if wallet.IsPocket() {
+			return ErrPocketWallet
+		}
+		if err := checkOwner(tx, wallet, ownerID); err != nil {
+			return err
+		}
+		user, err := repositories.NewUserRepository(tx).GetByID(details.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkUser(user); err != nil {
+			return err
+		}
+
+		memberRepo := repositories.NewWalletMemberRepository(tx)
+		if user.ID == wallet.UserID {
+			return ErrAlreadyMember
+		}
+		if _, err := memberRepo.Get(walletID, user.ID); err == nil {
+			return ErrAlreadyMember
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return memberRepo.Create(member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "wallet member added",
+		slog.Uint64("wallet_id", uint64(walletID)),
+		slog.Uint64("user_id", uint64(details.UserID)),
+		slog.String("role", string(details.Role)),
+	)
+	return member, nil
+}
+
+func (s *MembershipService) ListMembers(ctx context.Context, walletID uint) (_ []models.WalletMember, err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.ListMembers", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.WithContext(ctx).ListByWallet(walletID)
+}
+
+// UpdateMember changes a member's role or spending cap. An owner cannot be
+// made anything else while the approval rule needs them.
+func (s *MembershipService) UpdateMember(ctx context.Context, walletID, userID uint, update MemberUpdate) (_ *models.WalletMember, err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.UpdateMember",
+		attribute.Int64("wallet.id", int64(walletID)),
+		attribute.Int64("user.id", int64(userID)),
+	)
+	defer tracing.End(span, &err)
+
+	if update.Role != nil && !update.Role.Valid() {
+		return nil, errInvalid("role must be owner, spender or viewer")
+	}
+	ownerID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var member *models.WalletMember
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := repositories.NewWalletRepository(tx).GetForUpdate(walletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, ownerID); err != nil {
+			return err
+		}
+		memberRepo := repositories.NewWalletMemberRepository(tx)
+		member, err = memberRepo.Get(walletID, userID)
+		if err != nil {
+			return err
+		}
+
+		wasOwner := member.Role == models.WalletRoleOwner
+		if update.Role != nil {
+			member.Role = *update.Role
+		}
+		if update.SpendingCap != nil {
+			member.SpendingCap = update.SpendingCap
+			if *update.SpendingCap == 0 {
+				member.SpendingCap = nil
+			}
+		}
+		if err := checkSpendingCap(member.Role, member.SpendingCap); err != nil {
+			return err
+		}
+		if wasOwner && member.Role != models.WalletRoleOwner {
+			if err := checkOwnersLeft(tx, wallet); err != nil {
+				return err
+			}
+		}
+		return memberRepo.Update(member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "wallet member updated",
+		slog.Uint64("wallet_id", uint64(walletID)),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.String("role", string(member.Role)),
+	)
+	return member, nil
+}
+
+// RemoveMember takes the user out of the wallet. An owner cannot be removed
+// while the approval rule needs them.
+func (s *MembershipService) RemoveMember(ctx context.Context, walletID, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.RemoveMember",
+		attribute.Int64("wallet.id", int64(walletID)),
+		attribute.Int64("user.id", int64(userID)),
+	)
+	defer tracing.End(span, &err)
+
+	ownerID, err := initiator(ctx)
+	if err != nil {
+		return err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := repositories.NewWalletRepository(tx).GetForUpdate(walletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, ownerID); err != nil {
+			return err
+		}
+		memberRepo := repositories.NewWalletMemberRepository(tx)
+		member, err := memberRepo.Get(walletID, userID)
+		if err != nil {
+			return err
+		}
+		if err := memberRepo.Delete(walletID, userID); err != nil {
+			return err
+		}
+		if member.Role == models.WalletRoleOwner {
+			return checkOwnersLeft(tx, wallet)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.For("services").InfoContext(ctx, "wallet member removed",
+		slog.Uint64("wallet_id", uint64(walletID)),
+		slog.Uint64("user_id", uint64(userID)),
+	)
+	return nil
+}
+
+// SetApprovalRule makes transfers from the wallet above rule.Threshold wait
+// for rule.RequiredApprovals of its owners. Transfers already waiting keep
+// the rule they were made under.
+func (s *MembershipService) SetApprovalRule(ctx context.Context, walletID uint, rule ApprovalRule) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.SetApprovalRule", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	if rule.Threshold < 0 {
+		return nil, errInvalid("threshold cannot be negative")
+	}
+	if rule.RequiredApprovals < 1 {
+		return nil, errInvalid("required_approvals must be at least 1")
+	}
+	ownerID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+		wallet, err := walletRepo.GetForUpdate(walletID)
+		if err != nil {
+			return err
+		}
+		if wallet.IsPocket() {
+			return ErrPocketWallet
+		}
+		if err := checkOwner(tx, wallet, ownerID); err != nil {
+			return err
+		}
+		owners, err := countOwners(tx, walletID)
+		if err != nil {
+			return err
+		}
+		if rule.RequiredApprovals > owners {
+			return fmt.Errorf("%w: %d approvals required but the wallet has %d owners", ErrApprovalRuleUnmet, rule.RequiredApprovals, owners)
+		}
+		return walletRepo.SetApprovalRule(walletID, &rule.Threshold, rule.RequiredApprovals)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "wallet approval rule set",
+		slog.Uint64("wallet_id", uint64(walletID)),
+		slog.Int64("threshold", rule.Threshold),
+		slog.Int("required_approvals", rule.RequiredApprovals),
+	)
+	return s.walletRepo.WithContext(ctx).GetByID(walletID)
+}
+
+// ClearApprovalRule lets transfers from the wallet go ahead without
+// approval. Transfers already waiting still need theirs.
+func (s *MembershipService) ClearApprovalRule(ctx context.Context, walletID uint) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "MembershipService.ClearApprovalRule", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	ownerID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+		wallet, err := walletRepo.GetForUpdate(walletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, ownerID); err != nil {
+			return err
+		}
+		return walletRepo.SetApprovalRule(walletID, nil, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "wallet approval rule cleared", slog.Uint64("wallet_id", uint64(walletID)))
+	return s.walletRepo.WithContext(ctx).GetByID(walletID)
+}
+
+// checkSpendingCap rejects caps that are not positive and caps on viewers,
+// who cannot spend.
+func checkSpendingCap(role models.WalletRole, spendingCap *int64) error {
+	if spendingCap == nil {
+		return nil
+	}
+	if *spendingCap <= 0 {
+		return errInvalid("spending_cap must be positive")
+	}
+	if role == models.WalletRoleViewer {
+		return errInvalid("viewers cannot have a spending cap")
+	}
+	return nil
+}
+
+// checkOwnersLeft rejects a change that left the locked wallet with fewer
+// owners than its approval rule requires approvals.
+func checkOwnersLeft(tx *gorm.DB, wallet *models.Wallet) error {
+	if wallet.ApprovalThreshold == nil {
+		return nil
+	}
+	owners, err := countOwners(tx, wallet.ID)
+	if err != nil {
+		return err
+	}
+	if owners < wallet.RequiredApprovals {
+		return fmt.Errorf("%w: %d approvals required; remove the rule or lower it first", ErrApprovalRuleUnmet, wallet.RequiredApprovals)
+	}
+	return nil
+}
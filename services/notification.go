@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"wallet-api/logging"
+	"wallet-api/mail"
+	"wallet-api/models"
+	"wallet-api/repositories"
+)
+
+// TransferNotifier is told about every transfer that completes, whether
+// straight away or on release after review. Notifier implements it.
+type TransferNotifier interface {
+	TransferCompleted(ctx context.Context, t models.Transaction)
+}
+
+// NotificationThresholds decide which debits are worth an email, in minor
+// units. Zero turns the email off.
+type NotificationThresholds struct {
+	// LargeDebit is the amount from which senders are told of a transfer.
+	LargeDebit int64
+	// LowBalance is the balance under which a sender is warned when a
+	// transfer takes their wallet below it.
+	LowBalance int64
+}
+
+// Notifier emails wallet owners about their transfers: recipients about
+// money received, senders about large debits and balances running low.
+// Only verified addresses are written to.
+type Notifier struct {
+	userRepo   *repositories.UserRepository
+	walletRepo *repositories.WalletRepository
+	mailer     mail.Mailer
+	templates  *mail.Templates
+	thresholds NotificationThresholds
+}
+
+var _ TransferNotifier = &Notifier{}
+
+// NewNotifier returns a Notifier sending through mailer.
+func NewNotifier(
+	userRepo *repositories.UserRepository,
+	walletRepo *repositories.WalletRepository,
+	mailer mail.Mailer,
+	templates *mail.Templates,
+	thresholds NotificationThresholds,
+) *Notifier {
+	return &Notifier{
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+		mailer:     mailer,
+		templates:  templates,
+		thresholds: thresholds,
+	}
+}
+
+// TransferCompleted sends the emails t calls for. Balances are read after
+// the transfer committed, so a quick succession of transfers may report a
+// later balance. Failures are logged; the transfer has happened regardless.
+func (n *Notifier) TransferCompleted(ctx context.Context, t models.Transaction) {
+	if t.SourceWalletID == nil {
+		return
+	}
+	walletRepo := n.walletRepo.WithContext(ctx)
+
+	target, err := walletRepo.GetByID(t.TargetWalletID)
+	if err != nil {
+		n.failed(ctx, t, err)
+		return
+	}
+	n.send(ctx, t, target.UserID, mail.TemplateTransferReceived, mail.TransferData{
+		WalletID:             target.ID,
+		Currency:             target.Currency,
+		CounterpartyWalletID: *t.SourceWalletID,
+		Amount:               t.Amount,
+		Balance:              target.Balance,
+		Reference:            t.ReferenceNumber,
+		At:                   t.CreatedAt,
+	})
+
+	large := n.thresholds.LargeDebit > 0 && t.Amount >= n.thresholds.LargeDebit
+	low := n.thresholds.LowBalance > 0
+	if !large && !low {
+		return
+	}
+	source, err := walletRepo.GetByID(*t.SourceWalletID)
+	if err != nil {
+		n.failed(ctx, t, err)
+		return
+	}
+	if large {
+		n.send(ctx, t, source.UserID, mail.TemplateLargeDebit, mail.TransferData{
+			WalletID:             source.ID,
+			Currency:             source.Currency,
+			CounterpartyWalletID: target.ID,
+			Amount:               t.Amount,
+			Balance:              source.Balance,
+			Reference:            t.ReferenceNumber,
+			At:                   t.CreatedAt,
+		})
+	}
+	// Warn only on the transfer that crossed the threshold, not on every
+	// one after it.
+	if low && source.Balance < n.thresholds.LowBalance && source.Balance+t.Amount >= n.thresholds.LowBalance {
+		n.send(ctx, t, source.UserID, mail.TemplateLowBalance, mail.LowBalanceData{
+			WalletID:  source.ID,
+			Currency:  source.Currency,
+			Balance:   source.Balance,
+			Threshold: n.thresholds.LowBalance,
+		})
+	}
+}
+
+// send renders the template for the user and hands it to the mailer. The
+// data's Name is filled in from the user.
+func (n *Notifier) send(ctx context.Context, t models.Transaction, userID uint, template string, data any) {
+	user, err := n.userRepo.WithContext(ctx).GetByID(userID)
+	if err != nil {
+		n.failed(ctx, t, err)
+		return
+	}
+	if user.EmailVerifiedAt == nil || !user.Active() {
+		return
+	}
+
+	switch d := data.(type) {
+	case mail.TransferData:
+		d.Name = user.Name
+		data = d
+	case mail.LowBalanceData:
+		d.Name = user.Name
+		data = d
+	}
+	msg, err := n.templates.Render(template, user.Email, data)
+	if err != nil {
+		n.failed(ctx, t, err)
+		return
+	}
+	if err := n.mailer.Send(ctx, msg); err != nil {
+		n.failed(ctx, t, err)
+	}
+}
+
+func (n *Notifier) failed(ctx context.Context, t models.Transaction, err error) {
+	logging.For("services").ErrorContext(ctx, "transfer notification not sent",
+		slog.Uint64("transaction_id", uint64(t.ID)),
+		slog.String("error", err.Error()),
+	)
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/metrics"
+	"wallet-api/models"
+	"wallet-api/paylink"
+	"wallet-api/repositories"
+	"wallet-api/tenancy"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrPaymentLinkInactive is returned when a link that was used up or
+// revoked is paid or revoked.
+var ErrPaymentLinkInactive = errors.New("payment link is no longer active")
+
+// IPaymentLinkService hands out payment links and takes payments through
+// them. Resolve and Pay are public: the token names the link's tenant and
+// vouches for it.
+type IPaymentLinkService interface {
+	Create(ctx context.Context, details PaymentLinkDetails) (*models.PaymentLink, error)
+	Get(ctx context.Context, id uint) (*models.PaymentLink, error)
+	ListByWallet(ctx context.Context, walletID uint) ([]models.PaymentLink, error)
+	Revoke(ctx context.Context, id uint) (*models.PaymentLink, error)
+	Resolve(ctx context.Context, token string) (*models.PaymentLink, error)
+	Pay(ctx context.Context, token string, payerWalletID uint) (*models.PaymentLink, error)
+}
+
+// PaymentLinkDetails describes a new payment link. Links are single-use
+// unless Mode says otherwise, and MaxUses only applies to multi-use links;
+// without ExpiresAt the link lasts the configured TTL.
+type PaymentLinkDetails struct {
+	WalletID  uint
+	Amount    int64
+	Memo      string
+	Mode      string
+	MaxUses   *int
+	ExpiresAt *time.Time
+}
+
+// PaymentLinkConfig controls payment links. A link's URL is URL with its
+// token appended as the last path segment; links last TTL unless created
+// with an expiry.
+type PaymentLinkConfig struct {
+	URL string
+	TTL time.Duration
+}
+
+type PaymentLinkService struct {
+	linkRepo   *repositories.PaymentLinkRepository
+	walletRepo *repositories.WalletRepository
+	transfers  *TransferService
+	db         *gorm.DB
+	signer     *paylink.Signer
+	cfg        PaymentLinkConfig
+}
+
+var _ IPaymentLinkService = &PaymentLinkService{}
+
+// NewPaymentLinkService returns a PaymentLinkService signing tokens with
+// signer and taking payments through transfers.
+func NewPaymentLinkService(linkRepo *repositories.PaymentLinkRepository, walletRepo *repositories.WalletRepository, transfers *TransferService,
+	db *gorm.DB, signer *paylink.Signer, cfg PaymentLinkConfig) *PaymentLinkService {
+	return &PaymentLinkService{linkRepo: linkRepo, walletRepo: walletRepo, transfers: transfers, db: db, signer: signer, cfg: cfg}
+}
+
+func (s *PaymentLinkService) Create(ctx context.Context, details PaymentLinkDetails) (_ *models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.Create",
+		attribute.Int64("wallet.id", int64(details.WalletID)),
+		attribute.Int64("transaction.amount", details.Amount),
+	)
+	defer tracing.End(span, &err)
+
+	if details.Amount <= 0 {
+		return nil, errInvalid("amount must be positive")
+	}
+	memo := strings.TrimSpace(details.Memo)
+	if len(memo) > maxPaymentMemoLength {
+		return nil, errInvalid(fmt.Sprintf("memo must be at most %d characters", maxPaymentMemoLength))
+	}
+	if details.Mode == "" {
+		details.Mode = models.PaymentLinkModeSingle
+	}
+	switch details.Mode {
+	case models.PaymentLinkModeSingle:
+		if details.MaxUses != nil {
+			return nil, errInvalid("max_uses only applies to multi-use links")
+		}
+	case models.PaymentLinkModeMulti:
+		if details.MaxUses != nil && *details.MaxUses < 1 {
+			return nil, errInvalid("max_uses must be positive")
+		}
+	default:
+		return nil, errInvalid("mode must be single or multi")
+	}
+	expiresAt := time.Now().Add(s.cfg.TTL)
+	if details.ExpiresAt != nil {
+		expiresAt = *details.ExpiresAt
+	}
+	expiresAt = expiresAt.Truncate(time.Second)
+	if !expiresAt.After(time.Now()) {
+		return nil, errInvalid("expires_at must be in the future")
+	}
+
+	link := &models.PaymentLink{
+		WalletID:  details.WalletID,
+		Amount:    details.Amount,
+		Memo:      memo,
+		Mode:      details.Mode,
+		MaxUses:   details.MaxUses,
+		Status:    models.PaymentLinkStatusActive,
+		ExpiresAt: expiresAt,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := repositories.NewWalletRepository(tx).GetByID(details.WalletID)
+		if err != nil {
+			return err
+		}
+		if wallet.IsPocket() {
+			return ErrPocketWallet
+		}
+		if err := checkUser(&wallet.User); err != nil {
+			return err
+		}
+		return repositories.NewPaymentLinkRepository(tx).Create(link)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "payment link created",
+		slog.Uint64("payment_link_id", uint64(link.ID)),
+		slog.Uint64("wallet_id", uint64(link.WalletID)),
+		slog.Int64("amount", link.Amount),
+		slog.String("mode", link.Mode),
+	)
+	s.present(link, time.Now())
+	return link, nil
+}
+
+// Get returns the link with its token, URL and payments.
+func (s *PaymentLinkService) Get(ctx context.Context, id uint) (_ *models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.Get", attribute.Int64("payment_link.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	link, err := s.linkRepo.WithContext(ctx).GetWithUses(id)
+	if err != nil {
+		return nil, err
+	}
+	s.present(link, time.Now())
+	return link, nil
+}
+
+// ListByWallet returns the links paying into the wallet, oldest first.
+func (s *PaymentLinkService) ListByWallet(ctx context.Context, walletID uint) (_ []models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.ListByWallet", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return nil, err
+	}
+	links, err := s.linkRepo.WithContext(ctx).ListByWallet(walletID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range links {
+		s.present(&links[i], now)
+	}
+	return links, nil
+}
+
+// Revoke stops the link taking payments. Only an owner of the wallet it
+// pays into may revoke it, with their user API key.
+func (s *PaymentLinkService) Revoke(ctx context.Context, id uint) (_ *models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.Revoke",
+		attribute.Int64("payment_link.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	var link *models.PaymentLink
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		linkRepo := repositories.NewPaymentLinkRepository(tx)
+		link, err = linkRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if link.Status != models.PaymentLinkStatusActive {
+			return ErrPaymentLinkInactive
+		}
+
+		wallet, err := repositories.NewWalletRepository(tx).GetByID(link.WalletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, userID); err != nil {
+			return err
+		}
+
+		link.Status = models.PaymentLinkStatusRevoked
+		return linkRepo.Save(link)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "payment link revoked",
+		slog.Uint64("payment_link_id", uint64(link.ID)),
+		slog.Uint64("user_id", uint64(userID)),
+	)
+	s.present(link, time.Now())
+	return link, nil
+}
+
+// Resolve returns the link token is for, in the tenant it names. The
+// payments made through it are left out: they name the payers' wallets.
+func (s *PaymentLinkService) Resolve(ctx context.Context, token string) (_ *models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.Resolve")
+	defer tracing.End(span, &err)
+
+	claims, err := s.signer.Verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenancy.WithTenant(ctx, claims.TenantID)
+	span.SetAttributes(attribute.Int64("payment_link.id", int64(claims.LinkID)))
+
+	link, err := s.linkRepo.WithContext(ctx).GetByID(claims.LinkID)
+	if err != nil {
+		return nil, err
+	}
+	s.present(link, time.Now())
+	return link, nil
+}
+
+// Pay pays the link from payerWalletID by a transfer sent by the initiator
+// in ctx, in the tenant the token names, which must be the tenant of the
+// initiator's key. The link row stays locked until
+// the transfer is made, so concurrent payments never take it past its
+// limit. A transfer held for review counts as a use; one that cannot be
+// made does not.
+func (s *PaymentLinkService) Pay(ctx context.Context, token string, payerWalletID uint) (_ *models.PaymentLink, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentLinkService.Pay", attribute.Int64("wallet.source_id", int64(payerWalletID)))
+	defer tracing.End(span, &err)
+
+	claims, err := s.signer.Verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := tenancy.Authorize(ctx, claims.TenantID); err != nil {
+		return nil, err
+	}
+	ctx = tenancy.WithTenant(ctx, claims.TenantID)
+	span.SetAttributes(attribute.Int64("payment_link.id", int64(claims.LinkID)))
+
+	var link *models.PaymentLink
+	var result transferResult
+	var executed bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		linkRepo := repositories.NewPaymentLinkRepository(tx)
+		link, err = linkRepo.GetForUpdate(claims.LinkID)
+		if err != nil {
+			return err
+		}
+		if link.Status != models.PaymentLinkStatusActive {
+			return ErrPaymentLinkInactive
+		}
+		if payerWalletID == link.WalletID {
+			return errInvalid("source and target wallets cannot be the same")
+		}
+
+		executed = true
+		result, err = s.transfers.transferIn(ctx, tx, payerWalletID, link.WalletID, link.Amount, nil)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return errInvalid(fmt.Sprintf("wallet %d not found", payerWalletID))
+		case err != nil:
+			return err
+		case result.approval != nil:
+			executed = false
+			return ErrPaymentNeedsApproval
+		case result.denied:
+			return nil
+		}
+
+		link.UseCount++
+		if limit := link.Limit(); limit > 0 && link.UseCount >= limit {
+			link.Status = models.PaymentLinkStatusUsed
+		}
+		if err := linkRepo.Save(link); err != nil {
+			return err
+		}
+		return linkRepo.CreateUse(&models.PaymentLinkUse{
+			LinkID:        link.ID,
+			PayerWalletID: payerWalletID,
+			TransactionID: result.transaction.ID,
+		})
+	})
+
+	var transferErr error
+	if executed {
+		transferErr = err
+		if err == nil {
+			transferErr = s.transfers.finish(ctx, result)
+		}
+		metrics.ObserveTransfer(outcome(transferErr), link.Amount, result.currency)
+		logResult(ctx, "transfer", transferErr,
+			slog.Uint64("source_wallet_id", uint64(payerWalletID)),
+			slog.Uint64("target_wallet_id", uint64(link.WalletID)),
+			slog.Int64("amount", link.Amount),
+			slog.Uint64("payment_link_id", uint64(link.ID)),
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.present(link, time.Now())
+	return link, transferErr
+}
+
+// present fills in the link's token and URL and reports it as expired once
+// it has.
+func (s *PaymentLinkService) present(link *models.PaymentLink, now time.Time) {
+	link.Token = s.signer.Sign(paylink.Claims{LinkID: link.ID, TenantID: link.TenantID, ExpiresAt: link.ExpiresAt})
+	link.URL = strings.TrimRight(s.cfg.URL, "/") + "/" + link.Token
+	if link.Expired(now) {
+		link.Status = models.PaymentLinkStatusExpired
+	}
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/metrics"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrPaymentRequestClosed is returned when a payment request that is no
+// longer pending is paid, declined or cancelled.
+var ErrPaymentRequestClosed = errors.New("payment request is no longer pending")
+
+// ErrPaymentRequestExpired is returned when a payment request is acted on
+// after it expired.
+var ErrPaymentRequestExpired = errors.New("payment request expired")
+
+// ErrPaymentNeedsApproval is returned when a payment request is paid from a
+// joint wallet whose approval rule covers the amount. Such payments are
+// made as transfers, which wait for the owners.
+var ErrPaymentNeedsApproval = errors.New("the paying wallet's approval rule covers this amount; send it as a transfer")
+
+// maxPaymentMemoLength matches the size of models.PaymentRequest.Memo.
+const maxPaymentMemoLength = 255
+
+// IPaymentRequestService lets users ask each other for money. Paying a
+// request makes a transfer to the requester with every check a transfer
+// gets.
+type IPaymentRequestService interface {
+	Create(ctx context.Context, details PaymentRequestDetails) (*models.PaymentRequest, error)
+	Get(ctx context.Context, id uint) (*models.PaymentRequest, error)
+	ListIncoming(ctx context.Context, walletID uint, status string) ([]models.PaymentRequest, error)
+	ListOutgoing(ctx context.Context, walletID uint, status string) ([]models.PaymentRequest, error)
+	Accept(ctx context.Context, id, payerWalletID uint) (*models.PaymentRequest, error)
+	Decline(ctx context.Context, id uint) (*models.PaymentRequest, error)
+	Cancel(ctx context.Context, id uint) (*models.PaymentRequest, error)
+}
+
+// PaymentRequestDetails describes a new payment request. Without a
+// PayerWalletID the request is open; without ExpiresAt it does not expire.
+type PaymentRequestDetails struct {
+	RequesterWalletID uint
+	PayerWalletID     *uint
+	Amount            int64
+	Memo              string
+	ExpiresAt         *time.Time
+}
+
+type PaymentRequestService struct {
+	requestRepo *repositories.PaymentRequestRepository
+	walletRepo  *repositories.WalletRepository
+	transfers   *TransferService
+	db          *gorm.DB
+}
+
+var _ IPaymentRequestService = &PaymentRequestService{}
+
+// NewPaymentRequestService returns a PaymentRequestService paying requests
+// through transfers.
+func NewPaymentRequestService(requestRepo *repositories.PaymentRequestRepository, walletRepo *repositories.WalletRepository, transfers *TransferService, db *gorm.DB) *PaymentRequestService {
+	return &PaymentRequestService{requestRepo: requestRepo, walletRepo: walletRepo, transfers: transfers, db: db}
+}
+
+func (s *PaymentRequestService) Create(ctx context.Context, details PaymentRequestDetails) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.Create",
+		attribute.Int64("wallet.id", int64(details.RequesterWalletID)),
+		attribute.Int64("transaction.amount", details.Amount),
+	)
+	defer tracing.End(span, &err)
+
+	if details.Amount <= 0 {
+		return nil, errInvalid("amount must be positive")
+	}
+	memo := strings.TrimSpace(details.Memo)
+	if len(memo) > maxPaymentMemoLength {
+		return nil, errInvalid(fmt.Sprintf("memo must be at most %d characters", maxPaymentMemoLength))
+	}
+	if details.ExpiresAt != nil && !details.ExpiresAt.After(time.Now()) {
+		return nil, errInvalid("expires_at must be in the future")
+	}
+
+	request := &models.PaymentRequest{
+		RequesterWalletID: details.RequesterWalletID,
+		PayerWalletID:     details.PayerWalletID,
+		Amount:            details.Amount,
+		Memo:              memo,
+		Status:            models.PaymentRequestStatusPending,
+		ExpiresAt:         details.ExpiresAt,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+		requester, err := walletRepo.GetByID(details.RequesterWalletID)
+		if err != nil {
+			return err
+		}
+		if requester.IsPocket() {
+			return ErrPocketWallet
+		}
+		if err := checkUser(&requester.User); err != nil {
+			return err
+		}
+		if details.PayerWalletID != nil {
+			if *details.PayerWalletID == requester.ID {
+				return errInvalid("a wallet cannot request money from itself")
+			}
+			payer, err := walletRepo.GetByID(*details.PayerWalletID)
+			if err != nil {
+				return err
+			}
+			if payer.IsPocket() {
+				return ErrPocketWallet
+			}
+			if payer.Currency != requester.Currency {
+				return errInvalid("requester and payer wallets hold different currencies")
+			}
+		}
+		return repositories.NewPaymentRequestRepository(tx).Create(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "payment request created",
+		slog.Uint64("payment_request_id", uint64(request.ID)),
+		slog.Uint64("wallet_id", uint64(request.RequesterWalletID)),
+		slog.Int64("amount", request.Amount),
+	)
+	return request, nil
+}
+
+// Get returns the request, reported as expired once it has.
+func (s *PaymentRequestService) Get(ctx context.Context, id uint) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.Get", attribute.Int64("payment_request.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	request, err := s.requestRepo.WithContext(ctx).GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	markExpired(request, time.Now())
+	return request, nil
+}
+
+// ListIncoming returns the requests the wallet is asked to pay, oldest
+// first, optionally filtered by status. Open requests are not listed.
+func (s *PaymentRequestService) ListIncoming(ctx context.Context, walletID uint, status string) (_ []models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.ListIncoming", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	return s.list(ctx, walletID, status, s.requestRepo.WithContext(ctx).ListIncoming)
+}
+
+// ListOutgoing returns the requests for payment into the wallet, oldest
+// first, optionally filtered by status.
+func (s *PaymentRequestService) ListOutgoing(ctx context.Context, walletID uint, status string) (_ []models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.ListOutgoing", attribute.Int64("wallet.id", int64(walletID)))
+	defer tracing.End(span, &err)
+
+	return s.list(ctx, walletID, status, s.requestRepo.WithContext(ctx).ListOutgoing)
+}
+
+// list reads the wallet's requests with find. Expiry is not stored until a
+// request is acted on, so expired requests are found among pending ones.
+func (s *PaymentRequestService) list(ctx context.Context, walletID uint, status string, find func(walletID uint, status string) ([]models.PaymentRequest, error)) ([]models.PaymentRequest, error) {
+	stored := status
+	switch status {
+	case "", models.PaymentRequestStatusPending, models.PaymentRequestStatusPaid,
+		models.PaymentRequestStatusDeclined, models.PaymentRequestStatusCancelled:
+	case models.PaymentRequestStatusExpired:
+		stored = models.PaymentRequestStatusPending
+	default:
+		return nil, errInvalid("status must be pending, paid, declined, cancelled or expired")
+	}
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return nil, err
+	}
+	requests, err := find(walletID, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filtered := requests[:0]
+	for i := range requests {
+		markExpired(&requests[i], now)
+		if status == "" || requests[i].Status == status {
+			filtered = append(filtered, requests[i])
+		}
+	}
+	return filtered, nil
+}
+
+// Accept pays the request from payerWalletID, or from the wallet it names
+// when that is zero, by a transfer sent by the initiator in ctx. The request
+// row stays locked until the transfer is made, so of concurrent accepts one
+// pays it and the rest find it paid. A transfer held for review still pays
+// the request, with the held transaction; a transfer that cannot be made
+// leaves it pending.
+func (s *PaymentRequestService) Accept(ctx context.Context, id, payerWalletID uint) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.Accept",
+		attribute.Int64("payment_request.id", int64(id)),
+		attribute.Int64("wallet.source_id", int64(payerWalletID)),
+	)
+	defer tracing.End(span, &err)
+
+	var request *models.PaymentRequest
+	var result transferResult
+	var executed, expired bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		requestRepo := repositories.NewPaymentRequestRepository(tx)
+		request, err = requestRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if expired = request.Expired(time.Now()); expired {
+			request.Status = models.PaymentRequestStatusExpired
+			return requestRepo.Save(request)
+		}
+		if request.Status != models.PaymentRequestStatusPending {
+			return ErrPaymentRequestClosed
+		}
+
+		switch {
+		case !request.Open() && payerWalletID == 0:
+			payerWalletID = *request.PayerWalletID
+		case !request.Open() && payerWalletID != *request.PayerWalletID:
+			return errInvalid(fmt.Sprintf("the request is to be paid from wallet %d", *request.PayerWalletID))
+		case payerWalletID == 0:
+			return errInvalid("payer_wallet_id is required to pay an open request")
+		}
+		if payerWalletID == request.RequesterWalletID {
+			return errInvalid("source and target wallets cannot be the same")
+		}
+
+		executed = true
+		result, err = s.transfers.transferIn(ctx, tx, payerWalletID, request.RequesterWalletID, request.Amount, nil)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return errInvalid(fmt.Sprintf("wallet %d not found", payerWalletID))
+		case err != nil:
+			return err
+		case result.approval != nil:
+			executed = false
+			return ErrPaymentNeedsApproval
+		case result.denied:
+			// The denied transfer is kept on record; the request can still
+			// be paid another way.
+			return nil
+		}
+		now := time.Now()
+		request.Status = models.PaymentRequestStatusPaid
+		request.PaidFromWalletID = &payerWalletID
+		request.TransactionID = &result.transaction.ID
+		request.ClosedAt = &now
+		return requestRepo.Save(request)
+	})
+
+	var transferErr error
+	if executed {
+		transferErr = err
+		if err == nil {
+			transferErr = s.transfers.finish(ctx, result)
+		}
+		metrics.ObserveTransfer(outcome(transferErr), request.Amount, result.currency)
+		logResult(ctx, "transfer", transferErr,
+			slog.Uint64("source_wallet_id", uint64(payerWalletID)),
+			slog.Uint64("target_wallet_id", uint64(request.RequesterWalletID)),
+			slog.Int64("amount", request.Amount),
+			slog.Uint64("payment_request_id", uint64(request.ID)),
+		)
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case expired:
+		return request, ErrPaymentRequestExpired
+	}
+	return request, transferErr
+}
+
+// Decline records that the payer will not pay the request. Only an owner
+// of the wallet asked to pay may decline it, with their user API key; open
+// requests cannot be declined.
+func (s *PaymentRequestService) Decline(ctx context.Context, id uint) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.Decline",
+		attribute.Int64("payment_request.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	return s.close(ctx, id, userID, models.PaymentRequestStatusDeclined, func(request *models.PaymentRequest) (uint, error) {
+		if request.Open() {
+			return 0, errInvalid("open requests cannot be declined")
+		}
+		return *request.PayerWalletID, nil
+	})
+}
+
+// Cancel withdraws the request. Only an owner of the requesting wallet may
+// cancel it, with their user API key.
+func (s *PaymentRequestService) Cancel(ctx context.Context, id uint) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentRequestService.Cancel",
+		attribute.Int64("payment_request.id", int64(id)),
+	)
+	defer tracing.End(span, &err)
+
+	userID, err := initiator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int64("user.id", int64(userID)))
+
+	return s.close(ctx, id, userID, models.PaymentRequestStatusCancelled, func(request *models.PaymentRequest) (uint, error) {
+		return request.RequesterWalletID, nil
+	})
+}
+
+// close moves a pending request to status on behalf of userID, who must own
+// the wallet walletOf returns.
+func (s *PaymentRequestService) close(ctx context.Context, id, userID uint, status string, walletOf func(*models.PaymentRequest) (uint, error)) (*models.PaymentRequest, error) {
+	var request *models.PaymentRequest
+	var expired bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		requestRepo := repositories.NewPaymentRequestRepository(tx)
+		var err error
+		request, err = requestRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if expired = request.Expired(time.Now()); expired {
+			request.Status = models.PaymentRequestStatusExpired
+			return requestRepo.Save(request)
+		}
+		if request.Status != models.PaymentRequestStatusPending {
+			return ErrPaymentRequestClosed
+		}
+
+		walletID, err := walletOf(request)
+		if err != nil {
+			return err
+		}
+		wallet, err := repositories.NewWalletRepository(tx).GetByID(walletID)
+		if err != nil {
+			return err
+		}
+		if err := checkOwner(tx, wallet, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		request.Status = status
+		request.ClosedAt = &now
+		return requestRepo.Save(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return request, ErrPaymentRequestExpired
+	}
+
+	logging.For("services").InfoContext(ctx, "payment request closed",
+		slog.Uint64("payment_request_id", uint64(request.ID)),
+		slog.Uint64("user_id", uint64(userID)),
+		slog.String("status", request.Status),
+	)
+	return request, nil
+}
+
+// markExpired reports a pending request past its expiry as expired.
+func markExpired(request *models.PaymentRequest, now time.Time) {
+	if request.Expired(now) {
+		request.Status = models.PaymentRequestStatusExpired
+	}
+}
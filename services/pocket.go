@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrPocketWallet is returned when a pocket is used in a transfer or
+// deposit. Pockets only move money to and from their parent wallet.
+var ErrPocketWallet = errors.New("pockets only move money to and from their parent wallet")
+
+// ErrNotPocket is returned when a pocket operation names a wallet that is
+// not a pocket.
+var ErrNotPocket = errors.New("wallet is not a pocket")
+
+// ErrPocketNameTaken is returned when a pocket is given the name of another
+// pocket of the same wallet.
+var ErrPocketNameTaken = errors.New("the wallet already has a pocket with this name")
+
+// maxPocketNameLength matches the size of models.Wallet.Name.
+const maxPocketNameLength = 100
+
+// IPocketService manages pockets: named sub-wallets that set money aside
+// within a parent wallet.
+type IPocketService interface {
+	Create(ctx context.Context, parentID uint, details PocketDetails) (*models.Wallet, error)
+	List(ctx context.Context, parentID uint) ([]models.Wallet, error)
+	Update(ctx context.Context, id uint, update PocketUpdate) (*models.Wallet, error)
+	Fund(ctx context.Context, id uint, amount int64) (*models.Transaction, error)
+	Withdraw(ctx context.Context, id uint, amount int64) (*models.Transaction, error)
+	Close(ctx context.Context, id uint) (*models.Wallet, error)
+}
+
+// PocketDetails describes a new pocket. The target is optional.
+type PocketDetails struct {
+	Name         string
+	TargetAmount *int64
+	TargetDate   *time.Time
+}
+
+// PocketUpdate lists the pocket fields to change; nil fields are left
+// alone. A zero TargetAmount or TargetDate removes the target.
+type PocketUpdate struct {
+	Name         *string
+	TargetAmount *int64
+	TargetDate   *time.Time
+}
+
+type PocketService struct {
+	walletRepo *repositories.WalletRepository
+	db         *gorm.DB
+}
+
+var _ IPocketService = &PocketService{}
+
+func NewPocketService(walletRepo *repositories.WalletRepository, db *gorm.DB) *PocketService {
+	return &PocketService{
+		walletRepo: walletRepo,
+		db:         db,
+	}
+}
+
+// Create opens a pocket in the parent wallet, owned by the same user.
+// Pockets cannot have pockets of their own.
+func (s *PocketService) Create(ctx context.Context, parentID uint, details PocketDetails) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.Create", attribute.Int64("wallet.id", int64(parentID)))
+	defer tracing.End(span, &err)
+
+	name, err := pocketName(details.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPocketTarget(details.TargetAmount); err != nil {
+		return nil, err
+	}
+
+	pocket := &models.Wallet{
+		ParentID:     &parentID,
+		Name:         name,
+		TargetAmount: details.TargetAmount,
+		TargetDate:   details.TargetDate,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+
+		// Locking the parent serializes pocket creation, so two pockets
+		// cannot be given the same name at once.
+		parent, err := walletRepo.GetForUpdate(parentID)
+		if err != nil {
+			return err
+		}
+		if parent.IsPocket() {
+			return errInvalid("pockets cannot have pockets")
+		}
+		owner, err := repositories.NewUserRepository(tx).GetByID(parent.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkUser(owner); err != nil {
+			return err
+		}
+		taken, err := walletRepo.PocketNameTaken(parentID, name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrPocketNameTaken
+		}
+
+		pocket.UserID = parent.UserID
+		pocket.TenantID = parent.TenantID
+		pocket.Currency = parent.Currency
+		return walletRepo.Create(pocket)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "pocket created",
+		slog.Uint64("wallet_id", uint64(parentID)),
+		slog.Uint64("pocket_id", uint64(pocket.ID)),
+	)
+	return pocket, nil
+}
+
+// List returns the pockets of a wallet, oldest first.
+func (s *PocketService) List(ctx context.Context, parentID uint) (_ []models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.List", attribute.Int64("wallet.id", int64(parentID)))
+	defer tracing.End(span, &err)
+
+	walletRepo := s.walletRepo.WithContext(ctx)
+	if _, err := walletRepo.GetByID(parentID); err != nil {
+		return nil, err
+	}
+	return walletRepo.GetPockets(parentID)
+}
+
+// Update renames the pocket or changes its target.
+func (s *PocketService) Update(ctx context.Context, id uint, update PocketUpdate) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.Update", attribute.Int64("pocket.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	var pocket *models.Wallet
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+
+		parentID, err := s.parentOf(walletRepo, id)
+		if err != nil {
+			return err
+		}
+		if _, err := walletRepo.GetForUpdate(parentID); err != nil {
+			return err
+		}
+		pocket, err = walletRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if update.Name != nil {
+			name, err := pocketName(*update.Name)
+			if err != nil {
+				return err
+			}
+			taken, err := walletRepo.PocketNameTaken(parentID, name, id)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrPocketNameTaken
+			}
+			pocket.Name = name
+		}
+		if update.TargetAmount != nil {
+			if *update.TargetAmount == 0 {
+				pocket.TargetAmount = nil
+			} else if err := checkPocketTarget(update.TargetAmount); err != nil {
+				return err
+			} else {
+				pocket.TargetAmount = update.TargetAmount
+			}
+		}
+		if update.TargetDate != nil {
+			if update.TargetDate.IsZero() {
+				pocket.TargetDate = nil
+			} else {
+				pocket.TargetDate = update.TargetDate
+			}
+		}
+		return walletRepo.UpdatePocket(pocket)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pocket, nil
+}
+
+// Fund moves amount from the parent wallet into the pocket.
+func (s *PocketService) Fund(ctx context.Context, id uint, amount int64) (_ *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.Fund",
+		attribute.Int64("pocket.id", int64(id)),
+		attribute.Int64("transaction.amount", amount),
+	)
+	defer tracing.End(span, &err)
+
+	return s.move(ctx, id, amount, true)
+}
+
+// Withdraw moves amount from the pocket back to the parent wallet.
+func (s *PocketService) Withdraw(ctx context.Context, id uint, amount int64) (_ *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.Withdraw",
+		attribute.Int64("pocket.id", int64(id)),
+		attribute.Int64("transaction.amount", amount),
+	)
+	defer tracing.End(span, &err)
+
+	return s.move(ctx, id, amount, false)
+}
+
+// move books amount between the pocket and its parent, into the pocket when
+// in is set. The money stays with the same owner, so moves are instant:
+// they are not charged, not counted against the KYC limits and not screened
+// by the AML rules, but they are recorded as transactions so statements and
+// reconciliation still add up.
+func (s *PocketService) move(ctx context.Context, id uint, amount int64, in bool) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, errInvalid("amount must be positive")
+	}
+
+	var transaction models.Transaction
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+
+		parentID, err := s.parentOf(walletRepo, id)
+		if err != nil {
+			return err
+		}
+		// The parent is locked first, like every operation that touches a
+		// pocket, so moves cannot deadlock with each other or with
+		// transfers checking the parent's rollup balance.
+		parent, err := walletRepo.GetForUpdate(parentID)
+		if err != nil {
+			return err
+		}
+		pocket, err := walletRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if parent.Frozen() || pocket.Frozen() {
+			return ErrWalletFrozen
+		}
+		owner, err := repositories.NewUserRepository(tx).GetByID(parent.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkUser(owner); err != nil {
+			return err
+		}
+
+		transaction, err = bookPocketMove(tx, parent, pocket, amount, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "pocket move",
+		slog.Uint64("pocket_id", uint64(id)),
+		slog.Uint64("transaction_id", uint64(transaction.ID)),
+		slog.Int64("amount", amount),
+		slog.Bool("into_pocket", in),
+	)
+	return &transaction, nil
+}
+
+// Close moves whatever the pocket holds back to its parent and deletes it.
+func (s *PocketService) Close(ctx context.Context, id uint) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "PocketService.Close", attribute.Int64("pocket.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	var pocket *models.Wallet
+	var swept int64
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		walletRepo := repositories.NewWalletRepository(tx)
+
+		parentID, err := s.parentOf(walletRepo, id)
+		if err != nil {
+			return err
+		}
+		parent, err := walletRepo.GetForUpdate(parentID)
+		if err != nil {
+			return err
+		}
+		pocket, err = walletRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if swept = pocket.Balance; swept > 0 {
+			if parent.Frozen() || pocket.Frozen() {
+				return ErrWalletFrozen
+			}
+			if _, err := bookPocketMove(tx, parent, pocket, swept, false); err != nil {
+				return err
+			}
+		}
+		return walletRepo.Delete(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "pocket closed",
+		slog.Uint64("pocket_id", uint64(id)),
+		slog.Int64("swept", swept),
+	)
+	return pocket, nil
+}
+
+// parentOf returns the ID of the pocket's parent, before either is locked.
+// A pocket's parent never changes.
+func (s *PocketService) parentOf(walletRepo *repositories.WalletRepository, id uint) (uint, error) {
+	pocket, err := walletRepo.GetByID(id)
+	if err != nil {
+		return 0, err
+	}
+	if !pocket.IsPocket() {
+		return 0, ErrNotPocket
+	}
+	return *pocket.ParentID, nil
+}
+
+// bookPocketMove moves amount between the locked parent and pocket and
+// records the transaction.
+func bookPocketMove(tx *gorm.DB, parent, pocket *models.Wallet, amount int64, in bool) (models.Transaction, error) {
+	source, target := pocket, parent
+	if in {
+		source, target = parent, pocket
+	}
+	if source.Balance < amount {
+		return models.Transaction{}, ErrInsufficientBalance
+	}
+	if err := book(tx, source, target, amount); err != nil {
+		return models.Transaction{}, err
+	}
+
+	transaction := models.Transaction{
+		SourceWalletID:  &source.ID,
+		TargetWalletID:  target.ID,
+		Amount:          amount,
+		Type:            models.TransactionTypePocket,
+		ReferenceNumber: fmt.Sprintf("PKT-%d", time.Now().UnixNano()),
+		Status:          models.TransactionStatusCompleted,
+		Description:     pocket.Name,
+	}
+	return transaction, tx.Create(&transaction).Error
+}
+
+// rollupBalance is the wallet's balance together with its pockets'. The KYC
+// balance limits apply to it, so setting money aside in a pocket does not
+// make room for more.
+func rollupBalance(tx *gorm.DB, wallet *models.Wallet) (int64, error) {
+	pockets, err := repositories.NewWalletRepository(tx).PocketsBalance(wallet.ID)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.Balance + pockets, nil
+}
+
+func pocketName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errInvalid("pocket name is required")
+	}
+	if len([]rune(name)) > maxPocketNameLength {
+		return "", errInvalid(fmt.Sprintf("pocket name must be at most %d characters", maxPocketNameLength))
+	}
+	return name, nil
+}
+
+func checkPocketTarget(amount *int64) error {
+	if amount != nil && *amount <= 0 {
+		return errInvalid("target amount must be positive")
+	}
+	return nil
+}
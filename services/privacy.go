@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrErasureBalance is returned when a user asks to be erased while one of
+// their wallets still holds money.
+var ErrErasureBalance = errors.New("wallets must be empty before erasure")
+
+// ErrAlreadyErased is returned when a user is erased twice.
+var ErrAlreadyErased = errors.New("user already erased")
+
+// ErasedWalletReason is recorded on the wallets of an erased user, which are
+// frozen so no money reaches an owner who no longer exists.
+const ErasedWalletReason = "owner erased"
+
+// IPrivacyService handles data subject requests: handing users a copy of
+// their data and erasing it.
+type IPrivacyService interface {
+	Export(ctx context.Context, userID uint) (*models.UserExport, error)
+	Erase(ctx context.Context, userID uint, requestedBy, reason string) (*models.Erasure, error)
+	GetErasure(ctx context.Context, userID uint) (*models.Erasure, error)
+}
+
+type PrivacyService struct {
+	userRepo         *repositories.UserRepository
+	walletRepo       *repositories.WalletRepository
+	transactionRepo  *repositories.TransactionRepository
+	verificationRepo *repositories.VerificationCaseRepository
+	erasureRepo      *repositories.ErasureRepository
+	db               *gorm.DB
+	retention        time.Duration
+}
+
+var _ IPrivacyService = &PrivacyService{}
+
+// NewPrivacyService returns a PrivacyService keeping the financial records
+// of erased users for retention.
+func NewPrivacyService(
+	userRepo *repositories.UserRepository,
+	walletRepo *repositories.WalletRepository,
+	transactionRepo *repositories.TransactionRepository,
+	verificationRepo *repositories.VerificationCaseRepository,
+	erasureRepo *repositories.ErasureRepository,
+	db *gorm.DB,
+	retention time.Duration,
+) *PrivacyService {
+	return &PrivacyService{
+		userRepo:         userRepo,
+		walletRepo:       walletRepo,
+		transactionRepo:  transactionRepo,
+		verificationRepo: verificationRepo,
+		erasureRepo:      erasureRepo,
+		db:               db,
+		retention:        retention,
+	}
+}
+
+// Export collects the user's profile, wallets, transactions and
+// verification cases.
+func (s *PrivacyService) Export(ctx context.Context, userID uint) (_ *models.UserExport, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Export", attribute.Int64("user.id", int64(userID)))
+	defer tracing.End(span, &err)
+
+	user, err := s.userRepo.WithContext(ctx).GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	wallets, err := s.walletRepo.WithContext(ctx).GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactionRepo.WithContext(ctx).GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	verifications, err := s.verificationRepo.WithContext(ctx).ListByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "user data exported",
+		slog.Uint64("user_id", uint64(userID)),
+		slog.String("actor", actor(ctx, fmt.Sprintf("user:%d", userID))),
+	)
+	return &models.UserExport{
+		ExportedAt:    time.Now().UTC(),
+		User:          *user,
+		Wallets:       wallets,
+		Transactions:  transactions,
+		Verifications: verifications,
+	}, nil
+}
+
+// Erase pseudonymizes the user, freezes their wallets and removes them from
+// joint wallets. Wallets and transactions stay in place for the retention
+// period, so balances and statements still add up. Erasure is refused while
+// any wallet holds money, which must be paid out first.
+func (s *PrivacyService) Erase(ctx context.Context, userID uint, requestedBy, reason string) (_ *models.Erasure, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Erase", attribute.Int64("user.id", int64(userID)))
+	defer tracing.End(span, &err)
+
+	requestedBy = strings.TrimSpace(requestedBy)
+	if requestedBy == "" {
+		return nil, errors.New("requested_by is required")
+	}
+
+	var erasure *models.Erasure
+	var frozen int
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := repositories.NewErasureRepository(tx).GetByUserID(userID); err == nil {
+			return ErrAlreadyErased
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		userRepo := repositories.NewUserRepository(tx)
+		if _, err := userRepo.GetForUpdate(userID); err != nil {
+			return err
+		}
+		walletRepo := repositories.NewWalletRepository(tx)
+		wallets, err := walletRepo.GetByUserIDForUpdate(userID)
+		if err != nil {
+			return err
+		}
+		for _, wallet := range wallets {
+			if wallet.Balance != 0 {
+				return fmt.Errorf("%w: wallet %d holds %d", ErrErasureBalance, wallet.ID, wallet.Balance)
+			}
+		}
+
+		now := time.Now()
+		for _, wallet := range wallets {
+			if wallet.Frozen() {
+				continue
+			}
+			if err := walletRepo.SetFrozen(wallet.ID, &now, ErasedWalletReason); err != nil {
+				return err
+			}
+			frozen++
+		}
+		if err := userRepo.Pseudonymize(userID, now); err != nil {
+			return err
+		}
+		if err := repositories.NewEmailVerificationRepository(tx).DeleteByUserID(userID); err != nil {
+			return err
+		}
+		if err := repositories.NewScreeningHitRepository(tx).Pseudonymize(userID); err != nil {
+			return err
+		}
+		if err := repositories.NewVerificationCaseRepository(tx).Pseudonymize(userID); err != nil {
+			return err
+		}
+		// Erased users lose their place in other people's joint wallets.
+		if err := repositories.NewWalletMemberRepository(tx).DeleteByUserID(userID); err != nil {
+			return err
+		}
+
+		erasure = &models.Erasure{
+			UserID:      userID,
+			RequestedBy: requestedBy,
+			Reason:      reason,
+			RetainUntil: now.Add(s.retention),
+		}
+		return repositories.NewErasureRepository(tx).Create(erasure)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "user erased",
+		slog.Uint64("user_id", uint64(userID)),
+		slog.String("requested_by", requestedBy),
+		slog.Int("wallets_frozen", frozen),
+		slog.Time("retain_until", erasure.RetainUntil),
+	)
+	return erasure, nil
+}
+
+func (s *PrivacyService) GetErasure(ctx context.Context, userID uint) (_ *models.Erasure, err error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.GetErasure")
+	defer tracing.End(span, &err)
+
+	return s.erasureRepo.WithContext(ctx).GetByUserID(userID)
+}
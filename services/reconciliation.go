@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IReconciliationService proves every morning that the transaction history
+// explains each Wallet.Balance.
+type IReconciliationService interface {
+	RunEndOfDay(ctx context.Context, day time.Time) (*models.ReconciliationReport, error)
+	ListDiscrepancies(ctx context.Context, status string) ([]models.Discrepancy, error)
+	GetDiscrepancy(ctx context.Context, id uint) (*models.Discrepancy, error)
+	ResolveDiscrepancy(ctx context.Context, id uint, resolvedBy, note string) (*models.Discrepancy, error)
+}
+
+type ReconciliationService struct {
+	walletRepo      *repositories.WalletRepository
+	discrepancyRepo *repositories.DiscrepancyRepository
+	db              *gorm.DB
+}
+
+var _ IReconciliationService = &ReconciliationService{}
+
+func NewReconciliationService(
+	walletRepo *repositories.WalletRepository,
+	discrepancyRepo *repositories.DiscrepancyRepository,
+	db *gorm.DB,
+) *ReconciliationService {
+	return &ReconciliationService{
+		walletRepo:      walletRepo,
+		discrepancyRepo: discrepancyRepo,
+		db:              db,
+	}
+}
+
+// StartOfDay truncates t to midnight UTC, the boundary used for snapshots.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// RunEndOfDay snapshots the closing balance of every wallet for the given
+// UTC day and compares it with the previous snapshot plus the day's
+// transactions. Mismatches are recorded as discrepancies. Running the same
+// day again replaces its snapshots.
+func (s *ReconciliationService) RunEndOfDay(ctx context.Context, day time.Time) (_ *models.ReconciliationReport, err error) {
+	dayStart := StartOfDay(day)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	ctx, span := tracing.Start(ctx, "ReconciliationService.RunEndOfDay",
+		attribute.String("reconciliation.date", dayStart.Format("2006-01-02")),
+	)
+	defer tracing.End(span, &err)
+
+	walletIDs, err := s.walletRepo.WithContext(ctx).GetIDsCreatedBefore(dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReconciliationReport{
+		Date:          dayStart,
+		Discrepancies: []models.Discrepancy{},
+	}
+	for _, walletID := range walletIDs {
+		var discrepancy *models.Discrepancy
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			discrepancy, err = s.reconcileWallet(tx, walletID, dayStart, dayEnd)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		report.WalletsChecked++
+		if discrepancy != nil {
+			report.Discrepancies = append(report.Discrepancies, *discrepancy)
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ReconciliationService) reconcileWallet(tx *gorm.DB, walletID uint, dayStart, dayEnd time.Time) (*models.Discrepancy, error) {
+	transactionRepo := repositories.NewTransactionRepository(tx)
+	snapshotRepo := repositories.NewBalanceSnapshotRepository(tx)
+
+	// A share lock waits for in-flight transfers on this wallet and keeps new
+	// ones out until the balance and its transactions have been read together.
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&wallet, walletID).Error; err != nil {
+		return nil, err
+	}
+
+	// Wallet.Balance is the current value; back out anything booked after the
+	// day closed to get the closing balance.
+	since, err := transactionRepo.BalanceSince(walletID, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	actual := wallet.Balance - since
+
+	closing, err := transactionRepo.BalanceBefore(walletID, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := closing
+	previous, err := snapshotRepo.GetLatestBefore(walletID, dayStart)
+	switch {
+	case err == nil:
+		opening, err := transactionRepo.BalanceBefore(walletID, StartOfDay(previous.Date).AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		expected = previous.Balance + closing - opening
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// Reconciliation runs for every tenant at once, so the rows are given
+	// the wallet's tenant rather than one from the context.
+	snapshot := models.BalanceSnapshot{
+		TenantID:        wallet.TenantID,
+		WalletID:        walletID,
+		Date:            dayStart,
+		Balance:         actual,
+		ExpectedBalance: expected,
+	}
+	if err := snapshotRepo.Upsert(&snapshot); err != nil {
+		return nil, err
+	}
+
+	if actual == expected {
+		return nil, nil
+	}
+
+	discrepancy := models.Discrepancy{
+		TenantID:        wallet.TenantID,
+		WalletID:        walletID,
+		Date:            dayStart,
+		ExpectedBalance: expected,
+		ActualBalance:   actual,
+		Difference:      actual - expected,
+		Status:          models.DiscrepancyStatusOpen,
+	}
+	if err := repositories.NewDiscrepancyRepository(tx).Upsert(&discrepancy); err != nil {
+		return nil, err
+	}
+	return &discrepancy, nil
+}
+
+func (s *ReconciliationService) ListDiscrepancies(ctx context.Context, status string) (_ []models.Discrepancy, err error) {
+	ctx, span := tracing.Start(ctx, "ReconciliationService.ListDiscrepancies")
+	defer tracing.End(span, &err)
+
+	return s.discrepancyRepo.WithContext(ctx).List(status)
+}
+
+func (s *ReconciliationService) GetDiscrepancy(ctx context.Context, id uint) (_ *models.Discrepancy, err error) {
+	ctx, span := tracing.Start(ctx, "ReconciliationService.GetDiscrepancy")
+	defer tracing.End(span, &err)
+
+	return s.discrepancyRepo.WithContext(ctx).GetByID(id)
+}
+
+// ResolveDiscrepancy closes an open discrepancy with an explanatory note,
+// recording the operator who resolved it.
+func (s *ReconciliationService) ResolveDiscrepancy(ctx context.Context, id uint, resolvedBy, note string) (_ *models.Discrepancy, err error) {
+	ctx, span := tracing.Start(ctx, "ReconciliationService.ResolveDiscrepancy")
+	defer tracing.End(span, &err)
+
+	if note == "" {
+		return nil, errors.New("resolution note is required")
+	}
+	resolvedBy = strings.TrimSpace(resolvedBy)
+	if resolvedBy == "" {
+		return nil, errors.New("resolved_by is required")
+	}
+
+	discrepancyRepo := s.discrepancyRepo.WithContext(ctx)
+	discrepancy, err := discrepancyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if discrepancy.Status == models.DiscrepancyStatusResolved {
+		return nil, errors.New("discrepancy already resolved")
+	}
+
+	now := time.Now()
+	discrepancy.Status = models.DiscrepancyStatusResolved
+	discrepancy.ResolutionNote = note
+	discrepancy.ResolvedBy = resolvedBy
+	discrepancy.ResolvedAt = &now
+	if err := discrepancyRepo.Save(discrepancy); err != nil {
+		return nil, err
+	}
+	return discrepancy, nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/sanctions"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrScreeningPending is returned when a user whose name matched a sanctions
+// list acts before an operator has reviewed the match.
+var ErrScreeningPending = errors.New("user is pending sanctions review")
+
+// ErrScreeningBlocked is returned when a user confirmed as listed acts.
+var ErrScreeningBlocked = errors.New("user is blocked by sanctions screening")
+
+// ErrScreeningHitReviewed is returned when a hit is reviewed twice.
+var ErrScreeningHitReviewed = errors.New("screening hit already reviewed")
+
+// checkScreening rejects a user who may not use the service until, or
+// because of, a sanctions review.
+func checkScreening(user *models.User) error {
+	switch user.ScreeningStatus {
+	case models.ScreeningStatusPending:
+		return ErrScreeningPending
+	case models.ScreeningStatusBlocked:
+		return ErrScreeningBlocked
+	}
+	return nil
+}
+
+// screenName matches name against the lists and returns the hits to record
+// for it. Entries already cleared for the user are skipped, so a reviewed
+// false positive is not raised again.
+func screenName(tx *gorm.DB, screener *sanctions.Screener, userID uint, name, subject string) ([]models.ScreeningHit, error) {
+	matches := screener.Screen(name)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var cleared map[string]bool
+	if userID != 0 {
+		var err error
+		cleared, err = repositories.NewScreeningHitRepository(tx).ClearedEntries(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var hits []models.ScreeningHit
+	for _, m := range matches {
+		if cleared[m.Entry.List+"/"+m.Entry.ID] {
+			continue
+		}
+		hits = append(hits, models.ScreeningHit{
+			Subject:      subject,
+			UserID:       userID,
+			ScreenedName: name,
+			List:         m.Entry.List,
+			EntryID:      m.Entry.ID,
+			EntryName:    m.Name,
+			Score:        m.Score,
+			Status:       models.ScreeningHitStatusPending,
+		})
+	}
+	return hits, nil
+}
+
+// TransferScreening configures the sanctions screening of transfers. Both
+// owners' names are screened when the amount is at least MinAmount. A nil
+// Screener turns it off.
+type TransferScreening struct {
+	Screener  *sanctions.Screener
+	MinAmount int64
+}
+
+// IScreeningService is the operators' side of sanctions screening: reviewing
+// the hits that block users and transfers.
+type IScreeningService interface {
+	ListHits(ctx context.Context, status string) ([]models.ScreeningHit, error)
+	GetHit(ctx context.Context, id uint) (*models.ScreeningHit, error)
+	Clear(ctx context.Context, id uint, reviewer, note string) (*models.ScreeningHit, error)
+	Confirm(ctx context.Context, id uint, reviewer, note string) (*models.ScreeningHit, error)
+}
+
+type ScreeningService struct {
+	hitRepo   *repositories.ScreeningHitRepository
+	transfers *TransferService
+	db        *gorm.DB
+}
+
+var _ IScreeningService = &ScreeningService{}
+
+func NewScreeningService(
+	hitRepo *repositories.ScreeningHitRepository,
+	transfers *TransferService,
+	db *gorm.DB,
+) *ScreeningService {
+	return &ScreeningService{
+		hitRepo:   hitRepo,
+		transfers: transfers,
+		db:        db,
+	}
+}
+
+func (s *ScreeningService) ListHits(ctx context.Context, status string) (_ []models.ScreeningHit, err error) {
+	ctx, span := tracing.Start(ctx, "ScreeningService.ListHits")
+	defer tracing.End(span, &err)
+
+	return s.hitRepo.WithContext(ctx).List(status)
+}
+
+func (s *ScreeningService) GetHit(ctx context.Context, id uint) (_ *models.ScreeningHit, err error) {
+	ctx, span := tracing.Start(ctx, "ScreeningService.GetHit")
+	defer tracing.End(span, &err)
+
+	return s.hitRepo.WithContext(ctx).GetByID(id)
+}
+
+// Clear marks the hit a false positive. Once every hit on its subject is
+// cleared, the user becomes clear or the held transfer is booked; the hit
+// stays pending if the transfer no longer can be, e.g. for lack of funds.
+func (s *ScreeningService) Clear(ctx context.Context, id uint, reviewer, note string) (_ *models.ScreeningHit, err error) {
+	ctx, span := tracing.Start(ctx, "ScreeningService.Clear", attribute.Int64("screening.hit_id", int64(id)))
+	defer tracing.End(span, &err)
+
+	return s.review(ctx, id, models.ScreeningHitStatusCleared, reviewer, note)
+}
+
+// Confirm marks the hit a true match: the user is blocked and a held
+// transfer fails. A note recording the grounds is required.
+func (s *ScreeningService) Confirm(ctx context.Context, id uint, reviewer, note string) (_ *models.ScreeningHit, err error) {
+	ctx, span := tracing.Start(ctx, "ScreeningService.Confirm", attribute.Int64("screening.hit_id", int64(id)))
+	defer tracing.End(span, &err)
+
+	if strings.TrimSpace(note) == "" {
+		return nil, errors.New("a note is required to confirm a hit")
+	}
+	return s.review(ctx, id, models.ScreeningHitStatusConfirmed, reviewer, note)
+}
+
+func (s *ScreeningService) review(ctx context.Context, id uint, status, reviewer, note string) (*models.ScreeningHit, error) {
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		return nil, errors.New("reviewer is required")
+	}
+
+	var hit *models.ScreeningHit
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		hitRepo := repositories.NewScreeningHitRepository(tx)
+
+		var err error
+		hit, err = hitRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if hit.Status != models.ScreeningHitStatusPending {
+			return ErrScreeningHitReviewed
+		}
+
+		if err := s.apply(ctx, tx, hit, status); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		hit.Status = status
+		hit.Reviewer = reviewer
+		hit.Note = note
+		hit.ReviewedAt = &now
+		return hitRepo.Save(hit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []any{
+		slog.Uint64("hit_id", uint64(id)),
+		slog.String("subject", hit.Subject),
+		slog.Uint64("user_id", uint64(hit.UserID)),
+		slog.String("list", hit.List),
+		slog.String("entry_id", hit.EntryID),
+		slog.String("reviewer", reviewer),
+	}
+	if hit.TransactionID != nil {
+		attrs = append(attrs, slog.Uint64("transaction_id", uint64(*hit.TransactionID)))
+	}
+	logging.For("services").InfoContext(ctx, "screening hit "+status, attrs...)
+	if status == models.ScreeningHitStatusCleared && hit.Subject == models.ScreeningSubjectTransfer && hit.TransactionID != nil {
+		s.transfers.finishReleased(ctx, *hit.TransactionID)
+	}
+	return hit, nil
+}
+
+// apply carries the review of hit over to its subject.
+func (s *ScreeningService) apply(ctx context.Context, tx *gorm.DB, hit *models.ScreeningHit, status string) error {
+	userRepo := repositories.NewUserRepository(tx)
+	transfer := hit.Subject == models.ScreeningSubjectTransfer && hit.TransactionID != nil
+
+	if status == models.ScreeningHitStatusConfirmed {
+		if transfer {
+			if err := failHeldIfPending(tx, *hit.TransactionID); err != nil {
+				return err
+			}
+		}
+		return userRepo.SetScreeningStatus(hit.UserID, models.ScreeningStatusBlocked)
+	}
+
+	remaining, err := repositories.NewScreeningHitRepository(tx).CountUncleared(hit)
+	if err != nil || remaining > 0 {
+		return err
+	}
+	if transfer {
+		return s.transfers.releaseScreened(ctx, tx, *hit.TransactionID)
+	}
+	return userRepo.SetScreeningStatus(hit.UserID, models.ScreeningStatusClear)
+}
+
+// failHeldIfPending fails a held transfer unless an earlier confirmed hit
+// already has.
+func failHeldIfPending(tx *gorm.DB, transactionID uint) error {
+	transaction, err := repositories.NewTransactionRepository(tx).GetForUpdate(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.Status == models.TransactionStatusFailed {
+		return nil
+	}
+	return failHeld(tx, transactionID)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"gorm.io/gorm"
+)
+
+// IStatementService answers point-in-time questions about a wallet from its
+// transaction history rather than from Wallet.Balance.
+type IStatementService interface {
+	BalanceAsOf(ctx context.Context, walletID uint, asOf time.Time) (int64, error)
+	Statement(ctx context.Context, walletID uint, from, to time.Time) (*models.Statement, error)
+	StreamStatement(ctx context.Context, walletID uint, from, to time.Time, w StatementWriter) error
+}
+
+// StatementWriter renders a statement incrementally. Begin is called once
+// with the opening and closing balances, Line once per transaction, and End
+// after the last line.
+type StatementWriter interface {
+	Begin(header models.StatementHeader) error
+	Line(line models.StatementLine) error
+	End() error
+}
+
+type StatementService struct {
+	transactionRepo *repositories.TransactionRepository
+	walletRepo      *repositories.WalletRepository
+}
+
+var _ IStatementService = &StatementService{}
+
+func NewStatementService(
+	transactionRepo *repositories.TransactionRepository,
+	walletRepo *repositories.WalletRepository,
+) *StatementService {
+	return &StatementService{
+		transactionRepo: transactionRepo,
+		walletRepo:      walletRepo,
+	}
+}
+
+// BalanceAsOf returns the wallet balance including every completed
+// transaction created at or before asOf.
+func (s *StatementService) BalanceAsOf(ctx context.Context, walletID uint, asOf time.Time) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "StatementService.BalanceAsOf")
+	defer tracing.End(span, &err)
+
+	if _, err := s.walletRepo.WithContext(ctx).GetByID(walletID); err != nil {
+		return 0, err
+	}
+	return s.transactionRepo.WithContext(ctx).BalanceAt(walletID, asOf)
+}
+
+// Statement returns the opening balance at from, every completed transaction
+// in [from, to] with a running balance, and the closing balance at to.
+func (s *StatementService) Statement(ctx context.Context, walletID uint, from, to time.Time) (_ *models.Statement, err error) {
+	ctx, span := tracing.Start(ctx, "StatementService.Statement")
+	defer tracing.End(span, &err)
+
+	if to.Before(from) {
+		return nil, errors.New("from must not be after to")
+	}
+
+	// Like StreamStatement, the opening balance and the lines come from one
+	// snapshot, so a transfer committed in between cannot make them disagree.
+	var opening int64
+	var transactions []models.Transaction
+	err = s.transactionRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := repositories.NewWalletRepository(tx).GetByID(walletID); err != nil {
+			return err
+		}
+
+		transactionRepo := repositories.NewTransactionRepository(tx)
+		var err error
+		opening, err = transactionRepo.BalanceBefore(walletID, from)
+		if err != nil {
+			return err
+		}
+		transactions, err = transactionRepo.GetCompletedByWalletIDBetween(walletID, from, to)
+		return err
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.Statement{
+		WalletID:       walletID,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		Lines:          make([]models.StatementLine, 0, len(transactions)),
+	}
+	running := opening
+	for _, t := range transactions {
+		line := models.NewStatementLine(walletID, t, running)
+		running = line.RunningBalance
+		statement.Lines = append(statement.Lines, line)
+	}
+	statement.ClosingBalance = running
+
+	return statement, nil
+}
+
+// StreamStatement writes the same statement as Statement to w, reading
+// transactions row by row so large wallets are never held in memory. Nothing
+// is written to w if the wallet or range is invalid.
+func (s *StatementService) StreamStatement(ctx context.Context, walletID uint, from, to time.Time, w StatementWriter) (err error) {
+	ctx, span := tracing.Start(ctx, "StatementService.StreamStatement")
+	defer tracing.End(span, &err)
+
+	if to.Before(from) {
+		return errors.New("from must not be after to")
+	}
+
+	// The balances and the lines are read from one snapshot, so a transfer
+	// committed while the statement streams cannot make them disagree.
+	return s.transactionRepo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		wallet, err := repositories.NewWalletRepository(tx).GetByID(walletID)
+		if err != nil {
+			return err
+		}
+
+		transactionRepo := repositories.NewTransactionRepository(tx)
+		opening, err := transactionRepo.BalanceBefore(walletID, from)
+		if err != nil {
+			return err
+		}
+		closing, err := transactionRepo.BalanceAt(walletID, to)
+		if err != nil {
+			return err
+		}
+
+		err = w.Begin(models.StatementHeader{
+			WalletID:       walletID,
+			Currency:       wallet.Currency,
+			From:           from,
+			To:             to,
+			OpeningBalance: opening,
+			ClosingBalance: closing,
+			GeneratedAt:    time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		running := opening
+		err = transactionRepo.EachCompletedByWalletIDBetween(walletID, from, to, func(t models.Transaction) error {
+			line := models.NewStatementLine(walletID, t, running)
+			running = line.RunningBalance
+			return w.Line(line)
+		})
+		if err != nil {
+			return err
+		}
+		if running != closing {
+			return fmt.Errorf("statement for wallet %d is inconsistent: lines end at %d, closing balance is %d", walletID, running, closing)
+		}
+
+		return w.End()
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"wallet-api/logging"
+	"wallet-api/models"
+	"wallet-api/repositories"
+	"wallet-api/tenancy"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrTenantSlugTaken is returned when a tenant is given the slug of another.
+var ErrTenantSlugTaken = errors.New("tenant slug already in use")
+
+var (
+	tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	currencyPattern   = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// maxTenantNameLength and maxTenantSlugLength match the sizes of the
+// models.Tenant columns.
+const (
+	maxTenantNameLength = 100
+	maxTenantSlugLength = 50
+)
+
+// ITenantService manages the tenants the API is run for and their transfer
+// fees, KYC limits, currencies and escrow wallets.
+type ITenantService interface {
+	Create(ctx context.Context, details TenantDetails) (*models.Tenant, error)
+	Get(ctx context.Context, id uint) (*models.Tenant, error)
+	List(ctx context.Context) ([]models.Tenant, error)
+	Update(ctx context.Context, id uint, update TenantUpdate) (*models.Tenant, error)
+}
+
+// TenantDetails describes a new tenant. A tenant without currencies offers
+// only models.DefaultTenantCurrency. Fees credit a wallet of the tenant, so
+// they are set with Update once the tenant has one.
+type TenantDetails struct {
+	Name       string
+	Slug       string
+	Currencies []string
+	Limits     map[models.KYCLevel]models.TierLimit
+}
+
+// TenantUpdate lists the tenant fields to change; nil fields are left
+// alone. An empty Fees, Limits or EscrowWallets removes them all. Currencies still in use
+// by wallets can be removed; those wallets keep their balance but no new
+// wallet is opened in the currency.
+type TenantUpdate struct {
+	Name          *string
+	Currencies    []string
+	Fees          map[string]models.TransferFee
+	Limits        map[models.KYCLevel]models.TierLimit
+	EscrowWallets map[string]uint
+}
+
+type TenantService struct {
+	tenantRepo *repositories.TenantRepository
+	db         *gorm.DB
+}
+
+var _ ITenantService = &TenantService{}
+
+func NewTenantService(tenantRepo *repositories.TenantRepository, db *gorm.DB) *TenantService {
+	return &TenantService{
+		tenantRepo: tenantRepo,
+		db:         db,
+	}
+}
+
+func (s *TenantService) Create(ctx context.Context, details TenantDetails) (_ *models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Create")
+	defer tracing.End(span, &err)
+
+	name, err := tenantName(details.Name)
+	if err != nil {
+		return nil, err
+	}
+	slug := strings.TrimSpace(details.Slug)
+	if len(slug) > maxTenantSlugLength || !tenantSlugPattern.MatchString(slug) {
+		return nil, errInvalid("slug must be lower-case letters and digits separated by single hyphens")
+	}
+	currencies := details.Currencies
+	if len(currencies) == 0 {
+		currencies = []string{models.DefaultTenantCurrency}
+	}
+	tenant := &models.Tenant{
+		Name:       name,
+		Slug:       slug,
+		Currencies: currencies,
+		Limits:     details.Limits,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tenantRepo := repositories.NewTenantRepository(tx)
+		taken, err := tenantRepo.SlugTaken(slug, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrTenantSlugTaken
+		}
+		if err := normalizeCurrencies(tenant); err != nil {
+			return err
+		}
+		if err := checkTierLimits(tenant.Limits); err != nil {
+			return err
+		}
+		return tenantRepo.Create(tenant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "tenant created",
+		slog.Uint64("tenant_id", uint64(tenant.ID)),
+		slog.String("slug", tenant.Slug),
+	)
+	return tenant, nil
+}
+
+func (s *TenantService) Get(ctx context.Context, id uint) (_ *models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Get", attribute.Int64("tenant.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	return s.tenantRepo.WithContext(ctx).GetByID(id)
+}
+
+func (s *TenantService) List(ctx context.Context) (_ []models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.List")
+	defer tracing.End(span, &err)
+
+	return s.tenantRepo.WithContext(ctx).List()
+}
+
+// Update changes the tenant's name, currencies, fees, limits or escrow
+// wallets. They apply to transfers and escrow agreements made from then on.
+func (s *TenantService) Update(ctx context.Context, id uint, update TenantUpdate) (_ *models.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Update", attribute.Int64("tenant.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	var tenant *models.Tenant
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		tenant, err = repositories.NewTenantRepository(tx).GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if update.Name != nil {
+			if tenant.Name, err = tenantName(*update.Name); err != nil {
+				return err
+			}
+		}
+		if update.Currencies != nil {
+			tenant.Currencies = update.Currencies
+			if err := normalizeCurrencies(tenant); err != nil {
+				return err
+			}
+		}
+		if update.Fees != nil {
+			tenant.Fees = update.Fees
+		}
+		if update.Limits != nil {
+			tenant.Limits = update.Limits
+		}
+		if update.EscrowWallets != nil {
+			tenant.EscrowWallets = update.EscrowWallets
+		}
+		// Fees and escrow wallets are checked again when only the
+		// currencies change, since neither can outlive its currency.
+		if err := checkTenantFees(tx, tenant); err != nil {
+			return err
+		}
+		if err := checkEscrowWallets(tx, tenant); err != nil {
+			return err
+		}
+		if err := checkTierLimits(tenant.Limits); err != nil {
+			return err
+		}
+		return repositories.NewTenantRepository(tx).Save(tenant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "tenant updated",
+		slog.Uint64("tenant_id", uint64(tenant.ID)),
+	)
+	return tenant, nil
+}
+
+func tenantName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errInvalid("name must not be empty")
+	}
+	if len(name) > maxTenantNameLength {
+		return "", errInvalid(fmt.Sprintf("name must be at most %d characters", maxTenantNameLength))
+	}
+	return name, nil
+}
+
+// normalizeCurrencies upper-cases the tenant's currencies and checks they
+// are distinct ISO 4217 codes.
+func normalizeCurrencies(tenant *models.Tenant) error {
+	if len(tenant.Currencies) == 0 {
+		return errInvalid("a tenant needs at least one currency")
+	}
+	seen := make(map[string]bool, len(tenant.Currencies))
+	for i, currency := range tenant.Currencies {
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if !currencyPattern.MatchString(currency) {
+			return errInvalid(fmt.Sprintf("invalid currency %q, want an ISO 4217 code", currency))
+		}
+		if seen[currency] {
+			return errInvalid(fmt.Sprintf("currency %s listed twice", currency))
+		}
+		seen[currency] = true
+		tenant.Currencies[i] = currency
+	}
+	return nil
+}
+
+// checkTenantFees checks every fee is for one of the tenant's currencies and
+// credits a wallet of the tenant in that currency. The wallets are read in
+// the tenant's scope, so a wallet of another tenant is not found.
+func checkTenantFees(tx *gorm.DB, tenant *models.Tenant) error {
+	walletRepo := repositories.NewWalletRepository(tx.WithContext(tenancy.WithTenant(tx.Statement.Context, tenant.ID)))
+	for currency, fee := range tenant.Fees {
+		if !tenant.Supports(currency) {
+			return errInvalid(fmt.Sprintf("fee for %s, which the tenant does not offer", currency))
+		}
+		if fee.Fixed < 0 || fee.BasisPoints < 0 || fee.BasisPoints > 10_000 {
+			return errInvalid(fmt.Sprintf("fee for %s must be non-negative and at most 10000 basis points", currency))
+		}
+		if fee.Fixed == 0 && fee.BasisPoints == 0 {
+			continue
+		}
+		if fee.WalletID == 0 {
+			return errInvalid(fmt.Sprintf("fee for %s needs a wallet to credit", currency))
+		}
+		wallet, err := walletRepo.GetByID(fee.WalletID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalid(fmt.Sprintf("fee wallet %d not found", fee.WalletID))
+		}
+		if err != nil {
+			return err
+		}
+		if wallet.IsPocket() {
+			return errInvalid("a pocket cannot collect fees")
+		}
+		if wallet.Currency != currency {
+			return errInvalid(fmt.Sprintf("fee wallet %d does not hold %s", fee.WalletID, currency))
+		}
+	}
+	return nil
+}
+
+// checkEscrowWallets checks every escrow wallet is a wallet of the tenant
+// holding its currency. Escrow wallets hold money for others, so one may
+// not also collect fees.
+func checkEscrowWallets(tx *gorm.DB, tenant *models.Tenant) error {
+	walletRepo := repositories.NewWalletRepository(tx.WithContext(tenancy.WithTenant(tx.Statement.Context, tenant.ID)))
+	for currency, walletID := range tenant.EscrowWallets {
+		if !tenant.Supports(currency) {
+			return errInvalid(fmt.Sprintf("escrow wallet for %s, which the tenant does not offer", currency))
+		}
+		wallet, err := walletRepo.GetByID(walletID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalid(fmt.Sprintf("escrow wallet %d not found", walletID))
+		}
+		if err != nil {
+			return err
+		}
+		if wallet.IsPocket() {
+			return errInvalid("a pocket cannot hold escrow")
+		}
+		if wallet.Currency != currency {
+			return errInvalid(fmt.Sprintf("escrow wallet %d does not hold %s", walletID, currency))
+		}
+		if fee, ok := tenant.Fees[currency]; ok && fee.WalletID == walletID {
+			return errInvalid(fmt.Sprintf("wallet %d cannot both collect fees and hold escrow", walletID))
+		}
+	}
+	return nil
+}
+
+func checkTierLimits(limits map[models.KYCLevel]models.TierLimit) error {
+	for level, limit := range limits {
+		if level == "" || level.Rank() < 0 {
+			return errInvalid(fmt.Sprintf("unknown KYC level %q", level))
+		}
+		if limit.MaxBalance < 0 || limit.DailyOutgoing < 0 {
+			return errInvalid(fmt.Sprintf("limits for %s must not be negative", level))
+		}
+	}
+	return nil
+}
@@ -1,22 +1,127 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
+	"wallet-api/logging"
+	"wallet-api/metrics"
 	"wallet-api/models"
 	"wallet-api/repositories"
+	"wallet-api/risk"
+	"wallet-api/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// ErrInsufficientBalance is returned when a debit exceeds the wallet balance.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ErrWalletFrozen is returned when either side of a money movement is frozen.
+var ErrWalletFrozen = errors.New("wallet is frozen")
+
+// ErrKYCLimitExceeded is returned when a money movement would take a user
+// past the limits of their KYC level.
+var ErrKYCLimitExceeded = errors.New("KYC limit exceeded")
+
+// ErrTransferHeld is returned, wrapped in a *HeldError, when an AML rule or
+// a sanctions screening hit holds a transfer for review.
+var ErrTransferHeld = errors.New("transfer held for review")
+
+// ErrTransferDenied is returned when the fraud risk of a transfer is too
+// high for it to go ahead. The reasons are recorded on the failed
+// transaction but not revealed to the client.
+var ErrTransferDenied = errors.New("transfer denied by risk checks")
+
+// RiskScorer assesses the fraud risk of a transfer before it commits.
+// risk.Heuristic implements it.
+type RiskScorer interface {
+	// Score assesses t, about to be committed in tx.
+	Score(ctx context.Context, tx *gorm.DB, t *models.Transaction) (risk.Assessment, error)
+}
+
+// HeldError reports a transfer recorded as pending instead of booked. It
+// moves money only if an analyst clears its AML case, which risk reviews
+// open too, or an operator every screening hit on it.
+type HeldError struct {
+	TransactionID uint
+}
+
+func (e *HeldError) Error() string { return ErrTransferHeld.Error() }
+
+func (e *HeldError) Is(target error) bool { return target == ErrTransferHeld }
+
+// TierLimits caps what a user at one KYC level may hold in a wallet and send
+// in any 24 hours, in minor units. Zero means unlimited.
+type TierLimits struct {
+	MaxBalance    int64
+	DailyOutgoing int64
+}
+
+// KYCLimits maps each KYC level to its limits. Levels without an entry are
+// unlimited.
+type KYCLimits map[models.KYCLevel]TierLimits
+
+// DefaultKYCLimits matches the defaults in config.
+func DefaultKYCLimits() KYCLimits {
+	return KYCLimits{
+		models.KYCLevelNone:  {MaxBalance: 100_000, DailyOutgoing: 50_000},
+		models.KYCLevelBasic: {MaxBalance: 1_000_000, DailyOutgoing: 500_000},
+	}
+}
+
+// checkBalance rejects a balance above the level's maximum.
+func (l KYCLimits) checkBalance(level models.KYCLevel, balance int64) error {
+	max := l[normalizeLevel(level)].MaxBalance
+	if max > 0 && balance > max {
+		return fmt.Errorf("%w: balance would exceed %d allowed at KYC level %s", ErrKYCLimitExceeded, max, normalizeLevel(level))
+	}
+	return nil
+}
+
+// checkOutgoing rejects sending amount on top of sent in the last 24 hours.
+func (l KYCLimits) checkOutgoing(level models.KYCLevel, sent, amount int64) error {
+	max := l[normalizeLevel(level)].DailyOutgoing
+	if max > 0 && sent+amount > max {
+		return fmt.Errorf("%w: %d of %d allowed per 24 hours at KYC level %s already sent",
+			ErrKYCLimitExceeded, sent, max, normalizeLevel(level))
+	}
+	return nil
+}
+
+// with returns l with a tenant's limits in place of the levels they name.
+func (l KYCLimits) with(overrides map[models.KYCLevel]models.TierLimit) KYCLimits {
+	if len(overrides) == 0 {
+		return l
+	}
+	merged := make(KYCLimits, len(l)+len(overrides))
+	for level, limits := range l {
+		merged[level] = limits
+	}
+	for level, limits := range overrides {
+		merged[normalizeLevel(level)] = TierLimits{MaxBalance: limits.MaxBalance, DailyOutgoing: limits.DailyOutgoing}
+	}
+	return merged
+}
+
+func normalizeLevel(level models.KYCLevel) models.KYCLevel {
+	if level == "" {
+		return models.KYCLevelNone
+	}
+	return level
+}
+
 // ITransferService defines methods for wallet transactions
 type ITransferService interface {
-	Transfer(sourceWalletID, targetWalletID uint, amount int64) error
-	Deposit(walletID uint, amount int64) error
-	GetTransactionsByWalletID(walletID uint) ([]models.Transaction, error)
+	Transfer(ctx context.Context, sourceWalletID, targetWalletID uint, amount int64) error
+	Deposit(ctx context.Context, walletID uint, amount int64) error
+	GetTransactionsByWalletID(ctx context.Context, walletID uint) ([]models.Transaction, error)
 }
 
 // TransferService implements ITransferService
@@ -24,6 +129,11 @@ type TransferService struct {
 	transactionRepo *repositories.TransactionRepository
 	walletRepo      *repositories.WalletRepository
 	db              *gorm.DB
+	limits          KYCLimits
+	monitor         TransactionMonitor
+	screening       TransferScreening
+	scorer          RiskScorer
+	notifier        TransferNotifier
 }
 
 // ✅ Compile-time assertion to ensure TransferService implements ITransferService
@@ -34,92 +144,773 @@ func NewTransferService(
 	transactionRepo *repositories.TransactionRepository,
 	walletRepo *repositories.WalletRepository,
 	db *gorm.DB,
+	limits KYCLimits,
+	monitor TransactionMonitor,
+	screening TransferScreening,
+	scorer RiskScorer,
+	notifier TransferNotifier,
 ) *TransferService {
 	return &TransferService{
 		transactionRepo: transactionRepo,
 		walletRepo:      walletRepo,
 		db:              db,
+		limits:          limits,
+		monitor:         monitor,
+		screening:       screening,
+		scorer:          scorer,
+		notifier:        notifier,
 	}
 }
 
-func (s *TransferService) Transfer(sourceWalletID, targetWalletID uint, amount int64) error {
+func (s *TransferService) Transfer(ctx context.Context, sourceWalletID, targetWalletID uint, amount int64) (err error) {
+	ctx, span := tracing.Start(ctx, "TransferService.Transfer",
+		attribute.Int64("wallet.source_id", int64(sourceWalletID)),
+		attribute.Int64("wallet.target_id", int64(targetWalletID)),
+		attribute.Int64("transaction.amount", amount),
+	)
+	defer tracing.End(span, &err)
+
+	result, err := s.transfer(ctx, sourceWalletID, targetWalletID, amount)
+	metrics.ObserveTransfer(outcome(err), amount, result.currency)
+	logResult(ctx, "transfer", err,
+		slog.Uint64("source_wallet_id", uint64(sourceWalletID)),
+		slog.Uint64("target_wallet_id", uint64(targetWalletID)),
+		slog.Int64("amount", amount),
+	)
+	return err
+}
+
+func (s *TransferService) transfer(ctx context.Context, sourceWalletID, targetWalletID uint, amount int64) (transferResult, error) {
+	var result transferResult
 	if amount <= 0 {
-		return errors.New("amount must be positive")
+		return result, errInvalid("amount must be positive")
 	}
 	if sourceWalletID == targetWalletID {
-		return errors.New("source and target wallets cannot be the same")
+		return result, errInvalid("source and target wallets cannot be the same")
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = s.transferIn(ctx, tx, sourceWalletID, targetWalletID, amount, nil)
+		return err
+	})
+	if err != nil {
+		return result, err
 	}
+	return result, s.finish(ctx, result)
+}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var sourceWallet, targetWallet models.Wallet
+// transferResult is what became of a transfer made in a database
+// transaction. At most one of the flags is set; with none the transfer was
+// booked.
+type transferResult struct {
+	transaction models.Transaction
+	held        bool
+	denied      bool
+	// approval is set when the transfer waits for its wallet's owners
+	// instead; no transaction is recorded then.
+	approval *models.TransferApproval
+	// currency is the source wallet's, set once the wallets are locked.
+	currency string
+}
 
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&sourceWallet, sourceWalletID).Error; err != nil {
-			return err
+// transferIn makes a transfer in tx. approval is the approved request being
+// executed, whose requester sends the transfer; without one the sender is
+// the initiator in ctx, which must be authenticated with a user key, and a
+// transfer the wallet's approval rule covers waits for approval.
+func (s *TransferService) transferIn(ctx context.Context, tx *gorm.DB, sourceWalletID, targetWalletID uint, amount int64, approval *models.TransferApproval) (transferResult, error) {
+	var result transferResult
+	var sourceWallet, targetWallet models.Wallet
+
+	var initiatedBy uint
+	if approval != nil {
+		initiatedBy = approval.RequestedBy
+	} else {
+		var err error
+		if initiatedBy, err = initiator(ctx); err != nil {
+			return result, err
 		}
+	}
 
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&targetWallet, targetWalletID).Error; err != nil {
-			return err
+	lockStart := time.Now()
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&sourceWallet, sourceWalletID).Error; err != nil {
+		return result, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&targetWallet, targetWalletID).Error; err != nil {
+		return result, err
+	}
+	metrics.ObserveLockWait("transfer", time.Since(lockStart))
+	result.currency = sourceWallet.Currency
+
+	rules, err := s.tenantRules(tx, &sourceWallet)
+	if err != nil {
+		return result, err
+	}
+	if err := s.checkTransfer(tx, &sourceWallet, &targetWallet, initiatedBy, amount, rules); err != nil {
+		return result, err
+	}
+
+	if approval == nil {
+		pending, err := requestApproval(tx, &sourceWallet, targetWalletID, initiatedBy, amount)
+		if err != nil {
+			return result, err
+		}
+		if pending != nil {
+			result.approval = pending
+			return result, nil
 		}
+	}
+
+	result.transaction = models.Transaction{
+		SourceWalletID:  &sourceWalletID,
+		TargetWalletID:  targetWalletID,
+		Amount:          amount,
+		Type:            models.TransactionTypeTransfer,
+		ReferenceNumber: fmt.Sprintf("TRF-%d", time.Now().UnixNano()),
+		Status:          models.TransactionStatusCompleted,
+		DeviceID:        risk.Device(ctx),
+		Fee:             rules.feeFor(amount),
+		InitiatedBy:     &initiatedBy,
+	}
+	transaction := &result.transaction
+
+	hits, err := s.screenTransfer(tx, &sourceWallet, &targetWallet, amount)
+	if err != nil {
+		return result, err
+	}
+	if len(hits) > 0 {
+		result.held = true
+		return result, holdScreenedTransfer(tx, transaction, hits)
+	}
+
+	denied, alerts, err := s.assess(ctx, tx, transaction)
+	if err != nil {
+		return result, err
+	}
+	if denied {
+		result.denied = true
+		transaction.Status = models.TransactionStatusFailed
+		return result, tx.Create(transaction).Error
+	}
+	if len(alerts) > 0 {
+		result.held = true
+		return result, holdTransfer(tx, transaction, alerts)
+	}
+
+	if err := book(tx, &sourceWallet, &targetWallet, amount); err != nil {
+		return result, err
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return result, err
+	}
+	return result, chargeFee(tx, &sourceWallet, transaction, rules)
+}
+
+// finish reports the result of a committed transfer, and tells the monitor
+// and notifier about a booked one.
+func (s *TransferService) finish(ctx context.Context, result transferResult) error {
+	switch {
+	case result.denied:
+		return ErrTransferDenied
+	case result.held:
+		return &HeldError{TransactionID: result.transaction.ID}
+	case result.approval != nil:
+		return &ApprovalRequiredError{ApprovalID: result.approval.ID}
+	}
+	if s.monitor != nil {
+		s.monitor.Observe(ctx, result.transaction)
+	}
+	if s.notifier != nil {
+		s.notifier.TransferCompleted(ctx, result.transaction)
+	}
+	return nil
+}
+
+// checkTransfer rejects a transfer between the locked wallets, sent by the
+// user initiatedBy under the tenant's rules, that may not go ahead.
+func (s *TransferService) checkTransfer(tx *gorm.DB, source, target *models.Wallet, initiatedBy uint, amount int64, rules transferRules) error {
+	if source.IsPocket() || target.IsPocket() {
+		return ErrPocketWallet
+	}
+	if source.Currency != target.Currency {
+		return errInvalid("source and target wallets hold different currencies")
+	}
+	if source.Frozen() || target.Frozen() {
+		return ErrWalletFrozen
+	}
+	if err := checkSpender(tx, source, initiatedBy, amount); err != nil {
+		return err
+	}
+	if source.Balance < amount+rules.feeFor(amount) {
+		return ErrInsufficientBalance
+	}
+	return s.checkOwners(tx, source, target, amount, rules.limits)
+}
+
+// transferRules are what the tenant of a wallet applies to transfers from
+// it: its KYC limits and the fee, if it charges one in the wallet's
+// currency.
+type transferRules struct {
+	limits KYCLimits
+	fee    *models.TransferFee
+}
+
+func (r transferRules) feeFor(amount int64) int64 {
+	if r.fee == nil {
+		return 0
+	}
+	return r.fee.For(amount)
+}
+
+// tenantRules reads the rules of the tenant of wallet. A fee wallet does not
+// pay fees to itself.
+func (s *TransferService) tenantRules(tx *gorm.DB, wallet *models.Wallet) (transferRules, error) {
+	tenant, err := repositories.NewTenantRepository(tx).GetByID(wallet.TenantID)
+	if err != nil {
+		return transferRules{}, err
+	}
+	rules := transferRules{limits: s.limits.with(tenant.Limits)}
+	if fee, ok := tenant.Fees[wallet.Currency]; ok && fee.WalletID != wallet.ID {
+		rules.fee = &fee
+	}
+	return rules, nil
+}
+
+// chargeFee books the fee of a booked transfer from its locked source
+// wallet to the tenant's fee wallet, as a transaction of its own.
+func chargeFee(tx *gorm.DB, source *models.Wallet, transfer *models.Transaction, rules transferRules) error {
+	if transfer.Fee == 0 {
+		return nil
+	}
+	var feeWallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&feeWallet, rules.fee.WalletID).Error; err != nil {
+		return err
+	}
+	if err := book(tx, source, &feeWallet, transfer.Fee); err != nil {
+		return err
+	}
+	return tx.Create(&models.Transaction{
+		SourceWalletID:  transfer.SourceWalletID,
+		TargetWalletID:  feeWallet.ID,
+		Amount:          transfer.Fee,
+		Type:            models.TransactionTypeFee,
+		ReferenceNumber: "FEE-" + strings.TrimPrefix(transfer.ReferenceNumber, "TRF-"),
+		Status:          models.TransactionStatusCompleted,
+		Description:     "fee for transfer " + transfer.ReferenceNumber,
+		InitiatedBy:     transfer.InitiatedBy,
+	}).Error
+}
+
+// payOutEscrow moves amount held in an escrow wallet to the seller or back
+// to the buyer in tx. The money was screened and scored when it was paid
+// into escrow, so only the recipient is checked: it must be able to take
+// the money under its owner's KYC limits. initiatedBy is the user whose
+// action paid it out, nil for a timeout or an operator.
+func (s *TransferService) payOutEscrow(tx *gorm.DB, escrowWalletID, targetWalletID uint, amount int64, description string, initiatedBy *uint) (transferResult, error) {
+	var result transferResult
+	var escrowWallet, targetWallet models.Wallet
+
+	lockStart := time.Now()
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&escrowWallet, escrowWalletID).Error; err != nil {
+		return result, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&targetWallet, targetWalletID).Error; err != nil {
+		return result, err
+	}
+	metrics.ObserveLockWait("escrow", time.Since(lockStart))
+	result.currency = escrowWallet.Currency
+
+	if targetWallet.IsPocket() {
+		return result, ErrPocketWallet
+	}
+	if escrowWallet.Currency != targetWallet.Currency {
+		return result, errInvalid("escrow and target wallets hold different currencies")
+	}
+	if escrowWallet.Frozen() || targetWallet.Frozen() {
+		return result, ErrWalletFrozen
+	}
+	if escrowWallet.Balance < amount {
+		return result, ErrInsufficientBalance
+	}
+	recipient, err := repositories.NewUserRepository(tx).GetByID(targetWallet.UserID)
+	if err != nil {
+		return result, err
+	}
+	if err := checkUser(recipient); err != nil {
+		return result, err
+	}
+	rules, err := s.tenantRules(tx, &targetWallet)
+	if err != nil {
+		return result, err
+	}
+	balance, err := rollupBalance(tx, &targetWallet)
+	if err != nil {
+		return result, err
+	}
+	if err := rules.limits.checkBalance(recipient.KYCLevel, balance+amount); err != nil {
+		return result, err
+	}
+
+	if err := book(tx, &escrowWallet, &targetWallet, amount); err != nil {
+		return result, err
+	}
+	result.transaction = models.Transaction{
+		SourceWalletID:  &escrowWalletID,
+		TargetWalletID:  targetWalletID,
+		Amount:          amount,
+		Type:            models.TransactionTypeEscrow,
+		ReferenceNumber: fmt.Sprintf("ESC-%d", time.Now().UnixNano()),
+		Status:          models.TransactionStatusCompleted,
+		Description:     description,
+		InitiatedBy:     initiatedBy,
+	}
+	return result, tx.Create(&result.transaction).Error
+}
 
-		if sourceWallet.Balance < amount {
-			return errors.New("insufficient balance")
+// screenTransfer screens the names of both owners when amount is large
+// enough and returns the hits that hold the transfer.
+func (s *TransferService) screenTransfer(tx *gorm.DB, source, target *models.Wallet, amount int64) ([]models.ScreeningHit, error) {
+	if s.screening.Screener == nil || amount < s.screening.MinAmount {
+		return nil, nil
+	}
+
+	userIDs := []uint{source.UserID}
+	if target.UserID != source.UserID {
+		userIDs = append(userIDs, target.UserID)
+	}
+	var hits []models.ScreeningHit
+	for _, id := range userIDs {
+		user, err := repositories.NewUserRepository(tx).GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		found, err := screenName(tx, s.screening.Screener, user.ID, user.Name, models.ScreeningSubjectTransfer)
+		if err != nil {
+			return nil, err
 		}
+		hits = append(hits, found...)
+	}
+	return hits, nil
+}
 
-		sourceWallet.Balance -= amount
-		if err := tx.Save(&sourceWallet).Error; err != nil {
-			return err
+// assess runs the risk scorer and the blocking AML rules on a transfer about
+// to be booked and records its risk score. It reports whether the scorer
+// denied the transfer and, if not, the alerts that should hold it.
+func (s *TransferService) assess(ctx context.Context, tx *gorm.DB, transaction *models.Transaction) (bool, []models.AMLAlert, error) {
+	var alerts []models.AMLAlert
+	if s.scorer != nil {
+		assessment, err := s.scorer.Score(ctx, tx, transaction)
+		if err != nil {
+			return false, nil, err
 		}
+		transaction.RiskScore = &assessment.Score
+		transaction.RiskReasons = assessment.Reasons
+		switch assessment.Decision {
+		case risk.Deny:
+			return true, nil, nil
+		case risk.Review:
+			alerts = append(alerts, riskAlert(assessment))
+		}
+	}
+
+	if s.monitor != nil {
+		screened, err := s.monitor.Screen(ctx, tx, transaction)
+		if err != nil {
+			return false, nil, err
+		}
+		alerts = append(alerts, screened...)
+	}
+	return false, alerts, nil
+}
+
+// riskAlert puts a risk assessment calling for review into the AML case
+// queue, so analysts decide it like any other held transfer.
+func riskAlert(a risk.Assessment) models.AMLAlert {
+	return models.AMLAlert{
+		Rule:     risk.AlertRule,
+		Severity: models.AMLSeverityMedium,
+		Details:  fmt.Sprintf("risk score %d: %s", a.Score, strings.Join(a.Reasons, "; ")),
+	}
+}
+
+// book moves amount between the locked wallets.
+func book(tx *gorm.DB, source, target *models.Wallet, amount int64) error {
+	source.Balance -= amount
+	if err := tx.Save(source).Error; err != nil {
+		return err
+	}
+	target.Balance += amount
+	return tx.Save(target).Error
+}
+
+// holdTransfer records transaction as pending, without moving money, and
+// opens a held AML case for it with alerts.
+func holdTransfer(tx *gorm.DB, transaction *models.Transaction, alerts []models.AMLAlert) error {
+	transaction.Status = models.TransactionStatusPending
+	if err := tx.Create(transaction).Error; err != nil {
+		return err
+	}
+	return openHeldCase(tx, transaction, alerts)
+}
+
+// openHeldCase opens a held AML case with alerts for the pending transaction.
+func openHeldCase(tx *gorm.DB, transaction *models.Transaction, alerts []models.AMLAlert) error {
+	return repositories.NewAMLCaseRepository(tx).Create(&models.AMLCase{
+		TransactionID: transaction.ID,
+		WalletID:      *transaction.SourceWalletID,
+		Status:        models.AMLCaseStatusOpen,
+		Held:          true,
+		Alerts:        alerts,
+	})
+}
 
-		targetWallet.Balance += amount
-		if err := tx.Save(&targetWallet).Error; err != nil {
+// holdScreenedTransfer records transaction as pending, without moving money,
+// together with the screening hits holding it.
+func holdScreenedTransfer(tx *gorm.DB, transaction *models.Transaction, hits []models.ScreeningHit) error {
+	transaction.Status = models.TransactionStatusPending
+	if err := tx.Create(transaction).Error; err != nil {
+		return err
+	}
+	for i := range hits {
+		hits[i].TransactionID = &transaction.ID
+	}
+	return repositories.NewScreeningHitRepository(tx).Create(hits)
+}
+
+// releaseScreened releases a transfer once every screening hit on it is
+// cleared. Screening holds a transfer before it is scored, so the risk
+// scorer and the blocking AML rules run now: a denied transfer fails, and
+// one they flag stays pending under a held AML case instead of being
+// booked.
+func (s *TransferService) releaseScreened(ctx context.Context, tx *gorm.DB, transactionID uint) error {
+	transaction, err := repositories.NewTransactionRepository(tx).GetForUpdate(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.Status != models.TransactionStatusPending || transaction.SourceWalletID == nil {
+		return fmt.Errorf("transaction %d is not a held transfer", transactionID)
+	}
+
+	denied, alerts, err := s.assess(ctx, tx, transaction)
+	if err != nil {
+		return err
+	}
+	switch {
+	case denied:
+		transaction.Status = models.TransactionStatusFailed
+		return tx.Save(transaction).Error
+	case len(alerts) > 0:
+		if err := tx.Save(transaction).Error; err != nil {
 			return err
 		}
+		return openHeldCase(tx, transaction, alerts)
+	}
+	return s.releaseHeld(tx, transactionID)
+}
 
-		transaction := models.Transaction{
-			SourceWalletID:  &sourceWalletID,
-			TargetWalletID:  targetWalletID,
-			Amount:          amount,
-			Type:            models.TransactionTypeTransfer,
-			ReferenceNumber: fmt.Sprintf("TRF-%d", time.Now().UnixNano()),
-			Status:          "completed",
+// releaseHeld books a held transfer once its AML case, or every screening
+// hit on it, is cleared. The checks are repeated because balances and limits may have changed while
+// it waited. The transaction is dated when it is booked, so balances
+// computed from the ledger for earlier points in time stay correct.
+func (s *TransferService) releaseHeld(tx *gorm.DB, transactionID uint) error {
+	transaction, err := repositories.NewTransactionRepository(tx).GetForUpdate(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.Status != models.TransactionStatusPending || transaction.SourceWalletID == nil {
+		return fmt.Errorf("transaction %d is not a held transfer", transactionID)
+	}
+
+	var sourceWallet, targetWallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&sourceWallet, *transaction.SourceWalletID).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&targetWallet, transaction.TargetWalletID).Error; err != nil {
+		return err
+	}
+	initiatedBy := sourceWallet.UserID
+	if transaction.InitiatedBy != nil {
+		initiatedBy = *transaction.InitiatedBy
+	}
+	rules, err := s.tenantRules(tx, &sourceWallet)
+	if err != nil {
+		return err
+	}
+	if err := s.checkTransfer(tx, &sourceWallet, &targetWallet, initiatedBy, transaction.Amount, rules); err != nil {
+		return err
+	}
+	if err := book(tx, &sourceWallet, &targetWallet, transaction.Amount); err != nil {
+		return err
+	}
+
+	transaction.Status = models.TransactionStatusCompleted
+	transaction.Fee = rules.feeFor(transaction.Amount)
+	transaction.CreatedAt = time.Now()
+	if err := tx.Save(transaction).Error; err != nil {
+		return err
+	}
+	return chargeFee(tx, &sourceWallet, transaction, rules)
+}
+
+// finishReleased tells the monitor and notifier about a held transfer once
+// it has been booked, as finish does for transfers booked straight away.
+// Transfers still held, or failed, are skipped.
+func (s *TransferService) finishReleased(ctx context.Context, transactionID uint) {
+	if s.monitor == nil && s.notifier == nil {
+		return
+	}
+	transaction, err := s.transactionRepo.WithContext(ctx).GetByID(transactionID)
+	if err != nil || transaction.Status != models.TransactionStatusCompleted {
+		return
+	}
+	if s.monitor != nil {
+		s.monitor.Observe(ctx, *transaction)
+	}
+	if s.notifier != nil {
+		s.notifier.TransferCompleted(ctx, *transaction)
+	}
+}
+
+// failHeld marks a held transfer failed once its AML case or a screening hit
+// on it is confirmed.
+func failHeld(tx *gorm.DB, transactionID uint) error {
+	transaction, err := repositories.NewTransactionRepository(tx).GetForUpdate(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction.Status != models.TransactionStatusPending {
+		return fmt.Errorf("transaction %d is not a held transfer", transactionID)
+	}
+	transaction.Status = models.TransactionStatusFailed
+	return tx.Save(transaction).Error
+}
+
+// checkOwners rejects deactivated owners and owners under sanctions review
+// and applies the KYC limits of both. The sender's user row is locked so
+// concurrent transfers from different wallets of the same user are counted
+// against the daily limit one at a time.
+func (s *TransferService) checkOwners(tx *gorm.DB, source, target *models.Wallet, amount int64, limits KYCLimits) error {
+	sender, err := repositories.NewUserRepository(tx).GetForUpdate(source.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkUser(sender); err != nil {
+		return err
+	}
+	sent, err := repositories.NewTransactionRepository(tx).OutgoingSince(sender.ID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if err := limits.checkOutgoing(sender.KYCLevel, sent, amount); err != nil {
+		return err
+	}
+
+	recipient := sender
+	if target.UserID != source.UserID {
+		recipient, err = repositories.NewUserRepository(tx).GetByID(target.UserID)
+		if err != nil {
+			return err
 		}
+		if err := checkUser(recipient); err != nil {
+			return err
+		}
+	}
+	balance, err := rollupBalance(tx, target)
+	if err != nil {
+		return err
+	}
+	return limits.checkBalance(recipient.KYCLevel, balance+amount)
+}
 
-		return tx.Create(&transaction).Error
-	})
+func (s *TransferService) Deposit(ctx context.Context, walletID uint, amount int64) (err error) {
+	_, err = s.observedDeposit(ctx, walletID, amount, "")
+	return err
+}
+
+// ManualDeposit credits a wallet on an operator's behalf. reason is required
+// and stored as the transaction's description.
+func (s *TransferService) ManualDeposit(ctx context.Context, walletID uint, amount int64, reason string) (*models.Transaction, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errInvalid("a reason is required for a manual deposit")
+	}
+	return s.observedDeposit(ctx, walletID, amount, reason)
+}
+
+func (s *TransferService) observedDeposit(ctx context.Context, walletID uint, amount int64, description string) (_ *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "TransferService.Deposit",
+		attribute.Int64("wallet.target_id", int64(walletID)),
+		attribute.Int64("transaction.amount", amount),
+	)
+	defer tracing.End(span, &err)
+
+	transaction, currency, err := s.deposit(ctx, walletID, amount, description)
+	metrics.ObserveDeposit(outcome(err), amount, currency)
+	attrs := []slog.Attr{
+		slog.Uint64("target_wallet_id", uint64(walletID)),
+		slog.Int64("amount", amount),
+	}
+	if description != "" {
+		attrs = append(attrs, slog.String("description", description))
+	}
+	logResult(ctx, "deposit", err, attrs...)
+	return transaction, err
 }
 
-func (s *TransferService) Deposit(walletID uint, amount int64) error {
+// deposit credits the wallet, returning the wallet's currency along with
+// the transaction.
+func (s *TransferService) deposit(ctx context.Context, walletID uint, amount int64, description string) (*models.Transaction, string, error) {
 	if amount <= 0 {
-		return errors.New("amount must be positive")
+		return nil, "", errInvalid("amount must be positive")
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var wallet models.Wallet
+	var transaction models.Transaction
+	var wallet models.Wallet
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
+		lockStart := time.Now()
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			First(&wallet, walletID).Error; err != nil {
 			return err
 		}
+		metrics.ObserveLockWait("deposit", time.Since(lockStart))
+
+		if wallet.IsPocket() {
+			return ErrPocketWallet
+		}
+		if wallet.Frozen() {
+			return ErrWalletFrozen
+		}
+		var owner models.User
+		if err := tx.First(&owner, wallet.UserID).Error; err != nil {
+			return err
+		}
+		if err := checkUser(&owner); err != nil {
+			return err
+		}
+		balance, err := rollupBalance(tx, &wallet)
+		if err != nil {
+			return err
+		}
+		rules, err := s.tenantRules(tx, &wallet)
+		if err != nil {
+			return err
+		}
+		if err := rules.limits.checkBalance(owner.KYCLevel, balance+amount); err != nil {
+			return err
+		}
 
 		wallet.Balance += amount
 		if err := tx.Save(&wallet).Error; err != nil {
 			return err
 		}
 
-		transaction := models.Transaction{
+		transaction = models.Transaction{
 			TargetWalletID:  walletID,
 			Amount:          amount,
 			Type:            models.TransactionTypeDeposit,
 			ReferenceNumber: fmt.Sprintf("DEP-%d", time.Now().UnixNano()),
-			Status:          "completed",
+			Status:          models.TransactionStatusCompleted,
+			Description:     description,
 		}
 
 		return tx.Create(&transaction).Error
 	})
+	if err != nil {
+		return nil, wallet.Currency, err
+	}
+	if s.monitor != nil {
+		s.monitor.Observe(ctx, transaction)
+	}
+	return &transaction, wallet.Currency, nil
 }
 
-func (s *TransferService) GetTransactionsByWalletID(walletID uint) ([]models.Transaction, error) {
-	return s.transactionRepo.GetByWalletID(walletID)
+func (s *TransferService) GetTransactionsByWalletID(ctx context.Context, walletID uint) (_ []models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "TransferService.GetTransactionsByWalletID")
+	defer tracing.End(span, &err)
+
+	return s.transactionRepo.WithContext(ctx).GetByWalletID(walletID)
+}
+
+// GetTransactionByID returns a single transaction.
+func (s *TransferService) GetTransactionByID(ctx context.Context, id uint) (_ *models.Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "TransferService.GetTransactionByID")
+	defer tracing.End(span, &err)
+
+	return s.transactionRepo.WithContext(ctx).GetByID(id)
+}
+
+// logResult logs a committed or rejected money movement. Rejections the
+// client caused are logged at info, anything else at error.
+func logResult(ctx context.Context, operation string, err error, attrs ...slog.Attr) {
+	logger := logging.For("services")
+	result := outcome(err)
+	attrs = append(attrs, slog.String("outcome", result))
+	switch result {
+	case metrics.OutcomeSuccess:
+		logger.LogAttrs(ctx, slog.LevelInfo, operation+" committed", attrs...)
+	case metrics.OutcomeHeld:
+		logger.LogAttrs(ctx, slog.LevelWarn, operation+" held for review", attrs...)
+	case metrics.OutcomeAwaitingApproval:
+		logger.LogAttrs(ctx, slog.LevelInfo, operation+" awaiting approval", attrs...)
+	case metrics.OutcomeError:
+		logger.LogAttrs(ctx, slog.LevelError, operation+" failed", append(attrs, slog.String("error", err.Error()))...)
+	default:
+		logger.LogAttrs(ctx, slog.LevelInfo, operation+" rejected", append(attrs, slog.String("error", err.Error()))...)
+	}
+}
+
+// invalidError marks a request rejected before touching the database.
+type invalidError struct{ msg string }
+
+func (e *invalidError) Error() string { return e.msg }
+
+func errInvalid(msg string) error { return &invalidError{msg: msg} }
+
+// IsInvalid reports whether err rejects a request as malformed, such as a
+// non-positive amount, rather than for the state of the data it names.
+func IsInvalid(err error) bool {
+	var invalid *invalidError
+	return errors.As(err, &invalid)
+}
+
+// outcome classifies err for the transfer and deposit metrics.
+func outcome(err error) string {
+	var invalid *invalidError
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.Is(err, ErrTransferHeld):
+		return metrics.OutcomeHeld
+	case errors.Is(err, ErrApprovalRequired):
+		return metrics.OutcomeAwaitingApproval
+	case errors.Is(err, ErrTransferDenied):
+		return metrics.OutcomeDenied
+	case errors.Is(err, ErrInsufficientBalance):
+		return metrics.OutcomeInsufficientBalance
+	case errors.Is(err, ErrWalletFrozen),
+		errors.Is(err, ErrPocketWallet),
+		errors.Is(err, ErrNotWalletMember),
+		errors.Is(err, ErrWalletRoleForbidden),
+		errors.Is(err, ErrUserDeactivated),
+		errors.Is(err, ErrScreeningPending),
+		errors.Is(err, ErrScreeningBlocked):
+		return metrics.OutcomeInvalid
+	case errors.Is(err, ErrKYCLimitExceeded), errors.Is(err, ErrSpendingCapExceeded):
+		return metrics.OutcomeLimitExceeded
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return metrics.OutcomeNotFound
+	case errors.As(err, &invalid):
+		return metrics.OutcomeInvalid
+	default:
+		return metrics.OutcomeError
+	}
 }
@@ -1,35 +1,352 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"log/slog"
+	"strings"
+	"time"
 
+	"wallet-api/logging"
 	"wallet-api/models"
 	"wallet-api/repositories"
+	"wallet-api/sanctions"
+	"wallet-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrEmailInUse is returned when a user is created with, or changes to, an
+// email another user holds.
+var ErrEmailInUse = errors.New("email already in use")
+
+// ErrUserDeactivated is returned when a deactivated user's wallet is asked to
+// move money, and when a deactivated user is deactivated again.
+var ErrUserDeactivated = errors.New("user is deactivated")
+
+// ErrUserActive is returned when an active user is reactivated.
+var ErrUserActive = errors.New("user is not deactivated")
+
+// DefaultPageSize and MaxPageSize bound the pages of a user listing.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
 )
 
 type UserServiceInterface interface {
-	Create(user *models.User) error
-	GetByID(id uint) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	Update(ctx context.Context, id uint, update UserUpdate) (*models.User, error)
+	List(ctx context.Context, filter repositories.UserFilter, page, pageSize int) (*models.UserPage, error)
+	Deactivate(ctx context.Context, id uint, reason string) (*models.User, error)
+	Reactivate(ctx context.Context, id uint) (*models.User, error)
+}
+
+// UserUpdate lists the profile fields to change; nil fields are left alone.
+type UserUpdate struct {
+	Name  *string
+	Email *string
 }
 
 type UserService struct {
 	userRepo *repositories.UserRepository
+	db       *gorm.DB
+	screener *sanctions.Screener
+	verifier EmailVerifier
 }
 
-func NewUserService(userRepo *repositories.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+// NewUserService returns a UserService. New users' names are screened with
+// screener, and new or changed addresses are sent a verification through
+// verifier; nil turns either off.
+func NewUserService(userRepo *repositories.UserRepository, db *gorm.DB, screener *sanctions.Screener, verifier EmailVerifier) *UserService {
+	return &UserService{
+		userRepo: userRepo,
+		db:       db,
+		screener: screener,
+		verifier: verifier,
+	}
 }
 
-func (s *UserService) Create(user *models.User) error {
+func (s *UserService) Create(ctx context.Context, user *models.User) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Create")
+	defer tracing.End(span, &err)
+	userRepo := s.userRepo.WithContext(ctx)
+
 	// Check if email already exists
-	existingUser, err := s.userRepo.GetByEmail(user.Email)
+	existingUser, err := userRepo.GetByEmail(user.Email)
 	if err == nil && existingUser != nil {
-		return errors.New("email already in use")
+		return ErrEmailInUse
 	}
 
-	return s.userRepo.Create(user)
+	// Users start unverified and active; levels are only raised by
+	// KYCService
+	user.KYCLevel = models.KYCLevelNone
+	user.ScreeningStatus = models.ScreeningStatusClear
+	user.ErasedAt = nil
+	user.EmailVerifiedAt = nil
+	user.DeactivatedAt = nil
+	user.DeactivationReason = ""
+	// The tenant is the request's, not one the client names
+	user.TenantID = 0
+
+	if s.screener == nil {
+		err = userRepo.Create(user)
+	} else {
+		err = s.createScreened(ctx, user)
+	}
+	if err != nil {
+		return err
+	}
+	s.sendVerification(ctx, user)
+	return nil
 }
 
-func (s *UserService) GetByID(id uint) (*models.User, error) {
-	return s.userRepo.GetByID(id)
-}
\ No newline at end of file
+// sendVerification sends the user a link to verify their address. A failure
+// is logged rather than returned: the user exists either way and can ask for
+// the link again.
+func (s *UserService) sendVerification(ctx context.Context, user *models.User) {
+	if s.verifier == nil {
+		return
+	}
+	if err := s.verifier.SendVerification(ctx, user); err != nil {
+		logging.For("services").ErrorContext(ctx, "email verification not sent",
+			slog.Uint64("user_id", uint64(user.ID)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// createScreened creates the user and, if their name matches a sanctions
+// list, records the hits and leaves the user pending until they are
+// reviewed.
+func (s *UserService) createScreened(ctx context.Context, user *models.User) error {
+	var hits []models.ScreeningHit
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		hits, err = screenName(tx, s.screener, 0, user.Name, models.ScreeningSubjectUser)
+		if err != nil {
+			return err
+		}
+		if len(hits) > 0 {
+			user.ScreeningStatus = models.ScreeningStatusPending
+		}
+		if err := repositories.NewUserRepository(tx).Create(user); err != nil {
+			return err
+		}
+		if len(hits) == 0 {
+			return nil
+		}
+		for i := range hits {
+			hits[i].UserID = user.ID
+		}
+		return repositories.NewScreeningHitRepository(tx).Create(hits)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(hits) > 0 {
+		logging.For("services").WarnContext(ctx, "user pending sanctions review",
+			slog.Uint64("user_id", uint64(user.ID)),
+			slog.Int("hits", len(hits)),
+		)
+	}
+	return nil
+}
+
+func (s *UserService) GetByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByID")
+	defer tracing.End(span, &err)
+
+	return s.userRepo.WithContext(ctx).GetByID(id)
+}
+
+// Update changes the user's name and email. A new email must not be held by
+// another user; a new name is screened against the sanctions lists like a
+// new user's, leaving the user pending if it matches.
+func (s *UserService) Update(ctx context.Context, id uint, update UserUpdate) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Update", attribute.Int64("user.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	var user *models.User
+	var hits []models.ScreeningHit
+	var emailChanged bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		userRepo := repositories.NewUserRepository(tx)
+
+		var err error
+		user, err = userRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if update.Name != nil {
+			name := strings.TrimSpace(*update.Name)
+			if name == "" {
+				return errors.New("name must not be empty")
+			}
+			if name != user.Name && s.screener != nil {
+				hits, err = screenName(tx, s.screener, user.ID, name, models.ScreeningSubjectUser)
+				if err != nil {
+					return err
+				}
+				if len(hits) > 0 {
+					if err := repositories.NewScreeningHitRepository(tx).Create(hits); err != nil {
+						return err
+					}
+					if user.ScreeningStatus == models.ScreeningStatusClear {
+						user.ScreeningStatus = models.ScreeningStatusPending
+					}
+				}
+			}
+			user.Name = name
+		}
+
+		if update.Email != nil {
+			email := strings.TrimSpace(*update.Email)
+			if email == "" {
+				return errors.New("email must not be empty")
+			}
+			if email != user.Email {
+				taken, err := userRepo.EmailTaken(email, user.ID)
+				if err != nil {
+					return err
+				}
+				if taken {
+					return ErrEmailInUse
+				}
+				// The new address has to be verified afresh.
+				emailChanged = true
+				user.EmailVerifiedAt = nil
+			}
+			user.Email = email
+		}
+
+		return userRepo.Update(user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.For("services").InfoContext(ctx, "user updated",
+		slog.Uint64("user_id", uint64(id)),
+		slog.Bool("name_changed", update.Name != nil),
+		slog.Bool("email_changed", update.Email != nil),
+	)
+	if len(hits) > 0 {
+		logging.For("services").WarnContext(ctx, "user pending sanctions review",
+			slog.Uint64("user_id", uint64(id)),
+			slog.Int("hits", len(hits)),
+		)
+	}
+	if emailChanged {
+		s.sendVerification(ctx, user)
+	}
+	return user, nil
+}
+
+// List returns a page of the users matching filter, oldest first. Pages are
+// numbered from 1; pageSize defaults to DefaultPageSize and is capped at
+// MaxPageSize.
+func (s *UserService) List(ctx context.Context, filter repositories.UserFilter, page, pageSize int) (_ *models.UserPage, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.List")
+	defer tracing.End(span, &err)
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	users, total, err := s.userRepo.WithContext(ctx).List(filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+	return &models.UserPage{Users: users, Total: total, Page: page, PageSize: pageSize}, nil
+}
+
+// Deactivate stops the user's wallets from sending or receiving money until
+// Reactivate. Wallets are not frozen themselves, so an operator's freeze of
+// a single wallet survives reactivation. reason is required.
+func (s *UserService) Deactivate(ctx context.Context, id uint, reason string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Deactivate", attribute.Int64("user.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to deactivate a user")
+	}
+
+	now := time.Now()
+	user, err := s.setDeactivated(ctx, id, &now, reason)
+	if err != nil {
+		return nil, err
+	}
+	logging.For("services").InfoContext(ctx, "user deactivated",
+		slog.Uint64("user_id", uint64(id)),
+		slog.String("reason", reason),
+		slog.String("actor", actor(ctx, "operator")),
+	)
+	return user, nil
+}
+
+// Reactivate lets a deactivated user's wallets transact again.
+func (s *UserService) Reactivate(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Reactivate", attribute.Int64("user.id", int64(id)))
+	defer tracing.End(span, &err)
+
+	user, err := s.setDeactivated(ctx, id, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	logging.For("services").InfoContext(ctx, "user reactivated",
+		slog.Uint64("user_id", uint64(id)),
+		slog.String("actor", actor(ctx, "operator")),
+	)
+	return user, nil
+}
+
+func (s *UserService) setDeactivated(ctx context.Context, id uint, at *time.Time, reason string) (*models.User, error) {
+	var user *models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		userRepo := repositories.NewUserRepository(tx)
+
+		var err error
+		user, err = userRepo.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		switch {
+		case at != nil && !user.Active():
+			return ErrUserDeactivated
+		case at == nil && user.Active():
+			return ErrUserActive
+		}
+
+		if err := userRepo.SetDeactivated(id, at, reason); err != nil {
+			return err
+		}
+		user.DeactivatedAt = at
+		user.DeactivationReason = reason
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkUser rejects a user whose wallets may not move money: one who is
+// deactivated or held up by sanctions screening.
+func checkUser(user *models.User) error {
+	if !user.Active() {
+		return ErrUserDeactivated
+	}
+	return checkScreening(user)
+}
@@ -1,45 +1,148 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"wallet-api/logging"
 	"wallet-api/models"
 	"wallet-api/repositories"
+	"wallet-api/tracing"
+
+	"gorm.io/gorm"
 )
 
+// ErrUnsupportedCurrency is returned when a wallet asks for a currency its
+// tenant does not offer.
+var ErrUnsupportedCurrency = errors.New("currency not supported by the tenant")
+
 type IWalletService interface {
-	Create(wallet *models.Wallet) error
-	GetByID(id uint) (*models.Wallet, error)
-	GetByUserID(userID uint) ([]models.Wallet, error)
+	Create(ctx context.Context, wallet *models.Wallet) error
+	GetByID(ctx context.Context, id uint) (*models.Wallet, error)
+	GetByUserID(ctx context.Context, userID uint) ([]models.Wallet, error)
 }
 
 type WalletService struct {
 	walletRepo *repositories.WalletRepository
 	userRepo   *repositories.UserRepository
+	db         *gorm.DB
 }
 
 func NewWalletService(
 	walletRepo *repositories.WalletRepository,
 	userRepo *repositories.UserRepository,
+	db *gorm.DB,
 ) *WalletService {
 	return &WalletService{
 		walletRepo: walletRepo,
 		userRepo:   userRepo,
+		db:         db,
 	}
 }
 
-func (s *WalletService) Create(wallet *models.Wallet) error {
-	// Verify user exists
-	_, err := s.userRepo.GetByID(wallet.UserID)
-	if err != nil {
-		return err
+func (s *WalletService) Create(ctx context.Context, wallet *models.Wallet) (err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.Create")
+	defer tracing.End(span, &err)
+
+	// Only operators freeze wallets, pockets are opened through
+	// PocketService and approval rules are set through MembershipService
+	wallet.FrozenAt = nil
+	wallet.FrozenReason = ""
+	wallet.ParentID = nil
+	wallet.Name = ""
+	wallet.TargetAmount = nil
+	wallet.TargetDate = nil
+	wallet.ApprovalThreshold = nil
+	wallet.RequiredApprovals = 0
+
+	// The owner's row stays locked until the wallet exists, so a user being
+	// deleted, erased or deactivated concurrently cannot end up with a new
+	// wallet. Deleted users are not found.
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		user, err := repositories.NewUserRepository(tx).GetForUpdate(wallet.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkUser(user); err != nil {
+			return err
+		}
+		// The wallet belongs to its owner's tenant and holds one of the
+		// tenant's currencies, its default unless the client asked for
+		// another
+		tenant, err := repositories.NewTenantRepository(tx).GetByID(user.TenantID)
+		if err != nil {
+			return err
+		}
+		wallet.TenantID = user.TenantID
+		wallet.Currency = strings.ToUpper(strings.TrimSpace(wallet.Currency))
+		if wallet.Currency == "" {
+			wallet.Currency = tenant.DefaultCurrency()
+		}
+		if !tenant.Supports(wallet.Currency) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedCurrency, wallet.Currency)
+		}
+		return repositories.NewWalletRepository(tx).Create(wallet)
+	})
+}
+
+// GetByID returns the wallet. A parent wallet comes with its pockets and its
+// total balance including theirs.
+func (s *WalletService) GetByID(ctx context.Context, id uint) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.GetByID")
+	defer tracing.End(span, &err)
+
+	wallet, pockets, err := s.walletRepo.WithContext(ctx).GetWithPockets(id)
+	if err != nil || wallet.IsPocket() {
+		return wallet, err
+	}
+	wallet.Pockets = pockets
+	total := wallet.Balance
+	for _, pocket := range wallet.Pockets {
+		total += pocket.Balance
 	}
+	wallet.TotalBalance = &total
+	return wallet, nil
+}
+
+func (s *WalletService) GetByUserID(ctx context.Context, userID uint) (_ []models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.GetByUserID")
+	defer tracing.End(span, &err)
 
-	return s.walletRepo.Create(wallet)
+	return s.walletRepo.WithContext(ctx).GetByUserID(userID)
 }
 
-func (s *WalletService) GetByID(id uint) (*models.Wallet, error) {
-	return s.walletRepo.GetByID(id)
+// Freeze stops the wallet from sending or receiving money until Unfreeze.
+// reason is required and recorded on the wallet and in the audit log.
+func (s *WalletService) Freeze(ctx context.Context, id uint, reason string) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.Freeze")
+	defer tracing.End(span, &err)
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to freeze a wallet")
+	}
+
+	now := time.Now()
+	if err := s.walletRepo.WithContext(ctx).SetFrozen(id, &now, reason); err != nil {
+		return nil, err
+	}
+	logging.For("services").InfoContext(ctx, "wallet frozen",
+		slog.Uint64("wallet_id", uint64(id)), slog.String("reason", reason))
+	return s.walletRepo.WithContext(ctx).GetByID(id)
 }
 
-func (s *WalletService) GetByUserID(userID uint) ([]models.Wallet, error) {
-	return s.walletRepo.GetByUserID(userID)
-}
\ No newline at end of file
+// Unfreeze lifts a freeze.
+func (s *WalletService) Unfreeze(ctx context.Context, id uint) (_ *models.Wallet, err error) {
+	ctx, span := tracing.Start(ctx, "WalletService.Unfreeze")
+	defer tracing.End(span, &err)
+
+	if err := s.walletRepo.WithContext(ctx).SetFrozen(id, nil, ""); err != nil {
+		return nil, err
+	}
+	logging.For("services").InfoContext(ctx, "wallet unfrozen", slog.Uint64("wallet_id", uint64(id)))
+	return s.walletRepo.WithContext(ctx).GetByID(id)
+}
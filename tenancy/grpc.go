@@ -0,0 +1,67 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor puts the tenant named by the x-tenant-id metadata
+// into the context of each unary call, the gRPC counterpart of Middleware.
+// It goes after the auth interceptor.
+func (r *Resolver) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := r.resolveCall(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func (r *Resolver) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := r.resolveCall(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (r *Resolver) resolveCall(ctx context.Context) (context.Context, error) {
+	var value string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(strings.ToLower(Header)); len(values) > 0 {
+			value = values[0]
+		}
+	}
+	id, err := r.Resolve(ctx, value)
+	switch {
+	case errors.Is(err, ErrTenantRequired), errors.Is(err, ErrUnknownTenant):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrKeyRequired):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrTenantForbidden):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, "could not resolve tenant")
+	}
+	return WithTenant(ctx, id), nil
+}
+
+// tenantStream is a ServerStream whose context names the tenant.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,121 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Header names the tenant of an HTTP request. gRPC calls use the same name
+// in lower case as metadata.
+const Header = "X-Tenant-ID"
+
+var (
+	// ErrTenantRequired is returned for requests that name no tenant when
+	// the default tenant is not used for them.
+	ErrTenantRequired = errors.New("the " + Header + " header is required")
+	// ErrUnknownTenant is returned for requests naming a tenant that does
+	// not exist.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrTenantForbidden is returned when an API key names a tenant other
+	// than its own.
+	ErrTenantForbidden = errors.New("the API key belongs to another tenant")
+	// ErrKeyRequired is returned for anonymous requests naming a tenant
+	// other than the default one.
+	ErrKeyRequired = errors.New("an API key is required to act in this tenant")
+)
+
+// Resolver finds the tenant a request names.
+type Resolver struct {
+	db       *gorm.DB
+	required bool
+}
+
+// NewResolver returns a Resolver looking tenants up in db. Unless required,
+// requests that name no tenant belong to the default tenant.
+func NewResolver(db *gorm.DB, required bool) *Resolver {
+	return &Resolver{db: db, required: required}
+}
+
+// Resolve returns the ID of the tenant named by value, the raw header, for
+// the principal of ctx. Keys act in their own tenant unless an admin key
+// names another; see Authorize.
+func (r *Resolver) Resolve(ctx context.Context, value string) (uint, error) {
+	p, authenticated := auth.FromContext(ctx)
+	if value == "" {
+		switch {
+		case authenticated:
+			return p.TenantID, nil
+		case r.required:
+			return 0, ErrTenantRequired
+		}
+		return models.DefaultTenantID, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, ErrUnknownTenant
+	}
+	if err := Authorize(ctx, uint(id)); err != nil {
+		return 0, err
+	}
+	if !authenticated || p.Role != models.APIKeyRoleAdmin {
+		// Only the key's own tenant, or the default one, got this far.
+		return uint(id), nil
+	}
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Tenant{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, ErrUnknownTenant
+	}
+	return uint(id), nil
+}
+
+// Authorize checks that the principal of ctx may act in the tenant: admin
+// keys in any tenant, other keys in their own and anonymous requests in the
+// default tenant only.
+func Authorize(ctx context.Context, tenantID uint) error {
+	p, ok := auth.FromContext(ctx)
+	switch {
+	case !ok && tenantID != models.DefaultTenantID:
+		return ErrKeyRequired
+	case ok && p.Role != models.APIKeyRoleAdmin && p.TenantID != tenantID:
+		return ErrTenantForbidden
+	}
+	return nil
+}
+
+// Middleware puts the tenant named by the X-Tenant-ID header into the
+// request context, answering 400 when it names none that can be used, 401
+// when an anonymous request names another than the default tenant and 403
+// when a key names another than its own.
+func (r *Resolver) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		id, err := r.Resolve(ctx, c.GetHeader(Header))
+		switch {
+		case errors.Is(err, ErrTenantRequired), errors.Is(err, ErrUnknownTenant):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, ErrKeyRequired):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, ErrTenantForbidden):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not resolve tenant"})
+			return
+		}
+		c.Request = c.Request.WithContext(WithTenant(ctx, id))
+		c.Next()
+	}
+}
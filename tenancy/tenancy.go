@@ -0,0 +1,136 @@
+// Package tenancy keeps the data of the organizations the API is run for
+// apart. Requests carry their tenant in the context; a GORM plugin then
+// limits every statement run with that context to the tenant's rows and
+// stamps the tenant on every row it creates, so repositories cannot read or
+// write across tenants by accident.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Column is the column that ties a row to its tenant. Models without it,
+// such as the tenants themselves, are shared.
+const Column = "tenant_id"
+
+// ErrTenantMismatch is returned when a row created for one tenant names
+// another.
+var ErrTenantMismatch = errors.New("record belongs to another tenant")
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose queries are limited to the tenant.
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext returns the tenant of ctx. Without one, statements are not
+// limited, which is how background jobs and operators see every tenant.
+func FromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(tenantKey{}).(uint)
+	return id, ok
+}
+
+// Scope limits a statement on a tenant-owned model to the tenant's rows. It
+// is what the plugin applies, and can be passed to Scopes directly when a
+// statement must be limited to a tenant other than its context's.
+func Scope(tenantID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenantField(db.Statement) == nil {
+			return db
+		}
+		return db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: Column},
+			Value:  tenantID,
+		})
+	}
+}
+
+// tenantField returns the tenant field of the statement's model, or nil
+// for shared models and statements without one.
+func tenantField(stmt *gorm.Statement) *schema.Field {
+	if stmt.Schema == nil {
+		model := stmt.Model
+		if model == nil {
+			model = stmt.Dest
+		}
+		if model == nil || stmt.Parse(model) != nil {
+			return nil
+		}
+	}
+	return stmt.Schema.LookUpField(Column)
+}
+
+// GormPlugin applies Scope to every query, update and delete whose context
+// names a tenant, and assigns that tenant to every row created with it.
+type GormPlugin struct{}
+
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+func (p *GormPlugin) Name() string {
+	return "tenancy"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("tenancy:assign", assign); err != nil {
+		return err
+	}
+	for _, register := range []func(name string, fn func(*gorm.DB)) error{
+		cb.Query().Before("gorm:query").Register,
+		cb.Update().Before("gorm:update").Register,
+		cb.Delete().Before("gorm:delete").Register,
+		cb.Row().Before("gorm:row").Register,
+	} {
+		if err := register("tenancy:scope", scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scope(db *gorm.DB) {
+	if id, ok := FromContext(db.Statement.Context); ok {
+		Scope(id)(db)
+	}
+}
+
+// assign sets the tenant of ctx on rows that have none, and refuses rows
+// that already name another tenant.
+func assign(db *gorm.DB) {
+	id, ok := FromContext(db.Statement.Context)
+	if !ok {
+		return
+	}
+	field := tenantField(db.Statement)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	set := func(row reflect.Value) {
+		value, zero := field.ValueOf(ctx, row)
+		switch {
+		case zero:
+			db.AddError(field.Set(ctx, row, id))
+		case value != id:
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			set(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		set(rv)
+	}
+}
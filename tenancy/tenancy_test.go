@@ -0,0 +1,234 @@
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/auth"
+	"wallet-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupDB returns a database with the plugin installed. DryRun builds every
+// statement and runs the callbacks without a server; writes would still
+// open a transaction on one without SkipDefaultTransaction.
+func setupDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.Open("host=localhost dbname=wallet_db"), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+	return db
+}
+
+func TestGormPlugin_Scope(t *testing.T) {
+	db := setupDB(t)
+	ctx := WithTenant(context.Background(), 2)
+
+	t.Run("query", func(t *testing.T) {
+		var wallet models.Wallet
+		stmt := db.WithContext(ctx).First(&wallet, 42).Statement
+		assert.Contains(t, stmt.SQL.String(), `"wallets"."tenant_id" = $`)
+		assert.Contains(t, stmt.Vars, uint(2))
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		stmt := db.WithContext(ctx).Model(&models.Wallet{ID: 42}).Update("balance", 5).Statement
+		assert.Contains(t, stmt.SQL.String(), `"wallets"."tenant_id" = $`)
+
+		stmt = db.WithContext(ctx).Delete(&models.WalletMember{}, 7).Statement
+		assert.Contains(t, stmt.SQL.String(), `"wallet_members"."tenant_id" = $`)
+	})
+
+	t.Run("subquery", func(t *testing.T) {
+		scoped := db.WithContext(ctx)
+		var transactions []models.Transaction
+		stmt := scoped.Where("source_wallet_id IN (?)", scoped.Model(&models.Wallet{}).Select("id").Where("user_id = ?", 3)).
+			Find(&transactions).Statement
+		assert.Contains(t, stmt.SQL.String(), `"transactions"."tenant_id" = $`)
+		assert.Contains(t, stmt.SQL.String(), `"wallets"."tenant_id" = $`)
+	})
+
+	t.Run("shared models", func(t *testing.T) {
+		var tenants []models.Tenant
+		stmt := db.WithContext(ctx).Find(&tenants).Statement
+		assert.NotContains(t, stmt.SQL.String(), Column)
+	})
+
+	t.Run("no tenant", func(t *testing.T) {
+		var wallet models.Wallet
+		stmt := db.WithContext(context.Background()).First(&wallet, 42).Statement
+		assert.NotContains(t, stmt.SQL.String(), Column)
+	})
+
+	t.Run("explicit scope", func(t *testing.T) {
+		var wallet models.Wallet
+		stmt := db.Scopes(Scope(3)).First(&wallet, 42).Statement
+		assert.Contains(t, stmt.SQL.String(), `"wallets"."tenant_id" = $`)
+		assert.Contains(t, stmt.Vars, uint(3))
+	})
+}
+
+func TestGormPlugin_Assign(t *testing.T) {
+	db := setupDB(t)
+	ctx := WithTenant(context.Background(), 2)
+
+	t.Run("single row", func(t *testing.T) {
+		transaction := models.Transaction{TargetWalletID: 42, Amount: 100, ReferenceNumber: "DEP-1"}
+		require.NoError(t, db.WithContext(ctx).Create(&transaction).Error)
+		assert.Equal(t, uint(2), transaction.TenantID)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		hits := []models.ScreeningHit{{UserID: 1}, {UserID: 1, TenantID: 2}}
+		require.NoError(t, db.WithContext(ctx).Create(&hits).Error)
+		assert.Equal(t, uint(2), hits[0].TenantID)
+		assert.Equal(t, uint(2), hits[1].TenantID)
+	})
+
+	t.Run("another tenant", func(t *testing.T) {
+		wallet := models.Wallet{TenantID: 3, UserID: 1}
+		assert.ErrorIs(t, db.WithContext(ctx).Create(&wallet).Error, ErrTenantMismatch)
+
+		hits := []models.ScreeningHit{{UserID: 1}, {UserID: 1, TenantID: 3}}
+		assert.ErrorIs(t, db.WithContext(ctx).Create(&hits).Error, ErrTenantMismatch)
+	})
+
+	t.Run("no tenant", func(t *testing.T) {
+		wallet := models.Wallet{UserID: 1}
+		require.NoError(t, db.Create(&wallet).Error)
+		assert.Equal(t, models.DefaultTenantID, wallet.TenantID, "the column default")
+	})
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	id, err := NewResolver(nil, false).Resolve(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultTenantID, id)
+
+	_, err = NewResolver(nil, true).Resolve(ctx, "")
+	assert.ErrorIs(t, err, ErrTenantRequired)
+
+	for _, value := range []string{"acme", "0", "-1"} {
+		_, err = NewResolver(nil, false).Resolve(ctx, value)
+		assert.ErrorIs(t, err, ErrUnknownTenant, value)
+	}
+
+	// Anonymous requests stay in the default tenant
+	id, err = NewResolver(nil, false).Resolve(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultTenantID, id)
+	_, err = NewResolver(nil, false).Resolve(ctx, "2")
+	assert.ErrorIs(t, err, ErrKeyRequired)
+}
+
+func TestResolver_ResolvePrincipal(t *testing.T) {
+	user := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: 1, Role: models.APIKeyRoleUser, TenantID: 2})
+
+	// Keys act in their own tenant, named or not, even where a tenant is
+	// required
+	for _, value := range []string{"", "2"} {
+		id, err := NewResolver(nil, true).Resolve(user, value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, uint(2), id, value)
+	}
+	for _, value := range []string{"1", "3"} {
+		_, err := NewResolver(nil, false).Resolve(user, value)
+		assert.ErrorIs(t, err, ErrTenantForbidden, value)
+	}
+
+	operator := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: 2, Role: models.APIKeyRoleOperator, TenantID: 2})
+	_, err := NewResolver(nil, false).Resolve(operator, "3")
+	assert.ErrorIs(t, err, ErrTenantForbidden)
+
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: 3, Role: models.APIKeyRoleAdmin, TenantID: 2})
+	id, err := NewResolver(nil, true).Resolve(admin, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), id, "admins default to their own tenant")
+	assert.NoError(t, Authorize(admin, 3), "admins may act in any tenant")
+}
+
+func TestResolver_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(resolver *Resolver, header string) (*httptest.ResponseRecorder, uint) {
+		var tenant uint
+		router := gin.New()
+		router.GET("/", resolver.Middleware(), func(c *gin.Context) {
+			tenant, _ = FromContext(c.Request.Context())
+			c.Status(http.StatusNoContent)
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(Header, header)
+		}
+		router.ServeHTTP(w, req)
+		return w, tenant
+	}
+
+	t.Run("default tenant", func(t *testing.T) {
+		w, tenant := serve(NewResolver(nil, false), "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, models.DefaultTenantID, tenant)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		w, _ := serve(NewResolver(nil, true), "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w, _ := serve(NewResolver(nil, false), "acme")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("anonymous in another tenant", func(t *testing.T) {
+		w, _ := serve(NewResolver(nil, false), "2")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestResolver_UnaryServerInterceptor(t *testing.T) {
+	call := func(resolver *Resolver, ctx context.Context) (uint, error) {
+		var tenant uint
+		_, err := resolver.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{},
+			func(ctx context.Context, _ any) (any, error) {
+				tenant, _ = FromContext(ctx)
+				return nil, nil
+			})
+		return tenant, err
+	}
+
+	tenant, err := call(NewResolver(nil, false), context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultTenantID, tenant)
+
+	_, err = call(NewResolver(nil, true), context.Background())
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant-id", "acme"))
+	_, err = call(NewResolver(nil, false), ctx)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant-id", "2"))
+	_, err = call(NewResolver(nil, false), ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(NewResolver(nil, false), auth.WithPrincipal(ctx, auth.Principal{KeyID: 1, Role: models.APIKeyRoleUser, TenantID: 3}))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
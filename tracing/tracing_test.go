@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// setupRecorder installs a tracer provider that keeps finished spans in
+// memory for the duration of the test.
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, s := range spans {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware("wallet-api"))
+	router.GET("/wallets/:id", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "WalletService.GetByID")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	t.Run("continues incoming traceparent", func(t *testing.T) {
+		recorder.Reset()
+
+		req, _ := http.NewRequest(http.MethodGet, "/wallets/7", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		server := spanNamed(spans, "/wallets/:id")
+		require.NotNil(t, server, "server span is named after the route template")
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.True(t, server.Parent().IsRemote())
+
+		child := spanNamed(spans, "WalletService.GetByID")
+		require.NotNil(t, child)
+		assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	})
+
+	t.Run("starts a new trace without traceparent", func(t *testing.T) {
+		recorder.Reset()
+
+		req, _ := http.NewRequest(http.MethodGet, "/wallets/7", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		server := spanNamed(recorder.Ended(), "/wallets/:id")
+		require.NotNil(t, server)
+		assert.False(t, server.Parent().IsValid())
+	})
+
+	t.Run("probes are not traced", func(t *testing.T) {
+		recorder.Reset()
+
+		req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Empty(t, recorder.Ended())
+	})
+}
+
+func TestEnd(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	func() (err error) {
+		_, span := Start(context.Background(), "TransferService.Transfer")
+		defer End(span, &err)
+		return errors.New("insufficient balance")
+	}()
+	func() (err error) {
+		_, span := Start(context.Background(), "TransferService.Deposit")
+		defer End(span, &err)
+		return nil
+	}()
+
+	spans := recorder.Ended()
+	failed := spanNamed(spans, "TransferService.Transfer")
+	require.NotNil(t, failed)
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, "insufficient balance", failed.Status().Description)
+	assert.Len(t, failed.Events(), 1, "the error is recorded as an event")
+
+	ok := spanNamed(spans, "TransferService.Deposit")
+	require.NotNil(t, ok)
+	assert.Equal(t, codes.Unset, ok.Status().Code)
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	// DryRun builds every statement and runs the callbacks without a server.
+	db, err := gorm.Open(postgres.Open("host=localhost dbname=wallet_db"), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+
+	ctx, parent := Start(context.Background(), "TransferService.Transfer")
+	var wallet models.Wallet
+	db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, 42)
+	db.WithContext(ctx).Create(&models.Transaction{TargetWalletID: 42, Amount: 100, ReferenceNumber: "DEP-1"})
+	parent.End()
+
+	spans := recorder.Ended()
+
+	query := spanNamed(spans, "gorm.query")
+	require.NotNil(t, query)
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Equal(t, "wallets", attr(query, "db.collection.name").AsString())
+	assert.Contains(t, attr(query, "db.query.text").AsString(), "FOR UPDATE")
+	assert.Contains(t, attr(query, "db.query.text").AsString(), "$1")
+
+	create := spanNamed(spans, "gorm.create")
+	require.NotNil(t, create)
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.NotContains(t, attr(create, "db.query.text").AsString(), "DEP-1", "bind values are never recorded")
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "placeholders are kept",
+			sql:  `SELECT * FROM "wallets" WHERE "wallets"."id" = $1 LIMIT $2 FOR UPDATE`,
+			want: `SELECT * FROM "wallets" WHERE "wallets"."id" = $1 LIMIT $2 FOR UPDATE`,
+		},
+		{
+			name: "string literals",
+			sql:  `UPDATE users SET email = 'john@example.com', name = 'O''Brien'`,
+			want: `UPDATE users SET email = ?, name = ?`,
+		},
+		{
+			name: "numeric literals",
+			sql:  "UPDATE wallets SET balance = balance + 50 WHERE id = 12 AND rate > 0.5",
+			want: "UPDATE wallets SET balance = balance + ? WHERE id = ? AND rate > ?",
+		},
+		{
+			name: "identifiers with digits",
+			sql:  "SELECT col1 FROM table2",
+			want: "SELECT col1 FROM table2",
+		},
+		{
+			name: "whitespace",
+			sql:  "SELECT *\n\t FROM  wallets",
+			want: "SELECT * FROM wallets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeSQL(tt.sql))
+		})
+	}
+}